@@ -163,6 +163,7 @@ export const STATUS_RESPONSE = {
     YES: 'yes',
     NO: 'no',
     ERROR: 'error',
+    UNSUPPORTED: 'unsupported',
 };
 
 export const MODAL_TYPE = {
@@ -334,17 +334,19 @@ class Settings extends Component {
                     </div>
                 </div>
 
-                <div className="setup__group">
-                    <div className="setup__subtitle">
-                        <Trans>static_ip</Trans>
-                    </div>
+                {staticIp.static !== STATUS_RESPONSE.UNSUPPORTED && (
+                    <div className="setup__group">
+                        <div className="setup__subtitle">
+                            <Trans>static_ip</Trans>
+                        </div>
 
-                    <div className="mb-2">
-                        <Trans>static_ip_desc</Trans>
-                    </div>
+                        <div className="mb-2">
+                            <Trans>static_ip_desc</Trans>
+                        </div>
 
-                    {this.getStaticIpMessage(staticIp)}
-                </div>
+                        {this.getStaticIpMessage(staticIp)}
+                    </div>
+                )}
 
                 <Controls invalid={invalid} />
             </form>
@@ -0,0 +1,108 @@
+package aghnet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// ErrAddrInUse is returned by IfaceSetStaticIP when the address of the
+// interface is already used by another host on the LAN.
+const ErrAddrInUse errors.Error = "address is in use by another host"
+
+// addrProbeTimeout is the time IfaceSetStaticIP waits for the responses to the
+// address probes.
+const addrProbeTimeout = 1 * time.Second
+
+// AddrInUseOnLAN returns true if some other host on the LAN of the network
+// interface named ifaceName responds for ip within timeout.  For IPv4, an ARP
+// probe is sent, see RFC 5227, and for IPv6, a Neighbor Solicitation is.  Both
+// require raw sockets, which usually means CAP_NET_RAW on Linux, and are only
+// supported there.  If those aren't available, ip is pinged instead, which is
+// the best effort since the host may ignore the pings.  The responses from the
+// interface itself are ignored, but ip can't be pinged if it's local.
+func AddrInUseOnLAN(ifaceName string, ip netip.Addr, timeout time.Duration) (inUse bool, err error) {
+	defer func() { err = errors.Annotate(err, "probing %s on %s: %w", ip, ifaceName) }()
+
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return false, err
+	}
+
+	ip = CanonicalAddr(ip)
+	if !ip.IsValid() {
+		return false, errors.Error("bad ip address")
+	}
+
+	inUse, err = probeAddr(iface, ip, timeout)
+	if !errors.Is(err, os.ErrPermission) && !errors.As(err, new(*aghos.UnsupportedError)) {
+		return inUse, err
+	}
+
+	currentLogger().Debug("probing %s: %s; pinging instead", ip, err)
+
+	return pingInUse(iface, ip, timeout)
+}
+
+// pingInUse returns true if ip on iface responds to pings within timeout.
+func pingInUse(iface *net.Interface, ip netip.Addr, timeout time.Duration) (inUse bool, err error) {
+	isLocal, err := IsLocalAddr(ip.AsSlice())
+	if err != nil {
+		return false, fmt.Errorf("checking local addresses: %w", err)
+	} else if isLocal {
+		return false, errors.Error("can't ping own address")
+	}
+
+	if ip.IsLinkLocalUnicast() {
+		ip = ip.WithZone(iface.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, err = pingAddr(ctx, ip)
+
+	return err == nil, nil
+}
+
+// probeStaticIP checks if the address of ipNet is already in use by another
+// host on the LAN of the network interface named ifaceName.  Since the address
+// is assigned to the interface itself, it's never pinged, see AddrInUseOnLAN,
+// so the check is skipped if probing isn't available.  The errors of probing
+// are only logged, since those shouldn't prevent the configuration.
+func probeStaticIP(ifaceName string, ipNet *net.IPNet) (err error) {
+	if ipNet == nil {
+		return nil
+	}
+
+	ip, ok := netip.AddrFromSlice(ipNet.IP)
+	if !ok {
+		return nil
+	}
+
+	ip = CanonicalAddr(ip)
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		currentLogger().Debug("checking address before setting static ip: %s", err)
+
+		return nil
+	}
+
+	inUse, err := probeAddr(iface, ip, addrProbeTimeout)
+	if err != nil {
+		currentLogger().Debug("checking address before setting static ip: probing %s: %s", ip, err)
+
+		return nil
+	} else if inUse {
+		return fmt.Errorf("%s: %w", ip, ErrAddrInUse)
+	}
+
+	return nil
+}
@@ -0,0 +1,268 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/mdlayher/ethernet"
+	"github.com/mdlayher/raw"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+// probeAddr sends an ARP probe for the IPv4 ip or a Neighbor Solicitation for
+// the IPv6 one through iface and waits for the responses for timeout.
+func probeAddr(iface *net.Interface, ip netip.Addr, timeout time.Duration) (inUse bool, err error) {
+	if ip.Is4() {
+		return arpProbe(iface, ip, timeout)
+	}
+
+	return ndProbe(iface, ip, timeout)
+}
+
+// arpProbe sends an ARP probe for ip through iface and returns true if any
+// other host claims ip within timeout.
+func arpProbe(iface *net.Interface, ip netip.Addr, timeout time.Duration) (inUse bool, err error) {
+	frame, err := newARPProbe(iface.HardwareAddr, ip)
+	if err != nil {
+		return false, fmt.Errorf("encoding arp probe: %w", err)
+	}
+
+	c, err := raw.ListenPacket(iface, uint16(ethernet.EtherTypeARP), nil)
+	if err != nil {
+		return false, fmt.Errorf("listening: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, c.Close()) }()
+
+	err = c.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return false, fmt.Errorf("setting deadline: %w", err)
+	}
+
+	_, err = c.WriteTo(frame, &raw.Addr{HardwareAddr: layers.EthernetBroadcast})
+	if err != nil {
+		return false, fmt.Errorf("sending arp probe: %w", err)
+	}
+
+	buf := make([]byte, iface.MTU+14)
+	for {
+		var n int
+		n, _, err = c.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("reading: %w", err)
+		}
+
+		if arpClaims(buf[:n], iface.HardwareAddr, ip) {
+			return true, nil
+		}
+	}
+}
+
+// newARPProbe returns the Ethernet frame containing the ARP probe for ip sent
+// from hw.  The sender IP address of the probe is unspecified, see RFC 5227.
+func newARPProbe(hw net.HardwareAddr, ip netip.Addr) (frame []byte, err error) {
+	eth := &layers.Ethernet{
+		SrcMAC:       hw,
+		DstMAC:       layers.EthernetBroadcast,
+		EthernetType: layers.EthernetTypeARP,
+	}
+
+	arp := &layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     macLen,
+		ProtAddressSize:   net.IPv4len,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   hw,
+		SourceProtAddress: net.IPv4zero.To4(),
+		DstHwAddress:      make([]byte, macLen),
+		DstProtAddress:    ip.AsSlice(),
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	err = gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, eth, arp)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// arpClaims returns true if frame contains an ARP packet sent by a host other
+// than the one with ownHW from ip.
+func arpClaims(frame []byte, ownHW net.HardwareAddr, ip netip.Addr) (ok bool) {
+	pkt := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.NoCopy)
+	arp, ok := pkt.Layer(layers.LayerTypeARP).(*layers.ARP)
+	if !ok {
+		return false
+	}
+
+	return !bytes.Equal(arp.SourceHwAddress, ownHW) &&
+		bytes.Equal(arp.SourceProtAddress, ip.AsSlice())
+}
+
+// ndOptTargetLinkAddr is the type of the Target Link-Layer Address option of
+// the Neighbor Discovery messages, see RFC 4861.
+const ndOptTargetLinkAddr = 2
+
+// ndOptSourceLinkAddr is the type of the Source Link-Layer Address option of
+// the Neighbor Discovery messages, see RFC 4861.
+const ndOptSourceLinkAddr = 1
+
+// ndProbe sends a Neighbor Solicitation for ip through iface and returns true
+// if any other host advertises ip within timeout.
+func ndProbe(iface *net.Interface, ip netip.Addr, timeout time.Duration) (inUse bool, err error) {
+	c, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return false, fmt.Errorf("listening: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, c.Close()) }()
+
+	p := c.IPv6PacketConn()
+
+	err = p.SetControlMessage(ipv6.FlagInterface|ipv6.FlagHopLimit, true)
+	if err != nil {
+		return false, fmt.Errorf("setting control message flags: %w", err)
+	}
+
+	err = p.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return false, fmt.Errorf("setting deadline: %w", err)
+	}
+
+	b, err := newNSMsg(iface.HardwareAddr, ip)
+	if err != nil {
+		return false, fmt.Errorf("encoding neighbor solicitation: %w", err)
+	}
+
+	cm := &ipv6.ControlMessage{
+		HopLimit: ndHopLimit,
+		IfIndex:  iface.Index,
+	}
+
+	dst := &net.IPAddr{IP: solicitedNodeAddr(ip).AsSlice(), Zone: iface.Name}
+	if _, err = p.WriteTo(b, cm, dst); err != nil {
+		return false, fmt.Errorf("sending neighbor solicitation: %w", err)
+	}
+
+	return readNeighborAdvertisement(p, iface, ip)
+}
+
+// readNeighborAdvertisement reads the packets from p until a neighbor
+// advertisement for ip from another host arrives or the deadline exceeds.
+func readNeighborAdvertisement(
+	p *ipv6.PacketConn,
+	iface *net.Interface,
+	ip netip.Addr,
+) (inUse bool, err error) {
+	buf := make([]byte, 1500)
+	for {
+		var n int
+		var cm *ipv6.ControlMessage
+		n, cm, _, err = p.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return false, nil
+			}
+
+			return false, fmt.Errorf("reading: %w", err)
+		}
+
+		if cm == nil || cm.IfIndex != iface.Index || cm.HopLimit != ndHopLimit {
+			continue
+		}
+
+		if naClaims(buf[:n], iface.HardwareAddr, ip) {
+			return true, nil
+		}
+	}
+}
+
+// newNSMsg returns the ICMPv6 Neighbor Solicitation message for ip sent from
+// hw.
+func newNSMsg(hw net.HardwareAddr, ip netip.Addr) (b []byte, err error) {
+	target := ip.As16()
+
+	// The reserved field, the target address, and the source link-layer
+	// address option, which length is measured in units of 8 octets.
+	data := make([]byte, 0, 4+len(target)+2+len(hw))
+	data = append(data, 0, 0, 0, 0)
+	data = append(data, target[:]...)
+	data = append(data, ndOptSourceLinkAddr, byte((2+len(hw)+7)/8))
+	data = append(data, hw...)
+
+	msg := &icmp.Message{
+		Type: ipv6.ICMPTypeNeighborSolicitation,
+		Body: &icmp.RawBody{Data: data},
+	}
+
+	// The checksum of ICMPv6 messages is computed by the kernel.
+	return msg.Marshal(nil)
+}
+
+// naClaims returns true if b is the ICMPv6 Neighbor Advertisement for ip sent
+// by a host other than the one with ownHW.
+func naClaims(b []byte, ownHW net.HardwareAddr, ip netip.Addr) (ok bool) {
+	msg, err := icmp.ParseMessage(icmpv6Proto, b)
+	if err != nil || msg.Type != ipv6.ICMPTypeNeighborAdvertisement {
+		return false
+	}
+
+	body, ok := msg.Body.(*icmp.RawBody)
+	if !ok || len(body.Data) < 20 {
+		return false
+	}
+
+	target, ok := netip.AddrFromSlice(body.Data[4:20])
+	if !ok || target != ip {
+		return false
+	}
+
+	hw := ndOption(body.Data[20:], ndOptTargetLinkAddr)
+
+	return hw == nil || !bytes.Equal(hw, ownHW)
+}
+
+// ndOption returns the value of the first Neighbor Discovery option of typ
+// from opts or nil if there is none.
+func ndOption(opts []byte, typ byte) (val []byte) {
+	for len(opts) >= 2 {
+		l := int(opts[1]) * 8
+		if l == 0 || l > len(opts) {
+			return nil
+		}
+
+		if opts[0] == typ {
+			return opts[2:l]
+		}
+
+		opts = opts[l:]
+	}
+
+	return nil
+}
+
+// solicitedNodeAddr returns the solicited-node multicast address of ip, see
+// RFC 4291.
+func solicitedNodeAddr(ip netip.Addr) (addr netip.Addr) {
+	b := ip.As16()
+
+	return netip.AddrFrom16([16]byte{
+		0xff, 0x02, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0xff, b[13], b[14], b[15],
+	})
+}
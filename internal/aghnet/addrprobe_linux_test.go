@@ -0,0 +1,132 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+func TestARPProbe(t *testing.T) {
+	ownHW := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	otherHW := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x66}
+	ip := netip.MustParseAddr("192.168.1.2")
+
+	probe, err := newARPProbe(ownHW, ip)
+	require.NoError(t, err)
+
+	pkt := gopacket.NewPacket(probe, layers.LayerTypeEthernet, gopacket.Default)
+	arp, ok := pkt.Layer(layers.LayerTypeARP).(*layers.ARP)
+	require.True(t, ok)
+
+	assert.Equal(t, uint16(layers.ARPRequest), arp.Operation)
+	assert.Equal(t, []byte(ownHW), arp.SourceHwAddress)
+	assert.Equal(t, []byte{0, 0, 0, 0}, arp.SourceProtAddress)
+	assert.Equal(t, ip.AsSlice(), arp.DstProtAddress)
+
+	// The own probe doesn't claim the address.
+	assert.False(t, arpClaims(probe, ownHW, ip))
+
+	// Neither does the probe of another host.
+	otherProbe, err := newARPProbe(otherHW, ip)
+	require.NoError(t, err)
+
+	assert.False(t, arpClaims(otherProbe, ownHW, ip))
+
+	reply := newARPReply(t, otherHW, ip)
+	assert.True(t, arpClaims(reply, ownHW, ip))
+	assert.False(t, arpClaims(reply, otherHW, ip))
+	assert.False(t, arpClaims(reply, ownHW, netip.MustParseAddr("192.168.1.3")))
+
+	assert.False(t, arpClaims([]byte{1, 2, 3}, ownHW, ip))
+}
+
+// newARPReply is a helper that returns the Ethernet frame containing the ARP
+// reply from the host with hw and ip.
+func newARPReply(t *testing.T, hw net.HardwareAddr, ip netip.Addr) (frame []byte) {
+	t.Helper()
+
+	buf := gopacket.NewSerializeBuffer()
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, &layers.Ethernet{
+		SrcMAC:       hw,
+		DstMAC:       layers.EthernetBroadcast,
+		EthernetType: layers.EthernetTypeARP,
+	}, &layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     macLen,
+		ProtAddressSize:   net.IPv4len,
+		Operation:         layers.ARPReply,
+		SourceHwAddress:   hw,
+		SourceProtAddress: ip.AsSlice(),
+		DstHwAddress:      make([]byte, macLen),
+		DstProtAddress:    make([]byte, net.IPv4len),
+	})
+	require.NoError(t, err)
+
+	return buf.Bytes()
+}
+
+func TestNDProbe(t *testing.T) {
+	ownHW := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	otherHW := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x66}
+	ip := netip.MustParseAddr("2001:db8::2")
+
+	ns, err := newNSMsg(ownHW, ip)
+	require.NoError(t, err)
+
+	msg, err := icmp.ParseMessage(icmpv6Proto, ns)
+	require.NoError(t, err)
+	require.Equal(t, ipv6.ICMPTypeNeighborSolicitation, msg.Type)
+
+	body, ok := msg.Body.(*icmp.RawBody)
+	require.True(t, ok)
+	require.Len(t, body.Data, 28)
+
+	assert.Equal(t, ip.AsSlice(), body.Data[4:20])
+	assert.Equal(t, []byte(ownHW), ndOption(body.Data[20:], ndOptSourceLinkAddr))
+
+	// The solicitation isn't an advertisement.
+	assert.False(t, naClaims(ns, ownHW, ip))
+
+	na := newNAMsg(t, otherHW, ip)
+	assert.True(t, naClaims(na, ownHW, ip))
+	assert.False(t, naClaims(na, otherHW, ip))
+	assert.False(t, naClaims(na, ownHW, netip.MustParseAddr("2001:db8::3")))
+
+	assert.Equal(
+		t,
+		netip.MustParseAddr("ff02::1:ff00:2"),
+		solicitedNodeAddr(ip),
+	)
+}
+
+// newNAMsg is a helper that returns the ICMPv6 Neighbor Advertisement for ip
+// from the host with hw.
+func newNAMsg(t *testing.T, hw net.HardwareAddr, ip netip.Addr) (b []byte) {
+	t.Helper()
+
+	target := ip.As16()
+	data := append([]byte{0x60, 0, 0, 0}, target[:]...)
+	data = append(data, ndOptTargetLinkAddr, 1)
+	data = append(data, hw...)
+
+	msg := &icmp.Message{
+		Type: ipv6.ICMPTypeNeighborAdvertisement,
+		Body: &icmp.RawBody{Data: data},
+	}
+
+	b, err := msg.Marshal(nil)
+	require.NoError(t, err)
+
+	return b
+}
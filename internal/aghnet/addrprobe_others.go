@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+)
+
+// probeAddr returns an error, since probing the addresses isn't supported on
+// this OS yet.
+func probeAddr(_ *net.Interface, _ netip.Addr, _ time.Duration) (inUse bool, err error) {
+	return false, aghos.Unsupported("probing address")
+}
@@ -0,0 +1,35 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// controlFunc is the signature of net.ListenConfig.Control.
+type controlFunc func(network, address string, c syscall.RawConn) (err error)
+
+// bindToDeviceControl returns a function which sets the SO_BINDTODEVICE option
+// of the socket to ifaceName.  It's intended to be used as
+// net.ListenConfig.Control.
+func bindToDeviceControl(ifaceName string) (control controlFunc, err error) {
+	return func(_, _ string, c syscall.RawConn) (err error) {
+		var opErr error
+		err = c.Control(func(fd uintptr) {
+			opErr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, ifaceName)
+		})
+		if err != nil {
+			return err
+		}
+
+		if opErr != nil {
+			return fmt.Errorf("setting SO_BINDTODEVICE to %s: %w", ifaceName, opErr)
+		}
+
+		return nil
+	}, nil
+}
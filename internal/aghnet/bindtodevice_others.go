@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import (
+	"syscall"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+)
+
+// controlFunc is the signature of net.ListenConfig.Control.
+type controlFunc func(network, address string, c syscall.RawConn) (err error)
+
+// bindToDeviceControl returns an error, since binding sockets to network
+// interfaces isn't supported on this OS.
+func bindToDeviceControl(_ string) (control controlFunc, err error) {
+	return nil, aghos.Unsupported("binding to network interface")
+}
@@ -0,0 +1,23 @@
+package aghnet
+
+// Caps are the Linux capabilities relevant to the network features, which the
+// process effectively holds.
+type Caps struct {
+	// NetBindService is true if the process can bind to the privileged
+	// ports, those below 1024.
+	NetBindService bool `json:"net_bind_service"`
+
+	// NetRaw is true if the process can use raw sockets, which are required
+	// for DHCP and Router Advertisement detection.
+	NetRaw bool `json:"net_raw"`
+
+	// NetAdmin is true if the process can change the network configuration,
+	// like the addresses and the MTU of the interfaces.
+	NetAdmin bool `json:"net_admin"`
+}
+
+// Capabilities returns the effective Linux capabilities of the current process
+// relevant to the network features.  Other OSes aren't supported.
+func Capabilities() (caps Caps, err error) {
+	return capabilities()
+}
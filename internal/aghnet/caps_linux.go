@@ -0,0 +1,66 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+	"golang.org/x/sys/unix"
+)
+
+// procSelfStatusPath is the path to the status of the current process relative
+// to the root directory.
+const procSelfStatusPath = "proc/self/status"
+
+// capabilities reads the effective capabilities of the current process from
+// /proc/self/status.
+func capabilities() (caps Caps, err error) {
+	f, err := rootDirFS.Open(procSelfStatusPath)
+	if err != nil {
+		// Don't wrap the error, because it already contains the path.
+		return Caps{}, err
+	}
+	defer log.OnCloserError(f, log.DEBUG)
+
+	const prefix = "CapEff:"
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+
+		var mask uint64
+		mask, err = strconv.ParseUint(strings.TrimSpace(line[len(prefix):]), 16, 64)
+		if err != nil {
+			return Caps{}, fmt.Errorf("parsing %s: %w", procSelfStatusPath, err)
+		}
+
+		return capsFromMask(mask), nil
+	}
+
+	if err = s.Err(); err != nil {
+		return Caps{}, fmt.Errorf("reading %s: %w", procSelfStatusPath, err)
+	}
+
+	return Caps{}, errors.Error("no effective capabilities in " + procSelfStatusPath)
+}
+
+// capsFromMask decodes the bitmask of the capabilities as found in the CapEff
+// field of /proc/[pid]/status.  See capabilities(7).
+func capsFromMask(mask uint64) (caps Caps) {
+	has := func(c int) (ok bool) { return mask&(1<<c) != 0 }
+
+	return Caps{
+		NetBindService: has(unix.CAP_NET_BIND_SERVICE),
+		NetRaw:         has(unix.CAP_NET_RAW),
+		NetAdmin:       has(unix.CAP_NET_ADMIN),
+	}
+}
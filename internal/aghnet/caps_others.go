@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import "github.com/AdguardTeam/AdGuardHome/internal/aghos"
+
+// capabilities returns an error, since reading the effective capabilities of
+// the current process isn't supported on this OS.
+func capabilities() (caps Caps, err error) {
+	return Caps{}, aghos.Unsupported("getting capabilities")
+}
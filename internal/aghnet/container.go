@@ -0,0 +1,82 @@
+package aghnet
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// ErrContainerized is returned by functions changing the network configuration
+// of the host when AdGuard Home is running inside a container, where such
+// changes are both impossible and meaningless.
+const ErrContainerized errors.Error = "running inside a container"
+
+// Paths to the files used to detect containers.  See rootDirFS.
+const (
+	dockerEnvPath  = ".dockerenv"
+	procCgroupPath = "proc/1/cgroup"
+)
+
+// cgroupContainerHints are the substrings of the init process's cgroup paths
+// which are set by the common container runtimes.
+var cgroupContainerHints = []string{
+	"docker",
+	"kubepods",
+	"containerd",
+	"libpod",
+	"lxc",
+}
+
+// IsContainerized returns true if AdGuard Home seems to be running inside a
+// container, like Docker or LXC.  It checks for the presence of the /.dockerenv
+// file, the init process's cgroups, and the "container" environment variable,
+// which is set by systemd-nspawn, Podman, and LXC.
+func IsContainerized() (ok bool) {
+	if os.Getenv("container") != "" {
+		return true
+	}
+
+	_, err := fs.Stat(rootDirFS, dockerEnvPath)
+	if err == nil {
+		return true
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		currentLogger().Debug("checking %s: %s", dockerEnvPath, err)
+	}
+
+	return hasContainerCgroup()
+}
+
+// hasContainerCgroup returns true if any of the cgroups of the init process
+// contains one of cgroupContainerHints.
+func hasContainerCgroup() (ok bool) {
+	f, err := rootDirFS.Open(procCgroupPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			currentLogger().Debug("opening %s: %s", procCgroupPath, err)
+		}
+
+		return false
+	}
+	defer log.OnCloserError(f, log.DEBUG)
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// Each line has the format "hierarchy-ID:controller-list:cgroup-path".
+		fields := strings.SplitN(s.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		for _, hint := range cgroupContainerHints {
+			if strings.Contains(fields[2], hint) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package aghnet
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsContainerized(t *testing.T) {
+	const hostCgroup = `12:pids:/init.scope` + nl +
+		`11:memory:/init.scope` + nl +
+		`0::/init.scope` + nl
+
+	testCases := []struct {
+		fsys fstest.MapFS
+		name string
+		env  string
+		want bool
+	}{{
+		fsys: fstest.MapFS{
+			procCgroupPath: &fstest.MapFile{Data: []byte(hostCgroup)},
+		},
+		name: "host",
+		env:  "",
+		want: false,
+	}, {
+		fsys: fstest.MapFS{},
+		name: "no_files",
+		env:  "",
+		want: false,
+	}, {
+		fsys: fstest.MapFS{
+			dockerEnvPath:  &fstest.MapFile{},
+			procCgroupPath: &fstest.MapFile{Data: []byte(`0::/`)},
+		},
+		name: "dockerenv",
+		env:  "",
+		want: true,
+	}, {
+		fsys: fstest.MapFS{
+			procCgroupPath: &fstest.MapFile{
+				Data: []byte(`12:pids:/docker/0123456789abcdef` + nl + `0::/` + nl),
+			},
+		},
+		name: "docker_cgroup",
+		env:  "",
+		want: true,
+	}, {
+		fsys: fstest.MapFS{
+			procCgroupPath: &fstest.MapFile{
+				Data: []byte(`0::/kubepods/besteffort/pod01234567` + nl),
+			},
+		},
+		name: "kubernetes_cgroup",
+		env:  "",
+		want: true,
+	}, {
+		fsys: fstest.MapFS{
+			procCgroupPath: &fstest.MapFile{Data: []byte(hostCgroup)},
+		},
+		name: "env",
+		env:  "podman",
+		want: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			substRootDirFS(t, tc.fsys)
+			t.Setenv("container", tc.env)
+
+			assert.Equal(t, tc.want, IsContainerized())
+		})
+	}
+}
+
+func TestIfaceSetStaticIP_containerized(t *testing.T) {
+	substRootDirFS(t, fstest.MapFS{dockerEnvPath: &fstest.MapFile{}})
+
+	_, err := IfaceSetStaticIP("eth0")
+	assert.ErrorIs(t, err, ErrContainerized)
+}
@@ -1,6 +1,40 @@
 package aghnet
 
-// CheckOtherDHCP tries to discover another DHCP server in the network.
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// CheckOtherDHCP tries to discover another DHCP server in the network.  The
+// DHCPv4 servers are detected like DetectDHCPServers does.
 func CheckOtherDHCP(ifaceName string) (ok4, ok6 bool, err4, err6 error) {
 	return checkOtherDHCP(ifaceName)
 }
+
+// DHCPServerInfo is the information about a DHCPv4 server gathered from its
+// DHCPOFFER message.
+type DHCPServerInfo struct {
+	// ServerIP is the address of the server, as identified by the server
+	// identifier option or, if the option is absent, by the source address of
+	// the offer.
+	ServerIP net.IP
+
+	// OfferedIP is the address offered to the client.
+	OfferedIP net.IP
+
+	// LeaseTime is the offered lease duration.  It's zero if the server hasn't
+	// specified one.
+	LeaseTime time.Duration
+}
+
+// DetectDHCPServers broadcasts a DHCPDISCOVER message through the network
+// interface named ifaceName and collects the DHCPOFFER messages received until
+// ctx is done or, if ctx has no deadline, for a few seconds.  Each server is
+// only reported once, and the offers from the addresses of this host, i.e. from
+// the DHCP server of AdGuard Home itself, are ignored.  An empty result means
+// that no other server has responded.  On
+// Linux, the socket is bound to the interface, which requires CAP_NET_RAW.
+func DetectDHCPServers(ctx context.Context, ifaceName string) (srvs []DHCPServerInfo, err error) {
+	return detectDHCPServers(ctx, ifaceName)
+}
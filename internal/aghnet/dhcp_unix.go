@@ -5,6 +5,7 @@ package aghnet
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net"
 	"os"
@@ -16,7 +17,6 @@ import (
 	"github.com/insomniacslk/dhcp/dhcpv4"
 	"github.com/insomniacslk/dhcp/dhcpv6"
 	"github.com/insomniacslk/dhcp/dhcpv6/nclient6"
-	"github.com/insomniacslk/dhcp/iana"
 )
 
 // defaultDiscoverTime is the
@@ -68,127 +68,19 @@ func ifaceIPv4Subnet(iface *net.Interface) (subnet *net.IPNet, err error) {
 }
 
 // checkOtherDHCPv4 sends a DHCP request to the specified network interface, and
-// waits for a response for a period defined by defaultDiscoverTime.
+// waits for a response for a period defined by defaultDiscoverTime.  The
+// responses of the DHCP server of this host aren't considered.
 func checkOtherDHCPv4(iface *net.Interface) (ok bool, err error) {
-	var subnet *net.IPNet
-	if subnet, err = ifaceIPv4Subnet(iface); err != nil {
-		return false, err
-	}
-
-	// Resolve broadcast addr.
-	dst := netutil.IPPort{
-		IP:   BroadcastFromIPNet(subnet),
-		Port: 67,
-	}.String()
-	var dstAddr *net.UDPAddr
-	if dstAddr, err = net.ResolveUDPAddr("udp4", dst); err != nil {
-		return false, fmt.Errorf("couldn't resolve UDP address %s: %w", dst, err)
-	}
-
-	var hostname string
-	if hostname, err = os.Hostname(); err != nil {
-		return false, fmt.Errorf("couldn't get hostname: %w", err)
-	}
-
-	return discover4(iface, dstAddr, hostname)
-}
-
-func discover4(iface *net.Interface, dstAddr *net.UDPAddr, hostname string) (ok bool, err error) {
-	var req *dhcpv4.DHCPv4
-	if req, err = dhcpv4.NewDiscovery(iface.HardwareAddr); err != nil {
-		return false, fmt.Errorf("dhcpv4.NewDiscovery: %w", err)
-	}
-
-	req.Options.Update(dhcpv4.OptClientIdentifier(iface.HardwareAddr))
-	req.Options.Update(dhcpv4.OptHostName(hostname))
-	req.SetBroadcast()
-
-	// Bind to 0.0.0.0:68.
-	//
-	// On OpenBSD binding to the port 68 competes with dhclient's binding,
-	// so that all incoming packets are ignored and the discovering process
-	// is spoiled.
-	//
-	// It's also known that listening on the specified interface's address
-	// ignores broadcasted packets when reading.
-	var c net.PacketConn
-	if c, err = listenPacketReusable(iface.Name, "udp4", ":68"); err != nil {
-		return false, fmt.Errorf("couldn't listen on :68: %w", err)
-	}
-	defer func() { err = errors.WithDeferred(err, c.Close()) }()
-
-	// Send to broadcast.
-	if _, err = c.WriteTo(req.ToBytes(), dstAddr); err != nil {
-		return false, fmt.Errorf("couldn't send a packet to %s: %w", dstAddr, err)
-	}
-
-	for {
-		if err = c.SetDeadline(time.Now().Add(defaultDiscoverTime)); err != nil {
-			return false, fmt.Errorf("setting deadline: %w", err)
-		}
-
-		var next bool
-		ok, next, err = tryConn4(req, c, iface)
-		if next {
-			if err != nil {
-				log.Debug("dhcpv4: trying a connection: %s", err)
-			}
-
-			continue
-		}
-
-		if err != nil {
-			return false, err
-		}
-
-		return ok, nil
-	}
-}
-
-// TODO(a.garipov): Refactor further.  Inspect error handling, remove parameter
-// next, address the TODO, merge with tryConn6, etc.
-func tryConn4(req *dhcpv4.DHCPv4, c net.PacketConn, iface *net.Interface) (ok, next bool, err error) {
-	// TODO: replicate dhclient's behavior of retrying several times with
-	// progressively longer timeouts.
-	log.Tracef("dhcpv4: waiting %v for an answer", defaultDiscoverTime)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDiscoverTime)
+	defer cancel()
 
-	b := make([]byte, 1500)
-	n, _, err := c.ReadFrom(b)
+	srvs, err := discoverDHCPv4(ctx, iface)
 	if err != nil {
-		if isTimeout(err) {
-			log.Debug("dhcpv4: didn't receive dhcp response")
-
-			return false, false, nil
-		}
-
-		return false, false, fmt.Errorf("receiving packet: %w", err)
-	}
-
-	log.Tracef("dhcpv4: received packet, %d bytes", n)
-
-	response, err := dhcpv4.FromBytes(b[:n])
-	if err != nil {
-		log.Debug("dhcpv4: encoding: %s", err)
-
-		return false, true, err
-	}
-
-	log.Debug("dhcpv4: received message from server: %s", response.Summary())
-
-	if !(response.OpCode == dhcpv4.OpcodeBootReply &&
-		response.HWType == iana.HWTypeEthernet &&
-		bytes.Equal(response.ClientHWAddr, iface.HardwareAddr) &&
-		bytes.Equal(response.TransactionID[:], req.TransactionID[:]) &&
-		response.Options.Has(dhcpv4.OptionDHCPMessageType)) {
-
-		log.Debug("dhcpv4: received message from server doesn't match our request")
-
-		return false, true, nil
+		// Don't wrap the error, because it's informative enough as is.
+		return false, err
 	}
 
-	log.Tracef("dhcpv4: the packet is from an active dhcp server")
-
-	return true, false, nil
+	return len(srvs) > 0, nil
 }
 
 // checkOtherDHCPv6 sends a DHCP request to the specified network interface, and
@@ -260,7 +152,8 @@ func discover6(iface *net.Interface, udpAddr, dstAddr *net.UDPAddr) (ok bool, er
 	}
 }
 
-// TODO(a.garipov): See the comment on tryConn4.  Sigh…
+// TODO(a.garipov): Refactor further.  Inspect error handling, remove parameter
+// next, address the TODO, merge with discoverDHCPv4, etc.
 func tryConn6(req *dhcpv6.Message, c net.PacketConn) (ok, next bool, err error) {
 	// TODO: replicate dhclient's behavior of retrying several times with
 	// progressively longer timeouts.
@@ -318,6 +211,191 @@ func tryConn6(req *dhcpv6.Message, c net.PacketConn) (ok, next bool, err error)
 	return true, false, nil
 }
 
+// detectDHCPServers broadcasts a DHCPDISCOVER message through the network
+// interface named ifaceName and collects the offers until ctx is done.
+func detectDHCPServers(ctx context.Context, ifaceName string) (srvs []DHCPServerInfo, err error) {
+	defer func() { err = errors.Annotate(err, "detecting dhcp servers on %s: %w", ifaceName) }()
+
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	return discoverDHCPv4(ctx, iface)
+}
+
+// discoverDHCPv4 broadcasts a DHCPDISCOVER message through iface and collects
+// the offers until ctx is done, see readOffers.
+func discoverDHCPv4(ctx context.Context, iface *net.Interface) (srvs []DHCPServerInfo, err error) {
+	subnet, err := ifaceIPv4Subnet(iface)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	req, err := dhcpv4.NewDiscovery(iface.HardwareAddr)
+	if err != nil {
+		return nil, fmt.Errorf("creating discovery: %w", err)
+	}
+
+	req.Options.Update(dhcpv4.OptClientIdentifier(iface.HardwareAddr))
+	if hostname, hostErr := os.Hostname(); hostErr == nil {
+		req.Options.Update(dhcpv4.OptHostName(hostname))
+	}
+	req.SetBroadcast()
+
+	// Bind to 0.0.0.0:68.
+	//
+	// On OpenBSD binding to the port 68 competes with dhclient's binding,
+	// so that all incoming packets are ignored and the discovering process
+	// is spoiled.
+	//
+	// It's also known that listening on the specified interface's address
+	// ignores broadcasted packets when reading.
+	c, err := listenPacketReusable(iface.Name, "udp4", ":68")
+	if err != nil {
+		return nil, fmt.Errorf("listening on :68: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, c.Close()) }()
+
+	err = setDiscoverDeadline(ctx, c)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	dst := &net.UDPAddr{IP: BroadcastFromIPNet(subnet), Port: dhcpv4.ServerPort}
+	if _, err = c.WriteTo(req.ToBytes(), dst); err != nil {
+		return nil, fmt.Errorf("sending discovery to %s: %w", dst, err)
+	}
+
+	return readOffers(ctx, c, req)
+}
+
+// setDiscoverDeadline sets the deadline of c to the one of ctx or, if there is
+// none, to defaultDiscoverTime from now.  The deadline is moved to the past
+// once ctx is canceled, so that the pending reads are interrupted.
+func setDiscoverDeadline(ctx context.Context, c net.PacketConn) (err error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultDiscoverTime)
+	}
+
+	if err = c.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("setting deadline: %w", err)
+	}
+
+	go func() {
+		defer log.OnPanic("aghnet: interrupting dhcp discovery")
+
+		t := time.NewTimer(time.Until(deadline))
+		defer t.Stop()
+
+		select {
+		case <-ctx.Done():
+			// Don't check the error, since the connection may have been
+			// closed already.
+			_ = c.SetDeadline(time.Unix(1, 0))
+		case <-t.C:
+			// Go on.
+		}
+	}()
+
+	return nil
+}
+
+// readOffers reads the offers answering req from c until the deadline of c
+// exceeds.  The offers from the same server are only reported once, and the
+// ones from the addresses of this host, see IsLocalAddr, aren't reported at
+// all, since those come from the DHCP server of AdGuard Home itself.
+func readOffers(
+	ctx context.Context,
+	c net.PacketConn,
+	req *dhcpv4.DHCPv4,
+) (srvs []DHCPServerInfo, err error) {
+	l := loggerFromContext(ctx)
+	srvs = []DHCPServerInfo{}
+	seen := map[string]struct{}{}
+
+	b := make([]byte, 1500)
+	for {
+		var n int
+		var src net.Addr
+		n, src, err = c.ReadFrom(b)
+		if err != nil {
+			if isTimeout(err) {
+				return srvs, nil
+			}
+
+			return nil, fmt.Errorf("receiving packet: %w", err)
+		}
+
+		info, ok := offerInfo(l, req, b[:n], src)
+		if !ok || isOwnDHCPServer(l, info.ServerIP) {
+			continue
+		}
+
+		key := info.ServerIP.String()
+		if _, ok = seen[key]; !ok {
+			seen[key] = struct{}{}
+			srvs = append(srvs, info)
+		}
+	}
+}
+
+// offerInfo returns the information about the server if b is the DHCPOFFER
+// message answering req received from src.
+func offerInfo(
+	l Logger,
+	req *dhcpv4.DHCPv4,
+	b []byte,
+	src net.Addr,
+) (info DHCPServerInfo, ok bool) {
+	resp, err := dhcpv4.FromBytes(b)
+	if err != nil {
+		l.Debug("decoding dhcp packet: %s", err)
+
+		return DHCPServerInfo{}, false
+	}
+
+	if resp.OpCode != dhcpv4.OpcodeBootReply ||
+		resp.TransactionID != req.TransactionID ||
+		!bytes.Equal(resp.ClientHWAddr, req.ClientHWAddr) ||
+		resp.MessageType() != dhcpv4.MessageTypeOffer {
+		return DHCPServerInfo{}, false
+	}
+
+	srvIP := resp.ServerIdentifier()
+	if srvIP == nil {
+		if udpAddr, isUDP := src.(*net.UDPAddr); isUDP {
+			srvIP = udpAddr.IP
+		}
+	}
+
+	if srvIP == nil {
+		return DHCPServerInfo{}, false
+	}
+
+	l.Debug("received dhcp offer from %s: %s", srvIP, resp.Summary())
+
+	return DHCPServerInfo{
+		ServerIP:  srvIP,
+		OfferedIP: resp.YourIPAddr,
+		LeaseTime: resp.IPAddressLeaseTime(0),
+	}, true
+}
+
+// isOwnDHCPServer returns true if ip is one of the addresses of this host.
+func isOwnDHCPServer(l Logger, ip net.IP) (ok bool) {
+	ok, err := IsLocalAddr(ip)
+	if err != nil {
+		l.Debug("checking dhcp server address %s: %s", ip, err)
+	}
+
+	return ok
+}
+
 // isTimeout returns true if err is an operation timeout error from net package.
 //
 // TODO(e.burkov):  Consider moving into netutil.
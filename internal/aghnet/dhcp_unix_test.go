@@ -0,0 +1,159 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package aghnet
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfferInfo(t *testing.T) {
+	hw := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	req, err := dhcpv4.NewDiscovery(hw)
+	require.NoError(t, err)
+
+	srvIP := net.IP{192, 168, 1, 1}
+	yourIP := net.IP{192, 168, 1, 100}
+	src := &net.UDPAddr{IP: net.IP{192, 168, 1, 254}, Port: dhcpv4.ServerPort}
+
+	newReply := func(t *testing.T, typ dhcpv4.MessageType, mods ...dhcpv4.Modifier) (b []byte) {
+		t.Helper()
+
+		mods = append([]dhcpv4.Modifier{
+			dhcpv4.WithMessageType(typ),
+			dhcpv4.WithYourIP(yourIP),
+		}, mods...)
+
+		resp, rerr := dhcpv4.NewReplyFromRequest(req, mods...)
+		require.NoError(t, rerr)
+
+		return resp.ToBytes()
+	}
+
+	testCases := []struct {
+		name     string
+		wantInfo DHCPServerInfo
+		b        []byte
+		wantOK   bool
+	}{{
+		name: "offer",
+		wantInfo: DHCPServerInfo{
+			ServerIP:  srvIP,
+			OfferedIP: yourIP,
+			LeaseTime: time.Hour,
+		},
+		b: newReply(
+			t,
+			dhcpv4.MessageTypeOffer,
+			dhcpv4.WithServerIP(srvIP),
+			dhcpv4.WithOption(dhcpv4.OptServerIdentifier(srvIP)),
+			dhcpv4.WithLeaseTime(uint32(time.Hour.Seconds())),
+		),
+		wantOK: true,
+	}, {
+		name: "no_server_id",
+		wantInfo: DHCPServerInfo{
+			ServerIP:  src.IP,
+			OfferedIP: yourIP,
+		},
+		b:      newReply(t, dhcpv4.MessageTypeOffer),
+		wantOK: true,
+	}, {
+		name:     "ack",
+		wantInfo: DHCPServerInfo{},
+		b:        newReply(t, dhcpv4.MessageTypeAck),
+		wantOK:   false,
+	}, {
+		name:     "request",
+		wantInfo: DHCPServerInfo{},
+		b:        req.ToBytes(),
+		wantOK:   false,
+	}, {
+		name:     "garbage",
+		wantInfo: DHCPServerInfo{},
+		b:        []byte{1, 2, 3},
+		wantOK:   false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, ok := offerInfo(DiscardLogger, req, tc.b, src)
+			require.Equal(t, tc.wantOK, ok)
+
+			assert.True(t, tc.wantInfo.ServerIP.Equal(info.ServerIP))
+			assert.True(t, tc.wantInfo.OfferedIP.Equal(info.OfferedIP))
+			assert.Equal(t, tc.wantInfo.LeaseTime, info.LeaseTime)
+		})
+	}
+}
+
+// fakeOffersConn is a net.PacketConn returning the packets from offers one by
+// one and the timeout error after them.
+type fakeOffersConn struct {
+	// net.PacketConn is embedded here simply to make *fakeOffersConn
+	// a net.PacketConn without actually implementing all methods.
+	net.PacketConn
+
+	src    net.Addr
+	offers [][]byte
+}
+
+// ReadFrom implements the net.PacketConn interface for *fakeOffersConn.
+func (c *fakeOffersConn) ReadFrom(b []byte) (n int, addr net.Addr, err error) {
+	if len(c.offers) == 0 {
+		return 0, nil, &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}
+	}
+
+	n = copy(b, c.offers[0])
+	c.offers = c.offers[1:]
+
+	return n, c.src, nil
+}
+
+func TestReadOffers(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	hw := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	req, err := dhcpv4.NewDiscovery(hw)
+	require.NoError(t, err)
+
+	newOffer := func(t *testing.T, srvIP net.IP) (b []byte) {
+		t.Helper()
+
+		resp, rerr := dhcpv4.NewReplyFromRequest(
+			req,
+			dhcpv4.WithMessageType(dhcpv4.MessageTypeOffer),
+			dhcpv4.WithOption(dhcpv4.OptServerIdentifier(srvIP)),
+		)
+		require.NoError(t, rerr)
+
+		return resp.ToBytes()
+	}
+
+	foreignIP := net.IP{192, 168, 1, 1}
+	// The address of eth0 from fakeNetIfaceAddrs.
+	ownIP := net.IP{192, 168, 1, 2}
+
+	c := &fakeOffersConn{
+		src: &net.UDPAddr{IP: foreignIP, Port: dhcpv4.ServerPort},
+		offers: [][]byte{
+			newOffer(t, ownIP),
+			newOffer(t, foreignIP),
+			newOffer(t, foreignIP),
+		},
+	}
+
+	srvs, err := readOffers(context.Background(), c, req)
+	require.NoError(t, err)
+	require.Len(t, srvs, 1)
+
+	assert.True(t, foreignIP.Equal(srvs[0].ServerIP))
+}
@@ -3,7 +3,11 @@
 
 package aghnet
 
-import "github.com/AdguardTeam/AdGuardHome/internal/aghos"
+import (
+	"context"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+)
 
 func checkOtherDHCP(ifaceName string) (ok4, ok6 bool, err4, err6 error) {
 	return false,
@@ -11,3 +15,7 @@ func checkOtherDHCP(ifaceName string) (ok4, ok6 bool, err4, err6 error) {
 		aghos.Unsupported("CheckIfOtherDHCPServersPresentV4"),
 		aghos.Unsupported("CheckIfOtherDHCPServersPresentV6")
 }
+
+func detectDHCPServers(_ context.Context, _ string) (srvs []DHCPServerInfo, err error) {
+	return nil, aghos.Unsupported("detecting dhcp servers")
+}
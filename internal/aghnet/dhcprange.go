@@ -0,0 +1,156 @@
+package aghnet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// ErrNoIPv4Subnet is returned by SuggestDHCPRange when the network interface
+// has no IPv4 subnet suitable for DHCP.
+const ErrNoIPv4Subnet errors.Error = "no suitable ipv4 subnet"
+
+// Bounds of the suggested DHCP range in 256ths of the subnet size, so that it's
+// .100 to .200 for a /24 subnet.
+const (
+	dhcpRangeStartPart = 100
+	dhcpRangeEndPart   = 200
+)
+
+// SuggestDHCPRange returns the dynamic DHCP range suggested for the first IPv4
+// subnet of the network interface named ifaceName.  The range takes the same
+// part of the subnet as .100 to .200 does in a /24 one, leaving the lower
+// addresses for the static leases and the infrastructure.  It never includes
+// the network and the broadcast addresses, the addresses of the interface, and
+// its gateway, see GatewayIPAddr, unless the gateway can't be detected.  Only
+// the subnets from /8 to /30 are considered, and the link-local ones are
+// skipped.  err is ErrNoIPv4Subnet if there is no such subnet.
+func SuggestDHCPRange(ifaceName string) (start, end netip.Addr, err error) {
+	defer func() { err = errors.Annotate(err, "suggesting dhcp range on %q: %w", ifaceName) }()
+
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return netip.Addr{}, netip.Addr{}, err
+	}
+
+	addrs, err := netInterfaceAddrs(iface)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return netip.Addr{}, netip.Addr{}, err
+	}
+
+	subnet, reserved := dhcpSubnet(addrs)
+	if !subnet.IsValid() {
+		return netip.Addr{}, netip.Addr{}, ErrNoIPv4Subnet
+	}
+
+	// The gateway is only reserved if it's known, since the range is still
+	// useful without it.
+	gw, gwErr := GatewayIPAddr(ifaceName)
+	if gwErr != nil {
+		currentLogger().Info("suggesting dhcp range: gateway is not reserved: %s", gwErr)
+	} else if gw.IsValid() {
+		reserved = append(reserved, gw.Unmap())
+	}
+
+	start, end, ok := suggestDHCPRange(subnet, reserved)
+	if !ok {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("no free addresses in %s", subnet)
+	}
+
+	return start, end, nil
+}
+
+// dhcpSubnet returns the first IPv4 subnet from addrs suitable for DHCP along
+// with all the IPv4 addresses from addrs.  subnet is invalid if there is none.
+func dhcpSubnet(addrs []net.Addr) (subnet netip.Prefix, ipv4Addrs []netip.Addr) {
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		ip, ok := netip.AddrFromSlice(ipNet.IP)
+		if ip = ip.Unmap(); !ok || !ip.Is4() {
+			continue
+		}
+
+		ipv4Addrs = append(ipv4Addrs, ip)
+
+		ones, _ := ipNet.Mask.Size()
+		if !subnet.IsValid() && isDHCPSubnet(ip, ones) {
+			subnet = netip.PrefixFrom(ip, ones).Masked()
+		}
+	}
+
+	return subnet, ipv4Addrs
+}
+
+// isDHCPSubnet returns true if the IPv4 address ip with the prefix length ones
+// belongs to a subnet suitable for DHCP.
+func isDHCPSubnet(ip netip.Addr, ones int) (ok bool) {
+	if ip.IsLinkLocalUnicast() || ip.IsLoopback() {
+		return false
+	}
+
+	return ones >= 8 && ones <= 30
+}
+
+// suggestDHCPRange returns the DHCP range within the IPv4 subnet n as described
+// by SuggestDHCPRange excluding the reserved addresses.  If a reserved address
+// falls into the range, the larger of its parts is used.  ok is false if there
+// is no room for the range.
+func suggestDHCPRange(n netip.Prefix, reserved []netip.Addr) (start, end netip.Addr, ok bool) {
+	first, last := SubnetRange(n)
+	base, size := v4ToUint32(n.Masked().Addr()), uint64(1)<<(32-n.Bits())
+
+	lo, hi := v4ToUint32(first), v4ToUint32(last)
+	if off := uint32(size * dhcpRangeStartPart / 256); base+off > lo {
+		lo = base + off
+	}
+
+	if off := uint32(size * dhcpRangeEndPart / 256); base+off < hi {
+		hi = base + off
+	}
+
+	for _, r := range reserved {
+		if !r.Is4() {
+			continue
+		}
+
+		v := v4ToUint32(r)
+		switch {
+		case v < lo || v > hi:
+			// Go on.
+		case v-lo >= hi-v:
+			hi = v - 1
+		default:
+			lo = v + 1
+		}
+
+		if lo > hi {
+			return netip.Addr{}, netip.Addr{}, false
+		}
+	}
+
+	return uint32ToV4(lo), uint32ToV4(hi), true
+}
+
+// v4ToUint32 returns the IPv4 address ip as a number.
+func v4ToUint32(ip netip.Addr) (n uint32) {
+	b := ip.As4()
+
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// uint32ToV4 returns the IPv4 address from its numeric representation n.
+func uint32ToV4(n uint32) (ip netip.Addr) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], n)
+
+	return netip.AddrFrom4(b)
+}
@@ -0,0 +1,126 @@
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"os/exec"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/netutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestDHCPRange(t *testing.T) {
+	testCases := []struct {
+		name      string
+		prefix    netip.Prefix
+		reserved  []netip.Addr
+		wantStart netip.Addr
+		wantEnd   netip.Addr
+		wantOK    bool
+	}{{
+		name:      "ipv4_24",
+		prefix:    netip.MustParsePrefix("192.168.1.0/24"),
+		reserved:  []netip.Addr{netip.MustParseAddr("192.168.1.1")},
+		wantStart: netip.MustParseAddr("192.168.1.100"),
+		wantEnd:   netip.MustParseAddr("192.168.1.200"),
+		wantOK:    true,
+	}, {
+		name:      "ipv4_24_server_inside",
+		prefix:    netip.MustParsePrefix("192.168.1.0/24"),
+		reserved:  []netip.Addr{netip.MustParseAddr("192.168.1.120")},
+		wantStart: netip.MustParseAddr("192.168.1.121"),
+		wantEnd:   netip.MustParseAddr("192.168.1.200"),
+		wantOK:    true,
+	}, {
+		name:      "ipv4_24_gateway_inside",
+		prefix:    netip.MustParsePrefix("192.168.1.0/24"),
+		reserved:  []netip.Addr{netip.MustParseAddr("192.168.1.190")},
+		wantStart: netip.MustParseAddr("192.168.1.100"),
+		wantEnd:   netip.MustParseAddr("192.168.1.189"),
+		wantOK:    true,
+	}, {
+		name:      "ipv4_16",
+		prefix:    netip.MustParsePrefix("10.1.0.0/16"),
+		reserved:  nil,
+		wantStart: netip.MustParseAddr("10.1.100.0"),
+		wantEnd:   netip.MustParseAddr("10.1.200.0"),
+		wantOK:    true,
+	}, {
+		name:      "ipv4_28",
+		prefix:    netip.MustParsePrefix("192.168.1.16/28"),
+		reserved:  []netip.Addr{netip.MustParseAddr("192.168.1.17")},
+		wantStart: netip.MustParseAddr("192.168.1.22"),
+		wantEnd:   netip.MustParseAddr("192.168.1.28"),
+		wantOK:    true,
+	}, {
+		name:   "ipv4_30",
+		prefix: netip.MustParsePrefix("10.0.0.4/30"),
+		reserved: []netip.Addr{
+			netip.MustParseAddr("10.0.0.5"),
+			netip.MustParseAddr("2001:db8::1"),
+		},
+		wantStart: netip.MustParseAddr("10.0.0.6"),
+		wantEnd:   netip.MustParseAddr("10.0.0.6"),
+		wantOK:    true,
+	}, {
+		name:   "ipv4_30_full",
+		prefix: netip.MustParsePrefix("10.0.0.4/30"),
+		reserved: []netip.Addr{
+			netip.MustParseAddr("10.0.0.5"),
+			netip.MustParseAddr("10.0.0.6"),
+		},
+		wantStart: netip.Addr{},
+		wantEnd:   netip.Addr{},
+		wantOK:    false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start, end, ok := suggestDHCPRange(tc.prefix, tc.reserved)
+			require.Equal(t, tc.wantOK, ok)
+
+			assert.Equal(t, tc.wantStart, start)
+			assert.Equal(t, tc.wantEnd, end)
+		})
+	}
+}
+
+func TestSuggestDHCPRange_iface(t *testing.T) {
+	ifaceAddrs := map[string][]net.Addr{
+		"eth0": {&net.IPNet{
+			IP:   net.ParseIP("2001:db8::2"),
+			Mask: net.CIDRMask(64, netutil.IPv6BitLen),
+		}, &net.IPNet{
+			IP:   net.IPv4(169, 254, 1, 2),
+			Mask: net.CIDRMask(16, netutil.IPv4BitLen),
+		}, &net.IPNet{
+			IP:   net.IPv4(192, 168, 1, 150),
+			Mask: net.CIDRMask(24, netutil.IPv4BitLen),
+		}},
+		"eth1": {&net.IPNet{
+			IP:   net.ParseIP("2001:db8::3"),
+			Mask: net.CIDRMask(64, netutil.IPv6BitLen),
+		}},
+	}
+
+	substNetInterfaces(t, fakeNetIfaces, ifaceAddrs)
+
+	// Don't look up the actual gateways.
+	substLookPath(t, func(_ string) (path string, err error) {
+		return "", exec.ErrNotFound
+	})
+
+	start, end, err := SuggestDHCPRange("eth0")
+	require.NoError(t, err)
+
+	assert.Equal(t, netip.MustParseAddr("192.168.1.100"), start)
+	assert.Equal(t, netip.MustParseAddr("192.168.1.149"), end)
+
+	_, _, err = SuggestDHCPRange("eth1")
+	assert.ErrorIs(t, err, ErrNoIPv4Subnet)
+
+	_, _, err = SuggestDHCPRange("eth2")
+	assert.ErrorIs(t, err, ErrIfaceNotFound)
+}
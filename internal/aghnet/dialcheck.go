@@ -0,0 +1,123 @@
+package aghnet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// Reasons of the DialCheck failures.
+const (
+	// ErrDialTimeout means that the connection hasn't been established before
+	// the deadline.
+	ErrDialTimeout errors.Error = "dial timed out"
+
+	// ErrDialRefused means that the remote host has refused the connection.
+	ErrDialRefused errors.Error = "connection refused"
+
+	// ErrDialNoRoute means that the remote host or network is unreachable.
+	ErrDialNoRoute errors.Error = "no route to host"
+)
+
+// DialError is returned by DialCheck when the connection can't be
+// established.
+type DialError struct {
+	// Err is the underlying error.
+	Err error
+
+	// Reason is one of ErrDialTimeout, ErrDialRefused, and ErrDialNoRoute, or
+	// nil if the failure is of another kind.
+	Reason error
+
+	// Network is the network of the connection.
+	Network string
+
+	// Addr is the remote address.
+	Addr netip.AddrPort
+}
+
+// type check
+var _ error = (*DialError)(nil)
+
+// Error implements the error interface for *DialError.
+func (err *DialError) Error() (msg string) {
+	return fmt.Sprintf("dialing %s %s: %s", err.Network, err.Addr, err.Err)
+}
+
+// Is implements the interface used by errors.Is for *DialError.  It returns
+// true if target is the reason of the failure.
+func (err *DialError) Is(target error) (ok bool) {
+	return err.Reason != nil && target == err.Reason
+}
+
+// Unwrap implements the errors.Wrapper interface for *DialError.
+func (err *DialError) Unwrap() (unwrapped error) {
+	return err.Err
+}
+
+// DialCheck checks if addr is reachable over network, which must be either
+// "tcp" or "udp", and returns the time spent connecting.  The connection is
+// closed right away.  For TCP, the handshake is performed within the deadline
+// of ctx, if any.  For UDP, nothing is sent, so only the route to addr is
+// checked.  If the connection fails, err is *DialError.
+func DialCheck(
+	ctx context.Context,
+	network string,
+	addr netip.AddrPort,
+) (latency time.Duration, err error) {
+	switch network {
+	case "tcp", "udp":
+		// Go on.
+	default:
+		return 0, fmt.Errorf("dialing %s: bad network %q", addr, network)
+	}
+
+	d := &net.Dialer{}
+	start := time.Now()
+	conn, err := d.DialContext(ctx, network, addr.String())
+	latency = time.Since(start)
+	if err != nil {
+		return 0, &DialError{
+			Err:     err,
+			Reason:  dialFailureReason(ctx, err),
+			Network: network,
+			Addr:    addr,
+		}
+	}
+
+	err = conn.Close()
+	if err != nil {
+		// The connection has been established, so only log the error.
+		currentLogger().Debug("closing %s connection to %s: %s", network, addr, err)
+	}
+
+	return latency, nil
+}
+
+// dialFailureReason returns the reason of the dial failure err, if it's known.
+func dialFailureReason(ctx context.Context, err error) (reason error) {
+	var netErr net.Error
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrDialTimeout
+	}
+
+	var sysErr syscall.Errno
+	if !errors.As(err, &sysErr) {
+		return nil
+	}
+
+	switch {
+	case isConnRefused(sysErr):
+		return ErrDialRefused
+	case isNoRoute(sysErr):
+		return ErrDialNoRoute
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package aghnet
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialCheck(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	testutil.CleanupAndRequireSuccess(t, l.Close)
+
+	go func() {
+		for {
+			conn, lerr := l.Accept()
+			if lerr != nil {
+				return
+			}
+
+			_ = conn.Close()
+		}
+	}()
+
+	addr := l.Addr().(*net.TCPAddr).AddrPort()
+
+	t.Run("tcp", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		t.Cleanup(cancel)
+
+		latency, dialErr := DialCheck(ctx, "tcp", addr)
+		require.NoError(t, dialErr)
+
+		assert.Positive(t, latency)
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		_, dialErr := DialCheck(context.Background(), "udp", addr)
+		assert.NoError(t, dialErr)
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		// Get a port which is surely closed.
+		c, lerr := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, lerr)
+
+		closed := c.Addr().(*net.TCPAddr).AddrPort()
+		require.NoError(t, c.Close())
+
+		_, dialErr := DialCheck(context.Background(), "tcp", closed)
+		assert.ErrorIs(t, dialErr, ErrDialRefused)
+
+		dErr := &DialError{}
+		require.ErrorAs(t, dialErr, &dErr)
+
+		assert.Equal(t, "tcp", dErr.Network)
+		assert.Equal(t, closed, dErr.Addr)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		t.Cleanup(cancel)
+
+		_, dialErr := DialCheck(ctx, "tcp", addr)
+		assert.ErrorIs(t, dialErr, ErrDialTimeout)
+	})
+
+	t.Run("bad_network", func(t *testing.T) {
+		_, dialErr := DialCheck(context.Background(), "ip", netip.MustParseAddrPort("1.2.3.4:53"))
+		testutil.AssertErrorMsg(t, `dialing 1.2.3.4:53: bad network "ip"`, dialErr)
+	})
+}
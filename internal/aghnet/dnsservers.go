@@ -0,0 +1,65 @@
+package aghnet
+
+import (
+	"net/netip"
+)
+
+// defaultDNSPort is the default port for plain DNS.
+const defaultDNSPort = 53
+
+// SystemDNSServers returns the addresses of the DNS servers the operating
+// system is currently configured to use.  The port is set to 53 if the system
+// doesn't specify it.  The way the servers are obtained depends on the OS:
+//
+//   - on Windows, the DNS servers of all the network adapters are returned;
+//   - on macOS, the output of "scutil --dns" is parsed;
+//   - on other Unix systems, /etc/resolv.conf is parsed, and if it only contains
+//     the stub resolver address of systemd-resolved, the upstream servers are
+//     read from /run/systemd/resolve/resolv.conf.
+func SystemDNSServers() (servers []netip.AddrPort, err error) {
+	return systemDNSServers()
+}
+
+// SystemSearchDomains returns the DNS search domains the operating system is
+// currently configured to use for resolving the short names, in the order of
+// preference.  The domains are normalized, see NormalizeHostname, and
+// deduplicated.  The way the domains are obtained depends on the OS:
+//
+//   - on Windows, the DNS suffixes of all the network adapters are returned;
+//   - on macOS, the search domains from the output of "scutil --dns" are
+//     returned, while the per-domain resolvers are ignored;
+//   - on other Unix systems, the last search or domain directive of
+//     /etc/resolv.conf is used, as the resolver does, and if there is none and
+//     the file only contains the stub resolver address of systemd-resolved,
+//     the domains are read from /run/systemd/resolve/resolv.conf.
+func SystemSearchDomains() (domains []string, err error) {
+	return systemSearchDomains()
+}
+
+// appendUniqueDomain appends the normalized domain to domains unless it's empty
+// or already there.
+func appendUniqueDomain(domains []string, domain string) (res []string) {
+	domain = NormalizeHostname(domain)
+	if domain == "" {
+		return domains
+	}
+
+	for _, d := range domains {
+		if d == domain {
+			return domains
+		}
+	}
+
+	return append(domains, domain)
+}
+
+// appendUniqueAddrPort appends ap to aps unless it's already there.
+func appendUniqueAddrPort(aps []netip.AddrPort, ap netip.AddrPort) (res []netip.AddrPort) {
+	for _, a := range aps {
+		if a == ap {
+			return aps
+		}
+	}
+
+	return append(aps, ap)
+}
@@ -0,0 +1,111 @@
+//go:build darwin
+// +build darwin
+
+package aghnet
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+func systemDNSServers() (servers []netip.AddrPort, err error) {
+	out, err := scutilDNS()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseScutilDNS(out), nil
+}
+
+func systemSearchDomains() (domains []string, err error) {
+	out, err := scutilDNS()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseScutilSearchDomains(out), nil
+}
+
+// scutilDNS returns the output of "scutil --dns".
+func scutilDNS() (out string, err error) {
+	code, out, err := aghosRunCommand("scutil", "--dns")
+	if err != nil {
+		return "", fmt.Errorf("running scutil: %w", err)
+	} else if code != 0 {
+		return "", fmt.Errorf("scutil finished with code %d", code)
+	}
+
+	return out, nil
+}
+
+// parseScutilDNS returns the addresses of the nameservers from the output of
+// "scutil --dns", which contains lines like:
+//
+//   nameserver[0] : 192.168.1.1
+//   port          : 5353
+//
+// The port line, if any, follows the nameserver lines of the same resolver.
+func parseScutilDNS(out string) (servers []netip.AddrPort) {
+	var resolver []netip.Addr
+	port := uint16(defaultDNSPort)
+
+	flush := func() {
+		for _, addr := range resolver {
+			servers = appendUniqueAddrPort(servers, netip.AddrPortFrom(addr, port))
+		}
+
+		resolver, port = resolver[:0], defaultDNSPort
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "resolver #") {
+			flush()
+
+			continue
+		}
+
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		switch {
+		case strings.HasPrefix(key, "nameserver["):
+			if addr, err := netip.ParseAddr(val); err == nil {
+				resolver = append(resolver, addr)
+			}
+		case key == "port":
+			var p uint16
+			if _, err := fmt.Sscan(val, &p); err == nil && p != 0 {
+				port = p
+			}
+		}
+	}
+
+	flush()
+
+	return servers
+}
+
+// parseScutilSearchDomains returns the search domains from the output of
+// "scutil --dns", which contains lines like:
+//
+//   search domain[0] : example.com
+//
+// The domain lines describe the resolvers used only for the particular
+// domains, like "local" for mDNS, so those are skipped.
+func parseScutilSearchDomains(out string) (domains []string) {
+	for _, line := range strings.Split(out, "\n") {
+		key, val, ok := strings.Cut(line, ":")
+		if !ok || !strings.HasPrefix(strings.TrimSpace(key), "search domain[") {
+			continue
+		}
+
+		domains = appendUniqueDomain(domains, strings.TrimSpace(val))
+	}
+
+	return domains
+}
@@ -0,0 +1,62 @@
+//go:build darwin
+// +build darwin
+
+package aghnet
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScutilDNS(t *testing.T) {
+	const out = `DNS configuration` + nl +
+		nl +
+		`resolver #1` + nl +
+		`  search domain[0] : example.com` + nl +
+		`  nameserver[0] : 192.168.1.1` + nl +
+		`  nameserver[1] : fe80::1%en0` + nl +
+		`  if_index : 6 (en0)` + nl +
+		`  reach    : 0x00020002 (Reachable,Directly Reachable Address)` + nl +
+		nl +
+		`resolver #2` + nl +
+		`  domain   : local` + nl +
+		`  options  : mdns` + nl +
+		nl +
+		`resolver #3` + nl +
+		`  nameserver[0] : 127.0.0.1` + nl +
+		`  port     : 5353` + nl +
+		nl +
+		`DNS configuration (for scoped queries)` + nl +
+		nl +
+		`resolver #1` + nl +
+		`  nameserver[0] : 192.168.1.1` + nl
+
+	assert.Equal(t, []netip.AddrPort{
+		netip.MustParseAddrPort("192.168.1.1:53"),
+		netip.MustParseAddrPort("[fe80::1%en0]:53"),
+		netip.MustParseAddrPort("127.0.0.1:5353"),
+	}, parseScutilDNS(out))
+}
+
+func TestParseScutilSearchDomains(t *testing.T) {
+	const out = `DNS configuration` + nl +
+		nl +
+		`resolver #1` + nl +
+		`  search domain[0] : Example.COM` + nl +
+		`  search domain[1] : lan` + nl +
+		`  nameserver[0] : 192.168.1.1` + nl +
+		nl +
+		`resolver #2` + nl +
+		`  domain   : local` + nl +
+		`  options  : mdns` + nl +
+		nl +
+		`DNS configuration (for scoped queries)` + nl +
+		nl +
+		`resolver #1` + nl +
+		`  search domain[0] : example.com` + nl +
+		`  nameserver[0] : 192.168.1.1` + nl
+
+	assert.Equal(t, []string{"example.com", "lan"}, parseScutilSearchDomains(out))
+}
@@ -0,0 +1,173 @@
+//go:build !(darwin || windows)
+// +build !darwin,!windows
+
+package aghnet
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/netip"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// Paths to resolv.conf files relative to the root directory.
+const (
+	resolvConfPath = "etc/resolv.conf"
+
+	// resolvedResolvConfPath is the path to the file listing the upstream
+	// servers of systemd-resolved.
+	resolvedResolvConfPath = "run/systemd/resolve/resolv.conf"
+)
+
+// resolvedStubAddr is the address of systemd-resolved stub resolver.
+//
+// See https://www.freedesktop.org/software/systemd/man/systemd-resolved.service.html.
+var resolvedStubAddr = netip.AddrFrom4([4]byte{127, 0, 0, 53})
+
+func systemDNSServers() (servers []netip.AddrPort, err error) {
+	servers, err = readResolvConf(resolvConfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isResolvedStub(servers) {
+		return servers, nil
+	}
+
+	upstreams, err := readResolvConf(resolvedResolvConfPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return servers, nil
+		}
+
+		return nil, err
+	} else if len(upstreams) == 0 {
+		return servers, nil
+	}
+
+	return upstreams, nil
+}
+
+func systemSearchDomains() (domains []string, err error) {
+	var servers []netip.AddrPort
+	err = parseResolvConfFile(resolvConfPath, func(r io.Reader) (err error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		servers, err = parseResolvConf(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+
+		domains, err = parseResolvConfSearch(bytes.NewReader(data))
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	} else if len(domains) != 0 || !isResolvedStub(servers) {
+		return domains, nil
+	}
+
+	err = parseResolvConfFile(resolvedResolvConfPath, func(r io.Reader) (err error) {
+		domains, err = parseResolvConfSearch(r)
+
+		return err
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return domains, err
+}
+
+// isResolvedStub returns true if servers only contain the stub resolver of
+// systemd-resolved.
+func isResolvedStub(servers []netip.AddrPort) (ok bool) {
+	return len(servers) == 1 && servers[0].Addr() == resolvedStubAddr
+}
+
+// readResolvConf parses the resolv.conf file at the path relative to the root
+// directory.
+func readResolvConf(path string) (servers []netip.AddrPort, err error) {
+	err = parseResolvConfFile(path, func(r io.Reader) (err error) {
+		servers, err = parseResolvConf(r)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return servers, nil
+}
+
+// parseResolvConfFile opens the resolv.conf file at the path relative to the
+// root directory and parses it with parse.
+func parseResolvConfFile(path string, parse func(r io.Reader) (err error)) (err error) {
+	f, err := rootDirFS.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer func() { err = errors.WithDeferred(err, f.Close()) }()
+
+	err = parse(f)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// parseResolvConf returns the addresses of the nameserver directives from the
+// resolv.conf data read from r.  The invalid addresses are skipped, since the
+// resolver ignores those as well.
+//
+// See man resolv.conf(5).
+func parseResolvConf(r io.Reader) (servers []netip.AddrPort, err error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || fields[0] != "nameserver" {
+			continue
+		}
+
+		addr, perr := netip.ParseAddr(fields[1])
+		if perr != nil {
+			continue
+		}
+
+		servers = appendUniqueAddrPort(servers, netip.AddrPortFrom(addr, defaultDNSPort))
+	}
+
+	return servers, s.Err()
+}
+
+// parseResolvConfSearch returns the search domains from the resolv.conf data
+// read from r.  The search and the domain directives are mutually exclusive,
+// so the last of those wins.
+//
+// See man resolv.conf(5).
+func parseResolvConfSearch(r io.Reader) (domains []string, err error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || (fields[0] != "search" && fields[0] != "domain") {
+			continue
+		}
+
+		domains = nil
+		for _, d := range fields[1:] {
+			domains = appendUniqueDomain(domains, d)
+		}
+	}
+
+	return domains, s.Err()
+}
@@ -0,0 +1,171 @@
+//go:build !(darwin || windows)
+// +build !darwin,!windows
+
+package aghnet
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResolvConf(t *testing.T) {
+	const data = `# comment` + nl +
+		`nameserver 1.2.3.4` + nl +
+		`nameserver   fe80::1%eth0` + nl +
+		`nameserver 1.2.3.4` + nl +
+		`nameserver bad` + nl +
+		`nameserver` + nl +
+		`search example.com` + nl +
+		`options edns0` + nl +
+		`nameserver 2001:db8::1` + nl
+
+	servers, err := parseResolvConf(strings.NewReader(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, []netip.AddrPort{
+		netip.MustParseAddrPort("1.2.3.4:53"),
+		netip.MustParseAddrPort("[fe80::1%eth0]:53"),
+		netip.MustParseAddrPort("[2001:db8::1]:53"),
+	}, servers)
+}
+
+func TestSystemDNSServers_resolved(t *testing.T) {
+	stub := &fstest.MapFile{Data: []byte(`nameserver 127.0.0.53` + nl)}
+	upstreams := &fstest.MapFile{Data: []byte(`nameserver 192.168.1.1` + nl)}
+
+	testCases := []struct {
+		fsys fstest.MapFS
+		name string
+		want []netip.AddrPort
+	}{{
+		fsys: fstest.MapFS{
+			resolvConfPath:         stub,
+			resolvedResolvConfPath: upstreams,
+		},
+		name: "stub",
+		want: []netip.AddrPort{netip.MustParseAddrPort("192.168.1.1:53")},
+	}, {
+		fsys: fstest.MapFS{
+			resolvConfPath: stub,
+		},
+		name: "stub_only",
+		want: []netip.AddrPort{netip.MustParseAddrPort("127.0.0.53:53")},
+	}, {
+		fsys: fstest.MapFS{
+			resolvConfPath:         upstreams,
+			resolvedResolvConfPath: stub,
+		},
+		name: "no_stub",
+		want: []netip.AddrPort{netip.MustParseAddrPort("192.168.1.1:53")},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			substRootDirFS(t, tc.fsys)
+
+			servers, err := SystemDNSServers()
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, servers)
+		})
+	}
+}
+
+func TestParseResolvConfSearch(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		want []string
+	}{{
+		name: "none",
+		data: `nameserver 1.2.3.4` + nl,
+		want: nil,
+	}, {
+		name: "search",
+		data: `search Example.COM. lan example.com` + nl +
+			`nameserver 1.2.3.4` + nl,
+		want: []string{"example.com", "lan"},
+	}, {
+		name: "domain",
+		data: `domain lan` + nl,
+		want: []string{"lan"},
+	}, {
+		name: "last_wins",
+		data: `search example.com` + nl +
+			`domain lan` + nl +
+			`search home.arpa example.org` + nl,
+		want: []string{"home.arpa", "example.org"},
+	}, {
+		name: "root",
+		data: `search .` + nl,
+		want: nil,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			domains, err := parseResolvConfSearch(strings.NewReader(tc.data))
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, domains)
+		})
+	}
+}
+
+func TestSystemSearchDomains_resolved(t *testing.T) {
+	stub := &fstest.MapFile{Data: []byte(`nameserver 127.0.0.53` + nl)}
+	stubSearch := &fstest.MapFile{Data: []byte(
+		`nameserver 127.0.0.53` + nl + `search stub.lan` + nl,
+	)}
+	upstreams := &fstest.MapFile{Data: []byte(
+		`nameserver 192.168.1.1` + nl + `search lan` + nl,
+	)}
+
+	testCases := []struct {
+		fsys fstest.MapFS
+		name string
+		want []string
+	}{{
+		fsys: fstest.MapFS{
+			resolvConfPath:         stub,
+			resolvedResolvConfPath: upstreams,
+		},
+		name: "stub",
+		want: []string{"lan"},
+	}, {
+		fsys: fstest.MapFS{
+			resolvConfPath:         stubSearch,
+			resolvedResolvConfPath: upstreams,
+		},
+		name: "stub_with_search",
+		want: []string{"stub.lan"},
+	}, {
+		fsys: fstest.MapFS{
+			resolvConfPath: stub,
+		},
+		name: "stub_only",
+		want: nil,
+	}, {
+		fsys: fstest.MapFS{
+			resolvConfPath:         &fstest.MapFile{Data: []byte(`nameserver 1.1.1.1` + nl)},
+			resolvedResolvConfPath: upstreams,
+		},
+		name: "no_stub",
+		want: nil,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			substRootDirFS(t, tc.fsys)
+
+			domains, err := SystemSearchDomains()
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, domains)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+//go:build windows
+// +build windows
+
+package aghnet
+
+import (
+	"fmt"
+	"net/netip"
+	"unsafe"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"golang.org/x/sys/windows"
+)
+
+func systemDNSServers() (servers []netip.AddrPort, err error) {
+	var adapters *windows.IpAdapterAddresses
+	adapters, err = adaptersAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	for a := adapters; a != nil; a = a.Next {
+		if a.OperStatus != windows.IfOperStatusUp {
+			continue
+		}
+
+		for dns := a.FirstDnsServerAddress; dns != nil; dns = dns.Next {
+			addr, ok := netip.AddrFromSlice(dns.Address.IP())
+			if !ok {
+				continue
+			}
+
+			ap := netip.AddrPortFrom(addr.Unmap(), defaultDNSPort)
+			servers = appendUniqueAddrPort(servers, ap)
+		}
+	}
+
+	return servers, nil
+}
+
+func systemSearchDomains() (domains []string, err error) {
+	var adapters *windows.IpAdapterAddresses
+	adapters, err = adaptersAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	for a := adapters; a != nil; a = a.Next {
+		if a.OperStatus != windows.IfOperStatusUp || a.DnsSuffix == nil {
+			continue
+		}
+
+		domains = appendUniqueDomain(domains, windows.UTF16PtrToString(a.DnsSuffix))
+	}
+
+	return domains, nil
+}
+
+// adaptersAddresses returns the linked list of the network adapters addresses
+// obtained with GetAdaptersAddresses.
+func adaptersAddresses() (adapters *windows.IpAdapterAddresses, err error) {
+	// Start with the size recommended by the documentation.
+	//
+	// See https://docs.microsoft.com/en-us/windows/win32/api/iphlpapi/nf-iphlpapi-getadaptersaddresses.
+	size := uint32(15 * 1024)
+	for {
+		b := make([]byte, size)
+		adapters = (*windows.IpAdapterAddresses)(unsafe.Pointer(&b[0]))
+		err = windows.GetAdaptersAddresses(windows.AF_UNSPEC, windows.GAA_FLAG_INCLUDE_PREFIX, 0, adapters, &size)
+		if err == nil {
+			return adapters, nil
+		} else if !errors.Is(err, windows.ERROR_BUFFER_OVERFLOW) {
+			return nil, fmt.Errorf("getting adapters addresses: %w", err)
+		}
+	}
+}
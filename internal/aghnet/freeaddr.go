@@ -0,0 +1,83 @@
+package aghnet
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// ErrNoFreeAddr is returned by FindFreeAddr when all the addresses of the
+// subnet are either skipped or in use.
+const ErrNoFreeAddr errors.Error = "no free addresses"
+
+// FindFreeAddr returns the first assignable address of n, see SubnetRange,
+// which isn't in skip, isn't the default gateway of any network interface, see
+// AllGateways, and for which probe returns false.  probe should return true if
+// the address is in use, e.g. it may wrap AddrInUseOnLAN.  If probe is nil,
+// the addresses aren't probed.  The iteration stops with ctx's error once ctx
+// is done.  err is ErrNoFreeAddr if there are no free addresses.
+func FindFreeAddr(
+	ctx context.Context,
+	n netip.Prefix,
+	skip []netip.Addr,
+	probe func(ip netip.Addr) (inUse bool),
+) (ip netip.Addr, err error) {
+	defer func() { err = errors.Annotate(err, "finding free address in %s: %w", n) }()
+
+	if !n.IsValid() {
+		return netip.Addr{}, errors.Error("bad prefix")
+	}
+
+	skipSet := make(map[netip.Addr]struct{}, len(skip))
+	for _, a := range skip {
+		skipSet[CanonicalAddr(a).WithZone("")] = struct{}{}
+	}
+
+	addGatewaysToSkip(ctx, skipSet)
+
+	first, last := SubnetRange(n)
+	iterErr := IterateAddrRange(first, last, func(a netip.Addr) (cont bool) {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+
+		if _, ok := skipSet[a]; ok || (probe != nil && probe(a)) {
+			return true
+		}
+
+		ip = a
+
+		return false
+	})
+	if iterErr != nil {
+		// Shouldn't happen, since the range is computed from a valid prefix.
+		return netip.Addr{}, fmt.Errorf("iterating: %w", iterErr)
+	} else if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return netip.Addr{}, err
+	} else if !ip.IsValid() {
+		return netip.Addr{}, ErrNoFreeAddr
+	}
+
+	return ip, nil
+}
+
+// addGatewaysToSkip adds the default gateways of all network interfaces to
+// skipSet.  The errors are only logged, since the gateways are also expected
+// to respond to the probes.
+func addGatewaysToSkip(ctx context.Context, skipSet map[netip.Addr]struct{}) {
+	gws, err := AllGateways()
+	if err != nil {
+		loggerFromContext(ctx).Debug("skipping gateways: %s", err)
+
+		return
+	}
+
+	for _, gw := range gws {
+		if a, ok := netip.AddrFromSlice(gw); ok {
+			skipSet[CanonicalAddr(a)] = struct{}{}
+		}
+	}
+}
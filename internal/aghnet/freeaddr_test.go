@@ -0,0 +1,84 @@
+package aghnet
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindFreeAddr(t *testing.T) {
+	n := netip.MustParsePrefix("198.51.100.0/29")
+	inUse := func(used ...string) (probe func(ip netip.Addr) (inUse bool)) {
+		set := map[netip.Addr]struct{}{}
+		for _, u := range used {
+			set[netip.MustParseAddr(u)] = struct{}{}
+		}
+
+		return func(ip netip.Addr) (ok bool) {
+			_, ok = set[ip]
+
+			return ok
+		}
+	}
+
+	testCases := []struct {
+		probe      func(ip netip.Addr) (inUse bool)
+		want       netip.Addr
+		name       string
+		wantErrMsg string
+		skip       []netip.Addr
+	}{{
+		probe:      nil,
+		want:       netip.MustParseAddr("198.51.100.1"),
+		name:       "first",
+		wantErrMsg: "",
+		skip:       nil,
+	}, {
+		probe: inUse("198.51.100.3"),
+		want:  netip.MustParseAddr("198.51.100.4"),
+		name:  "skip_and_probe",
+		skip: []netip.Addr{
+			netip.MustParseAddr("198.51.100.1"),
+			netip.MustParseAddr("::ffff:198.51.100.2"),
+		},
+		wantErrMsg: "",
+	}, {
+		probe: inUse("198.51.100.4", "198.51.100.5", "198.51.100.6"),
+		want:  netip.Addr{},
+		name:  "exhausted",
+		skip: []netip.Addr{
+			netip.MustParseAddr("198.51.100.1"),
+			netip.MustParseAddr("198.51.100.2"),
+			netip.MustParseAddr("198.51.100.3"),
+		},
+		wantErrMsg: "finding free address in 198.51.100.0/29: no free addresses",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ip, err := FindFreeAddr(context.Background(), n, tc.skip, tc.probe)
+			if tc.wantErrMsg != "" {
+				assert.ErrorIs(t, err, ErrNoFreeAddr)
+				testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, ip)
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := FindFreeAddr(ctx, netip.MustParsePrefix("2001:db8::/64"), nil, nil)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,232 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// allGateways returns the gateways of the default routes from the main routing
+// table dumped through netlink.
+func allGateways() (gws map[string]net.IP, err error) {
+	return routeGateways(unix.AF_UNSPEC)
+}
+
+// ipv6Gateway returns the gateway of the IPv6 default route through iface from
+// the main routing table.
+func ipv6Gateway(iface *net.Interface) (gw netip.Addr, err error) {
+	gws, err := routeGateways(unix.AF_INET6)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	ip, ok := gws[iface.Name]
+	if !ok {
+		return netip.Addr{}, errors.Error("no ipv6 default gateway")
+	}
+
+	gw, _ = netip.AddrFromSlice(ip)
+	if gw.IsLinkLocalUnicast() {
+		gw = gw.WithZone(iface.Name)
+	}
+
+	return gw, nil
+}
+
+// routeGateways returns the gateways of the default routes of family, which is
+// either AF_INET, AF_INET6, or AF_UNSPEC for both, from the main routing table
+// dumped through netlink.
+func routeGateways(family uint8) (gws map[string]net.IP, err error) {
+	msgs, names, err := dumpRoutes(family)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	return gatewaysFromRoutes(msgs, names)
+}
+
+// defaultRouteIface returns the name of the network interface carrying the
+// default route from the main routing table dumped through netlink.
+func defaultRouteIface() (name string, err error) {
+	msgs, names, err := dumpRoutes(unix.AF_UNSPEC)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return "", err
+	}
+
+	return defaultRouteIfaceFromRoutes(msgs, names)
+}
+
+// dumpRoutes returns the routes of family dumped through netlink along with the
+// names of the network interfaces mapped by their indexes.
+func dumpRoutes(family uint8) (msgs []netlink.Message, names map[uint32]string, err error) {
+	ifaces, err := netInterfaces()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, nil, err
+	}
+
+	names = make(map[uint32]string, len(ifaces))
+	for _, iface := range ifaces {
+		names[uint32(iface.Index)] = iface.Name
+	}
+
+	req := make([]byte, unix.SizeofRtMsg)
+	req[0] = family
+
+	msgs, err = netlinkRouteDump(unix.RTM_GETROUTE, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dumping routes: %w", err)
+	}
+
+	return msgs, names, nil
+}
+
+// defaultRouteIfaceFromRoutes returns the name of the outgoing interface of the
+// default route from msgs with the lowest metric.  The IPv4 default routes are
+// preferred over the IPv6 ones, since those are the ones most of the traffic
+// goes through.  The routes without a gateway, e.g. through the point-to-point
+// links, are considered as well.  name is empty if there is no default route.
+// names maps the indexes of the network interfaces to their names.
+func defaultRouteIfaceFromRoutes(
+	msgs []netlink.Message,
+	names map[uint32]string,
+) (name string, err error) {
+	var best route
+	for _, msg := range msgs {
+		var r route
+		r, err = parseRouteMsg(msg.Data)
+		if err != nil {
+			return "", err
+		}
+
+		n, ok := names[r.oif]
+		if !ok || !r.isDefault || !r.isBetterThan(best, name != "") {
+			continue
+		}
+
+		best, name = r, n
+	}
+
+	return name, nil
+}
+
+// gatewaysFromRoutes returns the gateways of the default routes from msgs
+// mapped by the names of the outgoing interfaces.  For each interface, the
+// gateway of the route preferred by isBetterThan is chosen.  names maps the
+// indexes of the network interfaces to their names.
+func gatewaysFromRoutes(
+	msgs []netlink.Message,
+	names map[uint32]string,
+) (gws map[string]net.IP, err error) {
+	best := map[string]route{}
+	for _, msg := range msgs {
+		var r route
+		r, err = parseRouteMsg(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := names[r.oif]
+		if !ok || !r.isDefault || !r.gw.IsValid() {
+			continue
+		}
+
+		if prev, has := best[name]; r.isBetterThan(prev, has) {
+			best[name] = r
+		}
+	}
+
+	gws = make(map[string]net.IP, len(best))
+	for name, r := range best {
+		gws[name] = r.gw.AsSlice()
+	}
+
+	return gws, nil
+}
+
+// route is a single entry of the kernel's routing table.
+type route struct {
+	// gw is the gateway address, if any.
+	gw netip.Addr
+
+	// oif is the index of the outgoing network interface.
+	oif uint32
+
+	// priority is the metric of the route.  Lower is preferred.
+	priority uint32
+
+	// family is the address family of the route, either AF_INET or AF_INET6.
+	family uint8
+
+	// isDefault is true if the route is the default unicast one from the main
+	// routing table.
+	isDefault bool
+}
+
+// parseRouteMsg parses the data of the RTM_NEWROUTE netlink message, which
+// consists of struct rtmsg followed by the attributes.
+//
+// See man rtnetlink(7).
+func parseRouteMsg(data []byte) (r route, err error) {
+	if len(data) < unix.SizeofRtMsg {
+		return route{}, fmt.Errorf("route message is too short: %d bytes", len(data))
+	}
+
+	r.family = data[0]
+	dstLen, table, typ := data[1], uint32(data[4]), data[7]
+
+	ad, err := netlink.NewAttributeDecoder(data[unix.SizeofRtMsg:])
+	if err != nil {
+		return route{}, fmt.Errorf("decoding route attributes: %w", err)
+	}
+
+	table = r.decodeAttrs(ad, table)
+	r.isDefault = dstLen == 0 && table == unix.RT_TABLE_MAIN && typ == unix.RTN_UNICAST
+
+	return r, ad.Err()
+}
+
+// decodeAttrs sets the fields of r from the route attributes decoded by ad and
+// returns the routing table, which is table unless overridden by the attribute.
+func (r *route) decodeAttrs(ad *netlink.AttributeDecoder, table uint32) (res uint32) {
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.RTA_GATEWAY:
+			if gw, ok := netip.AddrFromSlice(ad.Bytes()); ok {
+				r.gw = gw.Unmap()
+			}
+		case unix.RTA_OIF:
+			r.oif = ad.Uint32()
+		case unix.RTA_PRIORITY:
+			r.priority = ad.Uint32()
+		case unix.RTA_TABLE:
+			// The table in the header is only a single byte, so the
+			// attribute takes precedence.
+			table = ad.Uint32()
+		}
+	}
+
+	return table
+}
+
+// isBetterThan returns true if r should be preferred over other, an IPv4 route
+// over an IPv6 one and a route with the lower metric otherwise.  hasOther is
+// false if there is no other route to compare with.
+func (r route) isBetterThan(other route, hasOther bool) (ok bool) {
+	if !hasOther {
+		return true
+	} else if r.family != other.family {
+		return r.family == unix.AF_INET
+	}
+
+	return r.priority < other.priority
+}
@@ -0,0 +1,173 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/mdlayher/netlink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// newRouteMsg is a helper that returns the RTM_NEWROUTE message with the
+// attributes describing the route via gw through the network interface with
+// the index.
+func newRouteMsg(t *testing.T, dstLen uint8, gw netip.Addr, oif uint32) (msg netlink.Message) {
+	t.Helper()
+
+	ae := netlink.NewAttributeEncoder()
+	if gw.IsValid() {
+		ae.Bytes(unix.RTA_GATEWAY, gw.AsSlice())
+	}
+	ae.Uint32(unix.RTA_OIF, oif)
+	ae.Uint32(unix.RTA_TABLE, unix.RT_TABLE_MAIN)
+
+	attrs, err := ae.Encode()
+	require.NoError(t, err)
+
+	data := make([]byte, unix.SizeofRtMsg, unix.SizeofRtMsg+len(attrs))
+	data[0] = unix.AF_INET
+	if gw.Is6() {
+		data[0] = unix.AF_INET6
+	}
+	data[1] = dstLen
+	data[4] = unix.RT_TABLE_MAIN
+	data[7] = unix.RTN_UNICAST
+
+	return netlink.Message{Data: append(data, attrs...)}
+}
+
+func TestGatewaysFromRoutes(t *testing.T) {
+	names := map[uint32]string{
+		2: "eth0",
+		3: "eth1",
+		4: "wlan0",
+	}
+
+	msgs := []netlink.Message{
+		// Default IPv6 route through eth0.
+		newRouteMsg(t, 0, netip.MustParseAddr("2001:db8::1"), 2),
+		// Default IPv4 route through eth0.
+		newRouteMsg(t, 0, netip.MustParseAddr("192.168.1.1"), 2),
+		// Non-default route through eth1.
+		newRouteMsg(t, 24, netip.MustParseAddr("10.0.0.1"), 3),
+		// Default IPv6 route through wlan0.
+		newRouteMsg(t, 0, netip.MustParseAddr("2001:db8:1::1"), 4),
+		// Default route without a gateway through an unknown interface.
+		newRouteMsg(t, 0, netip.Addr{}, 5),
+	}
+
+	gws, err := gatewaysFromRoutes(msgs, names)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]net.IP{
+		"eth0":  net.IP{192, 168, 1, 1},
+		"wlan0": net.ParseIP("2001:db8:1::1"),
+	}, gws)
+
+	t.Run("bad_msg", func(t *testing.T) {
+		_, err = gatewaysFromRoutes([]netlink.Message{{Data: []byte{1}}}, names)
+		testutil.AssertErrorMsg(t, "route message is too short: 1 bytes", err)
+	})
+
+	t.Run("metric", func(t *testing.T) {
+		gws, err = gatewaysFromRoutes([]netlink.Message{
+			newPrioRouteMsg(t, netip.MustParseAddr("192.168.1.1"), 2, 600),
+			newPrioRouteMsg(t, netip.MustParseAddr("192.168.2.1"), 2, 100),
+			newPrioRouteMsg(t, netip.MustParseAddr("192.168.3.1"), 2, 200),
+		}, names)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]net.IP{"eth0": {192, 168, 2, 1}}, gws)
+	})
+}
+
+// newPrioRouteMsg is a helper that returns the message like newRouteMsg does
+// with the additional metric of the route.
+func newPrioRouteMsg(
+	t *testing.T,
+	gw netip.Addr,
+	oif uint32,
+	prio uint32,
+) (msg netlink.Message) {
+	t.Helper()
+
+	msg = newRouteMsg(t, 0, gw, oif)
+
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint32(unix.RTA_PRIORITY, prio)
+
+	attrs, err := ae.Encode()
+	require.NoError(t, err)
+
+	msg.Data = append(msg.Data, attrs...)
+
+	return msg
+}
+
+func TestDefaultRouteIfaceFromRoutes(t *testing.T) {
+	names := map[uint32]string{
+		2: "eth0",
+		3: "eth1",
+		4: "wlan0",
+	}
+
+	gw4 := netip.MustParseAddr("192.168.1.1")
+	gw6 := netip.MustParseAddr("2001:db8::1")
+
+	testCases := []struct {
+		name string
+		want string
+		msgs []netlink.Message
+	}{{
+		name: "none",
+		want: "",
+		msgs: []netlink.Message{
+			newRouteMsg(t, 24, gw4, 2),
+		},
+	}, {
+		name: "ipv4_preferred",
+		want: "eth1",
+		msgs: []netlink.Message{
+			newRouteMsg(t, 0, gw6, 2),
+			newRouteMsg(t, 0, gw4, 3),
+		},
+	}, {
+		name: "ipv6_only",
+		want: "eth0",
+		msgs: []netlink.Message{
+			newRouteMsg(t, 0, gw6, 2),
+			newRouteMsg(t, 24, gw4, 3),
+		},
+	}, {
+		name: "metric",
+		want: "eth0",
+		msgs: []netlink.Message{
+			newPrioRouteMsg(t, gw4, 4, 600),
+			newPrioRouteMsg(t, gw4, 2, 100),
+			newPrioRouteMsg(t, gw4, 3, 200),
+		},
+	}, {
+		name: "unknown_iface",
+		want: "eth1",
+		msgs: []netlink.Message{
+			newRouteMsg(t, 0, gw4, 5),
+			newRouteMsg(t, 0, gw4, 3),
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, err := defaultRouteIfaceFromRoutes(tc.msgs, names)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, name)
+		})
+	}
+}
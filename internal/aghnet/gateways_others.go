@@ -0,0 +1,51 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import (
+	"net"
+)
+
+// allGateways calls GatewayIP for each network interface, since dumping the
+// routing table isn't supported on this OS yet.
+func allGateways() (gws map[string]net.IP, err error) {
+	ifaces, err := netInterfaces()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	gws = map[string]net.IP{}
+	for _, iface := range ifaces {
+		if gw := GatewayIP(iface.Name); gw != nil {
+			gws[iface.Name] = gw
+		}
+	}
+
+	return gws, nil
+}
+
+// defaultRouteIface returns the name of the first network interface with an
+// IPv4 gateway or, if there is none, the first one with any gateway, since
+// dumping the routing table isn't supported on this OS yet.
+func defaultRouteIface() (name string, err error) {
+	ifaces, err := netInterfaces()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return "", err
+	}
+
+	for _, iface := range ifaces {
+		gw := GatewayIP(iface.Name)
+		if gw == nil {
+			continue
+		} else if gw.To4() != nil {
+			return iface.Name, nil
+		} else if name == "" {
+			name = iface.Name
+		}
+	}
+
+	return name, nil
+}
@@ -0,0 +1,135 @@
+package aghnet
+
+import (
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/netutil"
+	"golang.org/x/net/idna"
+)
+
+// HostnameRule is the rule of hostname and domain name validation which has
+// been violated.
+type HostnameRule string
+
+// Hostname validation rules for HostnameError.
+const (
+	// HostnameRuleEmpty means that the name or one of its labels is empty.
+	HostnameRuleEmpty HostnameRule = "empty"
+
+	// HostnameRuleLabelLength means that one of the labels is longer than
+	// netutil.MaxDomainLabelLen.
+	HostnameRuleLabelLength HostnameRule = "label_length"
+
+	// HostnameRuleNameLength means that the whole name is longer than
+	// netutil.MaxDomainNameLen.
+	HostnameRuleNameLength HostnameRule = "name_length"
+
+	// HostnameRuleChar means that the name contains a character which isn't
+	// allowed in hostnames.
+	HostnameRuleChar HostnameRule = "char"
+
+	// HostnameRuleHyphen means that one of the labels starts or ends with a
+	// hyphen.
+	HostnameRuleHyphen HostnameRule = "hyphen"
+
+	// HostnameRuleIDNA means that the name can't be converted into punycode.
+	HostnameRuleIDNA HostnameRule = "idna"
+)
+
+// HostnameError is the type of errors returned by ValidateHostname and
+// ValidateDomainName.
+type HostnameError struct {
+	// Err is the underlying error, which is usually a *netutil.AddrError.
+	Err error
+
+	// Rule is the violated rule.
+	Rule HostnameRule
+
+	// Name is the invalid name as passed to the validation function.
+	Name string
+}
+
+// type check
+var _ error = (*HostnameError)(nil)
+
+// Error implements the error interface for *HostnameError.
+func (err *HostnameError) Error() (msg string) {
+	return err.Err.Error()
+}
+
+// Unwrap implements the errors.Wrapper interface for *HostnameError.
+func (err *HostnameError) Unwrap() (unwrapped error) {
+	return err.Err
+}
+
+// ValidateHostname returns an error if s isn't a valid hostname, that is a
+// single label of a domain name, like the ones sent by DHCP clients.
+// Internationalized hostnames are validated in their punycode form.  Any error
+// returned has the type of *HostnameError.
+func ValidateHostname(s string) (err error) {
+	ascii, err := idna.ToASCII(s)
+	if err != nil {
+		err = &netutil.AddrError{
+			Err:  err,
+			Kind: netutil.AddrKindLabel,
+			Addr: s,
+		}
+	} else {
+		err = netutil.ValidateDomainNameLabel(ascii)
+	}
+
+	return newHostnameError(err, s)
+}
+
+// ValidateDomainName returns an error if s isn't a valid domain name.
+// Internationalized domain names are validated in their punycode form.  A
+// trailing dot isn't allowed, so FQDNs should be passed through
+// NormalizeHostname first.  Any error returned has the type of *HostnameError.
+func ValidateDomainName(s string) (err error) {
+	return newHostnameError(netutil.ValidateDomainName(s), s)
+}
+
+// NormalizeHostname returns the lowercased s with the trailing dot removed.
+func NormalizeHostname(s string) (norm string) {
+	return strings.TrimSuffix(strings.ToLower(s), ".")
+}
+
+// newHostnameError wraps the validation error returned by netutil into a
+// *HostnameError with the appropriate rule.  It returns nil if err is nil.
+func newHostnameError(err error, name string) (herr error) {
+	if err == nil {
+		return nil
+	}
+
+	return &HostnameError{
+		Err:  err,
+		Rule: hostnameRule(err),
+		Name: name,
+	}
+}
+
+// hostnameRule returns the rule violation of which caused err.
+func hostnameRule(err error) (rule HostnameRule) {
+	lenErr := &netutil.LengthError{}
+	runeErr := &netutil.RuneError{}
+
+	switch {
+	case errors.Is(err, netutil.ErrAddrIsEmpty), errors.Is(err, netutil.ErrLabelIsEmpty):
+		return HostnameRuleEmpty
+	case errors.As(err, &lenErr):
+		if lenErr.Kind == netutil.AddrKindLabel {
+			return HostnameRuleLabelLength
+		}
+
+		return HostnameRuleNameLength
+	case errors.As(err, &runeErr):
+		if runeErr.Rune == '-' {
+			return HostnameRuleHyphen
+		}
+
+		return HostnameRuleChar
+	default:
+		return HostnameRuleIDNA
+	}
+}
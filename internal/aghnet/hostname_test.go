@@ -0,0 +1,136 @@
+package aghnet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDomainName(t *testing.T) {
+	longLabel := strings.Repeat("a", 64)
+	longName := strings.Repeat("a.", 127)
+
+	testCases := []struct {
+		name     string
+		in       string
+		wantRule HostnameRule
+		wantErr  bool
+	}{{
+		name:    "valid",
+		in:      "www.example.com",
+		wantErr: false,
+	}, {
+		name:    "idna",
+		in:      "пример.рф",
+		wantErr: false,
+	}, {
+		name:     "empty",
+		in:       "",
+		wantRule: HostnameRuleEmpty,
+		wantErr:  true,
+	}, {
+		name:     "trailing_dot",
+		in:       "example.com.",
+		wantRule: HostnameRuleEmpty,
+		wantErr:  true,
+	}, {
+		name:     "long_label",
+		in:       longLabel + ".com",
+		wantRule: HostnameRuleLabelLength,
+		wantErr:  true,
+	}, {
+		name:     "long_name",
+		in:       longName + "com",
+		wantRule: HostnameRuleNameLength,
+		wantErr:  true,
+	}, {
+		name:     "bad_char",
+		in:       "exa_mple.com",
+		wantRule: HostnameRuleChar,
+		wantErr:  true,
+	}, {
+		name:     "leading_hyphen",
+		in:       "-example.com",
+		wantRule: HostnameRuleHyphen,
+		wantErr:  true,
+	}, {
+		name:     "trailing_hyphen",
+		in:       "example-.com",
+		wantRule: HostnameRuleHyphen,
+		wantErr:  true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDomainName(tc.in)
+			if !tc.wantErr {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			herr := &HostnameError{}
+			require.True(t, errors.As(err, &herr))
+
+			assert.Equal(t, tc.wantRule, herr.Rule)
+			assert.Equal(t, tc.in, herr.Name)
+		})
+	}
+}
+
+func TestValidateHostname(t *testing.T) {
+	testCases := []struct {
+		name     string
+		in       string
+		wantRule HostnameRule
+		wantErr  bool
+	}{{
+		name:    "valid",
+		in:      "my-laptop",
+		wantErr: false,
+	}, {
+		name:    "idna",
+		in:      "ноутбук",
+		wantErr: false,
+	}, {
+		name:     "empty",
+		in:       "",
+		wantRule: HostnameRuleEmpty,
+		wantErr:  true,
+	}, {
+		name:     "dotted",
+		in:       "my.laptop",
+		wantRule: HostnameRuleChar,
+		wantErr:  true,
+	}, {
+		name:     "hyphen",
+		in:       "laptop-",
+		wantRule: HostnameRuleHyphen,
+		wantErr:  true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateHostname(tc.in)
+			if !tc.wantErr {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			herr := &HostnameError{}
+			require.True(t, errors.As(err, &herr))
+
+			assert.Equal(t, tc.wantRule, herr.Rule)
+		})
+	}
+}
+
+func TestNormalizeHostname(t *testing.T) {
+	assert.Equal(t, "example.com", NormalizeHostname("Example.COM."))
+	assert.Equal(t, "host", NormalizeHostname("host"))
+	assert.Equal(t, "", NormalizeHostname("."))
+}
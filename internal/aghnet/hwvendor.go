@@ -0,0 +1,79 @@
+package aghnet
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/hex"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ouiData is the trimmed IEEE OUI registry.  See oui.txt for the format.
+//
+//go:embed oui.txt
+var ouiData string
+
+// ouiLen is the length of an organizationally unique identifier in bytes.
+const ouiLen = 3
+
+// oui is an organizationally unique identifier, the first three octets of a
+// universally administered hardware address.
+type oui [ouiLen]byte
+
+var (
+	// ouiVendors maps OUIs to the names of organizations.  It's parsed from
+	// ouiData on the first call to HardwareVendor.
+	ouiVendors map[oui]string
+
+	// ouiVendorsOnce protects ouiVendors.
+	ouiVendorsOnce = &sync.Once{}
+)
+
+// parseOUIData parses the OUI registry data in the format of oui.txt.  Lines
+// that can't be parsed are skipped.
+func parseOUIData(data string) (vendors map[oui]string) {
+	vendors = map[oui]string{}
+
+	s := bufio.NewScanner(strings.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		prefix, name, ok := strings.Cut(line, "\t")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || hex.DecodedLen(len(prefix)) != ouiLen {
+			continue
+		}
+
+		var o oui
+		_, err := hex.Decode(o[:], []byte(prefix))
+		if err != nil {
+			continue
+		}
+
+		vendors[o] = name
+	}
+
+	return vendors
+}
+
+// HardwareVendor returns the name of the manufacturer of the network interface
+// with the hardware address hwaddr.  ok is false if the vendor is unknown or if
+// hwaddr is locally administered, since the first octets of such addresses
+// don't identify any organization.
+func HardwareVendor(hwaddr net.HardwareAddr) (vendor string, ok bool) {
+	// The U/L bit of the first octet is set for locally administered
+	// addresses.
+	if len(hwaddr) < ouiLen || hwaddr[0]&0b10 != 0 {
+		return "", false
+	}
+
+	ouiVendorsOnce.Do(func() { ouiVendors = parseOUIData(ouiData) })
+
+	vendor, ok = ouiVendors[oui{hwaddr[0], hwaddr[1], hwaddr[2]}]
+
+	return vendor, ok
+}
@@ -0,0 +1,66 @@
+package aghnet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHardwareVendor(t *testing.T) {
+	testCases := []struct {
+		name       string
+		hwaddr     net.HardwareAddr
+		wantVendor string
+		wantOK     bool
+	}{{
+		name:       "known",
+		hwaddr:     net.HardwareAddr{0xb8, 0x27, 0xeb, 0x01, 0x02, 0x03},
+		wantVendor: "Raspberry Pi Foundation",
+		wantOK:     true,
+	}, {
+		name:       "unknown",
+		hwaddr:     net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		wantVendor: "",
+		wantOK:     false,
+	}, {
+		name:       "locally_administered",
+		hwaddr:     net.HardwareAddr{0x52, 0x54, 0x00, 0x12, 0x34, 0x56},
+		wantVendor: "",
+		wantOK:     false,
+	}, {
+		name:       "short",
+		hwaddr:     net.HardwareAddr{0xb8, 0x27},
+		wantVendor: "",
+		wantOK:     false,
+	}, {
+		name:       "nil",
+		hwaddr:     nil,
+		wantVendor: "",
+		wantOK:     false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vendor, ok := HardwareVendor(tc.hwaddr)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantVendor, vendor)
+		})
+	}
+}
+
+func TestParseOUIData(t *testing.T) {
+	const data = `# Comment.` + nl +
+		nl +
+		`B827EB	Raspberry Pi Foundation` + nl +
+		`b827ec	Lower Case` + nl +
+		`B827	Too Short` + nl +
+		`ZZZZZZ	Not Hex` + nl +
+		`B827ED` + nl +
+		`B827EE	` + nl
+
+	assert.Equal(t, map[oui]string{
+		{0xb8, 0x27, 0xeb}: "Raspberry Pi Foundation",
+		{0xb8, 0x27, 0xec}: "Lower Case",
+	}, parseOUIData(data))
+}
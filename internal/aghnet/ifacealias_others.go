@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package aghnet
+
+// ifaceNameByAlias returns false, since the network interfaces have no aliases
+// other than their names on this OS.
+func ifaceNameByAlias(_ string) (name string, ok bool) {
+	return "", false
+}
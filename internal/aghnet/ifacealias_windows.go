@@ -0,0 +1,31 @@
+//go:build windows
+// +build windows
+
+package aghnet
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// ifaceNameByAlias returns the friendly name of the network adapter with the
+// GUID alias, with or without the curly braces, which Windows uses as the name
+// of the network interface.
+func ifaceNameByAlias(alias string) (name string, ok bool) {
+	adapters, err := adaptersAddresses()
+	if err != nil {
+		currentLogger().Debug("resolving interface alias %q: %s", alias, err)
+
+		return "", false
+	}
+
+	guid := strings.Trim(alias, "{}")
+	for a := adapters; a != nil; a = a.Next {
+		if strings.EqualFold(strings.Trim(windows.BytePtrToString(a.AdapterName), "{}"), guid) {
+			return windows.UTF16PtrToString(a.FriendlyName), true
+		}
+	}
+
+	return "", false
+}
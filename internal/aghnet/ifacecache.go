@@ -0,0 +1,177 @@
+package aghnet
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// localAddrsTTL is the time after which the local addresses cached for
+// IsLocalAddr are considered stale.
+const localAddrsTTL = 1 * time.Minute
+
+// localAddrsCache is the cache of the local addresses used by IsLocalAddr.  It
+// is only reassigned in tests.
+var localAddrsCache = newLocalAddrsCache()
+
+// newLocalAddrsCache returns a new cache for localAddrsCache.  It lists all the
+// addresses of the network interfaces, including the link-local ones, see
+// listIfaceAddrs.
+func newLocalAddrsCache() (c *InterfaceCache) {
+	return NewInterfaceCache(localAddrsTTL, listIfaceAddrs)
+}
+
+// listIfaceAddrs is an InterfaceLister returning the network interfaces with
+// only the names and all the IP addresses set, see CollectIfaceAddrs.
+func listIfaceAddrs() (ifaces []*NetInterface, err error) {
+	ifaceAddrs, err := CollectIfaceAddrs()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	ifaces = make([]*NetInterface, 0, len(ifaceAddrs))
+	for name, addrs := range ifaceAddrs {
+		iface := &NetInterface{Name: name}
+		for _, a := range addrs {
+			iface.Addresses = append(iface.Addresses, a.AsSlice())
+		}
+
+		ifaces = append(ifaces, iface)
+	}
+
+	return ifaces, nil
+}
+
+// ifaceAddrSet returns the set of the unicast addresses of ifaces as described
+// by LocalAddrSet.
+func ifaceAddrSet(ifaces []*NetInterface) (set map[netip.Addr]struct{}) {
+	set = map[netip.Addr]struct{}{}
+	for _, iface := range ifaces {
+		for _, ip := range iface.Addresses {
+			if a, ok := netip.AddrFromSlice(ip); ok {
+				addToLocalAddrSet(set, a)
+			}
+		}
+	}
+
+	return set
+}
+
+// InterfaceLister is the signature of functions returning the network
+// interfaces.
+type InterfaceLister func() (ifaces []*NetInterface, err error)
+
+// InterfaceCache is a cached snapshot of the network interfaces, which is
+// refreshed once it's older than the configured TTL.  It is safe for concurrent
+// use.
+type InterfaceCache struct {
+	// mu protects ifaces, addrs, and updated.
+	mu *sync.Mutex
+
+	// list is used to get the actual network interfaces.
+	list InterfaceLister
+
+	// now returns the current time.  It's only substituted in tests.
+	now func() (t time.Time)
+
+	// ifaces is the current snapshot.
+	ifaces []*NetInterface
+
+	// addrs is the set of the unicast addresses of ifaces, see LocalAddrSet.
+	addrs map[netip.Addr]struct{}
+
+	// updated is the time of the last refresh, either successful or not.
+	updated time.Time
+
+	// ttl is the time after which the snapshot is considered stale.
+	ttl time.Duration
+}
+
+// NewInterfaceCache returns a new properly initialized *InterfaceCache which
+// uses list to get the network interfaces.  If list is nil,
+// GetValidNetInterfacesForWeb is used.  If ttl is zero, the snapshot is only
+// updated by Refresh and by the first call to Get.
+func NewInterfaceCache(ttl time.Duration, list InterfaceLister) (c *InterfaceCache) {
+	if list == nil {
+		list = GetValidNetInterfacesForWeb
+	}
+
+	return &InterfaceCache{
+		mu:   &sync.Mutex{},
+		list: list,
+		now:  time.Now,
+		ttl:  ttl,
+	}
+}
+
+// Get returns the cached snapshot of the network interfaces refreshing it if
+// it's stale.  If the refresh fails, the error is logged and the previous
+// snapshot is returned until it's stale again.  Callers must not modify the returned interfaces.
+func (c *InterfaceCache) Get() (ifaces []*NetInterface) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.isStale() {
+		err := c.refresh()
+		if err != nil {
+			currentLogger().Debug("refreshing interfaces: %s", err)
+		}
+	}
+
+	return c.ifaces
+}
+
+// LocalAddrSet is like the package-level LocalAddrSet, but returns the cached
+// set refreshing it along with the interfaces if it's stale, like Get does.
+// The set is built from the addresses of the cached interfaces, so it only
+// contains the ones reported by the lister of c, e.g. the default one omits the
+// link-local addresses.  Callers must not modify the returned set.
+func (c *InterfaceCache) LocalAddrSet() (set map[netip.Addr]struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.isStale() {
+		err := c.refresh()
+		if err != nil {
+			currentLogger().Debug("refreshing interfaces: %s", err)
+		}
+	}
+
+	return c.addrs
+}
+
+// Refresh unconditionally updates the snapshot of the network interfaces.  The
+// previous snapshot is kept if err is not nil.
+func (c *InterfaceCache) Refresh() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.refresh()
+}
+
+// isStale returns true if the snapshot should be updated.  c.mu is expected to
+// be locked.
+func (c *InterfaceCache) isStale() (ok bool) {
+	if c.updated.IsZero() {
+		return true
+	}
+
+	return c.ttl > 0 && c.now().Sub(c.updated) >= c.ttl
+}
+
+// refresh updates the snapshot.  The time of the refresh is recorded even if it
+// fails, so that the failing lister isn't called on each access.  c.mu is
+// expected to be locked.
+func (c *InterfaceCache) refresh() (err error) {
+	c.updated = c.now()
+
+	ifaces, err := c.list()
+	if err != nil {
+		return err
+	}
+
+	c.ifaces, c.addrs = ifaces, ifaceAddrSet(ifaces)
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterfaceCache(t *testing.T) {
+	const ttl = time.Minute
+
+	var (
+		calls   int
+		listErr error
+	)
+	c := NewInterfaceCache(ttl, func() (ifaces []*NetInterface, err error) {
+		calls++
+		if listErr != nil {
+			return nil, listErr
+		}
+
+		return []*NetInterface{{Name: "eth0", MTU: calls}}, nil
+	})
+
+	now := time.Unix(0, 0)
+	c.now = func() (t time.Time) { return now }
+
+	ifaces := c.Get()
+	require.Len(t, ifaces, 1)
+	assert.Equal(t, 1, ifaces[0].MTU)
+
+	t.Run("cached", func(t *testing.T) {
+		now = now.Add(ttl / 2)
+
+		ifaces = c.Get()
+		require.Len(t, ifaces, 1)
+		assert.Equal(t, 1, ifaces[0].MTU)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		now = now.Add(ttl)
+
+		ifaces = c.Get()
+		require.Len(t, ifaces, 1)
+		assert.Equal(t, 2, ifaces[0].MTU)
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		require.NoError(t, c.Refresh())
+
+		ifaces = c.Get()
+		require.Len(t, ifaces, 1)
+		assert.Equal(t, 3, ifaces[0].MTU)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		const errTest errors.Error = "test error"
+
+		listErr = errTest
+		t.Cleanup(func() { listErr = nil })
+
+		assert.ErrorIs(t, c.Refresh(), errTest)
+
+		now = now.Add(ttl)
+
+		ifaces = c.Get()
+		require.Len(t, ifaces, 1)
+		assert.Equal(t, 3, ifaces[0].MTU)
+	})
+}
+
+func TestInterfaceCache_concurrent(t *testing.T) {
+	c := NewInterfaceCache(0, func() (ifaces []*NetInterface, err error) {
+		return []*NetInterface{{Name: "eth0"}}, nil
+	})
+
+	const n = 10
+
+	wg := &sync.WaitGroup{}
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+
+			_ = c.Refresh()
+			assert.Len(t, c.Get(), 1)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestInterfaceCache_LocalAddrSet(t *testing.T) {
+	ifaces := []*NetInterface{{
+		Name:      "eth0",
+		Addresses: []net.IP{net.IPv4(192, 168, 1, 2), net.ParseIP("2001:db8::2")},
+	}}
+
+	var calls int
+	c := NewInterfaceCache(0, func() (_ []*NetInterface, _ error) {
+		calls++
+
+		return ifaces, nil
+	})
+
+	assert.Equal(t, map[netip.Addr]struct{}{
+		netip.MustParseAddr("192.168.1.2"): {},
+		netip.MustParseAddr("2001:db8::2"): {},
+	}, c.LocalAddrSet())
+	assert.Len(t, c.Get(), 1)
+	assert.Equal(t, 1, calls)
+
+	ifaces = []*NetInterface{{
+		Name:      "eth0",
+		Addresses: []net.IP{{192, 168, 1, 3}},
+	}}
+	require.NoError(t, c.Refresh())
+
+	assert.Equal(t, map[netip.Addr]struct{}{
+		netip.MustParseAddr("192.168.1.3"): {},
+	}, c.LocalAddrSet())
+	assert.Equal(t, 2, calls)
+}
+
+func TestInterfaceCache_failing(t *testing.T) {
+	const ttl = time.Minute
+
+	var calls int
+	c := NewInterfaceCache(ttl, func() (_ []*NetInterface, _ error) {
+		calls++
+
+		return nil, ErrNoInterfaces
+	})
+
+	now := time.Unix(0, 0)
+	c.now = func() (t time.Time) { return now }
+
+	assert.Empty(t, c.Get())
+	assert.Empty(t, c.LocalAddrSet())
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(ttl)
+
+	assert.Empty(t, c.Get())
+	assert.Equal(t, 2, calls)
+}
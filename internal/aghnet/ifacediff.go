@@ -0,0 +1,290 @@
+package aghnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+)
+
+// InterfaceChanges are the differences between two snapshots of the network
+// interfaces.  All the slices are sorted by the interface names.
+type InterfaceChanges struct {
+	// Added are the interfaces present only in the current snapshot.
+	Added []*NetInterface
+
+	// Removed are the interfaces present only in the previous snapshot.
+	Removed []*NetInterface
+
+	// Changed are the changes of the interfaces present in both snapshots.
+	Changed []*InterfaceChange
+}
+
+// IsEmpty returns true if there are no changes.
+func (c *InterfaceChanges) IsEmpty() (ok bool) {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// InterfaceChange is the change of a single network interface present in both
+// snapshots.
+type InterfaceChange struct {
+	// Name is the name of the interface.
+	Name string
+
+	// AddedAddrs are the addresses the interface has acquired.
+	AddedAddrs []net.IP
+
+	// RemovedAddrs are the addresses the interface has lost.
+	RemovedAddrs []net.IP
+
+	// SetFlags are the flags which have been set.
+	SetFlags net.Flags
+
+	// ClearedFlags are the flags which have been cleared.
+	ClearedFlags net.Flags
+}
+
+// WentUp returns true if the interface has been brought up.
+func (c *InterfaceChange) WentUp() (ok bool) {
+	return c.SetFlags&net.FlagUp != 0
+}
+
+// WentDown returns true if the interface has been brought down.
+func (c *InterfaceChange) WentDown() (ok bool) {
+	return c.ClearedFlags&net.FlagUp != 0
+}
+
+// DiffInterfaces returns the changes between the previous and the current
+// snapshots of the network interfaces, as returned by
+// GetValidNetInterfacesForWeb.  The interfaces are matched by their names, so
+// the order of the snapshots doesn't matter.  The addresses are compared
+// regardless of their length, so the IPv4-mapped IPv6 addresses are equal to
+// the corresponding IPv4 ones.
+func DiffInterfaces(prev, cur []*NetInterface) (changes InterfaceChanges) {
+	prevByName, curByName := ifacesByName(prev), ifacesByName(cur)
+
+	for _, name := range sortedIfaceNames(curByName) {
+		curIface := curByName[name]
+		prevIface, ok := prevByName[name]
+		if !ok {
+			changes.Added = append(changes.Added, curIface)
+		} else if c := diffIface(prevIface, curIface); c != nil {
+			changes.Changed = append(changes.Changed, c)
+		}
+	}
+
+	for _, name := range sortedIfaceNames(prevByName) {
+		if _, ok := curByName[name]; !ok {
+			changes.Removed = append(changes.Removed, prevByName[name])
+		}
+	}
+
+	return changes
+}
+
+// ifacesByName returns the non-nil ifaces mapped by their names.
+func ifacesByName(ifaces []*NetInterface) (m map[string]*NetInterface) {
+	m = make(map[string]*NetInterface, len(ifaces))
+	for _, iface := range ifaces {
+		if iface != nil {
+			m[iface.Name] = iface
+		}
+	}
+
+	return m
+}
+
+// sortedIfaceNames returns the sorted keys of m.
+func sortedIfaceNames(m map[string]*NetInterface) (names []string) {
+	names = make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// diffIface returns the change between two snapshots of the same interface or
+// nil if there is none.
+func diffIface(prev, cur *NetInterface) (c *InterfaceChange) {
+	c = &InterfaceChange{
+		Name:         cur.Name,
+		AddedAddrs:   addrsDiff(cur.Addresses, prev.Addresses),
+		RemovedAddrs: addrsDiff(prev.Addresses, cur.Addresses),
+		SetFlags:     cur.Flags &^ prev.Flags,
+		ClearedFlags: prev.Flags &^ cur.Flags,
+	}
+
+	if len(c.AddedAddrs) == 0 &&
+		len(c.RemovedAddrs) == 0 &&
+		c.SetFlags == 0 &&
+		c.ClearedFlags == 0 {
+		return nil
+	}
+
+	return c
+}
+
+// addrsDiff returns the addresses from a which aren't in b.
+func addrsDiff(a, b []net.IP) (diff []net.IP) {
+	set := make(map[netip.Addr]struct{}, len(b))
+	for _, ip := range b {
+		if addr, ok := netip.AddrFromSlice(ip); ok {
+			set[addr.Unmap()] = struct{}{}
+		}
+	}
+
+	for _, ip := range a {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+
+		if _, has := set[addr.Unmap()]; !has {
+			diff = append(diff, ip)
+		}
+	}
+
+	return diff
+}
+
+// stableIface is the canonical JSON representation of a network interface used
+// by MarshalInterfacesStable.  It only contains the properties which describe
+// the configuration of the interface, so that the volatile ones, like the
+// traffic counters, don't affect the comparison.
+type stableIface struct {
+	Name         string    `json:"name"`
+	HardwareAddr string    `json:"hardware_address"`
+	Kind         IfaceKind `json:"kind"`
+	Addresses    []string  `json:"ip_addresses"`
+	Subnets      []string  `json:"subnets"`
+	Flags        net.Flags `json:"flags"`
+	MTU          int       `json:"mtu"`
+}
+
+// MarshalInterfacesStable returns the canonical JSON representation of ifaces
+// which is suitable for byte-by-byte comparison, e.g. to detect that the
+// network configuration has changed since the last start.  The interfaces are
+// sorted by their names, and the addresses and the CIDR subnets are unmapped
+// and sorted lexicographically.  The statistics and the link properties are
+// omitted, since those change regardless of the configuration.  Use
+// UnmarshalInterfacesStable and DiffInterfaces to find out what exactly has
+// changed.
+func MarshalInterfacesStable(ifaces []*NetInterface) (b []byte, err error) {
+	byName := ifacesByName(ifaces)
+	names := sortedIfaceNames(byName)
+
+	stable := make([]*stableIface, 0, len(names))
+	for _, name := range names {
+		stable = append(stable, newStableIface(byName[name]))
+	}
+
+	return json.Marshal(stable)
+}
+
+// newStableIface converts iface into its canonical representation.
+func newStableIface(iface *NetInterface) (s *stableIface) {
+	s = &stableIface{
+		Name:         iface.Name,
+		HardwareAddr: iface.HardwareAddr.String(),
+		Kind:         iface.Kind,
+		Addresses:    make([]string, 0, len(iface.Addresses)),
+		Subnets:      make([]string, 0, len(iface.Subnets)),
+		Flags:        iface.Flags,
+		MTU:          iface.MTU,
+	}
+
+	for _, ip := range iface.Addresses {
+		if addr, ok := netip.AddrFromSlice(ip); ok {
+			s.Addresses = append(s.Addresses, addr.Unmap().String())
+		}
+	}
+
+	for _, n := range iface.Subnets {
+		if n == nil {
+			continue
+		}
+
+		addr, ok := netip.AddrFromSlice(n.IP)
+		if !ok {
+			continue
+		}
+
+		ones, _ := n.Mask.Size()
+		s.Subnets = append(s.Subnets, netip.PrefixFrom(addr.Unmap(), ones).Masked().String())
+	}
+
+	sort.Strings(s.Addresses)
+	sort.Strings(s.Subnets)
+
+	return s
+}
+
+// UnmarshalInterfacesStable parses the data produced by MarshalInterfacesStable
+// back into the network interfaces, so that the previous snapshot can be
+// compared with the current one using DiffInterfaces.
+func UnmarshalInterfacesStable(b []byte) (ifaces []*NetInterface, err error) {
+	var stable []*stableIface
+	err = json.Unmarshal(b, &stable)
+	if err != nil {
+		return nil, fmt.Errorf("decoding interfaces: %w", err)
+	}
+
+	ifaces = make([]*NetInterface, 0, len(stable))
+	for i, s := range stable {
+		var iface *NetInterface
+		iface, err = s.toNetInterface()
+		if err != nil {
+			return nil, fmt.Errorf("interface at index %d: %w", i, err)
+		}
+
+		ifaces = append(ifaces, iface)
+	}
+
+	return ifaces, nil
+}
+
+// toNetInterface converts s back into the network interface.
+func (s *stableIface) toNetInterface() (iface *NetInterface, err error) {
+	iface = &NetInterface{
+		Name:  s.Name,
+		Kind:  s.Kind,
+		Flags: s.Flags,
+		MTU:   s.MTU,
+	}
+
+	if s.HardwareAddr != "" {
+		iface.HardwareAddr, err = net.ParseMAC(s.HardwareAddr)
+		if err != nil {
+			// Don't wrap the error, because it's informative enough as is.
+			return nil, err
+		}
+	}
+
+	for _, a := range s.Addresses {
+		var addr netip.Addr
+		addr, err = netip.ParseAddr(a)
+		if err != nil {
+			// Don't wrap the error, because it's informative enough as is.
+			return nil, err
+		}
+
+		iface.Addresses = append(iface.Addresses, addr.AsSlice())
+	}
+
+	for _, p := range s.Subnets {
+		var n *net.IPNet
+		_, n, err = net.ParseCIDR(p)
+		if err != nil {
+			// Don't wrap the error, because it's informative enough as is.
+			return nil, err
+		}
+
+		iface.Subnets = append(iface.Subnets, n)
+	}
+
+	return iface, nil
+}
@@ -0,0 +1,158 @@
+package aghnet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffInterfaces(t *testing.T) {
+	eth0 := &NetInterface{
+		Name:      "eth0",
+		Addresses: []net.IP{net.IP{192, 168, 1, 2}, net.ParseIP("2001:db8::2")},
+		Flags:     net.FlagUp | net.FlagBroadcast,
+	}
+	eth1 := &NetInterface{
+		Name:      "eth1",
+		Addresses: []net.IP{net.IP{10, 0, 0, 2}},
+		Flags:     net.FlagBroadcast,
+	}
+	wlan0 := &NetInterface{
+		Name:      "wlan0",
+		Addresses: []net.IP{net.IP{172, 16, 0, 2}},
+		Flags:     net.FlagUp,
+	}
+
+	t.Run("same", func(t *testing.T) {
+		// The order and the length of the addresses don't matter.
+		eth0Copy := *eth0
+		eth0Copy.Addresses = []net.IP{net.ParseIP("2001:db8::2"), net.ParseIP("192.168.1.2")}
+
+		changes := DiffInterfaces(
+			[]*NetInterface{eth0, eth1},
+			[]*NetInterface{eth1, &eth0Copy},
+		)
+		assert.True(t, changes.IsEmpty())
+	})
+
+	t.Run("added_removed", func(t *testing.T) {
+		changes := DiffInterfaces(
+			[]*NetInterface{eth0, eth1},
+			[]*NetInterface{wlan0, eth0},
+		)
+
+		assert.Equal(t, []*NetInterface{wlan0}, changes.Added)
+		assert.Equal(t, []*NetInterface{eth1}, changes.Removed)
+		assert.Empty(t, changes.Changed)
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		newEth0 := &NetInterface{
+			Name:      "eth0",
+			Addresses: []net.IP{net.IP{192, 168, 1, 3}, net.ParseIP("2001:db8::2")},
+			Flags:     net.FlagBroadcast,
+		}
+		newEth1 := &NetInterface{
+			Name:      "eth1",
+			Addresses: []net.IP{net.IP{10, 0, 0, 2}},
+			Flags:     net.FlagUp | net.FlagBroadcast,
+		}
+
+		changes := DiffInterfaces(
+			[]*NetInterface{eth0, eth1},
+			[]*NetInterface{newEth1, newEth0},
+		)
+		require.Len(t, changes.Changed, 2)
+
+		c := changes.Changed[0]
+		assert.Equal(t, "eth0", c.Name)
+		assert.Equal(t, []net.IP{{192, 168, 1, 3}}, c.AddedAddrs)
+		assert.Equal(t, []net.IP{{192, 168, 1, 2}}, c.RemovedAddrs)
+		assert.True(t, c.WentDown())
+		assert.False(t, c.WentUp())
+
+		c = changes.Changed[1]
+		assert.Equal(t, "eth1", c.Name)
+		assert.Empty(t, c.AddedAddrs)
+		assert.Empty(t, c.RemovedAddrs)
+		assert.True(t, c.WentUp())
+		assert.False(t, c.WentDown())
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		changes := DiffInterfaces(nil, []*NetInterface{nil, eth0})
+		assert.Equal(t, []*NetInterface{eth0}, changes.Added)
+	})
+}
+
+func TestMarshalInterfacesStable(t *testing.T) {
+	_, subnet4, err := net.ParseCIDR("192.168.1.2/24")
+	require.NoError(t, err)
+
+	_, subnet6, err := net.ParseCIDR("2001:db8::2/64")
+	require.NoError(t, err)
+
+	hw := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+
+	eth0 := &NetInterface{
+		Name:         "eth0",
+		HardwareAddr: hw,
+		Addresses:    []net.IP{net.ParseIP("2001:db8::2"), net.ParseIP("192.168.1.2")},
+		Subnets:      []*net.IPNet{subnet6, subnet4},
+		Flags:        net.FlagUp | net.FlagBroadcast,
+		Kind:         IfaceKindEthernet,
+		MTU:          1500,
+		Statistics:   &IfaceStatistics{RxBytes: 1},
+	}
+	lo := &NetInterface{
+		Name:      "lo",
+		Addresses: []net.IP{net.IP{127, 0, 0, 1}},
+		Flags:     net.FlagUp | net.FlagLoopback,
+		Kind:      IfaceKindLoopback,
+		MTU:       65536,
+	}
+
+	const want = `[{"name":"eth0","hardware_address":"00:11:22:33:44:55",` +
+		`"kind":"ethernet","ip_addresses":["192.168.1.2","2001:db8::2"],` +
+		`"subnets":["192.168.1.0/24","2001:db8::/64"],"flags":3,"mtu":1500},` +
+		`{"name":"lo","hardware_address":"","kind":"loopback",` +
+		`"ip_addresses":["127.0.0.1"],"subnets":[],"flags":5,"mtu":65536}]`
+
+	b, err := MarshalInterfacesStable([]*NetInterface{lo, eth0})
+	require.NoError(t, err)
+
+	assert.Equal(t, want, string(b))
+
+	t.Run("reordered", func(t *testing.T) {
+		eth0Copy := *eth0
+		eth0Copy.Addresses = []net.IP{{192, 168, 1, 2}, net.ParseIP("2001:db8::2")}
+		eth0Copy.Subnets = []*net.IPNet{subnet4, subnet6}
+		eth0Copy.Statistics = &IfaceStatistics{RxBytes: 2}
+
+		var got []byte
+		got, err = MarshalInterfacesStable([]*NetInterface{&eth0Copy, lo})
+		require.NoError(t, err)
+
+		assert.Equal(t, b, got)
+	})
+
+	t.Run("roundtrip", func(t *testing.T) {
+		var ifaces []*NetInterface
+		ifaces, err = UnmarshalInterfacesStable(b)
+		require.NoError(t, err)
+		require.Len(t, ifaces, 2)
+
+		assert.Equal(t, hw, ifaces[0].HardwareAddr)
+		assert.Equal(t, []*net.IPNet{subnet4, subnet6}, ifaces[0].Subnets)
+
+		changes := DiffInterfaces([]*NetInterface{eth0, lo}, ifaces)
+		assert.True(t, changes.IsEmpty())
+	})
+
+	t.Run("bad", func(t *testing.T) {
+		_, err = UnmarshalInterfacesStable([]byte(`[{"ip_addresses":["bad"]}]`))
+		assert.Error(t, err)
+	})
+}
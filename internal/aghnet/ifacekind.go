@@ -0,0 +1,94 @@
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IfaceKind is the kind of a network interface.
+type IfaceKind string
+
+// IfaceKind values.
+const (
+	IfaceKindEthernet IfaceKind = "ethernet"
+	IfaceKindWiFi     IfaceKind = "wifi"
+	IfaceKindTunnel   IfaceKind = "tunnel"
+	IfaceKindLoopback IfaceKind = "loopback"
+	IfaceKindBridge   IfaceKind = "bridge"
+)
+
+// Name prefixes of the network interfaces of different kinds, which are common
+// across the supported operating systems.
+var (
+	tunnelIfacePrefixes = []string{"tun", "tap", "wg", "ppp", "utun", "ipsec"}
+	wifiIfacePrefixes   = []string{"wlan", "wlp", "wlx", "wifi", "ath"}
+	bridgeIfacePrefixes = []string{"br", "bridge", "virbr"}
+)
+
+// hasAnyPrefix returns true if s starts with any of prefixes.
+func hasAnyPrefix(s string, prefixes []string) (ok bool) {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsTunnelInterface returns true if iface seems to be a tunnel one, like TUN,
+// TAP, WireGuard, or PPP.  On Linux, the sysfs is also checked for TUN and TAP
+// devices.
+func IsTunnelInterface(iface NetInterface) (ok bool) {
+	return isTunTapIface(iface.Name) || isConventionalTunnel(iface)
+}
+
+// isConventionalTunnel returns true if iface seems to be a tunnel one according
+// to its flags and the naming conventions only.
+func isConventionalTunnel(iface NetInterface) (ok bool) {
+	return iface.Flags&net.FlagPointToPoint != 0 || hasAnyPrefix(iface.Name, tunnelIfacePrefixes)
+}
+
+// IsWireless returns true if the network interface named ifaceName is
+// a wireless one.  Unlike the kind of the interface, it never relies on the
+// naming conventions.  On Linux, the sysfs is checked for the wireless
+// extensions and the cfg80211 PHY, and on macOS, the hardware ports reported by
+// networksetup are used.  On other OSes, it returns an *aghos.UnsupportedError.
+func IsWireless(ifaceName string) (ok bool, err error) {
+	ok, err = isWireless(ifaceName)
+	if err != nil {
+		return false, fmt.Errorf("checking if %s is wireless: %w", ifaceName, err)
+	}
+
+	return ok, nil
+}
+
+// ifaceKind returns the kind of iface.  If useOS is true, it uses the
+// information provided by the OS, when possible, and falls back to the flags and
+// the naming conventions otherwise.  Interfaces of unknown kinds are considered
+// Ethernet ones.
+func ifaceKind(iface NetInterface, useOS bool) (kind IfaceKind) {
+	switch {
+	case iface.Flags&net.FlagLoopback != 0:
+		return IfaceKindLoopback
+	case isConventionalTunnel(iface), useOS && isTunTapIface(iface.Name):
+		return IfaceKindTunnel
+	}
+
+	var ok bool
+	if useOS {
+		if kind, ok = ifaceKindFromOS(iface.Name); ok {
+			return kind
+		}
+	}
+
+	switch {
+	case hasAnyPrefix(iface.Name, wifiIfacePrefixes):
+		return IfaceKindWiFi
+	case hasAnyPrefix(iface.Name, bridgeIfacePrefixes):
+		return IfaceKindBridge
+	default:
+		return IfaceKindEthernet
+	}
+}
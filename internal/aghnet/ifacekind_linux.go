@@ -0,0 +1,40 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"io/fs"
+	"path"
+)
+
+// sysfsIfaceHas returns true if the sysfs directory of the network interface
+// contains a file or directory with the given name.
+func sysfsIfaceHas(ifaceName, name string) (ok bool) {
+	_, err := fs.Stat(rootDirFS, path.Join(sysClassNetPath, ifaceName, name))
+
+	return err == nil
+}
+
+// isTunTapIface returns true if the network interface is a TUN or a TAP device
+// according to the sysfs.
+func isTunTapIface(ifaceName string) (ok bool) {
+	return sysfsIfaceHas(ifaceName, "tun_flags")
+}
+
+// ifaceKindFromOS returns the kind of the network interface according to the
+// sysfs.  ok is false if the kind can't be determined.
+func ifaceKindFromOS(ifaceName string) (kind IfaceKind, ok bool) {
+	switch {
+	case sysfsIfaceHas(ifaceName, "wireless"), sysfsIfaceHas(ifaceName, "phy80211"):
+		return IfaceKindWiFi, true
+	case sysfsIfaceHas(ifaceName, "bridge"):
+		return IfaceKindBridge, true
+	case sysfsIfaceHas(ifaceName, "device"):
+		// Only physical devices have this link, and wireless ones are
+		// checked above.
+		return IfaceKindEthernet, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+// isTunTapIface returns false, since there is no OS-provided information about
+// TUN and TAP devices on this OS.
+func isTunTapIface(_ string) (ok bool) {
+	return false
+}
+
+// ifaceKindFromOS returns false, since there is no OS-provided information
+// about the kinds of network interfaces on this OS.
+func ifaceKindFromOS(_ string) (kind IfaceKind, ok bool) {
+	return "", false
+}
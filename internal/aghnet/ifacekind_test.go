@@ -0,0 +1,56 @@
+package aghnet
+
+import (
+	"net"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfaceKind(t *testing.T) {
+	// Make sure that the OS-provided information doesn't affect the results.
+	substRootDirFS(t, fstest.MapFS{})
+
+	testCases := []struct {
+		name  string
+		iface NetInterface
+		want  IfaceKind
+	}{{
+		name:  "loopback",
+		iface: NetInterface{Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+		want:  IfaceKindLoopback,
+	}, {
+		name:  "ethernet",
+		iface: NetInterface{Name: "eth0", Flags: net.FlagUp | net.FlagBroadcast},
+		want:  IfaceKindEthernet,
+	}, {
+		name:  "wifi",
+		iface: NetInterface{Name: "wlan0", Flags: net.FlagUp | net.FlagBroadcast},
+		want:  IfaceKindWiFi,
+	}, {
+		name:  "bridge",
+		iface: NetInterface{Name: "br0", Flags: net.FlagUp | net.FlagBroadcast},
+		want:  IfaceKindBridge,
+	}, {
+		name:  "wireguard",
+		iface: NetInterface{Name: "wg0", Flags: net.FlagUp},
+		want:  IfaceKindTunnel,
+	}, {
+		name:  "utun",
+		iface: NetInterface{Name: "utun3", Flags: net.FlagUp},
+		want:  IfaceKindTunnel,
+	}, {
+		name:  "point_to_point",
+		iface: NetInterface{Name: "vpn0", Flags: net.FlagUp | net.FlagPointToPoint},
+		want:  IfaceKindTunnel,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ifaceKind(tc.iface, true))
+			assert.Equal(t, tc.want, ifaceKind(tc.iface, false))
+			assert.Equal(t, tc.want == IfaceKindTunnel, IsTunnelInterface(tc.iface))
+		})
+	}
+}
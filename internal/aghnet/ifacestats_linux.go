@@ -0,0 +1,95 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// sysClassNetPath is the path to the sysfs directory containing the network
+// interfaces.  See rootDirFS.
+const sysClassNetPath = "sys/class/net"
+
+// ifaceStatistics returns the traffic counters of the network interface read
+// from sysfs.  stats is nil if any of the counters can't be read, for example
+// inside a container without sysfs.
+func ifaceStatistics(ifaceName string) (stats *IfaceStatistics) {
+	stats = &IfaceStatistics{}
+	for counter, dst := range map[string]*uint64{
+		"rx_bytes":   &stats.RxBytes,
+		"tx_bytes":   &stats.TxBytes,
+		"rx_packets": &stats.RxPackets,
+		"tx_packets": &stats.TxPackets,
+	} {
+		var err error
+		*dst, err = readIfaceCounter(ifaceName, counter)
+		if err != nil {
+			currentLogger().Debug("reading statistics of %s: %s", ifaceName, err)
+
+			return nil
+		}
+	}
+
+	return stats
+}
+
+// readIfaceCounter reads and parses a single traffic counter of the network
+// interface.
+func readIfaceCounter(ifaceName, counter string) (n uint64, err error) {
+	p := path.Join(sysClassNetPath, ifaceName, "statistics", counter)
+
+	var data []byte
+	data, err = fs.ReadFile(rootDirFS, p)
+	if err != nil {
+		// Don't wrap the error, because it already contains the path.
+		return 0, err
+	}
+
+	n, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, errors.Annotate(err, "parsing %s: %w", p)
+	}
+
+	return n, nil
+}
+
+// ifaceLinkMode returns the speed and the duplex mode of the network
+// interface's link read from sysfs.  speedMbps is zero and duplex is empty if
+// the link is down or if the corresponding file can't be read.
+func ifaceLinkMode(ifaceName string) (speedMbps int, duplex string) {
+	dir := path.Join(sysClassNetPath, ifaceName)
+
+	data, err := fs.ReadFile(rootDirFS, path.Join(dir, "speed"))
+	if err != nil {
+		currentLogger().Debug("reading speed of %s: %s", ifaceName, err)
+	} else if speedMbps, err = strconv.Atoi(strings.TrimSpace(string(data))); err != nil {
+		currentLogger().Debug("parsing speed of %s: %s", ifaceName, err)
+	}
+
+	// The kernel reports -1 for the links which are down.
+	if speedMbps < 0 {
+		speedMbps = 0
+	}
+
+	data, err = fs.ReadFile(rootDirFS, path.Join(dir, "duplex"))
+	if err != nil {
+		currentLogger().Debug("reading duplex of %s: %s", ifaceName, err)
+
+		return speedMbps, ""
+	}
+
+	switch duplex = strings.TrimSpace(string(data)); duplex {
+	case "full", "half":
+		return speedMbps, duplex
+	default:
+		// Most probably, "unknown", which is reported for the links which are
+		// down.
+		return speedMbps, ""
+	}
+}
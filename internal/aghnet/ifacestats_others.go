@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+// ifaceStatistics returns nil, since collecting the traffic counters of network
+// interfaces isn't supported on this OS yet.
+func ifaceStatistics(_ string) (stats *IfaceStatistics) {
+	return nil
+}
+
+// ifaceLinkMode returns zero values, since getting the link mode of network
+// interfaces isn't supported on this OS yet.
+func ifaceLinkMode(_ string) (speedMbps int, duplex string) {
+	return 0, ""
+}
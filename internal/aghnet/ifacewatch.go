@@ -0,0 +1,69 @@
+package aghnet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// WatchInterfaces returns the channel which receives the changes of the network
+// interfaces as they happen.  On Linux, the changes are pushed by the kernel
+// through the netlink notifications about the links and the addresses.  Other
+// OSes fall back on polling the interfaces periodically.  The channel is closed
+// once ctx is canceled.
+func WatchInterfaces(ctx context.Context) (changes <-chan InterfaceChanges, err error) {
+	var list InterfaceLister = GetValidNetInterfacesForWeb
+
+	prev, err := list()
+	if err != nil && !errors.Is(err, ErrNoInterfaces) {
+		return nil, fmt.Errorf("getting interfaces: %w", err)
+	}
+
+	events, err := ifaceEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("watching interfaces: %w", err)
+	}
+
+	ch := make(chan InterfaceChanges)
+	go relayIfaceChanges(ctx, events, list, prev, ch)
+
+	return ch, nil
+}
+
+// relayIfaceChanges lists the network interfaces on each event and sends the
+// changes since the previous snapshot to ch.  It closes ch once events is
+// closed or ctx is canceled.  It's intended to be used as a goroutine.
+func relayIfaceChanges(
+	ctx context.Context,
+	events <-chan struct{},
+	list InterfaceLister,
+	prev []*NetInterface,
+	ch chan<- InterfaceChanges,
+) {
+	defer log.OnPanic("aghnet: relaying interface changes")
+	defer close(ch)
+
+	for range events {
+		cur, err := list()
+		if err != nil && !errors.Is(err, ErrNoInterfaces) {
+			currentLogger().Debug("listing interfaces: %s", err)
+
+			continue
+		}
+
+		changes := DiffInterfaces(prev, cur)
+		prev = cur
+		if changes.IsEmpty() {
+			continue
+		}
+
+		select {
+		case ch <- changes:
+			// Go on.
+		case <-ctx.Done():
+			return
+		}
+	}
+}
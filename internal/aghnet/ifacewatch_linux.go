@@ -0,0 +1,78 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"context"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// ifaceEvents subscribes to the netlink notifications about the network links
+// and addresses and returns the channel receiving a value each time some of
+// those arrive.  The bursts of notifications are coalesced.  The channel is
+// closed and the netlink socket is closed once ctx is canceled or the socket
+// fails irrecoverably.
+func ifaceEvents(ctx context.Context) (events <-chan struct{}, err error) {
+	conn, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer log.OnPanic("aghnet: closing netlink watcher")
+
+		<-ctx.Done()
+		log.OnCloserError(conn, log.DEBUG)
+	}()
+
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer log.OnPanic("aghnet: receiving netlink notifications")
+
+		receiveIfaceEvents(ctx, conn, ch)
+	}()
+
+	return ch, nil
+}
+
+// netlinkReceiver is the part of *netlink.Conn used by receiveIfaceEvents.
+type netlinkReceiver interface {
+	Receive() (msgs []netlink.Message, err error)
+}
+
+// receiveIfaceEvents sends a value to ch each time a notification is received
+// from conn, unless there is a pending one already.  The ENOBUFS errors, which
+// mean that the socket buffer overran and some notifications were lost, are
+// also sent to ch, so that the callers relist the interfaces.  It closes ch when
+// conn returns any other error, which also happens after ctx is canceled and
+// conn is closed.
+func receiveIfaceEvents(ctx context.Context, conn netlinkReceiver, ch chan<- struct{}) {
+	defer close(ch)
+
+	for {
+		_, err := conn.Receive()
+		if errors.Is(err, unix.ENOBUFS) {
+			currentLogger().Debug("netlink notifications overrun: %s", err)
+		} else if err != nil {
+			if ctx.Err() == nil {
+				currentLogger().Debug("receiving netlink notifications: %s", err)
+			}
+
+			return
+		}
+
+		select {
+		case ch <- struct{}{}:
+			// Go on.
+		default:
+			// There is a pending event already.
+		}
+	}
+}
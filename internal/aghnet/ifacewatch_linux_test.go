@@ -0,0 +1,78 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/mdlayher/netlink"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// fakeNetlinkReceiver is a netlinkReceiver returning the errors from errs one
+// by one and the closed error after them.
+type fakeNetlinkReceiver struct {
+	errs []error
+}
+
+// Receive implements the netlinkReceiver interface for *fakeNetlinkReceiver.
+func (r *fakeNetlinkReceiver) Receive() (msgs []netlink.Message, err error) {
+	if len(r.errs) == 0 {
+		return nil, errors.Error("use of closed file")
+	}
+
+	err, r.errs = r.errs[0], r.errs[1:]
+
+	return nil, err
+}
+
+func TestReceiveIfaceEvents(t *testing.T) {
+	enobufs := &netlink.OpError{
+		Op:  "receive",
+		Err: os.NewSyscallError("recvmsg", unix.ENOBUFS),
+	}
+
+	testCases := []struct {
+		name       string
+		errs       []error
+		wantEvents int
+	}{{
+		name:       "closed",
+		errs:       nil,
+		wantEvents: 0,
+	}, {
+		name:       "message",
+		errs:       []error{nil},
+		wantEvents: 1,
+	}, {
+		name:       "enobufs",
+		errs:       []error{enobufs},
+		wantEvents: 1,
+	}, {
+		name:       "enobufs_then_message",
+		errs:       []error{enobufs, nil},
+		wantEvents: 2,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Make the channel big enough to not coalesce the events.
+			ch := make(chan struct{}, len(tc.errs)+1)
+			conn := &fakeNetlinkReceiver{errs: tc.errs}
+
+			receiveIfaceEvents(context.Background(), conn, ch)
+
+			var n int
+			for range ch {
+				n++
+			}
+
+			assert.Equal(t, tc.wantEvents, n)
+		})
+	}
+}
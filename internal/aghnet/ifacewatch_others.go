@@ -0,0 +1,44 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import (
+	"context"
+	"time"
+
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// ifaceWatchIvl is the interval between the polls of the network interfaces.
+const ifaceWatchIvl = 5 * time.Second
+
+// ifaceEvents returns the channel receiving a value every ifaceWatchIvl, since
+// the notifications about the network changes aren't supported on this OS yet.
+// The channel is closed once ctx is canceled.
+func ifaceEvents(ctx context.Context) (events <-chan struct{}, err error) {
+	ch := make(chan struct{})
+	go func() {
+		defer log.OnPanic("aghnet: polling interfaces")
+		defer close(ch)
+
+		t := time.NewTicker(ifaceWatchIvl)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				select {
+				case ch <- struct{}{}:
+					// Go on.
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,80 @@
+package aghnet
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayIfaceChanges(t *testing.T) {
+	prev := []*NetInterface{{
+		Name:  "eth0",
+		Flags: net.FlagUp,
+	}}
+	snapshots := [][]*NetInterface{
+		// No changes.
+		prev,
+		{{
+			Name:  "eth0",
+			Flags: net.FlagUp,
+		}, {
+			Name:  "eth1",
+			Flags: net.FlagUp,
+		}},
+	}
+
+	var i int
+	list := func() (ifaces []*NetInterface, err error) {
+		ifaces = snapshots[i]
+		i++
+
+		return ifaces, nil
+	}
+
+	events := make(chan struct{}, len(snapshots))
+	for range snapshots {
+		events <- struct{}{}
+	}
+	close(events)
+
+	ch := make(chan InterfaceChanges)
+	go relayIfaceChanges(context.Background(), events, list, prev, ch)
+
+	changes, ok := <-ch
+	require.True(t, ok)
+	require.Len(t, changes.Added, 1)
+
+	assert.Equal(t, "eth1", changes.Added[0].Name)
+	assert.Empty(t, changes.Removed)
+	assert.Empty(t, changes.Changed)
+
+	_, ok = <-ch
+	assert.False(t, ok)
+}
+
+func TestWatchInterfaces_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch, err := WatchInterfaces(ctx)
+	require.NoError(t, err)
+
+	cancel()
+
+	timer := time.NewTimer(time.Second)
+	t.Cleanup(func() { timer.Stop() })
+
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timer.C:
+			t.Fatal("channel isn't closed")
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package aghnet
+
+import "github.com/AdguardTeam/golibs/errors"
+
+// ErrForwardingPermission is returned by SetIPForwarding when the process isn't
+// permitted to change the IP forwarding status.  It's distinct from the
+// *aghos.UnsupportedError returned on the OSes where it's not supported at all.
+const ErrForwardingPermission errors.Error = "not permitted to change ip forwarding"
+
+// IPForwardingEnabled returns true if the kernel forwards the IP packets
+// between the network interfaces, which is required for routing the traffic of
+// the DHCP clients.  v6 selects the IPv6 forwarding instead of the IPv4 one.
+// It's only supported on Linux, where the sysctls are read.
+func IPForwardingEnabled(v6 bool) (ok bool, err error) {
+	return ipForwardingEnabled(v6)
+}
+
+// SetIPForwarding enables or disables the IP forwarding.  v6 selects the IPv6
+// forwarding instead of the IPv4 one.  The change isn't persistent and is lost
+// on reboot, so the users should be advised to configure the sysctls
+// permanently.  If the process lacks the privileges or the sysctls are
+// read-only, e.g. inside a container, err wraps ErrForwardingPermission.  It's
+// only supported on Linux.
+func SetIPForwarding(v6, enabled bool) (err error) {
+	return setIPForwarding(v6, enabled)
+}
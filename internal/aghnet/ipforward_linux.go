@@ -0,0 +1,68 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// Paths to the IP forwarding sysctls relative to the root directory.
+const (
+	ipv4ForwardingPath = "proc/sys/net/ipv4/ip_forward"
+	ipv6ForwardingPath = "proc/sys/net/ipv6/conf/all/forwarding"
+)
+
+// sysctlWriteFile is the function writing the sysctls.  It's only substituted
+// in tests.
+var sysctlWriteFile = os.WriteFile
+
+// forwardingPath returns the path to the forwarding sysctl of the family.
+func forwardingPath(v6 bool) (p string) {
+	if v6 {
+		return ipv6ForwardingPath
+	}
+
+	return ipv4ForwardingPath
+}
+
+func ipForwardingEnabled(v6 bool) (ok bool, err error) {
+	p := forwardingPath(v6)
+	data, err := fs.ReadFile(rootDirFS, p)
+	if err != nil {
+		// Don't wrap the error, because it already contains the path.
+		return false, err
+	}
+
+	switch val := string(bytes.TrimSpace(data)); val {
+	case "0":
+		return false, nil
+	case "1":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unexpected value %q in %s", val, p)
+	}
+}
+
+func setIPForwarding(v6, enabled bool) (err error) {
+	data := []byte("0\n")
+	if enabled {
+		data = []byte("1\n")
+	}
+
+	p := "/" + forwardingPath(v6)
+	err = sysctlWriteFile(p, data, 0o644)
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EROFS) {
+		return fmt.Errorf("%w: %s", ErrForwardingPermission, err)
+	} else if err != nil {
+		return fmt.Errorf("writing %s: %w", p, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPForwardingEnabled(t *testing.T) {
+	substRootDirFS(t, fstest.MapFS{
+		ipv4ForwardingPath: &fstest.MapFile{Data: []byte("1\n")},
+		ipv6ForwardingPath: &fstest.MapFile{Data: []byte("0\n")},
+	})
+
+	ok, err := IPForwardingEnabled(false)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = IPForwardingEnabled(true)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	t.Run("bad_value", func(t *testing.T) {
+		substRootDirFS(t, fstest.MapFS{
+			ipv4ForwardingPath: &fstest.MapFile{Data: []byte("yes\n")},
+		})
+
+		_, err = IPForwardingEnabled(false)
+		assert.Error(t, err)
+
+		_, err = IPForwardingEnabled(true)
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+func TestSetIPForwarding(t *testing.T) {
+	var gotPath, gotData string
+	var writeErr error
+	prev := sysctlWriteFile
+	t.Cleanup(func() { sysctlWriteFile = prev })
+	sysctlWriteFile = func(name string, data []byte, _ os.FileMode) (err error) {
+		gotPath, gotData = name, string(data)
+
+		return writeErr
+	}
+
+	require.NoError(t, SetIPForwarding(false, true))
+	assert.Equal(t, "/proc/sys/net/ipv4/ip_forward", gotPath)
+	assert.Equal(t, "1\n", gotData)
+
+	require.NoError(t, SetIPForwarding(true, false))
+	assert.Equal(t, "/proc/sys/net/ipv6/conf/all/forwarding", gotPath)
+	assert.Equal(t, "0\n", gotData)
+
+	testCases := []struct {
+		writeErr error
+		name     string
+		wantPerm bool
+	}{{
+		writeErr: &fs.PathError{Op: "open", Path: gotPath, Err: syscall.EACCES},
+		name:     "permission",
+		wantPerm: true,
+	}, {
+		writeErr: &fs.PathError{Op: "open", Path: gotPath, Err: syscall.EROFS},
+		name:     "read_only",
+		wantPerm: true,
+	}, {
+		writeErr: &fs.PathError{Op: "open", Path: gotPath, Err: syscall.ENOENT},
+		name:     "other",
+		wantPerm: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			writeErr = tc.writeErr
+
+			err := SetIPForwarding(false, true)
+			require.Error(t, err)
+
+			assert.Equal(t, tc.wantPerm, errors.Is(err, ErrForwardingPermission))
+		})
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import "github.com/AdguardTeam/AdGuardHome/internal/aghos"
+
+func ipForwardingEnabled(_ bool) (ok bool, err error) {
+	return false, aghos.Unsupported("getting ip forwarding status")
+}
+
+func setIPForwarding(_, _ bool) (err error) {
+	return aghos.Unsupported("setting ip forwarding")
+}
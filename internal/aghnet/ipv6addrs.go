@@ -0,0 +1,63 @@
+package aghnet
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"time"
+)
+
+// LifetimeInfinite is the lifetime of the IPv6 addresses which never expire.
+const LifetimeInfinite time.Duration = math.MaxInt64
+
+// IPv6AddrInfo is the information about an IPv6 address of a network
+// interface.
+type IPv6AddrInfo struct {
+	// Addr is the address itself.  The link-local addresses have the interface
+	// name as their zone.
+	Addr netip.Addr
+
+	// Prefix is the address along with its prefix length.  It never has a
+	// zone, since netip.Prefix doesn't keep it, see Addr.
+	Prefix netip.Prefix
+
+	// ValidLifetime is the remaining time the address stays valid.  It's
+	// LifetimeInfinite if the address never expires.
+	ValidLifetime time.Duration
+
+	// PreferredLifetime is the remaining time the address stays preferred.
+	// It's LifetimeInfinite if the address is never deprecated.
+	PreferredLifetime time.Duration
+
+	// Flags are the IFA_F_* flags of the address, see rtnetlink(7).
+	Flags uint32
+
+	// Temporary is true if the address is a temporary one generated for
+	// privacy, see RFC 8981.
+	Temporary bool
+
+	// Deprecated is true if the preferred lifetime of the address has expired.
+	Deprecated bool
+
+	// Tentative is true if the duplicate address detection hasn't been
+	// completed yet.
+	Tentative bool
+
+	// DADFailed is true if the duplicate address detection has failed.
+	DADFailed bool
+
+	// Permanent is true if the address has been configured statically.
+	Permanent bool
+}
+
+// IPv6AddrDetails returns the IPv6 addresses of the network interface named
+// ifaceName along with their lifetimes and flags.  It's only supported on Linux,
+// where those are taken from the kernel through netlink.
+func IPv6AddrDetails(ifaceName string) (infos []IPv6AddrInfo, err error) {
+	infos, err = ipv6AddrDetails(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("getting ipv6 addresses of %s: %w", ifaceName, err)
+	}
+
+	return infos, nil
+}
@@ -0,0 +1,130 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"time"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// ipv6AddrDetails returns the details of the IPv6 addresses of the network
+// interface named ifaceName from the addresses dumped through netlink.
+func ipv6AddrDetails(ifaceName string) (infos []IPv6AddrInfo, err error) {
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	req := make([]byte, unix.SizeofIfAddrmsg)
+	req[0] = unix.AF_INET6
+
+	msgs, err := netlinkRouteDump(unix.RTM_GETADDR, req)
+	if err != nil {
+		return nil, fmt.Errorf("dumping addresses: %w", err)
+	}
+
+	for _, msg := range msgs {
+		var info IPv6AddrInfo
+		var index uint32
+		info, index, err = parseIPv6AddrMsg(msg.Data, iface.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		// The kernel may ignore the family filter of the dump request.
+		if index == uint32(iface.Index) && info.Prefix.IsValid() {
+			infos = append(infos, info)
+		}
+	}
+
+	return infos, nil
+}
+
+// parseIPv6AddrMsg parses the data of the RTM_NEWADDR netlink message, which
+// consists of struct ifaddrmsg followed by the attributes, and returns the
+// information about the address along with the index of its network interface.
+// info.Addr and info.Prefix are invalid if the address isn't an IPv6 one.
+// ifaceName is used as the zone of the link-local info.Addr.
+//
+// See man rtnetlink(7).
+func parseIPv6AddrMsg(data []byte, ifaceName string) (info IPv6AddrInfo, index uint32, err error) {
+	if len(data) < unix.SizeofIfAddrmsg {
+		return IPv6AddrInfo{}, 0, fmt.Errorf("address message is too short: %d bytes", len(data))
+	}
+
+	family, bits, flags := data[0], int(data[1]), uint32(data[2])
+	index = aghos.NativeEndian.Uint32(data[4:8])
+	if family != unix.AF_INET6 {
+		return IPv6AddrInfo{}, index, nil
+	}
+
+	ad, err := netlink.NewAttributeDecoder(data[unix.SizeofIfAddrmsg:])
+	if err != nil {
+		return IPv6AddrInfo{}, 0, fmt.Errorf("decoding address attributes: %w", err)
+	}
+
+	info.ValidLifetime, info.PreferredLifetime = LifetimeInfinite, LifetimeInfinite
+
+	var addr netip.Addr
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.IFA_ADDRESS:
+			addr, _ = netip.AddrFromSlice(ad.Bytes())
+		case unix.IFA_CACHEINFO:
+			info.setLifetimes(ad.Bytes())
+		case unix.IFA_FLAGS:
+			// The flags in the header are only a single byte, so the
+			// attribute takes precedence.
+			flags = ad.Uint32()
+		}
+	}
+
+	if err = ad.Err(); err != nil {
+		return IPv6AddrInfo{}, 0, fmt.Errorf("decoding address attributes: %w", err)
+	}
+
+	info.Addr = withIfaceZone(addr, ifaceName)
+	info.Prefix = netip.PrefixFrom(addr, bits)
+	info.setFlags(flags)
+
+	return info, index, nil
+}
+
+// setLifetimes sets the lifetimes of info from the data of the IFA_CACHEINFO
+// attribute, which is struct ifa_cacheinfo.
+func (info *IPv6AddrInfo) setLifetimes(data []byte) {
+	if len(data) < unix.SizeofIfaCacheinfo {
+		return
+	}
+
+	info.PreferredLifetime = lifetime(aghos.NativeEndian.Uint32(data[0:4]))
+	info.ValidLifetime = lifetime(aghos.NativeEndian.Uint32(data[4:8]))
+}
+
+// lifetime converts the lifetime in seconds as reported by the kernel into
+// a time.Duration.
+func lifetime(secs uint32) (d time.Duration) {
+	if secs == math.MaxUint32 {
+		return LifetimeInfinite
+	}
+
+	return time.Duration(secs) * time.Second
+}
+
+// setFlags sets the flags of info from the IFA_F_* flags.
+func (info *IPv6AddrInfo) setFlags(flags uint32) {
+	info.Flags = flags
+	info.Temporary = flags&unix.IFA_F_TEMPORARY != 0
+	info.Deprecated = flags&unix.IFA_F_DEPRECATED != 0
+	info.Tentative = flags&unix.IFA_F_TENTATIVE != 0
+	info.DADFailed = flags&unix.IFA_F_DADFAILED != 0
+	info.Permanent = flags&unix.IFA_F_PERMANENT != 0
+}
@@ -0,0 +1,136 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"math"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/mdlayher/netlink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// newAddrMsg is a helper that returns the data of the RTM_NEWADDR message
+// describing prefix of the network interface with index.
+func newAddrMsg(
+	t *testing.T,
+	prefix netip.Prefix,
+	index uint32,
+	flags uint32,
+	preferred uint32,
+	valid uint32,
+) (data []byte) {
+	t.Helper()
+
+	cacheInfo := make([]byte, unix.SizeofIfaCacheinfo)
+	aghos.NativeEndian.PutUint32(cacheInfo[0:4], preferred)
+	aghos.NativeEndian.PutUint32(cacheInfo[4:8], valid)
+
+	ae := netlink.NewAttributeEncoder()
+	ae.Bytes(unix.IFA_ADDRESS, prefix.Addr().AsSlice())
+	ae.Bytes(unix.IFA_CACHEINFO, cacheInfo)
+	ae.Uint32(unix.IFA_FLAGS, flags)
+
+	attrs, err := ae.Encode()
+	require.NoError(t, err)
+
+	data = make([]byte, unix.SizeofIfAddrmsg, unix.SizeofIfAddrmsg+len(attrs))
+	data[0] = unix.AF_INET6
+	if prefix.Addr().Is4() {
+		data[0] = unix.AF_INET
+	}
+	data[1] = uint8(prefix.Bits())
+	aghos.NativeEndian.PutUint32(data[4:8], index)
+
+	return append(data, attrs...)
+}
+
+func TestParseIPv6AddrMsg(t *testing.T) {
+	testCases := []struct {
+		name string
+		want IPv6AddrInfo
+		data []byte
+	}{{
+		name: "temporary",
+		want: IPv6AddrInfo{
+			Addr:              netip.MustParseAddr("2001:db8::1234"),
+			Prefix:            netip.MustParsePrefix("2001:db8::1234/64"),
+			ValidLifetime:     time.Hour,
+			PreferredLifetime: 30 * time.Minute,
+			Flags:             unix.IFA_F_TEMPORARY,
+			Temporary:         true,
+		},
+		data: newAddrMsg(
+			t,
+			netip.MustParsePrefix("2001:db8::1234/64"),
+			2,
+			unix.IFA_F_TEMPORARY,
+			1800,
+			3600,
+		),
+	}, {
+		name: "permanent_link_local",
+		want: IPv6AddrInfo{
+			Addr:              netip.MustParseAddr("fe80::1%eth0"),
+			Prefix:            netip.MustParsePrefix("fe80::1/64"),
+			ValidLifetime:     LifetimeInfinite,
+			PreferredLifetime: LifetimeInfinite,
+			Flags:             unix.IFA_F_PERMANENT,
+			Permanent:         true,
+		},
+		data: newAddrMsg(
+			t,
+			netip.MustParsePrefix("fe80::1/64"),
+			2,
+			unix.IFA_F_PERMANENT,
+			math.MaxUint32,
+			math.MaxUint32,
+		),
+	}, {
+		name: "deprecated_tentative",
+		want: IPv6AddrInfo{
+			Addr:              netip.MustParseAddr("2001:db8::2"),
+			Prefix:            netip.MustParsePrefix("2001:db8::2/64"),
+			ValidLifetime:     time.Minute,
+			PreferredLifetime: 0,
+			Flags:             unix.IFA_F_DEPRECATED | unix.IFA_F_TENTATIVE,
+			Deprecated:        true,
+			Tentative:         true,
+		},
+		data: newAddrMsg(
+			t,
+			netip.MustParsePrefix("2001:db8::2/64"),
+			2,
+			unix.IFA_F_DEPRECATED|unix.IFA_F_TENTATIVE,
+			0,
+			60,
+		),
+	}, {
+		name: "ipv4",
+		want: IPv6AddrInfo{},
+		data: newAddrMsg(t, netip.MustParsePrefix("192.168.1.2/24"), 2, 0, 0, 0),
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, index, err := parseIPv6AddrMsg(tc.data, "eth0")
+			require.NoError(t, err)
+
+			assert.Equal(t, uint32(2), index)
+			assert.Equal(t, tc.want, info)
+			assert.Equal(t, tc.want.Addr.Zone(), info.Addr.Zone())
+		})
+	}
+
+	t.Run("short", func(t *testing.T) {
+		_, _, err := parseIPv6AddrMsg([]byte{1}, "eth0")
+		testutil.AssertErrorMsg(t, "address message is too short: 1 bytes", err)
+	})
+}
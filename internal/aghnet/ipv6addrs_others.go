@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import "github.com/AdguardTeam/AdGuardHome/internal/aghos"
+
+// ipv6AddrDetails returns an error, since getting the details of the addresses
+// isn't supported on this OS yet.
+func ipv6AddrDetails(_ string) (infos []IPv6AddrInfo, err error) {
+	return nil, aghos.Unsupported("getting ipv6 address details")
+}
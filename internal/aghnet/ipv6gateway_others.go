@@ -0,0 +1,17 @@
+//go:build !(linux || windows)
+// +build !linux,!windows
+
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+)
+
+// ipv6Gateway returns an error, since getting the IPv6 routes isn't supported
+// on this OS yet.
+func ipv6Gateway(_ *net.Interface) (gw netip.Addr, err error) {
+	return netip.Addr{}, aghos.Unsupported("getting ipv6 gateway")
+}
@@ -0,0 +1,39 @@
+//go:build windows
+// +build windows
+
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// ipv6Gateway returns the gateway of the IPv6 default route through iface with
+// the lowest metric from the routing table listed by netsh.
+func ipv6Gateway(iface *net.Interface) (gw netip.Addr, err error) {
+	if tmErr := lookTool("netsh", "ipv6 gateway detection"); tmErr != nil {
+		return netip.Addr{}, tmErr
+	}
+
+	code, out, err := aghosRunCommand("netsh", "interface", "ipv6", "show", "route")
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("running netsh: %w", err)
+	} else if code != 0 {
+		return netip.Addr{}, fmt.Errorf("netsh finished with code %d", code)
+	}
+
+	routes := parseNetshDefaultRoutes(out, iface.Index)
+	if len(routes) == 0 {
+		return netip.Addr{}, errors.Error("no ipv6 default gateway")
+	}
+
+	gw = routes[0].Gateway
+	if gw.IsLinkLocalUnicast() {
+		gw = gw.WithZone(iface.Name)
+	}
+
+	return gw, nil
+}
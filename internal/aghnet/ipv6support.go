@@ -0,0 +1,114 @@
+package aghnet
+
+import (
+	"net"
+	"sync"
+)
+
+// ipv6SupportCache is the cached result of IPv6Supported and
+// DualStackSupported.
+var ipv6SupportCache = struct {
+	// mu protects supported, dualStack, and ok.
+	mu *sync.Mutex
+
+	// supported is true if the IPv6 sockets can be used.
+	supported bool
+
+	// dualStack is true if the IPv6 sockets accept the IPv4 connections by
+	// default.
+	dualStack bool
+
+	// ok is true if supported and dualStack are set.
+	ok bool
+}{
+	mu: &sync.Mutex{},
+}
+
+// IPv6Supported returns true if the host supports IPv6 sockets, which may not
+// be the case, for example, in containers with IPv6 disabled, where binding to
+// :: fails.  It checks if IPv6 is disabled by the OS, which is only supported
+// on Linux, and if a UDP socket can be bound to ::1.  The result is cached,
+// see ResetIPv6SupportCache.
+func IPv6Supported() (ok bool) {
+	supported, _ := ipv6Support()
+
+	return supported
+}
+
+// DualStackSupported returns true if the host supports IPv6 sockets, see
+// IPv6Supported, and those accept the IPv4 connections as IPv4-mapped ones by
+// default, i.e. the IPV6_V6ONLY option isn't set on the new sockets.  If it
+// returns false, the IPv4 addresses should be bound separately.  The result is
+// cached, see ResetIPv6SupportCache.
+func DualStackSupported() (ok bool) {
+	_, dualStack := ipv6Support()
+
+	return dualStack
+}
+
+// ResetIPv6SupportCache makes the next call to IPv6Supported or
+// DualStackSupported check the support again.  It's mostly needed in tests.
+func ResetIPv6SupportCache() {
+	ipv6SupportCache.mu.Lock()
+	defer ipv6SupportCache.mu.Unlock()
+
+	ipv6SupportCache.supported = false
+	ipv6SupportCache.dualStack = false
+	ipv6SupportCache.ok = false
+}
+
+// ipv6Support returns the cached results of the IPv6 support checks performing
+// those if needed.
+func ipv6Support() (supported, dualStack bool) {
+	ipv6SupportCache.mu.Lock()
+	defer ipv6SupportCache.mu.Unlock()
+
+	if !ipv6SupportCache.ok {
+		supported = checkIPv6Supported()
+		if supported {
+			dualStack = checkDualStack()
+		}
+
+		ipv6SupportCache.supported = supported
+		ipv6SupportCache.dualStack = dualStack
+		ipv6SupportCache.ok = true
+	}
+
+	return ipv6SupportCache.supported, ipv6SupportCache.dualStack
+}
+
+// checkIPv6Supported checks if the IPv6 sockets can be used.
+func checkIPv6Supported() (ok bool) {
+	if ipv6DisabledByOS() {
+		currentLogger().Debug("checking ipv6 support: ipv6 is disabled by os")
+
+		return false
+	}
+
+	c, err := net.ListenPacket("udp6", "[::1]:0")
+	if err != nil {
+		currentLogger().Debug("checking ipv6 support: %s", err)
+
+		return false
+	}
+
+	err = c.Close()
+	if err != nil {
+		currentLogger().Debug("checking ipv6 support: closing: %s", err)
+	}
+
+	return true
+}
+
+// checkDualStack checks if the IPv6 sockets accept the IPv4 connections by
+// default.
+func checkDualStack() (ok bool) {
+	v6Only, err := v6OnlyByDefault()
+	if err != nil {
+		currentLogger().Debug("checking dual-stack support: %s", err)
+
+		return false
+	}
+
+	return !v6Only
+}
@@ -0,0 +1,27 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"bytes"
+	"io/fs"
+)
+
+// disableIPv6Path is the path to the sysctl disabling IPv6 on all the network
+// interfaces relative to the root directory.
+const disableIPv6Path = "proc/sys/net/ipv6/conf/all/disable_ipv6"
+
+// ipv6DisabledByOS returns true if IPv6 is disabled using sysctl.  The
+// unreadable sysctl, for example, inside a container without procfs, doesn't
+// mean IPv6 is disabled.
+func ipv6DisabledByOS() (ok bool) {
+	data, err := fs.ReadFile(rootDirFS, disableIPv6Path)
+	if err != nil {
+		currentLogger().Debug("reading ipv6 sysctl: %s", err)
+
+		return false
+	}
+
+	return string(bytes.TrimSpace(data)) == "1"
+}
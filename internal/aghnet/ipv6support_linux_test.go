@@ -0,0 +1,52 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPv6Supported(t *testing.T) {
+	t.Cleanup(ResetIPv6SupportCache)
+
+	t.Run("disabled", func(t *testing.T) {
+		substRootDirFS(t, fstest.MapFS{
+			disableIPv6Path: &fstest.MapFile{Data: []byte("1\n")},
+		})
+		ResetIPv6SupportCache()
+
+		assert.False(t, IPv6Supported())
+		assert.False(t, DualStackSupported())
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		substRootDirFS(t, fstest.MapFS{
+			disableIPv6Path: &fstest.MapFile{Data: []byte("0\n")},
+		})
+
+		assert.False(t, IPv6Supported())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		substRootDirFS(t, fstest.MapFS{
+			disableIPv6Path: &fstest.MapFile{Data: []byte("0\n")},
+		})
+		ResetIPv6SupportCache()
+
+		supported := IPv6Supported()
+		if !supported {
+			t.Skip("ipv6 sockets aren't supported on this host")
+		}
+
+		v6Only, err := v6OnlyByDefault()
+		if err != nil {
+			t.Skipf("can't get IPV6_V6ONLY: %s", err)
+		}
+
+		assert.Equal(t, !v6Only, DualStackSupported())
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+// ipv6DisabledByOS returns false, since there is no OS-provided information
+// about IPv6 being disabled on this OS.
+func ipv6DisabledByOS() (ok bool) {
+	return false
+}
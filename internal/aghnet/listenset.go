@@ -0,0 +1,159 @@
+package aghnet
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Listener is a configured listening address.
+type Listener struct {
+	// Proto is the transport protocol of the listener, e.g. "tcp" or "udp".
+	Proto string
+
+	// Addr is the address the listener binds to.
+	Addr netip.AddrPort
+}
+
+// String implements the fmt.Stringer interface for Listener.
+func (l Listener) String() (s string) {
+	return fmt.Sprintf("%s %s", l.Proto, l.Addr)
+}
+
+// ConflictKind is the kind of a conflict between two listeners.
+type ConflictKind string
+
+// ConflictKind values.
+const (
+	// ConflictDuplicate means that the listeners have the same protocol and
+	// address.
+	ConflictDuplicate ConflictKind = "duplicate"
+
+	// ConflictShadowed means that the listener with an unspecified address
+	// binds the port on all the addresses, including the one of the other
+	// listener with the same protocol.
+	ConflictShadowed ConflictKind = "shadowed"
+)
+
+// Conflict describes two listeners which can't be bound together.
+type Conflict struct {
+	// Kind is the kind of the conflict.
+	Kind ConflictKind
+
+	// First is the listener met earlier in the set.  For ConflictShadowed,
+	// it's always the one with the unspecified address.
+	First Listener
+
+	// Second is the other conflicting listener.
+	Second Listener
+}
+
+// type check
+var _ error = Conflict{}
+
+// Error implements the error interface for Conflict.
+func (c Conflict) Error() (msg string) {
+	if c.Kind == ConflictShadowed {
+		return fmt.Sprintf("%s shadows %s", c.First, c.Second)
+	}
+
+	return fmt.Sprintf("%s is a duplicate of %s", c.Second, c.First)
+}
+
+// ValidateListenSet returns the conflicts between the pairs of listeners,
+// which would make some of the binds fail.  The listeners conflict if they have
+// the same protocol and port and either the same address or one of them binds
+// to the unspecified address of the same family.  The IPv6 unspecified address
+// also shadows the IPv4 ones, since the IPv6 sockets are dual-stack by default.
+// The IPv4-mapped IPv6 addresses are treated as the IPv4 ones.  No binding is
+// actually performed.
+func ValidateListenSet(listeners []Listener) (conflicts []Conflict) {
+	for i, a := range listeners {
+		for _, b := range listeners[i+1:] {
+			kind, swap, ok := listenersConflict(a, b)
+			if !ok {
+				continue
+			}
+
+			c := Conflict{Kind: kind, First: a, Second: b}
+			if swap {
+				c.First, c.Second = b, a
+			}
+
+			conflicts = append(conflicts, c)
+		}
+	}
+
+	return conflicts
+}
+
+// listenersConflict returns the kind of conflict between a and b, if any.
+// swap is true if b shadows a.
+func listenersConflict(a, b Listener) (kind ConflictKind, swap, ok bool) {
+	if a.Proto != b.Proto || a.Addr.Port() != b.Addr.Port() {
+		return "", false, false
+	}
+
+	addrA, addrB := CanonicalAddr(a.Addr.Addr()), CanonicalAddr(b.Addr.Addr())
+	switch {
+	case addrA == addrB:
+		return ConflictDuplicate, false, true
+	case shadows(addrA, addrB):
+		return ConflictShadowed, false, true
+	case shadows(addrB, addrA):
+		return ConflictShadowed, true, true
+	default:
+		return "", false, false
+	}
+}
+
+// shadows returns true if binding to wildcard also binds to addr.
+func shadows(wildcard, addr netip.Addr) (ok bool) {
+	if !IsWildcardAddr(wildcard) {
+		return false
+	}
+
+	return wildcard.Is6() || addr.Is4()
+}
+
+// NormalizeBindAddrs removes the redundant entries from addrs, which are
+// either the repeats of the earlier ones or the ones shadowed by a wildcard
+// with the same port.  effective are the remaining entries and shadowed are the
+// removed ones, both in the original order and as is.  The unspecified address
+// of a family shadows all the addresses of the same family.  The IPv6
+// unspecified address also shadows the IPv4 ones, including 0.0.0.0, since the
+// IPv6 sockets are dual-stack by default, see ValidateListenSet.  The
+// IPv4-mapped IPv6 addresses are treated as the IPv4 ones.
+func NormalizeBindAddrs(addrs []netip.AddrPort) (effective, shadowed []netip.AddrPort) {
+	for i, a := range addrs {
+		if isRedundantBindAddr(addrs, i) {
+			shadowed = append(shadowed, a)
+		} else {
+			effective = append(effective, a)
+		}
+	}
+
+	return effective, shadowed
+}
+
+// isRedundantBindAddr returns true if the element of addrs at index i repeats
+// an earlier one or is shadowed by any other one.
+func isRedundantBindAddr(addrs []netip.AddrPort, i int) (ok bool) {
+	a := addrs[i]
+	addr := CanonicalAddr(a.Addr())
+	for j, b := range addrs {
+		if j == i || b.Port() != a.Port() {
+			continue
+		}
+
+		other := CanonicalAddr(b.Addr())
+		if other == addr {
+			if j < i {
+				return true
+			}
+		} else if shadows(other, addr) {
+			return true
+		}
+	}
+
+	return false
+}
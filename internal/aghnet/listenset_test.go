@@ -0,0 +1,150 @@
+package aghnet
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateListenSet(t *testing.T) {
+	newListener := func(proto, addr string) (l Listener) {
+		return Listener{Proto: proto, Addr: netip.MustParseAddrPort(addr)}
+	}
+
+	dnsUDP := newListener("udp", "192.168.1.1:53")
+	dnsTCP := newListener("tcp", "192.168.1.1:53")
+	wildUDP4 := newListener("udp", "0.0.0.0:53")
+	wildUDP6 := newListener("udp", "[::]:53")
+	doqUDP := newListener("udp", "192.168.1.1:853")
+	dotTCP := newListener("tcp", "192.168.1.1:853")
+	v6UDP := newListener("udp", "[2001:db8::1]:53")
+
+	testCases := []struct {
+		name      string
+		listeners []Listener
+		want      []Conflict
+	}{{
+		name:      "no_conflicts",
+		listeners: []Listener{dnsUDP, dnsTCP, doqUDP, dotTCP, v6UDP},
+		want:      nil,
+	}, {
+		name:      "duplicate",
+		listeners: []Listener{dnsUDP, dnsTCP, dnsUDP},
+		want: []Conflict{{
+			Kind:   ConflictDuplicate,
+			First:  dnsUDP,
+			Second: dnsUDP,
+		}},
+	}, {
+		name:      "duplicate_mapped",
+		listeners: []Listener{dnsUDP, newListener("udp", "[::ffff:192.168.1.1]:53")},
+		want: []Conflict{{
+			Kind:   ConflictDuplicate,
+			First:  dnsUDP,
+			Second: newListener("udp", "[::ffff:192.168.1.1]:53"),
+		}},
+	}, {
+		name:      "shadowed",
+		listeners: []Listener{dnsUDP, wildUDP4, dnsTCP, v6UDP},
+		want: []Conflict{{
+			Kind:   ConflictShadowed,
+			First:  wildUDP4,
+			Second: dnsUDP,
+		}},
+	}, {
+		name:      "shadowed_ipv6",
+		listeners: []Listener{wildUDP6, dnsUDP, v6UDP, doqUDP},
+		want: []Conflict{{
+			Kind:   ConflictShadowed,
+			First:  wildUDP6,
+			Second: dnsUDP,
+		}, {
+			Kind:   ConflictShadowed,
+			First:  wildUDP6,
+			Second: v6UDP,
+		}},
+	}, {
+		name:      "wildcards",
+		listeners: []Listener{wildUDP4, wildUDP6},
+		want: []Conflict{{
+			Kind:   ConflictShadowed,
+			First:  wildUDP6,
+			Second: wildUDP4,
+		}},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ValidateListenSet(tc.listeners))
+		})
+	}
+}
+
+func TestConflict_Error(t *testing.T) {
+	c := Conflict{
+		Kind:   ConflictShadowed,
+		First:  Listener{Proto: "udp", Addr: netip.MustParseAddrPort("0.0.0.0:53")},
+		Second: Listener{Proto: "udp", Addr: netip.MustParseAddrPort("192.168.1.1:53")},
+	}
+	assert.Equal(t, "udp 0.0.0.0:53 shadows udp 192.168.1.1:53", c.Error())
+
+	c.Kind, c.First = ConflictDuplicate, c.Second
+	assert.Equal(t, "udp 192.168.1.1:53 is a duplicate of udp 192.168.1.1:53", c.Error())
+}
+
+func TestNormalizeBindAddrs(t *testing.T) {
+	var (
+		specific4 = netip.MustParseAddrPort("192.168.1.1:53")
+		mapped4   = netip.MustParseAddrPort("[::ffff:192.168.1.1]:53")
+		other4    = netip.MustParseAddrPort("192.168.1.1:5353")
+		wild4     = netip.MustParseAddrPort("0.0.0.0:53")
+		specific6 = netip.MustParseAddrPort("[2001:db8::1]:53")
+		wild6     = netip.MustParseAddrPort("[::]:53")
+	)
+
+	testCases := []struct {
+		name          string
+		addrs         []netip.AddrPort
+		wantEffective []netip.AddrPort
+		wantShadowed  []netip.AddrPort
+	}{{
+		name:          "empty",
+		addrs:         nil,
+		wantEffective: nil,
+		wantShadowed:  nil,
+	}, {
+		name:          "no_redundant",
+		addrs:         []netip.AddrPort{specific4, other4, specific6},
+		wantEffective: []netip.AddrPort{specific4, other4, specific6},
+		wantShadowed:  nil,
+	}, {
+		name:          "duplicates",
+		addrs:         []netip.AddrPort{specific4, mapped4, specific4},
+		wantEffective: []netip.AddrPort{specific4},
+		wantShadowed:  []netip.AddrPort{mapped4, specific4},
+	}, {
+		name:          "wildcard_ipv4",
+		addrs:         []netip.AddrPort{specific4, wild4, other4, specific6},
+		wantEffective: []netip.AddrPort{wild4, other4, specific6},
+		wantShadowed:  []netip.AddrPort{specific4},
+	}, {
+		name:          "wildcard_ipv6",
+		addrs:         []netip.AddrPort{specific4, wild4, specific6, wild6, other4},
+		wantEffective: []netip.AddrPort{wild6, other4},
+		wantShadowed:  []netip.AddrPort{specific4, wild4, specific6},
+	}, {
+		name:          "duplicate_wildcards",
+		addrs:         []netip.AddrPort{wild4, specific4, wild4},
+		wantEffective: []netip.AddrPort{wild4},
+		wantShadowed:  []netip.AddrPort{specific4, wild4},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			effective, shadowed := NormalizeBindAddrs(tc.addrs)
+			assert.Equal(t, tc.wantEffective, effective)
+			assert.Equal(t, tc.wantShadowed, shadowed)
+		})
+	}
+}
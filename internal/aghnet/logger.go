@@ -0,0 +1,120 @@
+package aghnet
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// Logger is the logger used by the functions of this package.  The methods
+// have the same semantics as the corresponding functions of the golibs' log
+// package.
+type Logger interface {
+	// Debug writes a debug message.
+	Debug(format string, args ...interface{})
+
+	// Info writes an informational message.
+	Info(format string, args ...interface{})
+
+	// Error writes an error message.
+	Error(format string, args ...interface{})
+}
+
+// componentLogger is the default Logger which writes the messages to the
+// global golibs' logger prefixed with the component name.
+type componentLogger struct {
+	prefix string
+}
+
+// type check
+var _ Logger = componentLogger{}
+
+// Debug implements the Logger interface for componentLogger.
+func (l componentLogger) Debug(format string, args ...interface{}) {
+	log.Debug(l.prefix+format, args...)
+}
+
+// Info implements the Logger interface for componentLogger.
+func (l componentLogger) Info(format string, args ...interface{}) {
+	log.Info(l.prefix+format, args...)
+}
+
+// Error implements the Logger interface for componentLogger.
+func (l componentLogger) Error(format string, args ...interface{}) {
+	log.Error(l.prefix+format, args...)
+}
+
+// discardLogger is a Logger which discards all messages.
+type discardLogger struct{}
+
+// type check
+var _ Logger = discardLogger{}
+
+// Debug implements the Logger interface for discardLogger.
+func (discardLogger) Debug(_ string, _ ...interface{}) {}
+
+// Info implements the Logger interface for discardLogger.
+func (discardLogger) Info(_ string, _ ...interface{}) {}
+
+// Error implements the Logger interface for discardLogger.
+func (discardLogger) Error(_ string, _ ...interface{}) {}
+
+// DiscardLogger is a Logger which discards all messages.  It's useful to
+// silence the package in tests.
+var DiscardLogger Logger = discardLogger{}
+
+// defaultLogger is the default Logger of this package.
+var defaultLogger Logger = componentLogger{prefix: "aghnet: "}
+
+// loggerBox is the type of the value stored in pkgLogger, since atomic.Value
+// requires the stored values to have the same concrete type.
+type loggerBox struct {
+	Logger
+}
+
+// pkgLogger is the package-level logger.  It always contains a loggerBox.
+var pkgLogger = func() (v *atomic.Value) {
+	v = &atomic.Value{}
+	v.Store(loggerBox{Logger: defaultLogger})
+
+	return v
+}()
+
+// SetLogger sets the package-level logger used by the functions which don't
+// accept a context.Context or if the context carries no logger.  If l is nil,
+// the default logger, which writes to the global golibs' logger, is restored.
+// It is safe for concurrent use.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = defaultLogger
+	}
+
+	pkgLogger.Store(loggerBox{Logger: l})
+}
+
+// currentLogger returns the package-level logger.
+func currentLogger() (l Logger) {
+	return pkgLogger.Load().(loggerBox).Logger
+}
+
+// ctxKeyLogger is the context key for the logger.
+type ctxKeyLogger struct{}
+
+// ContextWithLogger returns a copy of the parent context carrying l, which is
+// used instead of the package-level logger by the functions accepting the
+// context.
+func ContextWithLogger(parent context.Context, l Logger) (ctx context.Context) {
+	return context.WithValue(parent, ctxKeyLogger{}, l)
+}
+
+// loggerFromContext returns the logger carried by ctx or the package-level one
+// if there is none.
+func loggerFromContext(ctx context.Context) (l Logger) {
+	l, ok := ctx.Value(ctxKeyLogger{}).(Logger)
+	if !ok || l == nil {
+		return currentLogger()
+	}
+
+	return l
+}
@@ -0,0 +1,49 @@
+package aghnet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordLogger is a Logger which records the debug messages.
+type recordLogger struct {
+	discardLogger
+
+	msgs []string
+}
+
+// Debug implements the Logger interface for *recordLogger.
+func (l *recordLogger) Debug(format string, args ...interface{}) {
+	l.msgs = append(l.msgs, fmt.Sprintf(format, args...))
+}
+
+func TestSetLogger(t *testing.T) {
+	prev := currentLogger()
+	t.Cleanup(func() { SetLogger(prev) })
+
+	l := &recordLogger{}
+	SetLogger(l)
+	currentLogger().Debug("msg %d", 1)
+
+	assert.Equal(t, []string{"msg 1"}, l.msgs)
+
+	SetLogger(nil)
+	assert.Equal(t, defaultLogger, currentLogger())
+}
+
+func TestContextWithLogger(t *testing.T) {
+	prev := currentLogger()
+	t.Cleanup(func() { SetLogger(prev) })
+
+	pkgLog, ctxLog := &recordLogger{}, &recordLogger{}
+	SetLogger(pkgLog)
+
+	loggerFromContext(context.Background()).Debug("pkg")
+	loggerFromContext(ContextWithLogger(context.Background(), ctxLog)).Debug("ctx")
+
+	assert.Equal(t, []string{"pkg"}, pkgLog.msgs)
+	assert.Equal(t, []string{"ctx"}, ctxLog.msgs)
+}
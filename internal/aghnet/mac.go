@@ -0,0 +1,126 @@
+package aghnet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// macLen is the length of a 48-bit MAC address in bytes.
+const macLen = 6
+
+// Errors returned by ValidateClientMAC.
+const (
+	// ErrMACBadLength is returned when the MAC address isn't a 48-bit one.
+	ErrMACBadLength errors.Error = "bad mac address length"
+
+	// ErrMACZero is returned when all the octets of the MAC address are zero.
+	ErrMACZero errors.Error = "mac address is all zeros"
+
+	// ErrMACBroadcast is returned when the MAC address is the broadcast one,
+	// i.e. all the octets are 0xff.
+	ErrMACBroadcast errors.Error = "mac address is broadcast"
+
+	// ErrMACMulticast is returned when the individual/group bit of the first
+	// octet of the MAC address is set.
+	ErrMACMulticast errors.Error = "mac address is multicast"
+)
+
+// ParseMAC parses s as a 48-bit MAC address.  In addition to the formats
+// accepted by net.ParseMAC, which are the ones with the octets separated by
+// colons or dashes and the dotted Cisco one, it accepts 12 hexadecimal digits
+// without any separators.  The 64-bit EUI and the 20-octet IP over InfiniBand
+// addresses are rejected.
+func ParseMAC(s string) (hwa net.HardwareAddr, err error) {
+	defer func() { err = errors.Annotate(err, "parsing mac address %q: %w", s) }()
+
+	if len(s) == 2*macLen {
+		hwa, err = hex.DecodeString(s)
+		if err != nil {
+			// Don't wrap the error, because it's informative enough as is.
+			return nil, err
+		}
+
+		return hwa, nil
+	}
+
+	hwa, err = net.ParseMAC(s)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	} else if l := len(hwa); l != macLen {
+		return nil, fmt.Errorf("bad length %d, want %d", l, macLen)
+	}
+
+	return hwa, nil
+}
+
+// NormalizeMAC returns the canonical form of hw, which is the lowercase octets
+// separated by colons.  Use it to compare MAC addresses entered by users.
+func NormalizeMAC(hw net.HardwareAddr) (norm string) {
+	return hw.String()
+}
+
+// LinkLocalFromMAC returns the IPv6 link-local address formed from the 48-bit
+// hw using the modified EUI-64 format, see RFC 4291 Appendix A.  The zone of
+// the address is empty.
+func LinkLocalFromMAC(hw net.HardwareAddr) (ip netip.Addr, err error) {
+	if l := len(hw); l != macLen {
+		return netip.Addr{}, fmt.Errorf("bad mac address length %d, want %d", l, macLen)
+	}
+
+	b := [16]byte{0: 0xfe, 1: 0x80}
+	// Invert the universal/local bit.
+	b[8] = hw[0] ^ 0x02
+	b[9], b[10] = hw[1], hw[2]
+	b[11], b[12] = 0xff, 0xfe
+	b[13], b[14], b[15] = hw[3], hw[4], hw[5]
+
+	return netip.AddrFrom16(b), nil
+}
+
+// IsRandomizedMAC returns true if hw looks like a randomized MAC address, which
+// modern devices use to avoid being tracked, often changing it per network.
+// Such addresses are the locally administered unicast ones, i.e. they have the
+// universal/local bit of the first octet set and the individual/group bit
+// clear.  Note that it's a heuristic, since the locally administered addresses
+// are also assigned manually and to the virtual network interfaces.
+func IsRandomizedMAC(hw net.HardwareAddr) (ok bool) {
+	if len(hw) == 0 {
+		return false
+	}
+
+	return hw[0]&0x02 != 0 && hw[0]&0x01 == 0
+}
+
+// ValidateClientMAC returns an error if hw can't be the MAC address of a DHCP
+// client, so that the static leases for such addresses, which would never match
+// any client, are rejected.  Unlike netutil.ValidateMAC, only the 48-bit
+// addresses are accepted.  The locally administered addresses, including the
+// randomized ones, see IsRandomizedMAC, are valid.  err is one of ErrMAC*
+// errors.
+func ValidateClientMAC(hw net.HardwareAddr) (err error) {
+	if l := len(hw); l != macLen {
+		return fmt.Errorf("%w %d, want %d", ErrMACBadLength, l, macLen)
+	}
+
+	var or, and byte = 0x00, 0xff
+	for _, b := range hw {
+		or, and = or|b, and&b
+	}
+
+	switch {
+	case or == 0x00:
+		return ErrMACZero
+	case and == 0xff:
+		// Check it before the multicast bit, since it's also set.
+		return ErrMACBroadcast
+	case hw[0]&0x01 != 0:
+		return ErrMACMulticast
+	default:
+		return nil
+	}
+}
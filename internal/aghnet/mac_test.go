@@ -0,0 +1,209 @@
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMAC(t *testing.T) {
+	want := net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}
+
+	testCases := []struct {
+		name       string
+		in         string
+		wantErrMsg string
+		want       net.HardwareAddr
+	}{{
+		name:       "colon",
+		in:         "00:1a:2b:3c:4d:5e",
+		wantErrMsg: "",
+		want:       want,
+	}, {
+		name:       "colon_upper",
+		in:         "00:1A:2B:3C:4D:5E",
+		wantErrMsg: "",
+		want:       want,
+	}, {
+		name:       "dash",
+		in:         "00-1a-2b-3c-4d-5e",
+		wantErrMsg: "",
+		want:       want,
+	}, {
+		name:       "dotted",
+		in:         "001a.2b3c.4d5e",
+		wantErrMsg: "",
+		want:       want,
+	}, {
+		name:       "no_separators",
+		in:         "001A2b3C4d5E",
+		wantErrMsg: "",
+		want:       want,
+	}, {
+		name: "eui64",
+		in:   "00:1a:2b:3c:4d:5e:6f:70",
+		wantErrMsg: `parsing mac address "00:1a:2b:3c:4d:5e:6f:70": ` +
+			`bad length 8, want 6`,
+		want: nil,
+	}, {
+		name: "bad_hex",
+		in:   "001a2b3c4d5g",
+		wantErrMsg: `parsing mac address "001a2b3c4d5g": ` +
+			`encoding/hex: invalid byte: U+0067 'g'`,
+		want: nil,
+	}, {
+		name: "bad",
+		in:   "00:1a:2b",
+		wantErrMsg: `parsing mac address "00:1a:2b": ` +
+			`address 00:1a:2b: invalid MAC address`,
+		want: nil,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hwa, err := ParseMAC(tc.in)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.want, hwa)
+		})
+	}
+}
+
+func TestNormalizeMAC(t *testing.T) {
+	hwa, err := ParseMAC("001A.2B3C.4D5E")
+	assert.NoError(t, err)
+	assert.Equal(t, "00:1a:2b:3c:4d:5e", NormalizeMAC(hwa))
+}
+
+func TestLinkLocalFromMAC(t *testing.T) {
+	testCases := []struct {
+		name       string
+		wantErrMsg string
+		hw         net.HardwareAddr
+		want       netip.Addr
+	}{{
+		name:       "universal",
+		wantErrMsg: "",
+		hw:         net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		want:       netip.MustParseAddr("fe80::211:22ff:fe33:4455"),
+	}, {
+		name:       "qemu",
+		wantErrMsg: "",
+		hw:         net.HardwareAddr{0x52, 0x54, 0x00, 0x12, 0x34, 0x56},
+		want:       netip.MustParseAddr("fe80::5054:ff:fe12:3456"),
+	}, {
+		name:       "local",
+		wantErrMsg: "",
+		hw:         net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01},
+		want:       netip.MustParseAddr("fe80::ff:fe00:1"),
+	}, {
+		name:       "eui64",
+		wantErrMsg: "bad mac address length 8, want 6",
+		hw:         net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77},
+		want:       netip.Addr{},
+	}, {
+		name:       "empty",
+		wantErrMsg: "bad mac address length 0, want 6",
+		hw:         nil,
+		want:       netip.Addr{},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ip, err := LinkLocalFromMAC(tc.hw)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.want, ip)
+		})
+	}
+}
+
+func TestIsRandomizedMAC(t *testing.T) {
+	testCases := []struct {
+		name string
+		hw   net.HardwareAddr
+		want bool
+	}{{
+		name: "universal",
+		hw:   net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		want: false,
+	}, {
+		name: "randomized",
+		hw:   net.HardwareAddr{0xda, 0xa1, 0x19, 0x12, 0x34, 0x56},
+		want: true,
+	}, {
+		name: "local_multicast",
+		hw:   net.HardwareAddr{0x03, 0x00, 0x00, 0x00, 0x00, 0x01},
+		want: false,
+	}, {
+		name: "universal_multicast",
+		hw:   net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x01},
+		want: false,
+	}, {
+		name: "empty",
+		hw:   nil,
+		want: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsRandomizedMAC(tc.hw))
+		})
+	}
+}
+
+func TestValidateClientMAC(t *testing.T) {
+	testCases := []struct {
+		wantErr    error
+		name       string
+		wantErrMsg string
+		hw         net.HardwareAddr
+	}{{
+		wantErr:    nil,
+		name:       "unicast",
+		wantErrMsg: "",
+		hw:         net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+	}, {
+		wantErr:    nil,
+		name:       "randomized",
+		wantErrMsg: "",
+		hw:         net.HardwareAddr{0x02, 0x11, 0x22, 0x33, 0x44, 0x55},
+	}, {
+		wantErr:    ErrMACBadLength,
+		name:       "short",
+		wantErrMsg: "bad mac address length 2, want 6",
+		hw:         net.HardwareAddr{0x00, 0x11},
+	}, {
+		wantErr:    ErrMACBadLength,
+		name:       "eui64",
+		wantErrMsg: "bad mac address length 8, want 6",
+		hw:         net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77},
+	}, {
+		wantErr:    ErrMACZero,
+		name:       "zero",
+		wantErrMsg: "mac address is all zeros",
+		hw:         net.HardwareAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	}, {
+		wantErr:    ErrMACBroadcast,
+		name:       "broadcast",
+		wantErrMsg: "mac address is broadcast",
+		hw:         net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}, {
+		wantErr:    ErrMACMulticast,
+		name:       "multicast",
+		wantErrMsg: "mac address is multicast",
+		hw:         net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x01},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateClientMAC(tc.hw)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// ErrNoNeighbor is returned by NeighborMAC when the system's neighbor table has
+// no complete entry for the address.
+const ErrNoNeighbor errors.Error = "no neighbor entry"
+
+// NeighborMAC returns the hardware address of the host with ip from the
+// system's ARP or NDP neighbor table.  The zone of ip, if any, is ignored.  If
+// the entry is absent or incomplete, it returns an error for which
+// errors.Is(err, ErrNoNeighbor) is true.
+func NeighborMAC(ip netip.Addr) (mac net.HardwareAddr, err error) {
+	if !ip.IsValid() {
+		return nil, fmt.Errorf("bad ip address %s", ip)
+	}
+
+	mac, err = neighborMAC(ip.Unmap().WithZone(""))
+	if err != nil {
+		return nil, fmt.Errorf("looking up %s: %w", ip, err)
+	}
+
+	return mac, nil
+}
+
+// findNeighborMAC looks for the hardware address of ip within the output of
+// neighbor table listing utilities, like arp or ndp, or within similar tables,
+// like /proc/net/arp on Linux.  Each line of out consists of fields separated
+// by spaces, the field with ipIdx index containing the IP address, possibly
+// within parentheses or with zone, and the one with macIdx index containing the
+// hardware address.  The lines with less fields and the headers are skipped.
+func findNeighborMAC(out string, ip netip.Addr, ipIdx, macIdx int) (mac net.HardwareAddr, err error) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) <= ipIdx || len(fields) <= macIdx {
+			continue
+		}
+
+		addr, perr := netip.ParseAddr(strings.Trim(fields[ipIdx], "()"))
+		if perr != nil || addr.Unmap().WithZone("") != ip {
+			continue
+		}
+
+		mac, perr = parseLooseMAC(fields[macIdx])
+		if perr != nil || isZeroMAC(mac) {
+			// Incomplete entries contain either no hardware address at all or
+			// a zero one.
+			continue
+		}
+
+		return mac, nil
+	}
+
+	return nil, ErrNoNeighbor
+}
+
+// parseLooseMAC parses the 48-bit hardware address with octets separated by
+// colons or dashes, allowing the octets to omit the leading zero, like the
+// BSD's arp utility prints them.
+func parseLooseMAC(s string) (mac net.HardwareAddr, err error) {
+	octets := strings.FieldsFunc(s, func(r rune) (ok bool) { return r == ':' || r == '-' })
+	if len(octets) != 6 {
+		return nil, fmt.Errorf("bad hardware address %q", s)
+	}
+
+	mac = make(net.HardwareAddr, 0, len(octets))
+	for _, o := range octets {
+		var b uint64
+		b, err = strconv.ParseUint(o, 16, 8)
+		if err != nil || len(o) > 2 {
+			return nil, fmt.Errorf("bad hardware address %q", s)
+		}
+
+		mac = append(mac, byte(b))
+	}
+
+	return mac, nil
+}
+
+// isZeroMAC returns true if all the octets of mac are zero.
+func isZeroMAC(mac net.HardwareAddr) (ok bool) {
+	for _, b := range mac {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,120 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// procNetARPPath is the path to the kernel's ARP table relative to the root
+// directory.
+const procNetARPPath = "proc/net/arp"
+
+func neighborMAC(ip netip.Addr) (mac net.HardwareAddr, err error) {
+	if ip.Is4() {
+		mac, err = procNetARPMAC(ip)
+		if err == nil {
+			return mac, nil
+		}
+	}
+
+	return netlinkNeighborMAC(ip)
+}
+
+// procNetARPMAC looks for the hardware address of ip in /proc/net/arp, which
+// looks like:
+//
+//   IP address       HW type     Flags       HW address            Mask     Device
+//   192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0
+//
+func procNetARPMAC(ip netip.Addr) (mac net.HardwareAddr, err error) {
+	f, err := rootDirFS.Open(procNetARPPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.WithDeferred(err, f.Close()) }()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return findNeighborMAC(string(data), ip, 0, 3)
+}
+
+// netlinkNeighborMAC looks for the hardware address of ip in the neighbor
+// table dumped through netlink.  It covers both ARP and NDP entries.
+func netlinkNeighborMAC(ip netip.Addr) (mac net.HardwareAddr, err error) {
+	req := make([]byte, unix.SizeofNdMsg)
+	msgs, err := netlinkRouteDump(unix.RTM_GETNEIGH, req)
+	if err != nil {
+		return nil, fmt.Errorf("dumping neighbors: %w", err)
+	}
+
+	for _, msg := range msgs {
+		var n neighbor
+		n, err = parseNeighborMsg(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		if n.addr != ip || n.state&(unix.NUD_INCOMPLETE|unix.NUD_FAILED) != 0 {
+			continue
+		}
+
+		if len(n.mac) == 0 || isZeroMAC(n.mac) {
+			continue
+		}
+
+		return n.mac, nil
+	}
+
+	return nil, ErrNoNeighbor
+}
+
+// neighbor is a single entry of the kernel's neighbor table.
+type neighbor struct {
+	addr  netip.Addr
+	mac   net.HardwareAddr
+	state uint16
+}
+
+// parseNeighborMsg parses the data of the RTM_NEWNEIGH netlink message, which
+// consists of struct ndmsg followed by the attributes.
+//
+// See man rtnetlink(7).
+func parseNeighborMsg(data []byte) (n neighbor, err error) {
+	if len(data) < unix.SizeofNdMsg {
+		return neighbor{}, fmt.Errorf("neighbor message is too short: %d bytes", len(data))
+	}
+
+	n.state = aghos.NativeEndian.Uint16(data[8:10])
+
+	ad, err := netlink.NewAttributeDecoder(data[unix.SizeofNdMsg:])
+	if err != nil {
+		return neighbor{}, fmt.Errorf("decoding neighbor attributes: %w", err)
+	}
+
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NDA_DST:
+			addr, ok := netip.AddrFromSlice(ad.Bytes())
+			if ok {
+				n.addr = addr.Unmap()
+			}
+		case unix.NDA_LLADDR:
+			n.mac = net.HardwareAddr(ad.Bytes())
+		}
+	}
+
+	return n, ad.Err()
+}
@@ -0,0 +1,36 @@
+//go:build !(linux || windows)
+// +build !linux,!windows
+
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// neighborMAC uses arp(8) for IPv4 and ndp(8) for IPv6.  Their outputs look
+// like:
+//
+//   ? (192.168.1.1) at 0:11:22:33:44:55 on en0 ifscope [ethernet]
+//
+// and:
+//
+//   Neighbor             Linklayer Address  Netif Expire    S Flags
+//   fe80::1%en0          0:11:22:33:44:55     en0 23h59m58s S R
+//
+func neighborMAC(ip netip.Addr) (mac net.HardwareAddr, err error) {
+	cmd, ipIdx, macIdx := "arp", 1, 3
+	if ip.Is6() {
+		cmd, ipIdx, macIdx = "ndp", 0, 1
+	}
+
+	code, out, err := aghosRunCommand(cmd, "-an")
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %w", cmd, err)
+	} else if code != 0 {
+		return nil, fmt.Errorf("%s finished with code %d", cmd, code)
+	}
+
+	return findNeighborMAC(out, ip, ipIdx, macIdx)
+}
@@ -0,0 +1,173 @@
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindNeighborMAC(t *testing.T) {
+	const (
+		procNetARP = `IP address       HW type     Flags       HW address            Mask     Device` + nl +
+			`192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0` + nl +
+			`192.168.1.5      0x1         0x0         00:00:00:00:00:00     *        eth0` + nl
+
+		bsdARP = `? (192.168.1.1) at 0:11:22:33:44:55 on en0 ifscope [ethernet]` + nl +
+			`? (192.168.1.5) at (incomplete) on en0 ifscope [ethernet]` + nl
+
+		bsdNDP = `Neighbor             Linklayer Address  Netif Expire    S Flags` + nl +
+			`fe80::1%en0          0:11:22:33:44:55     en0 23h59m58s S R` + nl +
+			`fe80::5%en0          (incomplete)         en0 expired   N` + nl
+
+		winARP = `Interface: 192.168.1.2 --- 0xb` + nl +
+			`  Internet Address      Physical Address      Type` + nl +
+			`  192.168.1.1           00-11-22-33-44-55     dynamic` + nl
+
+		winNetshNeighbors = `Interface 11: Ethernet` + nl +
+			nl +
+			`Internet Address                              Physical Address   Type` + nl +
+			`--------------------------------------------  -----------------  -----------` + nl +
+			`fe80::1                                       00-11-22-33-44-55  Reachable` + nl +
+			`fe80::5                                       00-00-00-00-00-00  Unreachable` + nl +
+			`ff02::1                                                          Permanent` + nl
+	)
+
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+
+	testCases := []struct {
+		wantErr error
+		name    string
+		out     string
+		ip      netip.Addr
+		want    net.HardwareAddr
+		ipIdx   int
+		macIdx  int
+	}{{
+		wantErr: nil,
+		name:    "proc_net_arp",
+		out:     procNetARP,
+		ip:      netip.MustParseAddr("192.168.1.1"),
+		want:    mac,
+		ipIdx:   0,
+		macIdx:  3,
+	}, {
+		wantErr: ErrNoNeighbor,
+		name:    "proc_net_arp_incomplete",
+		out:     procNetARP,
+		ip:      netip.MustParseAddr("192.168.1.5"),
+		want:    nil,
+		ipIdx:   0,
+		macIdx:  3,
+	}, {
+		wantErr: nil,
+		name:    "bsd_arp",
+		out:     bsdARP,
+		ip:      netip.MustParseAddr("192.168.1.1"),
+		want:    mac,
+		ipIdx:   1,
+		macIdx:  3,
+	}, {
+		wantErr: ErrNoNeighbor,
+		name:    "bsd_arp_incomplete",
+		out:     bsdARP,
+		ip:      netip.MustParseAddr("192.168.1.5"),
+		want:    nil,
+		ipIdx:   1,
+		macIdx:  3,
+	}, {
+		wantErr: nil,
+		name:    "bsd_ndp",
+		out:     bsdNDP,
+		ip:      netip.MustParseAddr("fe80::1"),
+		want:    mac,
+		ipIdx:   0,
+		macIdx:  1,
+	}, {
+		wantErr: ErrNoNeighbor,
+		name:    "bsd_ndp_incomplete",
+		out:     bsdNDP,
+		ip:      netip.MustParseAddr("fe80::5"),
+		want:    nil,
+		ipIdx:   0,
+		macIdx:  1,
+	}, {
+		wantErr: nil,
+		name:    "windows_arp",
+		out:     winARP,
+		ip:      netip.MustParseAddr("192.168.1.1"),
+		want:    mac,
+		ipIdx:   0,
+		macIdx:  1,
+	}, {
+		wantErr: nil,
+		name:    "windows_netsh",
+		out:     winNetshNeighbors,
+		ip:      netip.MustParseAddr("fe80::1"),
+		want:    mac,
+		ipIdx:   0,
+		macIdx:  1,
+	}, {
+		wantErr: ErrNoNeighbor,
+		name:    "windows_netsh_unreachable",
+		out:     winNetshNeighbors,
+		ip:      netip.MustParseAddr("fe80::5"),
+		want:    nil,
+		ipIdx:   0,
+		macIdx:  1,
+	}, {
+		wantErr: ErrNoNeighbor,
+		name:    "windows_netsh_no_mac",
+		out:     winNetshNeighbors,
+		ip:      netip.MustParseAddr("ff02::1"),
+		want:    nil,
+		ipIdx:   0,
+		macIdx:  1,
+	}, {
+		wantErr: ErrNoNeighbor,
+		name:    "absent",
+		out:     winARP,
+		ip:      netip.MustParseAddr("192.168.1.2"),
+		want:    nil,
+		ipIdx:   0,
+		macIdx:  1,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := findNeighborMAC(tc.out, tc.ip, tc.ipIdx, tc.macIdx)
+			require.True(t, errors.Is(err, tc.wantErr))
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseLooseMAC(t *testing.T) {
+	mac := net.HardwareAddr{0x00, 0x01, 0x22, 0x33, 0x0a, 0xff}
+
+	for _, s := range []string{
+		"00:01:22:33:0a:ff",
+		"0:1:22:33:a:ff",
+		"00-01-22-33-0A-FF",
+	} {
+		got, err := parseLooseMAC(s)
+		require.NoError(t, err, s)
+
+		assert.Equal(t, mac, got, s)
+	}
+
+	for _, s := range []string{
+		"",
+		"(incomplete)",
+		"00:01:22:33:0a",
+		"00:01:22:33:0a:fff",
+		"00:01:22:33:0a:gg",
+	} {
+		_, err := parseLooseMAC(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build windows
+// +build windows
+
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// neighborMAC uses "arp -a" for IPv4 and "netsh interface ipv6 show neighbors"
+// for IPv6.  Their outputs look like:
+//
+//   Interface: 192.168.1.2 --- 0xb
+//     Internet Address      Physical Address      Type
+//     192.168.1.1           00-11-22-33-44-55     dynamic
+//
+// and:
+//
+//   Interface 11: Ethernet
+//
+//   Internet Address                              Physical Address   Type
+//   --------------------------------------------  -----------------  -----------
+//   fe80::1                                       00-11-22-33-44-55  Reachable
+//
+func neighborMAC(ip netip.Addr) (mac net.HardwareAddr, err error) {
+	cmd, args := "arp", []string{"-a"}
+	if ip.Is6() {
+		cmd, args = "netsh", []string{"interface", "ipv6", "show", "neighbors"}
+	}
+
+	code, out, err := aghosRunCommand(cmd, args...)
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %w", cmd, err)
+	} else if code != 0 {
+		return nil, fmt.Errorf("%s finished with code %d", cmd, code)
+	}
+
+	return findNeighborMAC(out, ip, 0, 1)
+}
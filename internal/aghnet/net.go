@@ -2,19 +2,50 @@
 package aghnet
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
+	"io/fs"
 	"net"
+	"net/netip"
+	"os"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
 	"github.com/AdguardTeam/golibs/errors"
 	"github.com/AdguardTeam/golibs/log"
 	"github.com/AdguardTeam/golibs/netutil"
 )
 
+// Variables and functions to substitute in tests.
+var (
+	// aghosRunCommand is the function to run shell commands.
+	aghosRunCommand = aghos.RunCommand
+
+	// execLookPath is the function to search for the executables in PATH.
+	execLookPath = exec.LookPath
+
+	// netInterfaces is the function to get the available network interfaces.
+	netInterfaces = net.Interfaces
+
+	// netInterfaceAddrs is the function to get the addresses of the network
+	// interface.
+	netInterfaceAddrs = (*net.Interface).Addrs
+
+	// rootDirFS is the filesystem pointing to the root directory.  It's
+	// a readLinkFS unless substituted in tests.
+	rootDirFS fs.FS = newRootDirFS()
+
+	// ifaceSecondaryAddrs is the function to get the secondary IPv4 addresses
+	// mapped to the indexes of their network interfaces.
+	ifaceSecondaryAddrs = secondaryIPv4Addrs
+)
+
 // ErrNoStaticIPInfo is returned by IfaceHasStaticIP when no information about
 // the IP being static is available.
 const ErrNoStaticIPInfo errors.Error = "no information about static ip"
@@ -26,29 +57,380 @@ func IfaceHasStaticIP(ifaceName string) (has bool, err error) {
 	return ifaceHasStaticIP(ifaceName)
 }
 
-// IfaceSetStaticIP sets static IP address for network interface.
-func IfaceSetStaticIP(ifaceName string) (err error) {
+// RestoreFunc is the signature of a function restoring the previously saved
+// system configuration.
+type RestoreFunc func() (err error)
+
+// IfaceSetStaticIP configures the system to retain the current IP address of
+// the network interface as a static one.  If the applied configuration can't be
+// confirmed, the previous configuration is restored and an error is returned.
+// Otherwise restore function is returned which brings the previous
+// configuration back, so that the callers could undo the change if their
+// following steps fail.
+//
+// The configuration touched and confirmed is:
+//
+//   - on Linux, the /etc/dhcpcd.conf file, which is read back after writing,
+//     since dhcpcd only applies it on restart;
+//   - on macOS, the DNS servers and the IP configuration of the network service
+//     through networksetup, which are applied at once, so the interface is
+//     checked to still have the address.
+//
+// Other operating systems are unsupported.  ErrContainerized is returned if
+// AdGuard Home is running inside a container.  An error wrapping ErrAddrInUse
+// is returned if the current IPv4 address of the interface is used by another
+// host on the LAN, see AddrInUseOnLAN.  Unlike the latter, the address isn't
+// pinged if it can't be probed, since it's the own address of the interface.
+func IfaceSetStaticIP(ifaceName string) (restore RestoreFunc, err error) {
+	if IsContainerized() {
+		return nil, ErrContainerized
+	}
+
+	err = probeStaticIP(ifaceName, GetSubnet(ifaceName))
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
 	return ifaceSetStaticIP(ifaceName)
 }
 
-// GatewayIP returns IP address of interface's gateway.
+// ifaceHasIP returns true if the network interface has ip among its subnets.
+func ifaceHasIP(ifaceName string, ip net.IP) (ok bool) {
+	for _, subnet := range GetSubnets(ifaceName) {
+		if subnet.IP.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The range of MTU values accepted by IfaceSetMTU.  576 is the minimum
+// datagram size every IPv4 host must accept, see RFC 791, 1280 is the minimum
+// link MTU required by IPv6, see RFC 8200, and 9000 is the common size of jumbo
+// frames.
+const (
+	minMTUv4 = 576
+	minMTUv6 = 1280
+	maxMTU   = 9000
+)
+
+// IfaceSetMTU sets the MTU of the network interface.  mtu must be within the
+// range from 576 to 9000, both inclusive, or from 1280 to 9000 if the interface
+// has any IPv6 addresses.  Linux and Windows are supported.
+func IfaceSetMTU(ifaceName string, mtu int) (err error) {
+	minMTU, err := ifaceMinMTU(ifaceName)
+	if err != nil {
+		return fmt.Errorf("checking mtu for %s: %w", ifaceName, err)
+	}
+
+	if mtu < minMTU || mtu > maxMTU {
+		return fmt.Errorf("mtu %d is out of range [%d, %d]", mtu, minMTU, maxMTU)
+	}
+
+	err = ifaceSetMTU(ifaceName, mtu)
+	if err != nil {
+		return fmt.Errorf("setting mtu %d for %s: %w", mtu, ifaceName, err)
+	}
+
+	return nil
+}
+
+// ifaceMinMTU returns the minimum MTU acceptable for the network interface
+// based on the families of its addresses.
+func ifaceMinMTU(ifaceName string) (minMTU int, err error) {
+	ifaceAddrs, err := CollectIfaceAddrs()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return 0, err
+	}
+
+	for _, addr := range ifaceAddrs[ifaceName] {
+		if addr.Is6() {
+			return minMTUv6, nil
+		}
+	}
+
+	return minMTUv4, nil
+}
+
+// IfaceEnsureAddr adds the address from prefix to the network interface unless
+// it already has it.  The other addresses of the interface are left intact.
+// added is true if the address has been added.  The address is considered
+// present regardless of its prefix length.  Only Linux is supported.
+func IfaceEnsureAddr(ifaceName string, prefix netip.Prefix) (added bool, err error) {
+	if !prefix.IsValid() {
+		return false, fmt.Errorf("bad prefix %s", prefix)
+	}
+
+	has, err := ifaceHasAddr(ifaceName, prefix.Addr())
+	if err != nil {
+		return false, fmt.Errorf("checking addresses of %s: %w", ifaceName, err)
+	} else if has {
+		return false, nil
+	}
+
+	err = ifaceAddAddr(ifaceName, prefix)
+	if err != nil {
+		return false, fmt.Errorf("adding %s to %s: %w", prefix, ifaceName, err)
+	}
+
+	return true, nil
+}
+
+// ifaceHasAddr returns true if the network interface has ip among its
+// addresses.  The link-local addresses are considered as well, and the zones
+// are ignored.
+func ifaceHasAddr(ifaceName string, ip netip.Addr) (has bool, err error) {
+	ifaceAddrs, err := CollectIfaceAddrs()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return false, err
+	}
+
+	ip = ip.Unmap().WithZone("")
+	for _, addr := range ifaceAddrs[ifaceName] {
+		if addr.WithZone("") == ip {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GatewayIP returns IP address of interface's gateway.  It returns nil if the
+// gateway can't be found or detected, use GatewayIPAddr to tell those apart.
 func GatewayIP(ifaceName string) net.IP {
-	cmd := exec.Command("ip", "route", "show", "dev", ifaceName)
-	log.Tracef("executing %s %v", cmd.Path, cmd.Args)
-	d, err := cmd.Output()
-	if err != nil || cmd.ProcessState.ExitCode() != 0 {
+	gw, err := GatewayIPAddr(ifaceName)
+	if err != nil {
+		currentLogger().Debug("getting gateway of %s: %s", ifaceName, err)
+
+		return nil
+	} else if !gw.IsValid() {
 		return nil
 	}
 
-	fields := strings.Fields(string(d))
-	// The meaningful "ip route" command output should contain the word
-	// "default" at first field and default gateway IP address at third
-	// field.
-	if len(fields) < 3 || fields[0] != "default" {
-		return nil
+	return gw.AsSlice()
+}
+
+// AllGateways returns the default gateways of all the network interfaces which
+// have one, mapped by the interfaces' names.  The IPv4 gateway is preferred if
+// an interface has both.  On Linux, the gateways are taken from a single dump
+// of the kernel's routing table.  Other OSes fall back on GatewayIP.
+func AllGateways() (gws map[string]net.IP, err error) {
+	gws, err = allGateways()
+	if err != nil {
+		return nil, fmt.Errorf("getting gateways: %w", err)
+	}
+
+	return gws, nil
+}
+
+// GatewayInfo returns the IP and the hardware addresses of the default gateway
+// of the network interface.  The gateway is taken from the routing table, see
+// AllGateways, and its hardware address from the neighbor table, see
+// NeighborMAC.  If the neighbor entry is absent or incomplete, mac is nil and
+// err is nil.  err is ErrNoGateway if the interface has no gateway.
+func GatewayInfo(ifaceName string) (ip net.IP, mac net.HardwareAddr, err error) {
+	defer func() { err = errors.Annotate(err, "getting gateway of %s: %w", ifaceName) }()
+
+	gws, err := AllGateways()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, nil, err
+	}
+
+	ip, ok := gws[ifaceName]
+	if !ok {
+		return nil, nil, ErrNoGateway
+	}
+
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil, nil, fmt.Errorf("bad gateway ip %v", ip)
+	}
+
+	mac, err = NeighborMAC(addr)
+	if errors.Is(err, ErrNoNeighbor) {
+		currentLogger().Debug("no neighbor entry for gateway %s of %s", ip, ifaceName)
+
+		return ip, nil, nil
+	} else if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, nil, err
+	}
+
+	return ip, mac, nil
+}
+
+// IsDefaultRouteIface returns true if the network interface named ifaceName
+// carries the default route.  If both the IPv4 and the IPv6 default routes
+// exist through different interfaces, the IPv4 one wins, so that at most one
+// interface is reported.  If there are several default routes of the same
+// family, the one with the lowest metric wins.  On Linux, the routes are taken
+// from a single dump of the kernel's routing table.  Other OSes fall back on
+// GatewayIP.
+func IsDefaultRouteIface(ifaceName string) (ok bool, err error) {
+	name, err := defaultRouteIface()
+	if err != nil {
+		return false, fmt.Errorf("getting default route interface: %w", err)
+	}
+
+	return name != "" && name == ifaceName, nil
+}
+
+// GatewayIPAddr returns IP address of interface's gateway.  gw is the zero
+// netip.Addr if the gateway can't be found.  err is a *ToolMissingError if the
+// ip utility isn't available, since the gateway can't be detected at all then.
+// If there are several default routes, the gateway of the preferred one is
+// returned, see AllDefaultGateways.
+func GatewayIPAddr(ifaceName string) (gw netip.Addr, err error) {
+	routes, err := AllDefaultGateways(ifaceName)
+	if err != nil {
+		if errors.Is(err, ErrToolMissing) {
+			return netip.Addr{}, err
+		}
+
+		currentLogger().Debug("getting gateway: %s", err)
+
+		return netip.Addr{}, nil
+	} else if len(routes) == 0 {
+		return netip.Addr{}, nil
+	}
+
+	return routes[0].Gateway, nil
+}
+
+// GatewayRoute is a default route through a gateway.
+type GatewayRoute struct {
+	// Gateway is the address of the gateway.
+	Gateway netip.Addr
+
+	// Metric is the metric of the route.  The routes with lower metrics are
+	// preferred.
+	Metric int
+}
+
+// AllDefaultGateways returns the default routes through the network interface
+// named ifaceName reported by the ip utility, sorted by their metrics, so that
+// the first one is the preferred one.  The routes with no metric specified are
+// considered to have zero metric.  It returns a *ToolMissingError if the ip
+// utility isn't available.
+func AllDefaultGateways(ifaceName string) (routes []GatewayRoute, err error) {
+	if tmErr := lookTool("ip", "gateway detection"); tmErr != nil {
+		return nil, tmErr
+	}
+
+	code, out, err := aghosRunCommand("ip", "route", "show", "dev", ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("executing ip route: %w", err)
+	} else if code != 0 {
+		return nil, fmt.Errorf("executing ip route: unexpected exit code %d", code)
+	}
+
+	return parseDefaultRoutes(out), nil
+}
+
+// parseDefaultRoutes parses the default routes from the output of the `ip
+// route show` command, which looks like:
+//
+//   default via 192.168.1.1 proto dhcp metric 100
+//   default via 192.168.2.1 proto dhcp metric 600
+//   192.168.1.0/24 proto kernel scope link src 192.168.1.2 metric 100
+//
+// The routes without a valid gateway address are skipped.  The result is
+// sorted by the metrics keeping the order of the routes with the same metric.
+func parseDefaultRoutes(out string) (routes []GatewayRoute) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "default" {
+			continue
+		}
+
+		r, ok := parseDefaultRouteFields(fields[1:])
+		if ok {
+			routes = append(routes, r)
+		}
+	}
+
+	sort.SliceStable(routes, func(i, j int) (less bool) {
+		return routes[i].Metric < routes[j].Metric
+	})
+
+	return routes
+}
+
+// parseDefaultRouteFields parses the parameters of a single default route
+// following the "default" keyword.  Some of the parameters are single flags,
+// like "onlink", so the values are looked up right after the known keywords.
+// ok is false if there is no valid gateway.
+func parseDefaultRouteFields(fields []string) (r GatewayRoute, ok bool) {
+	for i := 0; i+1 < len(fields); i++ {
+		switch key, val := fields[i], fields[i+1]; key {
+		case "via":
+			gw, err := netip.ParseAddr(val)
+			if err != nil {
+				return GatewayRoute{}, false
+			}
+
+			r.Gateway = gw
+		case "metric":
+			metric, err := strconv.Atoi(val)
+			if err != nil {
+				return GatewayRoute{}, false
+			}
+
+			r.Metric = metric
+		default:
+			// Go on.
+		}
+	}
+
+	return r, r.Gateway.IsValid()
+}
+
+// parseNetshDefaultRoutes parses the IPv6 default routes through the network
+// interface with index from the output of the `netsh interface ipv6 show route`
+// command, which looks like:
+//
+//   Publish  Type      Met  Prefix                    Idx  Gateway/Interface Name
+//   -------  --------  ---  ------------------------  ---  ------------------------
+//   No       Manual    256  ::/0                       11  fe80::1
+//   No       System    256  fe80::/64                  11  Ethernet
+//
+// The routes without a valid gateway address are skipped.  The result is
+// sorted by the metrics keeping the order of the routes with the same metric.
+func parseNetshDefaultRoutes(out string, index int) (routes []GatewayRoute) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || fields[3] != "::/0" {
+			continue
+		}
+
+		idx, err := strconv.Atoi(fields[4])
+		if err != nil || idx != index {
+			continue
+		}
+
+		metric, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+
+		gw, err := netip.ParseAddr(fields[5])
+		if err != nil {
+			continue
+		}
+
+		routes = append(routes, GatewayRoute{Gateway: gw, Metric: metric})
 	}
 
-	return net.ParseIP(fields[2])
+	sort.SliceStable(routes, func(i, j int) (less bool) {
+		return routes[i].Metric < routes[j].Metric
+	})
+
+	return routes
 }
 
 // CanBindPort checks if we can bind to the given port.
@@ -68,10 +450,69 @@ func CanBindPort(port int) (can bool, err error) {
 	return true, nil
 }
 
+// privPortsCache is the cached result of CanBindPrivilegedPorts.
+var privPortsCache = struct {
+	// mu protects can and ok.
+	mu *sync.Mutex
+
+	// can is the result of the last successful check.
+	can bool
+
+	// ok is true if can is set.
+	ok bool
+}{
+	mu: &sync.Mutex{},
+}
+
 // CanBindPrivilegedPorts checks if current process can bind to privileged
-// ports.
+// ports.  The result of the first successful check is cached, since it doesn't
+// change during the lifetime of the process unless the capabilities are
+// changed, see ResetPrivilegedPortsCache.
 func CanBindPrivilegedPorts() (can bool, err error) {
-	return canBindPrivilegedPorts()
+	privPortsCache.mu.Lock()
+	defer privPortsCache.mu.Unlock()
+
+	if privPortsCache.ok {
+		return privPortsCache.can, nil
+	}
+
+	can, err = canBindPrivilegedPorts()
+	if err != nil {
+		return false, err
+	}
+
+	privPortsCache.can, privPortsCache.ok = can, true
+
+	return can, nil
+}
+
+// ResetPrivilegedPortsCache makes the next call to CanBindPrivilegedPorts check
+// the privileges again.  It's only needed if the process changes its own
+// capabilities at runtime.
+func ResetPrivilegedPortsCache() {
+	privPortsCache.mu.Lock()
+	defer privPortsCache.mu.Unlock()
+
+	privPortsCache.can, privPortsCache.ok = false, false
+}
+
+// CanUseRawSockets checks if the current process can open raw sockets, which
+// are required for detecting the DHCP servers, probing the addresses, and
+// listening for the router advertisements.  On Linux, it tries to open an
+// AF_PACKET socket, which requires CAP_NET_RAW.  On other OSes, it tries to
+// open a raw ICMP socket, which is only the best-effort approximation.  can is
+// false and err is nil if the permission is denied.
+func CanUseRawSockets() (can bool, err error) {
+	err = openRawSocket()
+	if err == nil {
+		return true, nil
+	} else if errors.Is(err, os.ErrPermission) {
+		currentLogger().Debug("opening raw socket: %s", err)
+
+		return false, nil
+	}
+
+	return false, fmt.Errorf("opening raw socket: %w", err)
 }
 
 // NetInterface represents an entry of network interfaces map.
@@ -82,8 +523,42 @@ type NetInterface struct {
 	Subnets      []*net.IPNet     `json:"-"`
 	Name         string           `json:"name"`
 	HardwareAddr net.HardwareAddr `json:"hardware_address"`
-	Flags        net.Flags        `json:"flags"`
-	MTU          int              `json:"mtu"`
+	// Statistics are the traffic counters of the network interface.  It's nil
+	// unless requested and supported by the OS.
+	Statistics *IfaceStatistics `json:"statistics,omitempty"`
+	// Duplex is the duplex mode of the link, either "full" or "half".  It's
+	// empty unless requested and supported by the OS or if the link is down.
+	Duplex string `json:"duplex,omitempty"`
+	// Kind is the kind of the network interface.  It's only determined by the
+	// flags and the name unless the statistics are requested, see
+	// NetInterfacesOpts.
+	Kind  IfaceKind `json:"kind"`
+	Flags net.Flags `json:"flags"`
+	// Index is the index of the network interface, see IfaceIndex.
+	Index int `json:"-"`
+	MTU   int `json:"mtu"`
+	// SpeedMbps is the speed of the link in megabits per second.  It's zero
+	// under the same conditions as Duplex.
+	SpeedMbps int `json:"speed_mbps,omitempty"`
+	// Wireless is true if the network interface is a wireless one, see
+	// IsWireless.  It's false unless requested and supported by the OS.
+	Wireless bool `json:"wireless,omitempty"`
+	// Warnings describe the problems with the configuration of the network
+	// interface.  They're only collected if requested, see NetInterfacesOpts.
+	Warnings []string `json:"warnings,omitempty"`
+	// PrimaryAddr is the primary IPv4 address of the network interface, the
+	// rest of the IPv4 Addresses are the secondary ones, i.e. aliases.  It's
+	// the first IPv4 address if the OS doesn't distinguish those, and it's
+	// invalid if there are no IPv4 addresses.
+	PrimaryAddr netip.Addr `json:"primary_address"`
+}
+
+// IfaceStatistics are the traffic counters of a network interface.
+type IfaceStatistics struct {
+	RxBytes   uint64 `json:"rx_bytes"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	TxPackets uint64 `json:"tx_packets"`
 }
 
 // MarshalJSON implements the json.Marshaler interface for NetInterface.
@@ -100,189 +575,1121 @@ func (iface NetInterface) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// ErrNoInterfaces is returned by GetValidNetInterfacesForWeb when the OS
+// reports no network interfaces at all, which may legitimately be the case
+// within minimal containers and network namespaces.  Callers should generally
+// treat it as the need to bind to a manually-entered address rather than as
+// a failure.
+const ErrNoInterfaces errors.Error = "no network interfaces found"
+
 // GetValidNetInterfacesForWeb returns interfaces that are eligible for DNS and WEB only
-// we do not return link-local addresses here
+// we do not return link-local addresses here.  The statistics, the speed, the
+// duplex mode, and the wireless flag of the interfaces aren't collected, use
+// GetValidNetInterfacesForWebWithOpts for those.
+// If there are no network interfaces, it returns an empty slice and an error
+// wrapping ErrNoInterfaces.
 func GetValidNetInterfacesForWeb() ([]*NetInterface, error) {
-	ifaces, err := net.Interfaces()
+	return GetValidNetInterfacesForWebWithOpts(NetInterfacesOpts{})
+}
+
+// NetInterfacesOpts are the options for GetValidNetInterfacesForWebWithOpts.
+type NetInterfacesOpts struct {
+	// WithStats, if true, makes the statistics, the speed, the duplex mode,
+	// and the wireless flag of the interfaces collected, and their kinds
+	// determined using the information provided by the OS.
+	WithStats bool
+
+	// WithWarnings, if true, makes the problems with the configuration of the
+	// interfaces reported in their Warnings.  Currently, those are the APIPA
+	// addresses, see IsAPIPA, which usually mean that DHCP has failed.  Such
+	// addresses are still never returned, but the interfaces having only those
+	// are returned as well, so that the warnings could be shown.
+	WithWarnings bool
+
+	// Sorted, if true, makes the result ordered deterministically instead of
+	// the order reported by the OS.  The interface carrying the default route,
+	// see IsDefaultRouteIface, goes first, and the rest are sorted by their
+	// names, so that the first one is the best default choice.  The addresses
+	// of each interface, along with the corresponding subnets, are sorted with
+	// the IPv4 ones going before the IPv6 ones and numerically within the
+	// family.
+	Sorted bool
+}
+
+// GetValidNetInterfacesForWebWithOpts is like GetValidNetInterfacesForWeb but
+// allows to configure the collected information.
+func GetValidNetInterfacesForWebWithOpts(opts NetInterfacesOpts) ([]*NetInterface, error) {
+	ifaces, err := netInterfaces()
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get interfaces: %w", err)
 	}
 	if len(ifaces) == 0 {
-		return nil, errors.Error("couldn't find any legible interface")
+		return []*NetInterface{}, fmt.Errorf("couldn't find any legible interface: %w", ErrNoInterfaces)
 	}
 
 	var netInterfaces []*NetInterface
+	for i := range ifaces {
+		iface := &ifaces[i]
 
-	for _, iface := range ifaces {
 		var addrs []net.Addr
-		addrs, err = iface.Addrs()
+		addrs, err = netInterfaceAddrs(iface)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get addresses for interface %s: %w", iface.Name, err)
 		}
 
 		netIface := &NetInterface{
+			Index:        iface.Index,
 			MTU:          iface.MTU,
 			Name:         iface.Name,
 			HardwareAddr: iface.HardwareAddr,
 			Flags:        iface.Flags,
 		}
 
-		// Collect network interface addresses.
-		for _, addr := range addrs {
-			ipNet, ok := addr.(*net.IPNet)
-			if !ok {
-				// Should be net.IPNet, this is weird.
-				return nil, fmt.Errorf("got iface.Addrs() element %s that is not net.IPNet, it is %T", addr, addr)
-			}
-			// Ignore link-local.
-			if ipNet.IP.IsLinkLocalUnicast() {
-				continue
-			}
-			netIface.Addresses = append(netIface.Addresses, ipNet.IP)
-			netIface.Subnets = append(netIface.Subnets, ipNet)
+		netIface.Kind = ifaceKind(*netIface, opts.WithStats)
+
+		if opts.WithStats {
+			netIface.collectDetails()
+		}
+
+		err = netIface.collectAddrs(addrs, opts.WithWarnings)
+		if err != nil {
+			return nil, err
 		}
 
 		// Discard interfaces with no addresses.
-		if len(netIface.Addresses) != 0 {
+		if len(netIface.Addresses) != 0 || len(netIface.Warnings) != 0 {
 			netInterfaces = append(netInterfaces, netIface)
 		}
 	}
 
+	setPrimaryAddrs(netInterfaces)
+
+	if opts.Sorted {
+		sortNetInterfaces(netInterfaces, sortingDefaultIface())
+	}
+
 	return netInterfaces, nil
 }
 
-// GetInterfaceByIP returns the name of interface containing provided ip.
-func GetInterfaceByIP(ip net.IP) string {
-	ifaces, err := GetValidNetInterfacesForWeb()
-	if err != nil {
-		return ""
+// setPrimaryAddrs sets the primary IPv4 addresses of ifaces.  The secondary
+// addresses are only retrieved if any of ifaces has more than one IPv4 address,
+// since the only one is always the primary.
+func setPrimaryAddrs(ifaces []*NetInterface) {
+	var secondary map[netip.Addr]int
+	for _, iface := range ifaces {
+		if ipv4Count(iface.Addresses) > 1 {
+			secondary = secondaryAddrsForWeb()
+
+			break
+		}
 	}
 
 	for _, iface := range ifaces {
-		for _, addr := range iface.Addresses {
-			if ip.Equal(addr) {
-				return iface.Name
-			}
+		iface.PrimaryAddr = primaryIfaceAddr(iface.Addresses, iface.Index, secondary)
+	}
+}
+
+// ipv4Count returns the number of IPv4 addresses among ips.
+func ipv4Count(ips []net.IP) (n int) {
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			n++
 		}
 	}
 
-	return ""
+	return n
 }
 
-// GetSubnet returns pointer to net.IPNet for the specified interface or nil if
-// the search fails.
-func GetSubnet(ifaceName string) *net.IPNet {
-	netIfaces, err := GetValidNetInterfacesForWeb()
+// secondaryAddrsForWeb returns the secondary IPv4 addresses of all the network
+// interfaces mapped to the indexes of the interfaces.  It returns nil if those
+// can't be retrieved, since every address is then considered a primary one.
+func secondaryAddrsForWeb() (secondary map[netip.Addr]int) {
+	secondary, err := ifaceSecondaryAddrs()
 	if err != nil {
-		log.Error("Could not get network interfaces info: %v", err)
-		return nil
+		currentLogger().Debug("getting secondary addresses: %s", err)
 	}
 
-	for _, netIface := range netIfaces {
-		if netIface.Name == ifaceName && len(netIface.Subnets) > 0 {
-			return netIface.Subnets[0]
+	return secondary
+}
+
+// primaryIfaceAddr returns the first IPv4 address among ips which isn't
+// a secondary one of the network interface with index, or the first IPv4
+// address if all of them are.  It returns an invalid address if ips contain no
+// IPv4 addresses.
+func primaryIfaceAddr(ips []net.IP, index int, secondary map[netip.Addr]int) (primary netip.Addr) {
+	var first netip.Addr
+	for _, ip := range ips {
+		addr, ok := netip.AddrFromSlice(ip)
+		addr = addr.Unmap()
+		if !ok || !addr.Is4() {
+			continue
+		}
+
+		if secIndex, isSec := secondary[addr]; !isSec || secIndex != index {
+			return addr
+		} else if !first.IsValid() {
+			first = addr
 		}
 	}
 
-	return nil
+	return first
 }
 
-// CheckPort checks if the port is available for binding.  network is expected
-// to be one of "udp" and "tcp".
-func CheckPort(network string, ip net.IP, port int) (err error) {
-	var c io.Closer
-	addr := netutil.IPPort{IP: ip, Port: port}.String()
-	switch network {
-	case "tcp":
-		c, err = net.Listen(network, addr)
-	case "udp":
-		c, err = net.ListenPacket(network, addr)
-	default:
-		return nil
-	}
-
+// sortingDefaultIface returns the name of the interface carrying the default
+// route or an empty string if it can't be determined, since the order is
+// still deterministic without it.
+func sortingDefaultIface() (name string) {
+	name, err := defaultRouteIface()
 	if err != nil {
-		return err
+		currentLogger().Debug("sorting interfaces: getting default route interface: %s", err)
 	}
 
-	return closePortChecker(c)
+	return name
 }
 
-// IsAddrInUse checks if err is about unsuccessful address binding.
-func IsAddrInUse(err error) (ok bool) {
-	var sysErr syscall.Errno
-	if !errors.As(err, &sysErr) {
-		return false
+// sortNetInterfaces sorts ifaces and their addresses as described by
+// NetInterfacesOpts.Sorted.  defaultName is the name of the interface carrying
+// the default route, if any.
+func sortNetInterfaces(ifaces []*NetInterface, defaultName string) {
+	sort.SliceStable(ifaces, func(i, j int) (less bool) {
+		a, b := ifaces[i].Name, ifaces[j].Name
+		if (a == defaultName) != (b == defaultName) {
+			return a == defaultName
+		}
+
+		return a < b
+	})
+
+	for _, iface := range ifaces {
+		sort.Stable(ifaceAddrsSorter{iface: iface})
 	}
+}
 
-	return isAddrInUse(sysErr)
+// ifaceAddrsSorter sorts the addresses of iface along with the corresponding
+// subnets, which must have the same length.  The IPv4 addresses go first, and
+// the addresses of the same family are sorted numerically.
+type ifaceAddrsSorter struct {
+	iface *NetInterface
 }
 
-// SplitHost is a wrapper for net.SplitHostPort for the cases when the hostport
-// does not necessarily contain a port.
-func SplitHost(hostport string) (host string, err error) {
-	host, _, err = net.SplitHostPort(hostport)
-	if err != nil {
-		// Check for the missing port error.  If it is that error, just
-		// use the host as is.
-		//
-		// See the source code for net.SplitHostPort.
-		const missingPort = "missing port in address"
+// type check
+var _ sort.Interface = ifaceAddrsSorter{}
 
-		addrErr := &net.AddrError{}
-		if !errors.As(err, &addrErr) || addrErr.Err != missingPort {
-			return "", err
-		}
+// Len implements the sort.Interface interface for ifaceAddrsSorter.
+func (s ifaceAddrsSorter) Len() (n int) {
+	return len(s.iface.Addresses)
+}
 
-		host = hostport
-	}
+// Less implements the sort.Interface interface for ifaceAddrsSorter.
+func (s ifaceAddrsSorter) Less(i, j int) (less bool) {
+	a, _ := netip.AddrFromSlice(s.iface.Addresses[i])
+	b, _ := netip.AddrFromSlice(s.iface.Addresses[j])
 
-	return host, nil
+	return a.Unmap().Less(b.Unmap())
 }
 
-// CollectAllIfacesAddrs returns the slice of all network interfaces IP
-// addresses without port number.
-func CollectAllIfacesAddrs() (addrs []string, err error) {
-	var ifaces []net.Interface
-	ifaces, err = net.Interfaces()
+// Swap implements the sort.Interface interface for ifaceAddrsSorter.
+func (s ifaceAddrsSorter) Swap(i, j int) {
+	addrs := s.iface.Addresses
+	addrs[i], addrs[j] = addrs[j], addrs[i]
+
+	if subnets := s.iface.Subnets; len(subnets) == len(addrs) {
+		subnets[i], subnets[j] = subnets[j], subnets[i]
+	}
+}
+
+// collectDetails fills the statistics, the link mode, and the wireless flag of
+// iface, which require additional syscalls.  The kind of a wireless iface is
+// set to IfaceKindWiFi.
+func (iface *NetInterface) collectDetails() {
+	iface.Statistics = ifaceStatistics(iface.Name)
+	iface.SpeedMbps, iface.Duplex = ifaceLinkMode(iface.Name)
+
+	var err error
+	iface.Wireless, err = IsWireless(iface.Name)
 	if err != nil {
-		return nil, fmt.Errorf("getting network interfaces: %w", err)
+		currentLogger().Debug("getting details of interface: %s", err)
 	}
 
-	for _, iface := range ifaces {
-		var ifaceAddrs []net.Addr
-		ifaceAddrs, err = iface.Addrs()
-		if err != nil {
-			return nil, fmt.Errorf("getting addresses for %q: %w", iface.Name, err)
-		}
+	// Keep the kind consistent with the wireless flag, since the latter may
+	// come from a different source, e.g. networksetup on macOS.
+	if iface.Wireless {
+		iface.Kind = IfaceKindWiFi
+	}
+}
 
-		for _, addr := range ifaceAddrs {
-			cidr := addr.String()
-			var ip net.IP
-			ip, _, err = net.ParseCIDR(cidr)
-			if err != nil {
-				return nil, fmt.Errorf("parsing cidr: %w", err)
+// collectAddrs fills the addresses and the subnets of iface from addrs
+// skipping the link-local ones.  If withWarnings is true, the APIPA addresses
+// are reported in the warnings.
+func (iface *NetInterface) collectAddrs(addrs []net.Addr, withWarnings bool) (err error) {
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			// Should be net.IPNet, this is weird.
+			return fmt.Errorf("got iface.Addrs() element %s that is not net.IPNet, it is %T", addr, addr)
+		}
+		// Ignore link-local.
+		if ipNet.IP.IsLinkLocalUnicast() {
+			if ip, _ := netip.AddrFromSlice(ipNet.IP); withWarnings && IsAPIPA(ip) {
+				iface.Warnings = append(
+					iface.Warnings,
+					fmt.Sprintf("self-assigned address %s, dhcp may have failed", ip.Unmap()),
+				)
 			}
 
-			addrs = append(addrs, ip.String())
+			continue
 		}
+		iface.Addresses = append(iface.Addresses, ipNet.IP)
+		iface.Subnets = append(iface.Subnets, ipNet)
 	}
 
-	return addrs, nil
+	return nil
 }
 
-// BroadcastFromIPNet calculates the broadcast IP address for n.
-func BroadcastFromIPNet(n *net.IPNet) (dc net.IP) {
-	dc = netutil.CloneIP(n.IP)
+// ErrIfaceNotFound is returned by IfaceIndex and IfaceName when there is no
+// such network interface.
+const ErrIfaceNotFound errors.Error = "network interface not found"
 
-	mask := n.Mask
-	if mask == nil {
-		mask = dc.DefaultMask()
+// IfaceIndex returns the index of the network interface named name.  err is
+// ErrIfaceNotFound if there is no such interface.
+func IfaceIndex(name string) (index int, err error) {
+	iface, err := ifaceByName(name)
+	if err != nil {
+		return 0, fmt.Errorf("getting index of %q: %w", name, err)
 	}
 
-	for i, b := range mask {
+	return iface.Index, nil
+}
+
+// IfaceName returns the name of the network interface with index.  err is
+// ErrIfaceNotFound if there is no such interface.
+func IfaceName(index int) (name string, err error) {
+	iface, err := findIface(func(iface *net.Interface) (ok bool) { return iface.Index == index })
+	if err != nil {
+		return "", fmt.Errorf("getting name of interface %d: %w", index, err)
+	}
+
+	return iface.Name, nil
+}
+
+// ResolveIfaceName returns the name of the network interface referenced by
+// name, which is either the name of the interface, its MAC address in any of
+// the forms accepted by ParseMAC, or an OS-specific alias.  The only supported
+// alias is the adapter GUID on Windows, where the names are the friendly ones.
+// Referencing the interface by its MAC address allows the configuration to
+// survive renames and moving between the OSes.  err is ErrIfaceNotFound if
+// there is no such interface.
+func ResolveIfaceName(name string) (ifaceName string, err error) {
+	defer func() { err = errors.Annotate(err, "resolving interface %q: %w", name) }()
+
+	iface, err := ifaceByName(name)
+	if err == nil {
+		return iface.Name, nil
+	} else if !errors.Is(err, ErrIfaceNotFound) {
+		// Don't wrap the error, because it's informative enough as is.
+		return "", err
+	}
+
+	if hw, macErr := ParseMAC(name); macErr == nil {
+		iface, err = findIface(func(iface *net.Interface) (ok bool) {
+			return bytes.Equal(iface.HardwareAddr, hw)
+		})
+		if err != nil {
+			// Don't wrap the error, because it's informative enough as is.
+			return "", err
+		}
+
+		return iface.Name, nil
+	}
+
+	ifaceName, ok := ifaceNameByAlias(name)
+	if !ok {
+		return "", ErrIfaceNotFound
+	}
+
+	return ifaceName, nil
+}
+
+// ifaceByName is like net.InterfaceByName, but it uses netInterfaces, so that
+// the interfaces could be substituted in tests.  err is ErrIfaceNotFound if
+// there is no such interface.
+func ifaceByName(name string) (iface *net.Interface, err error) {
+	return findIface(func(iface *net.Interface) (ok bool) { return iface.Name == name })
+}
+
+// findIface returns the first network interface matching f.
+func findIface(f func(iface *net.Interface) (ok bool)) (iface *net.Interface, err error) {
+	ifaces, err := netInterfaces()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	for i := range ifaces {
+		if f(&ifaces[i]) {
+			return &ifaces[i], nil
+		}
+	}
+
+	return nil, ErrIfaceNotFound
+}
+
+// GetInterfaceByIP returns the name of interface containing provided ip.  The
+// IPv4-mapped IPv6 addresses match the corresponding IPv4 ones.
+func GetInterfaceByIP(ip net.IP) string {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return ""
+	}
+
+	return GetInterfaceByAddr(addr)
+}
+
+// GetInterfaceByAddr returns the name of interface containing provided addr.
+// Both addr and the addresses of the interfaces are compared in their
+// canonical forms, see CanonicalAddr.
+func GetInterfaceByAddr(addr netip.Addr) string {
+	ifaces, err := GetValidNetInterfacesForWeb()
+	if err != nil {
+		return ""
+	}
+
+	addr = CanonicalAddr(addr)
+	for _, iface := range ifaces {
+		for _, ifaceIP := range iface.Addresses {
+			ifaceAddr, ok := netip.AddrFromSlice(ifaceIP)
+			if ok && CanonicalAddr(ifaceAddr) == addr {
+				return iface.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+// SubnetIsLocal returns the name of the first network interface having an
+// address within n, which means the host participates in the subnet, e.g. its
+// DHCP replies reach the clients there.  Unlike SameSubnet, it takes the whole
+// subnet instead of a single address.  The IPv4-mapped IPv6 prefixes match the
+// IPv4 ones.  The link-local addresses aren't considered, see
+// GetValidNetInterfacesForWeb, as well as the interfaces which are down or the
+// loopback ones.  ok is false if there is no such interface.
+func SubnetIsLocal(n netip.Prefix) (ifaceName string, ok bool) {
+	if !n.IsValid() {
+		return "", false
+	}
+
+	ifaces, err := GetValidNetInterfacesForWeb()
+	if err != nil {
+		currentLogger().Debug("checking if %s is local: %s", n, err)
+
+		return "", false
+	}
+
+	n = canonicalPrefix(n)
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		for _, ifaceIP := range iface.Addresses {
+			ifaceAddr, valid := netip.AddrFromSlice(ifaceIP)
+			if valid && n.Contains(CanonicalAddr(ifaceAddr)) {
+				return iface.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// ErrWildcardBind is returned by IfaceForBindAddr when the bind address is
+// unspecified, so that no single interface owns it.  Callers should generally
+// fall back to the interface of PrimaryIPv4 then.
+const ErrWildcardBind errors.Error = "bind address is unspecified"
+
+// IfaceForBindAddr returns the name of the network interface owning the
+// configured bind address.  The IPv4-mapped IPv6 addresses match the IPv4
+// ones, see GetInterfaceByAddr, and the zone of a scoped address is the name
+// itself.  err is ErrWildcardBind if bind is unspecified and ErrIfaceNotFound if
+// no interface has the address.
+func IfaceForBindAddr(bind netip.Addr) (ifaceName string, err error) {
+	switch {
+	case !bind.IsValid():
+		return "", fmt.Errorf("bad bind address %s", bind)
+	case IsWildcardAddr(bind):
+		return "", ErrWildcardBind
+	case bind.Zone() != "":
+		return bind.Zone(), nil
+	}
+
+	ifaceName = GetInterfaceByAddr(bind)
+	if ifaceName == "" {
+		return "", fmt.Errorf("looking up interface of %s: %w", bind, ErrIfaceNotFound)
+	}
+
+	return ifaceName, nil
+}
+
+// GetSubnets returns all the subnets of the specified interface or nil if the
+// search fails.
+func GetSubnets(ifaceName string) (subnets []*net.IPNet) {
+	netIfaces, err := GetValidNetInterfacesForWeb()
+	if err != nil {
+		log.Error("Could not get network interfaces info: %v", err)
+
+		return nil
+	}
+
+	for _, netIface := range netIfaces {
+		if netIface.Name == ifaceName {
+			return netIface.Subnets
+		}
+	}
+
+	return nil
+}
+
+// GetSubnetForFamily returns the first subnet of the specified interface which
+// belongs to the IPv6 family if v6 is true and to the IPv4 one otherwise.  It
+// returns nil if the search fails.
+func GetSubnetForFamily(ifaceName string, v6 bool) (subnet *net.IPNet) {
+	for _, subnet = range GetSubnets(ifaceName) {
+		if isIPv4 := subnet.IP.To4() != nil; isIPv4 != v6 {
+			return subnet
+		}
+	}
+
+	return nil
+}
+
+// GetSubnet returns the first IPv4 subnet of the specified interface or nil if
+// the search fails.
+func GetSubnet(ifaceName string) *net.IPNet {
+	return GetSubnetForFamily(ifaceName, false)
+}
+
+// SameSubnet returns the name of the first network interface having a subnet
+// which contains ip, so that the host with ip is directly reachable from it.
+// Both the IPv4 and the IPv6 subnets are checked, and the IPv4-mapped IPv6
+// addresses match the IPv4 ones.  The loopback interfaces as well as the
+// unspecified and the loopback addresses are ignored.
+func SameSubnet(ip netip.Addr) (ifaceName string, ok bool) {
+	ip = CanonicalAddr(ip)
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() {
+		return "", false
+	}
+
+	ifaces, err := GetValidNetInterfacesForWeb()
+	if err != nil {
+		currentLogger().Debug("checking subnets for %s: %s", ip, err)
+
+		return "", false
+	}
+
+	ipSlice := ip.AsSlice()
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		for _, subnet := range iface.Subnets {
+			if SubnetContains(subnet, ipSlice) {
+				return iface.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// OutboundIP returns the local address which the OS would use as the source
+// address for packets sent to dst.  No packets are actually sent.
+func OutboundIP(dst netip.Addr) (src netip.Addr, err error) {
+	if !dst.IsValid() {
+		return netip.Addr{}, fmt.Errorf("bad destination address %s", dst)
+	}
+
+	dst = dst.Unmap()
+	network := "udp4"
+	if dst.Is6() {
+		network = "udp6"
+	}
+
+	// Connecting a UDP socket only makes the OS choose the route and the
+	// source address, so the port doesn't really matter.
+	raddr := net.UDPAddrFromAddrPort(netip.AddrPortFrom(dst, defaultDNSPort))
+
+	conn, err := net.DialUDP(network, nil, raddr)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("connecting to %s: %w", dst, err)
+	}
+	defer func() { err = errors.WithDeferred(err, conn.Close()) }()
+
+	laddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("unexpected local address type %T", conn.LocalAddr())
+	}
+
+	return laddr.AddrPort().Addr().Unmap(), nil
+}
+
+// ReplySrcAddr returns the local address which should be used as the source
+// address of the packets constructed manually and sent to dst.  The address of
+// an up network interface directly connected to the subnet containing dst is
+// preferred, the most specific subnet winning.  Otherwise, the address chosen
+// by the OS routing is returned, see OutboundIP.
+func ReplySrcAddr(dst netip.Addr) (src netip.Addr, err error) {
+	dst = CanonicalAddr(dst)
+	if !dst.IsValid() {
+		return netip.Addr{}, fmt.Errorf("bad destination address %s", dst)
+	}
+
+	ifaces, err := GetValidNetInterfacesForWeb()
+	if err != nil && !errors.Is(err, ErrNoInterfaces) {
+		// Don't wrap the error, because it's informative enough as is.
+		return netip.Addr{}, err
+	}
+
+	var ok bool
+	src, ok = directSrcAddr(ifaces, dst)
+	if ok {
+		return src, nil
+	}
+
+	return OutboundIP(dst)
+}
+
+// directSrcAddr returns the address of the up non-loopback interface from
+// ifaces within the most specific subnet containing dst.
+func directSrcAddr(ifaces []*NetInterface, dst netip.Addr) (src netip.Addr, ok bool) {
+	bestBits := -1
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		for _, subnet := range iface.Subnets {
+			bits, matched := subnetMatch(subnet, dst)
+			if !matched || bits <= bestBits {
+				continue
+			}
+
+			src, _ = netip.AddrFromSlice(subnet.IP)
+			bestBits = bits
+		}
+	}
+
+	return src.Unmap(), bestBits >= 0
+}
+
+// subnetMatch returns the prefix length of subnet if it contains addr.
+// IPv4-mapped IPv6 subnets are treated as the IPv4 ones.
+func subnetMatch(subnet *net.IPNet, addr netip.Addr) (bits int, ok bool) {
+	if subnet == nil {
+		return 0, false
+	}
+
+	ones, _ := subnet.Mask.Size()
+	ip, ok := netip.AddrFromSlice(subnet.IP)
+	if !ok {
+		return 0, false
+	}
+
+	ip = ip.Unmap()
+	if ip.Is4() && ones > netutil.IPv4BitLen {
+		ones -= netutil.IPv6BitLen - netutil.IPv4BitLen
+	}
+
+	prefix, err := ip.Prefix(ones)
+	if err != nil || !prefix.Contains(addr) {
+		return 0, false
+	}
+
+	return ones, true
+}
+
+// IsAddrInUse checks if err is about unsuccessful address binding.
+func IsAddrInUse(err error) (ok bool) {
+	var sysErr syscall.Errno
+	if !errors.As(err, &sysErr) {
+		return false
+	}
+
+	return isAddrInUse(sysErr)
+}
+
+// IsAddrPermDenied checks if err is about the lack of permissions to bind the
+// address, for example a privileged port without the CAP_NET_BIND_SERVICE
+// capability on Linux.  On Unix, both EACCES and EPERM are considered, since
+// the latter is returned when the bind is denied by a security policy.  On
+// Windows, the corresponding error is WSAEACCES.
+func IsAddrPermDenied(err error) (ok bool) {
+	var sysErr syscall.Errno
+	if !errors.As(err, &sysErr) {
+		return false
+	}
+
+	return isAddrPermDenied(sysErr)
+}
+
+// SplitHost is a wrapper for net.SplitHostPort for the cases when the hostport
+// does not necessarily contain a port.  IPv6 addresses without a port may be
+// not enclosed in square brackets, and may have a zone.
+func SplitHost(hostport string) (host string, err error) {
+	host, _, err = net.SplitHostPort(hostport)
+	if err != nil {
+		if !isPortlessAddr(hostport, err) {
+			return "", err
+		}
+
+		host = hostport
+	}
+
+	return host, nil
+}
+
+// Messages of the errors returned by net.SplitHostPort.  See its source code.
+const (
+	errMsgMissingPort   = "missing port in address"
+	errMsgTooManyColons = "too many colons in address"
+)
+
+// isPortlessAddr returns true if err, returned by net.SplitHostPort for
+// hostport, means that hostport is a host without a port, including an IPv6
+// address without square brackets.
+func isPortlessAddr(hostport string, err error) (ok bool) {
+	addrErr := &net.AddrError{}
+	if !errors.As(err, &addrErr) {
+		return false
+	}
+
+	switch addrErr.Err {
+	case errMsgMissingPort:
+		return true
+	case errMsgTooManyColons:
+		// Use netip.ParseAddr, since net.ParseIP doesn't accept the zones.
+		_, parseErr := netip.ParseAddr(hostport)
+
+		return parseErr == nil
+	default:
+		return false
+	}
+}
+
+// SplitHostPortDefault splits hostport into the host and the port.  hostport
+// may or may not contain a port, in the latter case port is defPort.  IPv6
+// addresses with a port must be enclosed in square brackets, but the brackets
+// are optional for the ones without it.  The host must not be empty.
+func SplitHostPortDefault(hostport string, defPort int) (host string, port int, err error) {
+	var portStr string
+	host, portStr, err = net.SplitHostPort(hostport)
+	hasPort := err == nil
+	if !hasPort {
+		if !isPortlessAddr(hostport, err) {
+			return "", 0, err
+		}
+
+		host = unbracket(hostport)
+	}
+
+	if host == "" {
+		return "", 0, fmt.Errorf("empty host in address %q", hostport)
+	} else if !hasPort {
+		return host, defPort, nil
+	}
+
+	port, err = strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("bad port %q in address %q", portStr, hostport)
+	}
+
+	err = ValidatePort(port)
+	if err != nil {
+		return "", 0, fmt.Errorf("bad port in address %q: %w", hostport, err)
+	}
+
+	return host, port, nil
+}
+
+// unbracket returns host without the enclosing square brackets, if any.
+func unbracket(host string) (res string) {
+	if l := len(host); l > 1 && host[0] == '[' && host[l-1] == ']' {
+		return host[1 : l-1]
+	}
+
+	return host
+}
+
+// IfaceFilter describes the flags of network interfaces to match.  The zero
+// IfaceFilter matches any interface.
+type IfaceFilter struct {
+	// Required are the flags the interface must have all set.
+	Required net.Flags
+
+	// Excluded are the flags the interface must have none set.
+	Excluded net.Flags
+}
+
+// Match returns true if iface matches f.
+func (f IfaceFilter) Match(iface *net.Interface) (ok bool) {
+	return iface.Flags&f.Required == f.Required && iface.Flags&f.Excluded == 0
+}
+
+// CollectIfaceAddrs returns the IP addresses of all the network interfaces
+// grouped by the interface name.
+func CollectIfaceAddrs() (addrs map[string][]netip.Addr, err error) {
+	return CollectIfaceAddrsFiltered(IfaceFilter{})
+}
+
+// CollectIfaceAddrsFiltered returns the IP addresses of the network interfaces
+// matching f grouped by the interface name.  The IPv6 link-local and the
+// scoped multicast addresses have the interface name as their zone, see
+// withIfaceZone, so that those are unambiguous and suitable for binding.
+func CollectIfaceAddrsFiltered(f IfaceFilter) (addrs map[string][]netip.Addr, err error) {
+	var ifaces []net.Interface
+	ifaces, err = netInterfaces()
+	if err != nil {
+		return nil, fmt.Errorf("getting network interfaces: %w", err)
+	}
+
+	addrs = make(map[string][]netip.Addr, len(ifaces))
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if !f.Match(iface) {
+			continue
+		}
+
+		var ifaceAddrs []net.Addr
+		ifaceAddrs, err = netInterfaceAddrs(iface)
+		if err != nil {
+			return nil, fmt.Errorf("getting addresses for %q: %w", iface.Name, err)
+		}
+
+		for _, addr := range ifaceAddrs {
+			var ip net.IP
+			switch addr := addr.(type) {
+			case *net.IPNet:
+				ip = addr.IP
+			case *net.IPAddr:
+				ip = addr.IP
+			default:
+				return nil, fmt.Errorf("unexpected address type %T of %q", addr, addr)
+			}
+
+			ipAddr, ok := netip.AddrFromSlice(ip)
+			if !ok {
+				return nil, fmt.Errorf("bad ip address %q", ip)
+			}
+
+			ipAddr = withIfaceZone(ipAddr.Unmap(), iface.Name)
+			addrs[iface.Name] = append(addrs[iface.Name], ipAddr)
+		}
+	}
+
+	return addrs, nil
+}
+
+// withIfaceZone returns addr with the zone set to ifaceName if addr is an IPv6
+// address which is only meaningful within the scope of the network interface,
+// i.e. a link-local unicast or an interface- or link-local multicast one.
+// Other addresses are returned as is.
+func withIfaceZone(addr netip.Addr, ifaceName string) (zoned netip.Addr) {
+	if !addr.Is6() || addr.Is4In6() {
+		return addr
+	}
+
+	if addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() {
+		return addr.WithZone(ifaceName)
+	}
+
+	return addr
+}
+
+// CollectAllIfacesAddrs returns the slice of all network interfaces IP
+// addresses.
+func CollectAllIfacesAddrs() (addrs []string, err error) {
+	return CollectAllIfacesAddrsFiltered(IfaceFilter{})
+}
+
+// CollectAllIfacesAddrsFiltered returns the slice of IP addresses of the
+// network interfaces matching f.
+func CollectAllIfacesAddrsFiltered(f IfaceFilter) (addrs []string, err error) {
+	var ifaceAddrs map[string][]netip.Addr
+	ifaceAddrs, err = CollectIfaceAddrsFiltered(f)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(ifaceAddrs))
+	for name := range ifaceAddrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, addr := range ifaceAddrs[name] {
+			addrs = append(addrs, addr.String())
+		}
+	}
+
+	return addrs, nil
+}
+
+// IsLocalAddr returns true if ip is assigned to any of the network interfaces
+// or if it's an unspecified address, which a listener can always be bound to.
+// IPv4-mapped IPv6 addresses are considered equal to the corresponding IPv4
+// ones.  Use it to validate the bind addresses before actually binding.  The
+// addresses are cached, but the cache is refreshed if ip isn't found, since it
+// could have been assigned recently.
+func IsLocalAddr(ip net.IP) (ok bool, err error) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false, fmt.Errorf("bad ip address %q", ip)
+	}
+
+	addr = addr.Unmap()
+	if IsWildcardAddr(addr) {
+		return true, nil
+	}
+
+	if _, ok = localAddrsCache.LocalAddrSet()[addr]; ok {
+		return true, nil
+	}
+
+	err = localAddrsCache.Refresh()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return false, err
+	}
+
+	_, ok = localAddrsCache.LocalAddrSet()[addr]
+
+	return ok, nil
+}
+
+// IsSelfAddr returns true if the DNS server at addr is one of ownListeners, so
+// that using it as an upstream would cause a query loop.  The wildcard
+// listeners are matched against all the addresses of the network interfaces,
+// including the loopback ones, so that 0.0.0.0:53 covers every local IPv4
+// address on port 53, and [::]:53 also covers the IPv4 ones, like
+// ExpandWildcardBind expands them.  The addresses are taken from the same cache
+// as the ones of IsLocalAddr, see LocalAddrSet.  The unspecified addr is
+// considered a loopback one.  The zones aren't compared.
+func IsSelfAddr(addr netip.AddrPort, ownListeners []netip.AddrPort) (ok bool) {
+	if !addr.IsValid() {
+		return false
+	}
+
+	ip := CanonicalAddr(addr.Addr().WithZone(""))
+
+	for _, l := range ownListeners {
+		if !l.IsValid() || l.Port() != addr.Port() {
+			continue
+		}
+
+		lIP := CanonicalAddr(l.Addr().WithZone(""))
+		if !IsWildcardAddr(lIP) {
+			if lIP == ip {
+				return true
+			}
+
+			continue
+		}
+
+		if wildcardAccepts(lIP, ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wildcardAccepts returns true if a listener bound to the canonical unspecified
+// address wildcard accepts the connections to the canonical address ip, see
+// ExpandWildcardBind.
+func wildcardAccepts(wildcard, ip netip.Addr) (ok bool) {
+	if wildcard.Is4() && !ip.Is4() {
+		return false
+	} else if ip.IsUnspecified() || ip.IsLoopback() {
+		return true
+	}
+
+	_, ok = localAddrsCache.LocalAddrSet()[ip]
+
+	return ok
+}
+
+// LocalAddrSet returns the set of all the unicast addresses of the network
+// interfaces, including the loopback and the link-local ones.  The addresses
+// are canonical, see CanonicalAddr, and have no zones, so the membership of an
+// address should be checked after the same normalization.  Use
+// InterfaceCache.LocalAddrSet to avoid enumerating the interfaces on each call.
+func LocalAddrSet() (set map[netip.Addr]struct{}, err error) {
+	ifaces, err := listIfaceAddrs()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	return ifaceAddrSet(ifaces), nil
+}
+
+// addToLocalAddrSet adds the canonical form of addr without zone to set unless
+// it isn't a unicast one.
+func addToLocalAddrSet(set map[netip.Addr]struct{}, addr netip.Addr) {
+	addr = CanonicalAddr(addr.WithZone(""))
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsMulticast() {
+		return
+	}
+
+	set[addr] = struct{}{}
+}
+
+// ExpandWildcardBind returns the local addresses a listener bound to bind
+// accepts the connections on.  If bind is a specified address, it's returned
+// as is.  For 0.0.0.0, those are all the non-loopback unicast IPv4 addresses of
+// the network interfaces.  For ::, those are all the non-loopback unicast IPv6
+// and IPv4 addresses, since dual-stack sockets also accept the IPv4 connections
+// as IPv4-mapped ones.  The addresses are sorted, and the IPv4 ones come first.
+// The link-local addresses have the interface names as their zones.
+func ExpandWildcardBind(bind netip.Addr) (addrs []netip.Addr, err error) {
+	if !bind.IsValid() {
+		return nil, fmt.Errorf("bad bind address %s", bind)
+	} else if !IsWildcardAddr(bind) {
+		return []netip.Addr{bind}, nil
+	}
+
+	ifaceAddrs, err := CollectIfaceAddrs()
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	withIPv6 := bind.Is6() && !bind.Is4In6()
+	set := map[netip.Addr]struct{}{}
+	for _, ifaceAddr := range ifaceAddrs {
+		for _, a := range ifaceAddr {
+			if isWildcardCovered(a, withIPv6) {
+				// Keep the zones of the link-local addresses, since those
+				// are required to bind to them.
+				set[a] = struct{}{}
+			}
+		}
+	}
+
+	addrs = make([]netip.Addr, 0, len(set))
+	for a := range set {
+		addrs = append(addrs, a)
+	}
+
+	sort.Slice(addrs, func(i, j int) (less bool) { return addrs[i].Less(addrs[j]) })
+
+	return addrs, nil
+}
+
+// isWildcardCovered returns true if a listener bound to an unspecified address
+// accepts the connections on a.  withIPv6 is true if the unspecified address is
+// an IPv6 one.
+func isWildcardCovered(a netip.Addr, withIPv6 bool) (ok bool) {
+	if a.IsLoopback() || a.IsMulticast() || a.IsUnspecified() {
+		return false
+	}
+
+	return a.Is4() || withIPv6
+}
+
+// BroadcastFromIPNet calculates the broadcast IP address for n.  The IPv4
+// addresses are normalized to 4 bytes, so that the 16-byte representation
+// works with both the 4-byte and the 16-byte masks.
+func BroadcastFromIPNet(n *net.IPNet) (dc net.IP) {
+	ip, mask := n.IP, n.Mask
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if len(mask) == net.IPv6len {
+			mask = mask[net.IPv6len-net.IPv4len:]
+		}
+	}
+
+	dc = netutil.CloneIP(ip)
+	if mask == nil {
+		mask = dc.DefaultMask()
+	}
+
+	if len(mask) != len(dc) {
+		return dc
+	}
+
+	for i, b := range mask {
 		dc[i] |= ^b
 	}
 
 	return dc
 }
+
+// InterfaceBroadcasts returns the broadcast addresses of all the IPv4 subnets
+// of the network interface.  The IPv6 subnets are skipped.  If the interface
+// has no IPv4 subnets, an empty slice is returned.
+func InterfaceBroadcasts(ifaceName string) (bcs []net.IP, err error) {
+	defer func() { err = errors.Annotate(err, "getting broadcasts of %q: %w", ifaceName) }()
+
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	addrs, err := netInterfaceAddrs(iface)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	bcs = []net.IP{}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil {
+			continue
+		}
+
+		bcs = append(bcs, BroadcastFromIPNet(ipNet))
+	}
+
+	return bcs, nil
+}
+
+// CanonicalIP returns the canonical form of ip suitable for comparisons and
+// storing as a key: the 4-byte one for IPv4 addresses, including the
+// IPv4-mapped IPv6 ones, and the 16-byte one for IPv6 addresses.  It returns
+// nil if ip is not a valid IP address.
+func CanonicalIP(ip net.IP) (canon net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+
+	return ip.To16()
+}
+
+// CanonicalAddr returns the canonical form of addr suitable for comparisons and
+// storing as a key, which means the IPv4-mapped IPv6 addresses are unmapped.
+func CanonicalAddr(addr netip.Addr) (canon netip.Addr) {
+	return addr.Unmap()
+}
+
+// canonicalPrefix returns p with the IPv4-mapped IPv6 address converted into
+// the IPv4 one.
+func canonicalPrefix(p netip.Prefix) (canon netip.Prefix) {
+	addr := p.Addr()
+	if !p.IsValid() || !addr.Is4In6() {
+		return p
+	}
+
+	bits := p.Bits() - (netutil.IPv6BitLen - netutil.IPv4BitLen)
+	if bits < 0 {
+		return netip.Prefix{}
+	}
+
+	return netip.PrefixFrom(addr.Unmap(), bits)
+}
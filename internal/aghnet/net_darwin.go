@@ -5,6 +5,8 @@ package aghnet
 
 import (
 	"fmt"
+	"net"
+	"net/netip"
 	"os"
 	"regexp"
 	"strings"
@@ -14,7 +16,8 @@ import (
 )
 
 // hardwarePortInfo contains information about the current state of the internet
-// connection obtained from macOS networksetup.
+// connection obtained from macOS networksetup.  name is the name of the network
+// service.
 type hardwarePortInfo struct {
 	name      string
 	ip        string
@@ -38,55 +41,76 @@ func ifaceHasStaticIP(ifaceName string) (bool, error) {
 
 // getCurrentHardwarePortInfo gets information for the specified network interface.
 func getCurrentHardwarePortInfo(ifaceName string) (hardwarePortInfo, error) {
-	// First of all we should find hardware port name
-	m := getNetworkSetupHardwareReports()
-	hardwarePort, ok := m[ifaceName]
+	if tmErr := lookTool("networksetup", "static ip configuration"); tmErr != nil {
+		return hardwarePortInfo{}, tmErr
+	}
+
+	// First of all we should find the network service name.
+	_, out, err := aghos.RunCommand("networksetup", "-listnetworkserviceorder")
+	if err != nil {
+		return hardwarePortInfo{}, fmt.Errorf("listing network services: %w", err)
+	}
+
+	service, ok := parseNetworkServiceOrder(out)[ifaceName]
 	if !ok {
-		return hardwarePortInfo{}, fmt.Errorf("could not find hardware port for %s", ifaceName)
+		return hardwarePortInfo{}, fmt.Errorf("could not find network service for %s", ifaceName)
 	}
 
-	return getHardwarePortInfo(hardwarePort)
+	return getHardwarePortInfo(service)
 }
 
-// getNetworkSetupHardwareReports parses the output of the `networksetup
-// -listallhardwareports` command it returns a map where the key is the
-// interface name, and the value is the "hardware port" returns nil if it fails
-// to parse the output
+// serviceOrderRe matches the pair of lines describing a single network service
+// in the output of `networksetup -listnetworkserviceorder`.  The disabled
+// services are marked with an asterisk.
+var serviceOrderRe = regexp.MustCompile(
+	`(?m)^\(\*?[0-9]*\) (.+)\n\(Hardware Port: .*?, Device: (.*?)\)$`,
+)
+
+// parseNetworkServiceOrder parses the output of the `networksetup
+// -listnetworkserviceorder` command.  It returns a map where the key is the BSD
+// interface name, and the value is the network service name.  The output looks
+// like:
+//
+//   An asterisk (*) denotes that a network service is disabled.
+//   (1) Wi-Fi
+//   (Hardware Port: Wi-Fi, Device: en0)
+//
+//   (2) Thunderbolt Bridge
+//   (Hardware Port: Thunderbolt Bridge, Device: bridge0)
 //
 // TODO(e.burkov):  There should be more proper approach than parsing the
 // command output.  For example, see
 // https://developer.apple.com/documentation/systemconfiguration.
-func getNetworkSetupHardwareReports() map[string]string {
-	_, out, err := aghos.RunCommand("networksetup", "-listallhardwareports")
-	if err != nil {
-		return nil
-	}
-
-	re, err := regexp.Compile("Hardware Port: (.*?)\nDevice: (.*?)\n")
-	if err != nil {
-		return nil
-	}
-
-	m := make(map[string]string)
-
-	matches := re.FindAllStringSubmatch(out, -1)
-	for i := range matches {
-		port := matches[i][1]
-		device := matches[i][2]
-		m[device] = port
+func parseNetworkServiceOrder(out string) (services map[string]string) {
+	services = map[string]string{}
+	for _, m := range serviceOrderRe.FindAllStringSubmatch(out, -1) {
+		service, device := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		if device == "" {
+			continue
+		}
+
+		services[device] = service
 	}
 
-	return m
+	return services
 }
 
-func getHardwarePortInfo(hardwarePort string) (hardwarePortInfo, error) {
-	h := hardwarePortInfo{}
-
-	_, out, err := aghos.RunCommand("networksetup", "-getinfo", hardwarePort)
+// getHardwarePortInfo gets the information about the network service from the
+// output of `networksetup -getinfo`.
+func getHardwarePortInfo(service string) (h hardwarePortInfo, err error) {
+	_, out, err := aghos.RunCommand("networksetup", "-getinfo", service)
 	if err != nil {
 		return h, err
 	}
 
+	return parseHardwarePortInfo(service, out)
+}
+
+// parseHardwarePortInfo parses the output of `networksetup -getinfo` for the
+// service.  The first line of the output describes the configuration method,
+// which is either "DHCP Configuration" or "Manual Configuration", possibly with
+// some qualifiers, like "Manually Using DHCP Router Configuration".
+func parseHardwarePortInfo(service, out string) (h hardwarePortInfo, err error) {
 	re := regexp.MustCompile("IP address: (.*?)\nSubnet mask: (.*?)\nRouter: (.*?)\n")
 
 	match := re.FindStringSubmatch(out)
@@ -94,59 +118,152 @@ func getHardwarePortInfo(hardwarePort string) (hardwarePortInfo, error) {
 		return h, errors.Error("could not find hardware port info")
 	}
 
-	h.name = hardwarePort
+	h.name = service
 	h.ip = match[1]
 	h.subnet = match[2]
 	h.gatewayIP = match[3]
 
-	if strings.Index(out, "Manual Configuration") == 0 {
+	method := out
+	if i := strings.IndexByte(out, '\n'); i >= 0 {
+		method = out[:i]
+	}
+
+	switch {
+	case strings.HasPrefix(method, "DHCP"), strings.HasPrefix(method, "BOOTP"):
+		h.static = false
+	case strings.HasPrefix(method, "Manual"):
 		h.static = true
+	default:
+		return h, fmt.Errorf("configuration method %q: %w", method, ErrNoStaticIPInfo)
 	}
 
 	return h, nil
 }
 
-func ifaceSetStaticIP(ifaceName string) (err error) {
+// ifaceSetStaticIP configures the network service of the interface to retain
+// its current IP through networksetup.
+func ifaceSetStaticIP(ifaceName string) (restore RestoreFunc, err error) {
 	portInfo, err := getCurrentHardwarePortInfo(ifaceName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if portInfo.static {
-		return errors.Error("IP address is already static")
+		return nil, errors.Error("IP address is already static")
 	}
 
-	dnsAddrs, err := getEtcResolvConfServers()
+	ip := net.ParseIP(portInfo.ip)
+	if ip == nil {
+		return nil, fmt.Errorf("bad ip address %q", portInfo.ip)
+	}
+
+	prevDNS, err := getServiceDNSServers(portInfo.name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	args := make([]string, 0)
-	args = append(args, "-setdnsservers", portInfo.name)
-	args = append(args, dnsAddrs...)
+	dnsAddrs, err := getEtcResolvConfServers()
+	if err != nil {
+		return nil, err
+	}
 
 	// Setting DNS servers is necessary when configuring a static IP
-	code, _, err := aghos.RunCommand("networksetup", args...)
+	err = runNetworkSetup("setting dns servers", append([]string{
+		"-setdnsservers",
+		portInfo.name,
+	}, dnsAddrs...)...)
 	if err != nil {
-		return err
-	}
-	if code != 0 {
-		return fmt.Errorf("failed to set DNS servers, code=%d", code)
+		return nil, err
 	}
 
+	restore = hardwarePortRestorer(portInfo, prevDNS)
+
 	// Actually configures hardware port to have static IP
-	code, _, err = aghos.RunCommand("networksetup", "-setmanual",
-		portInfo.name, portInfo.ip, portInfo.subnet, portInfo.gatewayIP)
+	err = runNetworkSetup(
+		"setting static ip",
+		"-setmanual",
+		portInfo.name,
+		portInfo.ip,
+		portInfo.subnet,
+		portInfo.gatewayIP,
+	)
+	if err == nil && !ifaceHasIP(ifaceName, ip) {
+		err = fmt.Errorf("interface %s lost address %s", ifaceName, ip)
+	}
+
 	if err != nil {
-		return err
+		return nil, errors.WithDeferred(err, errors.Annotate(restore(), "restoring: %w"))
 	}
-	if code != 0 {
-		return fmt.Errorf("failed to set DNS servers, code=%d", code)
+
+	return restore, nil
+}
+
+// hardwarePortRestorer returns a function restoring the previous IP
+// configuration of the network service, either the manual one or DHCP, and its
+// previous DNS servers.
+func hardwarePortRestorer(prev hardwarePortInfo, prevDNS []string) (restore RestoreFunc) {
+	return func() (err error) {
+		if prev.static {
+			err = runNetworkSetup(
+				"restoring static ip",
+				"-setmanual",
+				prev.name,
+				prev.ip,
+				prev.subnet,
+				prev.gatewayIP,
+			)
+		} else {
+			err = runNetworkSetup("restoring dhcp", "-setdhcp", prev.name)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return runNetworkSetup("restoring dns servers", append([]string{
+			"-setdnsservers",
+			prev.name,
+		}, prevDNS...)...)
+	}
+}
+
+// runNetworkSetup runs networksetup with args and returns an error if it
+// doesn't succeed.  action is used for error messages.
+func runNetworkSetup(action string, args ...string) (err error) {
+	code, _, err := aghos.RunCommand("networksetup", args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	} else if code != 0 {
+		return fmt.Errorf("%s: networksetup finished with code %d", action, code)
 	}
 
 	return nil
 }
 
+// getServiceDNSServers returns the DNS servers explicitly configured for the
+// network service.  It returns a slice containing a single "Empty" element,
+// which is accepted by `networksetup -setdnsservers`, if there are none.
+func getServiceDNSServers(service string) (addrs []string, err error) {
+	code, out, err := aghos.RunCommand("networksetup", "-getdnsservers", service)
+	if err != nil {
+		return nil, fmt.Errorf("getting dns servers: %w", err)
+	} else if code != 0 {
+		return nil, fmt.Errorf("getting dns servers: networksetup finished with code %d", code)
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); net.ParseIP(line) != nil {
+			addrs = append(addrs, line)
+		}
+	}
+
+	if len(addrs) == 0 {
+		return []string{"Empty"}, nil
+	}
+
+	return addrs, nil
+}
+
 // getEtcResolvConfServers returns a list of nameservers configured in
 // /etc/resolv.conf.
 func getEtcResolvConfServers() ([]string, error) {
@@ -169,3 +286,11 @@ func getEtcResolvConfServers() ([]string, error) {
 
 	return addrs, nil
 }
+
+func ifaceSetMTU(string, int) (err error) {
+	return aghos.Unsupported("setting mtu")
+}
+
+func ifaceAddAddr(string, netip.Prefix) (err error) {
+	return aghos.Unsupported("adding address")
+}
@@ -0,0 +1,88 @@
+//go:build darwin
+// +build darwin
+
+package aghnet
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNetworkServiceOrder(t *testing.T) {
+	const out = `An asterisk (*) denotes that a network service is disabled.` + nl +
+		`(1) Wi-Fi` + nl +
+		`(Hardware Port: Wi-Fi, Device: en0)` + nl +
+		nl +
+		`(2) USB 10/100/1000 LAN 2` + nl +
+		`(Hardware Port: USB 10/100/1000 LAN, Device: en5)` + nl +
+		nl +
+		`(*) Thunderbolt Bridge` + nl +
+		`(Hardware Port: Thunderbolt Bridge, Device: bridge0)` + nl +
+		nl +
+		`(3) VPN` + nl +
+		`(Hardware Port: L2TP, Device: )` + nl
+
+	assert.Equal(t, map[string]string{
+		"en0":     "Wi-Fi",
+		"en5":     "USB 10/100/1000 LAN 2",
+		"bridge0": "Thunderbolt Bridge",
+	}, parseNetworkServiceOrder(out))
+}
+
+func TestParseHardwarePortInfo(t *testing.T) {
+	const addrs = `IP address: 192.168.1.2` + nl +
+		`Subnet mask: 255.255.255.0` + nl +
+		`Router: 192.168.1.1` + nl +
+		`Client ID:` + nl
+
+	testCases := []struct {
+		name       string
+		out        string
+		wantErr    error
+		wantStatic bool
+	}{{
+		name:       "dhcp",
+		out:        `DHCP Configuration` + nl + addrs,
+		wantErr:    nil,
+		wantStatic: false,
+	}, {
+		name:       "manual",
+		out:        `Manual Configuration` + nl + addrs,
+		wantErr:    nil,
+		wantStatic: true,
+	}, {
+		name:       "manual_dhcp_router",
+		out:        `Manually Using DHCP Router Configuration` + nl + addrs,
+		wantErr:    nil,
+		wantStatic: true,
+	}, {
+		name:       "unknown",
+		out:        `Some Configuration` + nl + addrs,
+		wantErr:    ErrNoStaticIPInfo,
+		wantStatic: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := parseHardwarePortInfo("Wi-Fi", tc.out)
+			if tc.wantErr != nil {
+				require.True(t, errors.Is(err, tc.wantErr))
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, hardwarePortInfo{
+				name:      "Wi-Fi",
+				ip:        "192.168.1.2",
+				subnet:    "255.255.255.0",
+				gatewayIP: "192.168.1.1",
+				static:    tc.wantStatic,
+			}, h)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/netip"
 	"strings"
 
 	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
@@ -53,6 +54,14 @@ func (n interfaceName) rcConfStaticConfig(r io.Reader) (_ []string, cont bool, e
 	return nil, true, s.Err()
 }
 
-func ifaceSetStaticIP(string) (err error) {
-	return aghos.Unsupported("setting static ip")
+func ifaceSetStaticIP(string) (restore RestoreFunc, err error) {
+	return nil, aghos.Unsupported("setting static ip")
+}
+
+func ifaceSetMTU(string, int) (err error) {
+	return aghos.Unsupported("setting mtu")
+}
+
+func ifaceAddAddr(string, netip.Prefix) (err error) {
+	return aghos.Unsupported("adding address")
 }
@@ -8,12 +8,14 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/netip"
 	"os"
 	"strings"
 
 	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
 	"github.com/AdguardTeam/golibs/errors"
 	"github.com/google/renameio/maybe"
+	"github.com/mdlayher/netlink"
 	"golang.org/x/sys/unix"
 )
 
@@ -105,10 +107,101 @@ func ifaceHasStaticIP(ifaceName string) (has bool, err error) {
 		}
 	}
 
-	return false, ErrNoStaticIPInfo
+	return nmcliHasStaticIP(ifaceName)
 }
 
+// nmcliHasStaticIP checks if the interface is configured by NetworkManager to
+// have a static IP.  It returns ErrNoStaticIPInfo if NetworkManager doesn't
+// manage the interface or isn't available.
+func nmcliHasStaticIP(ifaceName string) (has bool, err error) {
+	if tmErr := lookTool("nmcli", "static ip detection"); tmErr != nil {
+		tmErr.Err = ErrNoStaticIPInfo
+
+		return false, tmErr
+	}
+
+	conn, ok := nmcliField("GENERAL.CONNECTION", "device", "show", ifaceName)
+	if !ok || conn == "" || conn == "--" {
+		return false, ErrNoStaticIPInfo
+	}
+
+	method, ok := nmcliField("ipv4.method", "connection", "show", conn)
+	if !ok {
+		return false, ErrNoStaticIPInfo
+	}
+
+	switch method {
+	case "manual":
+		return true, nil
+	case "auto":
+		return false, nil
+	default:
+		return false, fmt.Errorf("ipv4 method %q: %w", method, ErrNoStaticIPInfo)
+	}
+}
+
+// nmcliField runs nmcli in terse mode with args requesting the field and
+// returns its value.  ok is false if the command fails or there is no such
+// field in the output.
+func nmcliField(field string, args ...string) (val string, ok bool) {
+	args = append([]string{"--terse", "--fields", field}, args...)
+	code, out, err := aghosRunCommand("nmcli", args...)
+	if err != nil || code != 0 {
+		return "", false
+	}
+
+	return parseNmcliField(out, field)
+}
+
+// parseNmcliField looks for the field in the terse output of nmcli, which
+// consists of lines like:
+//
+//   GENERAL.CONNECTION:Wired connection 1
+//
+// Colons and backslashes within values are escaped with a backslash.
+func parseNmcliField(out, field string) (val string, ok bool) {
+	for _, line := range strings.Split(out, "\n") {
+		name, escaped, found := strings.Cut(line, ":")
+		if !found || name != field {
+			continue
+		}
+
+		return unescapeNmcli(strings.TrimRight(escaped, "\r")), true
+	}
+
+	return "", false
+}
+
+// unescapeNmcli removes the escaping backslashes from the terse nmcli value.
+func unescapeNmcli(s string) (unescaped string) {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+
+		// The error is always nil.
+		_ = b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// canBindPrivilegedPorts checks the effective capabilities of the process and
+// falls back on checking the ambient capabilities and the root privileges if
+// those can't be read.
 func canBindPrivilegedPorts() (can bool, err error) {
+	caps, err := capabilities()
+	if err == nil {
+		return caps.NetBindService, nil
+	}
+
+	currentLogger().Debug("getting capabilities: %s", err)
+
 	cnbs, err := unix.PrctlRetInt(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_IS_SET, unix.CAP_NET_BIND_SERVICE, 0, 0)
 	// Don't check the error because it's always nil on Linux.
 	adm, _ := aghos.HaveAdminRights()
@@ -130,31 +223,88 @@ func findIfaceLine(s *bufio.Scanner, name string) (ok bool) {
 	return false
 }
 
+// dhcpcdConfPath is the path to the configuration file of dhcpcd.
+const dhcpcdConfPath = "/etc/dhcpcd.conf"
+
 // ifaceSetStaticIP configures the system to retain its current IP on the
-// interface through dhcpdc.conf.
-func ifaceSetStaticIP(ifaceName string) (err error) {
+// interface through dhcpdc.conf.  The configuration is restored if the
+// interface doesn't have the IP afterwards.
+func ifaceSetStaticIP(ifaceName string) (restore RestoreFunc, err error) {
 	ipNet := GetSubnet(ifaceName)
-	if ipNet.IP == nil {
-		return errors.Error("can't get IP address")
+	if ipNet == nil {
+		return nil, errors.Error("can't get IP address")
 	}
 
 	gatewayIP := GatewayIP(ifaceName)
 	add := dhcpcdConfIface(ifaceName, ipNet, gatewayIP, ipNet.IP)
 
-	body, err := os.ReadFile("/etc/dhcpcd.conf")
+	body, err := os.ReadFile(dhcpcdConfPath)
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		return err
+		return nil, err
 	}
 
-	body = append(body, []byte(add)...)
-	err = maybe.WriteFile("/etc/dhcpcd.conf", body, 0o644)
+	restore = dhcpcdConfRestorer(body, err == nil)
+
+	err = maybe.WriteFile(dhcpcdConfPath, append(body[:len(body):len(body)], add...), 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("writing conf: %w", err)
+	}
+
+	err = checkDHCPCDConf(ifaceName)
+	if err == nil && !ifaceHasIP(ifaceName, ipNet.IP) {
+		err = fmt.Errorf("interface %s lost address %s", ifaceName, ipNet.IP)
+	}
+
+	if err != nil {
+		return nil, errors.WithDeferred(err, errors.Annotate(restore(), "restoring: %w"))
+	}
+
+	return restore, nil
+}
+
+// checkDHCPCDConf returns an error if dhcpcd.conf doesn't configure the
+// network interface to have a static IP, e.g. because the written data has
+// been lost or the file has been changed concurrently.
+func checkDHCPCDConf(ifaceName string) (err error) {
+	f, err := os.Open(dhcpcdConfPath)
 	if err != nil {
-		return fmt.Errorf("writing conf: %w", err)
+		return fmt.Errorf("checking conf: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, f.Close()) }()
+
+	return dhcpcdConfHasStaticIP(f, ifaceName)
+}
+
+// dhcpcdConfHasStaticIP returns an error if the dhcpcd.conf data from r doesn't
+// configure the network interface to have a static IP.
+func dhcpcdConfHasStaticIP(r io.Reader, ifaceName string) (err error) {
+	_, notFound, err := interfaceName(ifaceName).dhcpcdStaticConfig(r)
+	if err != nil {
+		return fmt.Errorf("checking conf: %w", err)
+	} else if notFound {
+		return fmt.Errorf("checking conf: no static ip for %s", ifaceName)
 	}
 
 	return nil
 }
 
+// dhcpcdConfRestorer returns a function restoring the dhcpcd.conf file to the
+// previous body.  If existed is false, the file is removed instead.
+func dhcpcdConfRestorer(body []byte, existed bool) (restore RestoreFunc) {
+	return func() (err error) {
+		if !existed {
+			err = os.Remove(dhcpcdConfPath)
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		return maybe.WriteFile(dhcpcdConfPath, body, 0o644)
+	}
+}
+
 // dhcpcdConfIface returns configuration lines for the dhcpdc.conf files that
 // configure the interface to have a static IP.
 func dhcpcdConfIface(ifaceName string, ipNet *net.IPNet, gatewayIP, dnsIP net.IP) (conf string) {
@@ -176,3 +326,85 @@ func dhcpcdConfIface(ifaceName string, ipNet *net.IPNet, gatewayIP, dnsIP net.IP
 
 	return string(body)
 }
+
+// ifaceSetMTU sets the MTU of the network interface using the netlink
+// RTM_SETLINK request.
+func ifaceSetMTU(ifaceName string, mtu int) (err error) {
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		return err
+	}
+
+	msg, err := newSetMTUMsg(iface.Index, mtu)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+
+	return netlinkRouteRequest(unix.RTM_SETLINK, 0, msg)
+}
+
+// newSetMTUMsg returns the data of the RTM_SETLINK message setting mtu for the
+// network interface with the index.  See rtnetlink(7).
+func newSetMTUMsg(index, mtu int) (msg []byte, err error) {
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint32(unix.IFLA_MTU, uint32(mtu))
+
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	// The message starts with struct ifinfomsg with the address family set
+	// to AF_UNSPEC, which is zero.
+	msg = make([]byte, unix.SizeofIfInfomsg, unix.SizeofIfInfomsg+len(attrs))
+	aghos.NativeEndian.PutUint32(msg[4:8], uint32(index))
+
+	return append(msg, attrs...), nil
+}
+
+// ifaceAddAddr adds the address from prefix to the network interface using
+// the netlink RTM_NEWADDR request.
+func ifaceAddAddr(ifaceName string, prefix netip.Prefix) (err error) {
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		return err
+	}
+
+	msg, err := newAddAddrMsg(iface.Index, prefix)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+
+	return netlinkRouteRequest(unix.RTM_NEWADDR, netlink.Create|netlink.Excl, msg)
+}
+
+// newAddAddrMsg returns the data of the RTM_NEWADDR message adding the address
+// from prefix to the network interface with the index.  See rtnetlink(7).
+func newAddAddrMsg(index int, prefix netip.Prefix) (msg []byte, err error) {
+	ip := prefix.Addr().Unmap()
+	family, bits := uint8(unix.AF_INET6), prefix.Bits()
+	if ip.Is4() {
+		family = unix.AF_INET
+		if prefix.Addr().Is4In6() {
+			bits -= 96
+		}
+	}
+
+	ae := netlink.NewAttributeEncoder()
+	ae.Bytes(unix.IFA_LOCAL, ip.AsSlice())
+	ae.Bytes(unix.IFA_ADDRESS, ip.AsSlice())
+
+	attrs, err := ae.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	// The message starts with struct ifaddrmsg.  The flags and the scope are
+	// left zero, which means the universe scope.
+	msg = make([]byte, unix.SizeofIfAddrmsg, unix.SizeofIfAddrmsg+len(attrs))
+	msg[0] = family
+	msg[1] = uint8(bits)
+	aghos.NativeEndian.PutUint32(msg[4:8], uint32(index))
+
+	return append(msg, attrs...), nil
+}
@@ -5,11 +5,24 @@ package aghnet
 
 import (
 	"bytes"
+	"context"
+	"io/fs"
 	"net"
+	"net/netip"
+	"os/exec"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/netutil"
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/mdlayher/netlink"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
 )
 
 func TestDHCPCDStaticConfig(t *testing.T) {
@@ -150,6 +163,692 @@ func TestSetStaticIPdhcpcdConf(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			s := dhcpcdConfIface("wlan0", ipNet, tc.routers, net.IP{192, 168, 0, 2})
 			assert.Equal(t, tc.dhcpcdConf, s)
+
+			// The written configuration must be recognized when checked.
+			assert.NoError(t, dhcpcdConfHasStaticIP(strings.NewReader(s), "wlan0"))
+		})
+	}
+
+	t.Run("other_iface", func(t *testing.T) {
+		s := dhcpcdConfIface("wlan0", ipNet, nil, net.IP{192, 168, 0, 2})
+		err := dhcpcdConfHasStaticIP(strings.NewReader(s), "eth0")
+		testutil.AssertErrorMsg(t, "checking conf: no static ip for eth0", err)
+	})
+}
+
+func TestParseNmcliField(t *testing.T) {
+	const out = `GENERAL.DEVICE:eth0` + nl +
+		`GENERAL.CONNECTION:Conn\:with\\colon` + nl +
+		`ipv4.method:manual` + nl
+
+	testCases := []struct {
+		name   string
+		field  string
+		want   string
+		wantOK bool
+	}{{
+		name:   "simple",
+		field:  "ipv4.method",
+		want:   "manual",
+		wantOK: true,
+	}, {
+		name:   "escaped",
+		field:  "GENERAL.CONNECTION",
+		want:   `Conn:with\colon`,
+		wantOK: true,
+	}, {
+		name:   "absent",
+		field:  "ipv6.method",
+		want:   "",
+		wantOK: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			val, ok := parseNmcliField(out, tc.field)
+			require.Equal(t, tc.wantOK, ok)
+
+			assert.Equal(t, tc.want, val)
+		})
+	}
+}
+
+func TestNmcliHasStaticIP(t *testing.T) {
+	const iface = "eth0"
+
+	testCases := []struct {
+		wantErr error
+		outputs map[string]string
+		name    string
+		want    bool
+	}{{
+		wantErr: nil,
+		outputs: map[string]string{
+			"device":     "GENERAL.CONNECTION:Wired connection 1" + nl,
+			"connection": "ipv4.method:manual" + nl,
+		},
+		name: "manual",
+		want: true,
+	}, {
+		wantErr: nil,
+		outputs: map[string]string{
+			"device":     "GENERAL.CONNECTION:Wired connection 1" + nl,
+			"connection": "ipv4.method:auto" + nl,
+		},
+		name: "auto",
+		want: false,
+	}, {
+		wantErr: ErrNoStaticIPInfo,
+		outputs: map[string]string{
+			"device": "GENERAL.CONNECTION:" + nl,
+		},
+		name: "unmanaged",
+		want: false,
+	}, {
+		wantErr: ErrNoStaticIPInfo,
+		outputs: map[string]string{},
+		name:    "no_nmcli",
+		want:    false,
+	}}
+
+	substLookPath(t, func(file string) (path string, err error) {
+		return "/usr/bin/" + file, nil
+	})
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			substRunCommand(t, func(cmd string, args ...string) (code int, out string, err error) {
+				require.Equal(t, "nmcli", cmd)
+				require.Len(t, args, 6)
+
+				out, ok := tc.outputs[args[3]]
+				if !ok {
+					return 0, "", errors.Error("executable file not found")
+				}
+
+				return 0, out, nil
+			})
+
+			has, err := nmcliHasStaticIP(iface)
+			require.ErrorIs(t, err, tc.wantErr)
+
+			assert.Equal(t, tc.want, has)
+		})
+	}
+
+	t.Run("missing_tool", func(t *testing.T) {
+		substLookPath(t, func(_ string) (path string, err error) {
+			return "", exec.ErrNotFound
+		})
+
+		has, err := nmcliHasStaticIP(iface)
+		assert.False(t, has)
+		assert.ErrorIs(t, err, ErrNoStaticIPInfo)
+		assert.ErrorIs(t, err, ErrToolMissing)
+	})
+}
+
+func TestParseNeighborMsg(t *testing.T) {
+	data := make([]byte, unix.SizeofNdMsg)
+	data[0] = unix.AF_INET6
+	aghos.NativeEndian.PutUint16(data[8:10], unix.NUD_REACHABLE)
+
+	attrs, err := netlink.MarshalAttributes([]netlink.Attribute{{
+		Type: unix.NDA_DST,
+		Data: net.ParseIP("fe80::1"),
+	}, {
+		Type: unix.NDA_LLADDR,
+		Data: []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+	}})
+	require.NoError(t, err)
+
+	n, err := parseNeighborMsg(append(data, attrs...))
+	require.NoError(t, err)
+
+	assert.Equal(t, neighbor{
+		addr:  netip.MustParseAddr("fe80::1"),
+		mac:   net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		state: unix.NUD_REACHABLE,
+	}, n)
+
+	_, err = parseNeighborMsg(data[:4])
+	assert.Error(t, err)
+}
+
+func TestProcNetARPMAC(t *testing.T) {
+	substRootDirFS(t, fstest.MapFS{
+		procNetARPPath: &fstest.MapFile{
+			Data: []byte(`IP address       HW type     Flags       HW address            Mask     Device` + nl +
+				`192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0` + nl),
+		},
+	})
+
+	mac, err := procNetARPMAC(netip.MustParseAddr("192.168.1.1"))
+	require.NoError(t, err)
+
+	assert.Equal(t, net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, mac)
+
+	_, err = procNetARPMAC(netip.MustParseAddr("192.168.1.2"))
+	assert.ErrorIs(t, err, ErrNoNeighbor)
+}
+
+func TestIfaceStatistics(t *testing.T) {
+	const statsDir = sysClassNetPath + "/eth0/statistics/"
+
+	full := fstest.MapFS{
+		statsDir + "rx_bytes":   &fstest.MapFile{Data: []byte("1024\n")},
+		statsDir + "tx_bytes":   &fstest.MapFile{Data: []byte("2048\n")},
+		statsDir + "rx_packets": &fstest.MapFile{Data: []byte("10\n")},
+		statsDir + "tx_packets": &fstest.MapFile{Data: []byte("20\n")},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		substRootDirFS(t, full)
+
+		assert.Equal(t, &IfaceStatistics{
+			RxBytes:   1024,
+			TxBytes:   2048,
+			RxPackets: 10,
+			TxPackets: 20,
+		}, ifaceStatistics("eth0"))
+	})
+
+	t.Run("missing_file", func(t *testing.T) {
+		fsys := fstest.MapFS{}
+		for k, v := range full {
+			fsys[k] = v
+		}
+		delete(fsys, statsDir+"tx_packets")
+
+		substRootDirFS(t, fsys)
+
+		assert.Nil(t, ifaceStatistics("eth0"))
+	})
+
+	t.Run("bad_counter", func(t *testing.T) {
+		fsys := fstest.MapFS{}
+		for k, v := range full {
+			fsys[k] = v
+		}
+		fsys[statsDir+"rx_bytes"] = &fstest.MapFile{Data: []byte("bad\n")}
+
+		substRootDirFS(t, fsys)
+
+		assert.Nil(t, ifaceStatistics("eth0"))
+	})
+
+	t.Run("no_sysfs", func(t *testing.T) {
+		substRootDirFS(t, fstest.MapFS{})
+
+		assert.Nil(t, ifaceStatistics("eth0"))
+	})
+}
+
+func TestIfaceKindFromOS(t *testing.T) {
+	substRootDirFS(t, fstest.MapFS{
+		sysClassNetPath + "/enp3s0/device/vendor": &fstest.MapFile{},
+		sysClassNetPath + "/wlp2s0/device/vendor": &fstest.MapFile{},
+		sysClassNetPath + "/wlp2s0/phy80211/name": &fstest.MapFile{},
+		sysClassNetPath + "/lan/bridge/bridge_id": &fstest.MapFile{},
+		sysClassNetPath + "/client/tun_flags":     &fstest.MapFile{},
+		sysClassNetPath + "/veth0/address":        &fstest.MapFile{},
+	})
+
+	testCases := []struct {
+		name string
+		want IfaceKind
+	}{{
+		name: "enp3s0",
+		want: IfaceKindEthernet,
+	}, {
+		name: "wlp2s0",
+		want: IfaceKindWiFi,
+	}, {
+		name: "lan",
+		want: IfaceKindBridge,
+	}, {
+		name: "client",
+		want: IfaceKindTunnel,
+	}, {
+		name: "veth0",
+		want: IfaceKindEthernet,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ifaceKind(NetInterface{
+				Name:  tc.name,
+				Flags: net.FlagUp | net.FlagBroadcast,
+			}, true))
+		})
+	}
+}
+
+func TestIfaceLinkMode(t *testing.T) {
+	const ifaceDir = sysClassNetPath + "/eth0/"
+
+	testCases := []struct {
+		fsys       fstest.MapFS
+		name       string
+		wantDuplex string
+		wantSpeed  int
+	}{{
+		fsys: fstest.MapFS{
+			ifaceDir + "speed":  &fstest.MapFile{Data: []byte("1000\n")},
+			ifaceDir + "duplex": &fstest.MapFile{Data: []byte("full\n")},
+		},
+		name:       "up",
+		wantDuplex: "full",
+		wantSpeed:  1000,
+	}, {
+		fsys: fstest.MapFS{
+			ifaceDir + "speed":  &fstest.MapFile{Data: []byte("-1\n")},
+			ifaceDir + "duplex": &fstest.MapFile{Data: []byte("unknown\n")},
+		},
+		name:       "down",
+		wantDuplex: "",
+		wantSpeed:  0,
+	}, {
+		fsys: fstest.MapFS{
+			ifaceDir + "duplex": &fstest.MapFile{Data: []byte("half\n")},
+		},
+		name:       "no_speed",
+		wantDuplex: "half",
+		wantSpeed:  0,
+	}, {
+		fsys:       fstest.MapFS{},
+		name:       "no_sysfs",
+		wantDuplex: "",
+		wantSpeed:  0,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			substRootDirFS(t, tc.fsys)
+
+			speed, duplex := ifaceLinkMode("eth0")
+			assert.Equal(t, tc.wantSpeed, speed)
+			assert.Equal(t, tc.wantDuplex, duplex)
+		})
+	}
+}
+
+func TestNewSetMTUMsg(t *testing.T) {
+	msg, err := newSetMTUMsg(2, 1400)
+	require.NoError(t, err)
+	require.Len(t, msg, unix.SizeofIfInfomsg+8)
+
+	assert.Equal(t, uint32(2), aghos.NativeEndian.Uint32(msg[4:8]))
+
+	ad, err := netlink.NewAttributeDecoder(msg[unix.SizeofIfInfomsg:])
+	require.NoError(t, err)
+	require.True(t, ad.Next())
+
+	assert.Equal(t, uint16(unix.IFLA_MTU), ad.Type())
+	assert.Equal(t, uint32(1400), ad.Uint32())
+	assert.False(t, ad.Next())
+}
+
+func TestNewAddAddrMsg(t *testing.T) {
+	testCases := []struct {
+		name       string
+		prefix     netip.Prefix
+		wantIP     net.IP
+		wantFamily uint8
+		wantBits   uint8
+	}{{
+		name:       "ipv4",
+		prefix:     netip.MustParsePrefix("192.168.1.1/24"),
+		wantIP:     net.IP{192, 168, 1, 1},
+		wantFamily: unix.AF_INET,
+		wantBits:   24,
+	}, {
+		name:       "ipv4_mapped",
+		prefix:     netip.MustParsePrefix("::ffff:192.168.1.1/120"),
+		wantIP:     net.IP{192, 168, 1, 1},
+		wantFamily: unix.AF_INET,
+		wantBits:   24,
+	}, {
+		name:       "ipv6",
+		prefix:     netip.MustParsePrefix("2001:db8::1/64"),
+		wantIP:     net.ParseIP("2001:db8::1"),
+		wantFamily: unix.AF_INET6,
+		wantBits:   64,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := newAddAddrMsg(2, tc.prefix)
+			require.NoError(t, err)
+			require.Greater(t, len(msg), unix.SizeofIfAddrmsg)
+
+			assert.Equal(t, tc.wantFamily, msg[0])
+			assert.Equal(t, tc.wantBits, msg[1])
+			assert.Equal(t, uint32(2), aghos.NativeEndian.Uint32(msg[4:8]))
+
+			ad, err := netlink.NewAttributeDecoder(msg[unix.SizeofIfAddrmsg:])
+			require.NoError(t, err)
+
+			for _, typ := range []uint16{unix.IFA_LOCAL, unix.IFA_ADDRESS} {
+				require.True(t, ad.Next())
+
+				assert.Equal(t, typ, ad.Type())
+				assert.Equal(t, []byte(tc.wantIP), ad.Bytes())
+			}
+
+			assert.False(t, ad.Next())
+		})
+	}
+}
+
+func TestPortHolder(t *testing.T) {
+	const header = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when ` +
+		`retrnsmt   uid  timeout inode` + nl
+
+	const tcpData = header +
+		// 127.0.0.1:53, listening.
+		`   0: 0100007F:0035 00000000:0000 0A 00000000:00000000 00:00000000 ` +
+		`00000000   101        0 1001 1 0000000000000000 100 0 0 10 0` + nl +
+		// 127.0.0.1:80, established.
+		`   1: 0100007F:0050 0200007F:D431 01 00000000:00000000 00:00000000 ` +
+		`00000000  1000        0 1002 1 0000000000000000 20 4 30 10 -1` + nl
+
+	const tcp6Data = header +
+		// [::]:8080, listening.
+		`   0: 00000000000000000000000000000000:1F90 ` +
+		`00000000000000000000000000000000:0000 0A 00000000:00000000 ` +
+		`00:00000000 00000000     0        0 1003 1 0000000000000000 100 0 0 10 0` + nl
+
+	substRootDirFS(t, &fakeLinkFS{
+		MapFS: fstest.MapFS{
+			"proc/net/tcp":  &fstest.MapFile{Data: []byte(tcpData)},
+			"proc/net/tcp6": &fstest.MapFile{Data: []byte(tcp6Data)},
+			"proc/cpuinfo":  &fstest.MapFile{},
+			"proc/101/comm": &fstest.MapFile{Data: []byte("resolver" + nl)},
+			"proc/101/fd/0": &fstest.MapFile{},
+			"proc/101/fd/3": &fstest.MapFile{},
+			"proc/202/comm": &fstest.MapFile{Data: []byte("server" + nl)},
+			"proc/202/fd/4": &fstest.MapFile{},
+			"proc/303/comm": &fstest.MapFile{Data: []byte("client" + nl)},
+			"proc/303/fd/5": &fstest.MapFile{},
+		},
+		links: map[string]string{
+			"proc/101/fd/0": "/dev/null",
+			"proc/101/fd/3": "socket:[1001]",
+			"proc/202/fd/4": "socket:[1003]",
+			"proc/303/fd/5": "socket:[1002]",
+		},
+	})
+
+	testCases := []struct {
+		name    string
+		network string
+		want    string
+		addr    netutil.IPPort
+	}{{
+		name:    "exact",
+		network: "tcp",
+		want:    "resolver (pid 101)",
+		addr:    netutil.IPPort{IP: net.IP{127, 0, 0, 1}, Port: 53},
+	}, {
+		name:    "unspecified",
+		network: "tcp",
+		want:    "resolver (pid 101)",
+		addr:    netutil.IPPort{IP: net.IPv4zero, Port: 53},
+	}, {
+		name:    "other_ip",
+		network: "tcp",
+		want:    "",
+		addr:    netutil.IPPort{IP: net.IP{127, 0, 0, 2}, Port: 53},
+	}, {
+		name:    "not_listening",
+		network: "tcp",
+		want:    "",
+		addr:    netutil.IPPort{IP: net.IP{127, 0, 0, 1}, Port: 80},
+	}, {
+		name:    "dual_stack",
+		network: "tcp",
+		want:    "server (pid 202)",
+		addr:    netutil.IPPort{IP: net.IP{192, 168, 1, 1}, Port: 8080},
+	}, {
+		name:    "no_procfs_file",
+		network: "udp",
+		want:    "",
+		addr:    netutil.IPPort{IP: net.IP{127, 0, 0, 1}, Port: 53},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, portHolder(tc.network, tc.addr))
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	const statusHead = "Name:\tAdGuardHome\n" +
+		"CapInh:\t0000000000000000\n" +
+		"CapPrm:\t0000000000003400\n"
+
+	testCases := []struct {
+		name       string
+		data       string
+		wantErrMsg string
+		want       Caps
+	}{{
+		name:       "bind_only",
+		data:       statusHead + "CapEff:\t0000000000000400\n",
+		wantErrMsg: "",
+		want:       Caps{NetBindService: true},
+	}, {
+		name:       "all",
+		data:       statusHead + "CapEff:\t000001ffffffffff\n",
+		wantErrMsg: "",
+		want:       Caps{NetBindService: true, NetRaw: true, NetAdmin: true},
+	}, {
+		name:       "raw_and_admin",
+		data:       statusHead + "CapEff:\t0000000000003000\n",
+		wantErrMsg: "",
+		want:       Caps{NetRaw: true, NetAdmin: true},
+	}, {
+		name:       "none",
+		data:       statusHead + "CapEff:\t0000000000000000\n",
+		wantErrMsg: "",
+		want:       Caps{},
+	}, {
+		name: "bad",
+		data: statusHead + "CapEff:\tnope\n",
+		wantErrMsg: `parsing proc/self/status: strconv.ParseUint: ` +
+			`parsing "nope": invalid syntax`,
+		want: Caps{},
+	}, {
+		name:       "missing",
+		data:       statusHead,
+		wantErrMsg: "no effective capabilities in proc/self/status",
+		want:       Caps{},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			substRootDirFS(t, fstest.MapFS{
+				procSelfStatusPath: &fstest.MapFile{Data: []byte(tc.data)},
+			})
+
+			caps, err := Capabilities()
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.want, caps)
+		})
+	}
+}
+
+func TestCanBindPrivilegedPorts_cache(t *testing.T) {
+	newFS := func(capEff string) (fsys fs.FS) {
+		return fstest.MapFS{
+			procSelfStatusPath: &fstest.MapFile{Data: []byte("CapEff:\t" + capEff + nl)},
+		}
+	}
+
+	ResetPrivilegedPortsCache()
+	t.Cleanup(ResetPrivilegedPortsCache)
+
+	substRootDirFS(t, newFS("0000000000000400"))
+
+	can, err := CanBindPrivilegedPorts()
+	require.NoError(t, err)
+	assert.True(t, can)
+
+	substRootDirFS(t, newFS("0000000000000000"))
+
+	can, err = CanBindPrivilegedPorts()
+	require.NoError(t, err)
+	assert.True(t, can)
+
+	ResetPrivilegedPortsCache()
+
+	can, err = CanBindPrivilegedPorts()
+	require.NoError(t, err)
+	assert.False(t, can)
+}
+
+func TestCanUseRawSockets(t *testing.T) {
+	can, err := CanUseRawSockets()
+	require.NoError(t, err)
+
+	caps, err := capabilities()
+	if err != nil {
+		t.Skipf("can't get capabilities: %s", err)
+	}
+
+	assert.Equal(t, caps.NetRaw, can)
+}
+
+func TestCheckPortOnIface(t *testing.T) {
+	lo, err := net.InterfaceByIndex(1)
+	require.NoError(t, err)
+
+	c, err := net.ListenPacket("udp", "127.0.0.1:")
+	require.NoError(t, err)
+
+	ipp := netutil.IPPortFromAddr(c.LocalAddr())
+	require.NotNil(t, ipp)
+
+	err = CheckPortOnIface("udp", lo.Name, ipp.IP, ipp.Port)
+	assert.True(t, IsAddrInUse(err))
+
+	// Close the connection explicitly to check the port once again.
+	err = c.Close()
+	require.NoError(t, err)
+
+	err = CheckPortOnIface("udp", lo.Name, ipp.IP, ipp.Port)
+	assert.NoError(t, err)
+
+	err = CheckPortOnIface("udp", "nonexistent0", ipp.IP, ipp.Port)
+	assert.ErrorIs(t, err, unix.ENODEV)
+}
+
+func TestGatewayInfo_noGateway(t *testing.T) {
+	lo, err := net.InterfaceByIndex(1)
+	require.NoError(t, err)
+
+	ip, mac, err := GatewayInfo(lo.Name)
+	assert.ErrorIs(t, err, ErrNoGateway)
+	assert.Nil(t, ip)
+	assert.Nil(t, mac)
+}
+
+func TestIfaceByName_substituted(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	const ifaceName = "eth9"
+
+	ctx := context.Background()
+	testCases := []struct {
+		call func() (err error)
+		name string
+	}{{
+		call: func() (err error) {
+			_, err = RouterFromRA(ifaceName, time.Millisecond)
+
+			return err
+		},
+		name: "router_from_ra",
+	}, {
+		call: func() (err error) {
+			_, err = detectDHCPServers(ctx, ifaceName)
+
+			return err
+		},
+		name: "detect_dhcp_servers",
+	}, {
+		call: func() (err error) {
+			_, err = AddrInUseOnLAN(ifaceName, netip.MustParseAddr("192.168.1.3"), time.Millisecond)
+
+			return err
+		},
+		name: "addr_in_use_on_lan",
+	}, {
+		call: func() (err error) {
+			_, err = ipv6AddrDetails(ifaceName)
+
+			return err
+		},
+		name: "ipv6_addr_details",
+	}, {
+		call: func() (err error) {
+			_, err = PathMTUToGateway(ctx, ifaceName)
+
+			return err
+		},
+		name: "path_mtu_to_gateway",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, tc.call(), ErrIfaceNotFound)
+		})
+	}
+}
+
+func TestGetValidNetInterfacesForWebWithOpts_kind(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+	substRootDirFS(t, fstest.MapFS{
+		sysClassNetPath + "/eth0/phy80211/name": &fstest.MapFile{},
+	})
+
+	testCases := []struct {
+		name         string
+		wantKind     IfaceKind
+		withStats    bool
+		wantWireless bool
+	}{{
+		name:         "with_stats",
+		wantKind:     IfaceKindWiFi,
+		withStats:    true,
+		wantWireless: true,
+	}, {
+		name:         "without_stats",
+		wantKind:     IfaceKindEthernet,
+		withStats:    false,
+		wantWireless: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ifaces, err := GetValidNetInterfacesForWebWithOpts(NetInterfacesOpts{
+				WithStats: tc.withStats,
+			})
+			require.NoError(t, err)
+
+			var iface *NetInterface
+			for _, i := range ifaces {
+				if i.Name == "eth0" {
+					iface = i
+				}
+			}
+			require.NotNil(t, iface)
+
+			assert.Equal(t, tc.wantKind, iface.Kind)
+			assert.Equal(t, tc.wantWireless, iface.Wireless)
 		})
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/netip"
 	"strings"
 
 	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
@@ -38,6 +39,14 @@ func hostnameIfStaticConfig(r io.Reader) (_ []string, ok bool, err error) {
 	return nil, true, s.Err()
 }
 
-func ifaceSetStaticIP(string) (err error) {
-	return aghos.Unsupported("setting static ip")
+func ifaceSetStaticIP(string) (restore RestoreFunc, err error) {
+	return nil, aghos.Unsupported("setting static ip")
+}
+
+func ifaceSetMTU(string, int) (err error) {
+	return aghos.Unsupported("setting mtu")
+}
+
+func ifaceAddAddr(string, netip.Prefix) (err error) {
+	return aghos.Unsupported("adding address")
 }
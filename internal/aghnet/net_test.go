@@ -1,7 +1,11 @@
 package aghnet
 
 import (
+	"io/fs"
+	"math/rand"
 	"net"
+	"net/netip"
+	"os/exec"
 	"testing"
 
 	"github.com/AdguardTeam/AdGuardHome/internal/aghtest"
@@ -12,9 +16,125 @@ import (
 )
 
 func TestMain(m *testing.M) {
+	SetLogger(DiscardLogger)
+
 	aghtest.DiscardLogOutput(m)
 }
 
+// substNetInterfaces replaces the functions listing the network interfaces and
+// their addresses with the ones returning ifaces and ifaceAddrs for the
+// duration of the test.
+func substNetInterfaces(t testing.TB, ifaces []net.Interface, ifaceAddrs map[string][]net.Addr) {
+	t.Helper()
+
+	prevIfaces, prevAddrs, prevCache := netInterfaces, netInterfaceAddrs, localAddrsCache
+	t.Cleanup(func() {
+		netInterfaces, netInterfaceAddrs, localAddrsCache = prevIfaces, prevAddrs, prevCache
+	})
+
+	localAddrsCache = newLocalAddrsCache()
+
+	netInterfaces = func() (_ []net.Interface, _ error) {
+		return ifaces, nil
+	}
+	netInterfaceAddrs = func(iface *net.Interface) (_ []net.Addr, _ error) {
+		return ifaceAddrs[iface.Name], nil
+	}
+
+	substSecondaryAddrs(t, nil)
+}
+
+// substSecondaryAddrs replaces the function getting the secondary IPv4
+// addresses with the one returning secondary for the duration of the test.
+func substSecondaryAddrs(t testing.TB, secondary map[netip.Addr]int) {
+	t.Helper()
+
+	prev := ifaceSecondaryAddrs
+	t.Cleanup(func() { ifaceSecondaryAddrs = prev })
+
+	ifaceSecondaryAddrs = func() (_ map[netip.Addr]int, _ error) {
+		return secondary, nil
+	}
+}
+
+// substRunCommand replaces the function running shell commands with f for the
+// duration of the test.
+func substRunCommand(t testing.TB, f func(cmd string, args ...string) (code int, out string, err error)) {
+	t.Helper()
+
+	prev := aghosRunCommand
+	t.Cleanup(func() { aghosRunCommand = prev })
+
+	aghosRunCommand = f
+}
+
+// substLookPath replaces the function searching for the executables with f for
+// the duration of the test.
+func substLookPath(t testing.TB, f func(file string) (path string, err error)) {
+	t.Helper()
+
+	prev := execLookPath
+	t.Cleanup(func() { execLookPath = prev })
+
+	execLookPath = f
+}
+
+// substRootDirFS replaces the filesystem pointing to the root directory with
+// fsys for the duration of the test.
+func substRootDirFS(t testing.TB, fsys fs.FS) {
+	t.Helper()
+
+	prev := rootDirFS
+	t.Cleanup(func() { rootDirFS = prev })
+
+	rootDirFS = fsys
+}
+
+// fakeNetIfaces is the common set of network interfaces for tests.
+var fakeNetIfaces = []net.Interface{{
+	Index: 1,
+	MTU:   65536,
+	Name:  "lo",
+	Flags: net.FlagUp | net.FlagLoopback,
+}, {
+	Index:        2,
+	MTU:          1500,
+	Name:         "eth0",
+	HardwareAddr: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+	Flags:        net.FlagUp | net.FlagBroadcast | net.FlagMulticast,
+}, {
+	Index:        3,
+	MTU:          1500,
+	Name:         "eth1",
+	HardwareAddr: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x66},
+	Flags:        net.FlagBroadcast | net.FlagMulticast,
+}}
+
+// fakeNetIfaceAddrs are the addresses of fakeNetIfaces.
+var fakeNetIfaceAddrs = map[string][]net.Addr{
+	"lo": {&net.IPNet{
+		IP:   net.IP{127, 0, 0, 1},
+		Mask: net.CIDRMask(8, netutil.IPv4BitLen),
+	}, &net.IPNet{
+		IP:   net.IPv6loopback,
+		Mask: net.CIDRMask(128, netutil.IPv6BitLen),
+	}},
+	"eth0": {&net.IPNet{
+		IP:   net.IPv4(192, 168, 1, 2),
+		Mask: net.CIDRMask(24, netutil.IPv4BitLen),
+	}, &net.IPNet{
+		IP:   net.ParseIP("2001:db8::2"),
+		Mask: net.CIDRMask(64, netutil.IPv6BitLen),
+	}, &net.IPNet{
+		IP:   net.ParseIP("fe80::211:22ff:fe33:4455"),
+		Mask: net.CIDRMask(64, netutil.IPv6BitLen),
+	}},
+	"eth1": {&net.IPNet{
+		IP:   net.IP{10, 0, 0, 2},
+		Mask: net.CIDRMask(8, netutil.IPv4BitLen),
+	}},
+}
+
 func TestGetValidNetInterfacesForWeb(t *testing.T) {
 	ifaces, err := GetValidNetInterfacesForWeb()
 	require.NoErrorf(t, err, "cannot get net interfaces: %s", err)
@@ -24,67 +144,1534 @@ func TestGetValidNetInterfacesForWeb(t *testing.T) {
 	}
 }
 
-func TestBroadcastFromIPNet(t *testing.T) {
-	known6 := net.IP{
-		1, 2, 3, 4,
-		5, 6, 7, 8,
-		9, 10, 11, 12,
-		13, 14, 15, 16,
+func TestGetValidNetInterfacesForWeb_none(t *testing.T) {
+	substNetInterfaces(t, nil, nil)
+
+	ifaces, err := GetValidNetInterfacesForWeb()
+	assert.ErrorIs(t, err, ErrNoInterfaces)
+
+	assert.NotNil(t, ifaces)
+	assert.Empty(t, ifaces)
+}
+
+func TestGetValidNetInterfacesForWebWithOpts_warnings(t *testing.T) {
+	ifaceAddrs := map[string][]net.Addr{
+		"eth0": fakeNetIfaceAddrs["eth0"],
+		"eth1": {&net.IPNet{
+			IP:   net.IPv4(169, 254, 1, 2),
+			Mask: net.CIDRMask(16, netutil.IPv4BitLen),
+		}},
+	}
+
+	substNetInterfaces(t, fakeNetIfaces, ifaceAddrs)
+
+	t.Run("without_warnings", func(t *testing.T) {
+		ifaces, err := GetValidNetInterfacesForWebWithOpts(NetInterfacesOpts{})
+		require.NoError(t, err)
+		require.Len(t, ifaces, 1)
+
+		assert.Equal(t, "eth0", ifaces[0].Name)
+		assert.Empty(t, ifaces[0].Warnings)
+	})
+
+	t.Run("with_warnings", func(t *testing.T) {
+		ifaces, err := GetValidNetInterfacesForWebWithOpts(NetInterfacesOpts{
+			WithWarnings: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, ifaces, 2)
+
+		assert.Equal(t, "eth0", ifaces[0].Name)
+		assert.Empty(t, ifaces[0].Warnings)
+
+		eth1 := ifaces[1]
+		assert.Equal(t, "eth1", eth1.Name)
+		assert.Empty(t, eth1.Addresses)
+		assert.Equal(t, []string{
+			"self-assigned address 169.254.1.2, dhcp may have failed",
+		}, eth1.Warnings)
+	})
+}
+
+func TestGetValidNetInterfacesForWeb_primaryAddr(t *testing.T) {
+	ifaceAddrs := map[string][]net.Addr{
+		"eth0": {&net.IPNet{
+			IP:   net.ParseIP("2001:db8::2"),
+			Mask: net.CIDRMask(64, netutil.IPv6BitLen),
+		}, &net.IPNet{
+			IP:   net.IPv4(192, 168, 1, 3),
+			Mask: net.CIDRMask(24, netutil.IPv4BitLen),
+		}, &net.IPNet{
+			IP:   net.IPv4(192, 168, 1, 2),
+			Mask: net.CIDRMask(24, netutil.IPv4BitLen),
+		}},
+		"eth1": fakeNetIfaceAddrs["eth1"],
+	}
+
+	substNetInterfaces(t, fakeNetIfaces, ifaceAddrs)
+
+	t.Run("no_distinction", func(t *testing.T) {
+		ifaces, err := GetValidNetInterfacesForWeb()
+		require.NoError(t, err)
+		require.Len(t, ifaces, 2)
+
+		assert.Equal(t, netip.MustParseAddr("192.168.1.3"), ifaces[0].PrimaryAddr)
+		assert.Equal(t, netip.MustParseAddr("10.0.0.2"), ifaces[1].PrimaryAddr)
+	})
+
+	t.Run("alias", func(t *testing.T) {
+		substSecondaryAddrs(t, map[netip.Addr]int{
+			netip.MustParseAddr("192.168.1.3"): 2,
+			// The same address of another interface shouldn't matter.
+			netip.MustParseAddr("10.0.0.2"): 2,
+		})
+
+		ifaces, err := GetValidNetInterfacesForWeb()
+		require.NoError(t, err)
+		require.Len(t, ifaces, 2)
+
+		assert.Equal(t, netip.MustParseAddr("192.168.1.2"), ifaces[0].PrimaryAddr)
+		assert.Len(t, ifaces[0].Addresses, 3)
+		assert.Equal(t, netip.MustParseAddr("10.0.0.2"), ifaces[1].PrimaryAddr)
+	})
+
+	t.Run("no_ipv4", func(t *testing.T) {
+		substNetInterfaces(t, fakeNetIfaces, map[string][]net.Addr{
+			"eth0": fakeNetIfaceAddrs["eth0"][1:],
+		})
+
+		ifaces, err := GetValidNetInterfacesForWeb()
+		require.NoError(t, err)
+		require.Len(t, ifaces, 1)
+
+		assert.False(t, ifaces[0].PrimaryAddr.IsValid())
+	})
+
+	t.Run("single_ipv4", func(t *testing.T) {
+		substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+		prev := ifaceSecondaryAddrs
+		t.Cleanup(func() { ifaceSecondaryAddrs = prev })
+
+		ifaceSecondaryAddrs = func() (_ map[netip.Addr]int, _ error) {
+			t.Error("secondary addresses requested")
+
+			return nil, nil
+		}
+
+		ifaces, err := GetValidNetInterfacesForWeb()
+		require.NoError(t, err)
+		require.Len(t, ifaces, 3)
+
+		assert.Equal(t, netip.MustParseAddr("192.168.1.2"), ifaces[1].PrimaryAddr)
+	})
+}
+
+func TestSortNetInterfaces(t *testing.T) {
+	newIface := func(name string, cidrs ...string) (iface *NetInterface) {
+		iface = &NetInterface{Name: name}
+		for _, c := range cidrs {
+			ip, n, err := net.ParseCIDR(c)
+			require.NoError(t, err)
+
+			n.IP = ip
+			iface.Addresses = append(iface.Addresses, ip)
+			iface.Subnets = append(iface.Subnets, n)
+		}
+
+		return iface
+	}
+
+	ifaces := []*NetInterface{
+		newIface("wlan0", "192.168.2.10/24"),
+		newIface("eth1", "2001:db8::10/64", "10.0.0.2/8", "10.0.0.1/8"),
+		newIface("br0", "172.16.0.1/12"),
+		newIface("eth0", "2001:db8::2/64", "192.168.1.2/24", "2001:db8::1/64"),
+	}
+
+	r := rand.New(rand.NewSource(1))
+	r.Shuffle(len(ifaces), func(i, j int) {
+		ifaces[i], ifaces[j] = ifaces[j], ifaces[i]
+	})
+
+	sortNetInterfaces(ifaces, "eth0")
+
+	names := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+
+	assert.Equal(t, []string{"eth0", "br0", "eth1", "wlan0"}, names)
+
+	ifaceStrings := func(iface *NetInterface) (addrs, subnets []string) {
+		for i, ip := range iface.Addresses {
+			addrs = append(addrs, ip.String())
+			subnets = append(subnets, iface.Subnets[i].String())
+		}
+
+		return addrs, subnets
+	}
+
+	addrs, subnets := ifaceStrings(ifaces[0])
+	assert.Equal(t, []string{"192.168.1.2", "2001:db8::1", "2001:db8::2"}, addrs)
+	assert.Equal(t, []string{"192.168.1.2/24", "2001:db8::1/64", "2001:db8::2/64"}, subnets)
+
+	addrs, subnets = ifaceStrings(ifaces[2])
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "2001:db8::10"}, addrs)
+	assert.Equal(t, []string{"10.0.0.1/8", "10.0.0.2/8", "2001:db8::10/64"}, subnets)
+
+	t.Run("no_default", func(t *testing.T) {
+		sortNetInterfaces(ifaces, "")
+
+		assert.Equal(t, "br0", ifaces[0].Name)
+		assert.Equal(t, "wlan0", ifaces[len(ifaces)-1].Name)
+	})
+}
+
+func TestGetSubnetForFamily(t *testing.T) {
+	ifaces, err := GetValidNetInterfacesForWeb()
+	require.NoError(t, err)
+
+	for _, iface := range ifaces {
+		t.Run(iface.Name, func(t *testing.T) {
+			subnets := GetSubnets(iface.Name)
+			require.Len(t, subnets, len(iface.Subnets))
+
+			if subnet4 := GetSubnet(iface.Name); subnet4 != nil {
+				assert.NotNil(t, subnet4.IP.To4())
+			}
+
+			if subnet6 := GetSubnetForFamily(iface.Name, true); subnet6 != nil {
+				assert.Nil(t, subnet6.IP.To4())
+			}
+		})
+	}
+
+	assert.Nil(t, GetSubnets("non-existent-iface"))
+	assert.Nil(t, GetSubnet("non-existent-iface"))
+}
+
+func TestCollectIfaceAddrs(t *testing.T) {
+	ifaceAddrs, err := CollectIfaceAddrs()
+	require.NoError(t, err)
+	require.NotEmpty(t, ifaceAddrs)
+
+	var total int
+	for name, addrs := range ifaceAddrs {
+		for _, addr := range addrs {
+			assert.Truef(t, addr.IsValid(), "invalid address of %s", name)
+			assert.Falsef(t, addr.Is4In6(), "mapped address %s of %s", addr, name)
+		}
+
+		total += len(addrs)
+	}
+
+	flat, err := CollectAllIfacesAddrs()
+	require.NoError(t, err)
+
+	assert.Len(t, flat, total)
+}
+
+func TestExpandWildcardBind(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	ipv4Addrs := []netip.Addr{
+		netip.MustParseAddr("10.0.0.2"),
+		netip.MustParseAddr("192.168.1.2"),
 	}
 
 	testCases := []struct {
-		name   string
-		subnet *net.IPNet
-		want   net.IP
+		name string
+		bind netip.Addr
+		want []netip.Addr
 	}{{
-		name: "full",
-		subnet: &net.IPNet{
-			IP:   net.IP{192, 168, 0, 1},
-			Mask: net.IPMask{255, 255, 15, 0},
-		},
-		want: net.IP{192, 168, 240, 255},
+		name: "concrete",
+		bind: netip.MustParseAddr("192.168.1.2"),
+		want: []netip.Addr{netip.MustParseAddr("192.168.1.2")},
 	}, {
-		name: "ipv6_no_mask",
-		subnet: &net.IPNet{
-			IP: known6,
-		},
-		want: known6,
+		name: "ipv4_unspecified",
+		bind: netip.IPv4Unspecified(),
+		want: ipv4Addrs,
 	}, {
-		name: "ipv4_no_mask",
-		subnet: &net.IPNet{
-			IP: net.IP{192, 168, 1, 2},
-		},
-		want: net.IP{192, 168, 1, 255},
+		name: "ipv4_mapped_unspecified",
+		bind: netip.MustParseAddr("::ffff:0.0.0.0"),
+		want: ipv4Addrs,
 	}, {
-		name: "unspecified",
-		subnet: &net.IPNet{
-			IP:   net.IP{0, 0, 0, 0},
-			Mask: net.IPMask{0, 0, 0, 0},
-		},
-		want: net.IPv4bcast,
+		name: "ipv6_unspecified",
+		bind: netip.IPv6Unspecified(),
+		want: append(ipv4Addrs,
+			netip.MustParseAddr("2001:db8::2"),
+			netip.MustParseAddr("fe80::211:22ff:fe33:4455%eth0"),
+		),
 	}}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			bc := BroadcastFromIPNet(tc.subnet)
-			assert.True(t, bc.Equal(tc.want), bc)
+			addrs, err := ExpandWildcardBind(tc.bind)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, addrs)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ExpandWildcardBind(netip.Addr{})
+		testutil.AssertErrorMsg(t, "bad bind address invalid IP", err)
+	})
+}
+
+func TestParseNetshDefaultRoutes(t *testing.T) {
+	const nl = "\n"
+
+	const out = nl +
+		`Publish  Type      Met  Prefix                    Idx  Gateway/Interface Name` + nl +
+		`-------  --------  ---  ------------------------  ---  ------------------------` + nl +
+		`No       Manual    512  ::/0                       11  fe80::2` + nl +
+		`No       Manual    256  ::/0                       11  fe80::1` + nl +
+		`No       Manual    256  ::/0                       12  fe80::3` + nl +
+		`No       Manual    many ::/0                       11  fe80::4` + nl +
+		`No       System    256  ::1/128                     1  Loopback Pseudo-Interface 1` + nl +
+		`No       System    256  fe80::/64                  11  Ethernet` + nl
+
+	testCases := []struct {
+		name  string
+		want  []GatewayRoute
+		index int
+	}{{
+		name: "found",
+		want: []GatewayRoute{{
+			Gateway: netip.MustParseAddr("fe80::1"),
+			Metric:  256,
+		}, {
+			Gateway: netip.MustParseAddr("fe80::2"),
+			Metric:  512,
+		}},
+		index: 11,
+	}, {
+		name:  "not_found",
+		want:  nil,
+		index: 1,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, parseNetshDefaultRoutes(out, tc.index))
+		})
+	}
+}
+
+func TestParseDefaultRoutes(t *testing.T) {
+	const nl = "\n"
+
+	testCases := []struct {
+		name string
+		out  string
+		want []GatewayRoute
+	}{{
+		name: "empty",
+		out:  "",
+		want: nil,
+	}, {
+		name: "single_no_metric",
+		out: `default via 192.168.1.1 proto static` + nl +
+			`192.168.1.0/24 proto kernel scope link src 192.168.1.2` + nl,
+		want: []GatewayRoute{{
+			Gateway: netip.MustParseAddr("192.168.1.1"),
+			Metric:  0,
+		}},
+	}, {
+		name: "multiple",
+		out: `default via 192.168.2.1 proto dhcp src 192.168.2.2 metric 600` + nl +
+			`default via 192.168.1.1 proto dhcp metric 100 onlink` + nl +
+			`default dev ppp0 scope link metric 50` + nl +
+			`default via 192.168.3.1 onlink metric 100` + nl +
+			`192.168.1.0/24 proto kernel scope link src 192.168.1.2 metric 100` + nl,
+		want: []GatewayRoute{{
+			Gateway: netip.MustParseAddr("192.168.1.1"),
+			Metric:  100,
+		}, {
+			Gateway: netip.MustParseAddr("192.168.3.1"),
+			Metric:  100,
+		}, {
+			Gateway: netip.MustParseAddr("192.168.2.1"),
+			Metric:  600,
+		}},
+	}, {
+		name: "bad",
+		out: `default via 192.168.1.256` + nl +
+			`default via 192.168.1.1 metric many` + nl,
+		want: nil,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, parseDefaultRoutes(tc.out))
+		})
+	}
+}
+
+func TestAllDefaultGateways(t *testing.T) {
+	substLookPath(t, func(file string) (path string, err error) {
+		return "/usr/bin/" + file, nil
+	})
+
+	var gotArgs []string
+	substRunCommand(t, func(cmd string, args ...string) (code int, out string, err error) {
+		gotArgs = append([]string{cmd}, args...)
+
+		return 0, "default via 192.168.2.1 metric 600\ndefault via 192.168.1.1 metric 100\n", nil
+	})
+
+	routes, err := AllDefaultGateways("eth0")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ip", "route", "show", "dev", "eth0"}, gotArgs)
+	assert.Equal(t, []GatewayRoute{{
+		Gateway: netip.MustParseAddr("192.168.1.1"),
+		Metric:  100,
+	}, {
+		Gateway: netip.MustParseAddr("192.168.2.1"),
+		Metric:  600,
+	}}, routes)
+
+	gw, err := GatewayIPAddr("eth0")
+	require.NoError(t, err)
+
+	assert.Equal(t, netip.MustParseAddr("192.168.1.1"), gw)
+
+	t.Run("exit_code", func(t *testing.T) {
+		substRunCommand(t, func(_ string, _ ...string) (code int, out string, err error) {
+			return 1, "", nil
+		})
+
+		_, err = AllDefaultGateways("eth0")
+		testutil.AssertErrorMsg(t, "executing ip route: unexpected exit code 1", err)
+
+		gw, err = GatewayIPAddr("eth0")
+		require.NoError(t, err)
+
+		assert.Equal(t, netip.Addr{}, gw)
+	})
+
+	t.Run("tool_missing", func(t *testing.T) {
+		substLookPath(t, func(_ string) (path string, err error) {
+			return "", exec.ErrNotFound
+		})
+
+		gw, err = GatewayIPAddr("eth0")
+		assert.ErrorIs(t, err, ErrToolMissing)
+
+		assert.Equal(t, netip.Addr{}, gw)
+	})
+}
+
+func TestLocalAddrSet(t *testing.T) {
+	ifaceAddrs := map[string][]net.Addr{
+		"lo":   fakeNetIfaceAddrs["lo"],
+		"eth0": append(fakeNetIfaceAddrs["eth0"], &net.IPAddr{IP: net.ParseIP("ff02::1")}),
+		"eth1": {&net.IPNet{
+			IP:   net.ParseIP("::ffff:10.0.0.2"),
+			Mask: net.CIDRMask(104, netutil.IPv6BitLen),
+		}, &net.IPNet{
+			IP:   net.ParseIP("fe80::211:22ff:fe33:4455"),
+			Mask: net.CIDRMask(64, netutil.IPv6BitLen),
+		}},
+	}
+
+	substNetInterfaces(t, fakeNetIfaces, ifaceAddrs)
+
+	set, err := LocalAddrSet()
+	require.NoError(t, err)
+
+	assert.Equal(t, map[netip.Addr]struct{}{
+		netip.MustParseAddr("127.0.0.1"):                {},
+		netip.MustParseAddr("::1"):                      {},
+		netip.MustParseAddr("192.168.1.2"):              {},
+		netip.MustParseAddr("2001:db8::2"):              {},
+		netip.MustParseAddr("fe80::211:22ff:fe33:4455"): {},
+		netip.MustParseAddr("10.0.0.2"):                 {},
+	}, set)
+}
+
+func TestIsLocalAddr(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		name string
+		ip   net.IP
+		want bool
+	}{{
+		name: "ipv4",
+		ip:   net.IP{192, 168, 1, 2},
+		want: true,
+	}, {
+		name: "ipv4_mapped",
+		ip:   net.ParseIP("::ffff:192.168.1.2"),
+		want: true,
+	}, {
+		name: "ipv6",
+		ip:   net.ParseIP("2001:db8::2"),
+		want: true,
+	}, {
+		name: "link_local",
+		ip:   net.ParseIP("fe80::211:22ff:fe33:4455"),
+		want: true,
+	}, {
+		name: "unspecified_ipv4",
+		ip:   net.IPv4zero,
+		want: true,
+	}, {
+		name: "unspecified_ipv6",
+		ip:   net.IPv6unspecified,
+		want: true,
+	}, {
+		name: "not_local",
+		ip:   net.IP{192, 168, 1, 3},
+		want: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, err := IsLocalAddr(tc.ip)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, ok)
+		})
+	}
+
+	t.Run("bad_ip", func(t *testing.T) {
+		_, err := IsLocalAddr(net.IP{1, 2, 3})
+		testutil.AssertErrorMsg(t, `bad ip address "?010203"`, err)
+	})
+
+	t.Run("assigned_after_caching", func(t *testing.T) {
+		newIP := net.IP{192, 168, 1, 3}
+
+		prev := netInterfaceAddrs
+		t.Cleanup(func() { netInterfaceAddrs = prev })
+
+		netInterfaceAddrs = func(iface *net.Interface) (addrs []net.Addr, err error) {
+			addrs, err = prev(iface)
+			if iface.Name == "eth0" {
+				addrs = append(addrs, &net.IPNet{
+					IP:   newIP,
+					Mask: net.CIDRMask(24, netutil.IPv4BitLen),
+				})
+			}
+
+			return addrs, err
+		}
+
+		ok, err := IsLocalAddr(newIP)
+		require.NoError(t, err)
+
+		assert.True(t, ok)
+	})
+}
+
+func TestIsSelfAddr(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	v4Wildcard := []netip.AddrPort{netip.MustParseAddrPort("0.0.0.0:53")}
+	v6Wildcard := []netip.AddrPort{netip.MustParseAddrPort("[::]:53")}
+	specified := []netip.AddrPort{
+		netip.MustParseAddrPort("127.0.0.1:53"),
+		netip.MustParseAddrPort("[2001:db8::2]:5353"),
+	}
+
+	testCases := []struct {
+		name      string
+		addr      netip.AddrPort
+		listeners []netip.AddrPort
+		want      bool
+	}{{
+		name:      "v4_wildcard_local",
+		addr:      netip.MustParseAddrPort("192.168.1.2:53"),
+		listeners: v4Wildcard,
+		want:      true,
+	}, {
+		name:      "v4_wildcard_down_iface",
+		addr:      netip.MustParseAddrPort("10.0.0.2:53"),
+		listeners: v4Wildcard,
+		want:      true,
+	}, {
+		name:      "v4_wildcard_loopback",
+		addr:      netip.MustParseAddrPort("127.0.0.53:53"),
+		listeners: v4Wildcard,
+		want:      true,
+	}, {
+		name:      "v4_wildcard_mapped",
+		addr:      netip.MustParseAddrPort("[::ffff:192.168.1.2]:53"),
+		listeners: v4Wildcard,
+		want:      true,
+	}, {
+		name:      "v4_wildcard_other_port",
+		addr:      netip.MustParseAddrPort("192.168.1.2:5353"),
+		listeners: v4Wildcard,
+		want:      false,
+	}, {
+		name:      "v4_wildcard_remote",
+		addr:      netip.MustParseAddrPort("192.168.1.1:53"),
+		listeners: v4Wildcard,
+		want:      false,
+	}, {
+		name:      "v4_wildcard_ipv6",
+		addr:      netip.MustParseAddrPort("[2001:db8::2]:53"),
+		listeners: v4Wildcard,
+		want:      false,
+	}, {
+		name:      "v6_wildcard_ipv6",
+		addr:      netip.MustParseAddrPort("[2001:db8::2]:53"),
+		listeners: v6Wildcard,
+		want:      true,
+	}, {
+		name:      "v6_wildcard_ipv4",
+		addr:      netip.MustParseAddrPort("192.168.1.2:53"),
+		listeners: v6Wildcard,
+		want:      true,
+	}, {
+		name:      "v6_wildcard_unspecified",
+		addr:      netip.MustParseAddrPort("[::]:53"),
+		listeners: v6Wildcard,
+		want:      true,
+	}, {
+		name:      "specified",
+		addr:      netip.MustParseAddrPort("127.0.0.1:53"),
+		listeners: specified,
+		want:      true,
+	}, {
+		name:      "specified_zone",
+		addr:      netip.MustParseAddrPort("[2001:db8::2%eth0]:5353"),
+		listeners: specified,
+		want:      true,
+	}, {
+		name:      "specified_other_addr",
+		addr:      netip.MustParseAddrPort("192.168.1.2:53"),
+		listeners: specified,
+		want:      false,
+	}, {
+		name:      "no_listeners",
+		addr:      netip.MustParseAddrPort("127.0.0.1:53"),
+		listeners: nil,
+		want:      false,
+	}, {
+		name:      "invalid",
+		addr:      netip.AddrPort{},
+		listeners: v4Wildcard,
+		want:      false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsSelfAddr(tc.addr, tc.listeners))
 		})
 	}
 }
 
-func TestCheckPort(t *testing.T) {
-	l, err := net.Listen("tcp", "127.0.0.1:")
+func TestCollectIfaceAddrs_zones(t *testing.T) {
+	ifaces := []net.Interface{{
+		Index: 2,
+		Name:  "eth0",
+		Flags: net.FlagUp | net.FlagMulticast,
+	}, {
+		Index: 3,
+		Name:  "wlan0",
+		Flags: net.FlagUp | net.FlagMulticast,
+	}}
+
+	linkLocal := &net.IPNet{
+		IP:   net.ParseIP("fe80::1"),
+		Mask: net.CIDRMask(64, netutil.IPv6BitLen),
+	}
+	ifaceAddrs := map[string][]net.Addr{
+		"eth0": {linkLocal, &net.IPAddr{IP: net.ParseIP("ff02::1")}, &net.IPNet{
+			IP:   net.ParseIP("2001:db8::2"),
+			Mask: net.CIDRMask(64, netutil.IPv6BitLen),
+		}},
+		"wlan0": {linkLocal, &net.IPNet{
+			IP:   net.IP{192, 168, 1, 2},
+			Mask: net.CIDRMask(24, netutil.IPv4BitLen),
+		}},
+	}
+
+	substNetInterfaces(t, ifaces, ifaceAddrs)
+
+	addrs, err := CollectIfaceAddrs()
 	require.NoError(t, err)
-	testutil.CleanupAndRequireSuccess(t, l.Close)
 
-	ipp := netutil.IPPortFromAddr(l.Addr())
-	require.NotNil(t, ipp)
-	require.NotNil(t, ipp.IP)
-	require.NotZero(t, ipp.Port)
+	assert.Equal(t, map[string][]netip.Addr{
+		"eth0": {
+			netip.MustParseAddr("fe80::1%eth0"),
+			netip.MustParseAddr("ff02::1%eth0"),
+			netip.MustParseAddr("2001:db8::2"),
+		},
+		"wlan0": {
+			netip.MustParseAddr("fe80::1%wlan0"),
+			netip.MustParseAddr("192.168.1.2"),
+		},
+	}, addrs)
+}
+
+func TestCollectAllIfacesAddrsFiltered(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		name string
+		want []string
+		f    IfaceFilter
+	}{{
+		name: "all",
+		want: []string{
+			"192.168.1.2",
+			"2001:db8::2",
+			"fe80::211:22ff:fe33:4455%eth0",
+			"10.0.0.2",
+			"127.0.0.1",
+			"::1",
+		},
+		f: IfaceFilter{},
+	}, {
+		name: "up",
+		want: []string{
+			"192.168.1.2",
+			"2001:db8::2",
+			"fe80::211:22ff:fe33:4455%eth0",
+			"127.0.0.1",
+			"::1",
+		},
+		f: IfaceFilter{Required: net.FlagUp},
+	}, {
+		name: "up_no_loopback",
+		want: []string{
+			"192.168.1.2",
+			"2001:db8::2",
+			"fe80::211:22ff:fe33:4455%eth0",
+		},
+		f: IfaceFilter{Required: net.FlagUp, Excluded: net.FlagLoopback},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addrs, err := CollectAllIfacesAddrsFiltered(tc.f)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, addrs)
+		})
+	}
+}
+
+func TestIfaceHasIP(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	assert.True(t, ifaceHasIP("eth0", net.IP{192, 168, 1, 2}))
+	assert.True(t, ifaceHasIP("eth0", net.ParseIP("2001:db8::2")))
+	assert.False(t, ifaceHasIP("eth0", net.IP{10, 0, 0, 2}))
+	assert.False(t, ifaceHasIP("eth2", net.IP{192, 168, 1, 2}))
+}
+
+func TestBroadcastFromIPNet(t *testing.T) {
+	known6 := net.IP{
+		1, 2, 3, 4,
+		5, 6, 7, 8,
+		9, 10, 11, 12,
+		13, 14, 15, 16,
+	}
+
+	testCases := []struct {
+		name   string
+		subnet *net.IPNet
+		want   net.IP
+	}{{
+		name: "full",
+		subnet: &net.IPNet{
+			IP:   net.IP{192, 168, 0, 1},
+			Mask: net.IPMask{255, 255, 15, 0},
+		},
+		want: net.IP{192, 168, 240, 255},
+	}, {
+		name: "ipv6_no_mask",
+		subnet: &net.IPNet{
+			IP: known6,
+		},
+		want: known6,
+	}, {
+		name: "ipv4_no_mask",
+		subnet: &net.IPNet{
+			IP: net.IP{192, 168, 1, 2},
+		},
+		want: net.IP{192, 168, 1, 255},
+	}, {
+		name: "unspecified",
+		subnet: &net.IPNet{
+			IP:   net.IP{0, 0, 0, 0},
+			Mask: net.IPMask{0, 0, 0, 0},
+		},
+		want: net.IPv4bcast,
+	}, {
+		name: "ipv4_16_bytes",
+		subnet: &net.IPNet{
+			IP:   net.IPv4(192, 168, 1, 2),
+			Mask: net.CIDRMask(24, netutil.IPv4BitLen),
+		},
+		want: net.IP{192, 168, 1, 255},
+	}, {
+		name: "ipv4_16_bytes_mask",
+		subnet: &net.IPNet{
+			IP:   net.IP{10, 0, 0, 2},
+			Mask: net.CIDRMask(104, netutil.IPv6BitLen),
+		},
+		want: net.IP{10, 255, 255, 255},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bc := BroadcastFromIPNet(tc.subnet)
+			assert.True(t, bc.Equal(tc.want), bc)
+		})
+	}
+}
+
+func TestInterfaceBroadcasts(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		name       string
+		ifaceName  string
+		wantErrMsg string
+		want       []net.IP
+	}{{
+		name:       "ipv4_and_ipv6",
+		ifaceName:  "eth0",
+		wantErrMsg: "",
+		want:       []net.IP{{192, 168, 1, 255}},
+	}, {
+		name:       "ipv4",
+		ifaceName:  "eth1",
+		wantErrMsg: "",
+		want:       []net.IP{{10, 255, 255, 255}},
+	}, {
+		name:       "not_found",
+		ifaceName:  "eth2",
+		wantErrMsg: `getting broadcasts of "eth2": ` + string(ErrIfaceNotFound),
+		want:       nil,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bcs, err := InterfaceBroadcasts(tc.ifaceName)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.want, bcs)
+		})
+	}
+
+	t.Run("no_ipv4", func(t *testing.T) {
+		substNetInterfaces(t, fakeNetIfaces, map[string][]net.Addr{
+			"eth0": {&net.IPNet{
+				IP:   net.ParseIP("2001:db8::2"),
+				Mask: net.CIDRMask(64, netutil.IPv6BitLen),
+			}},
+		})
+
+		bcs, err := InterfaceBroadcasts("eth0")
+		require.NoError(t, err)
+
+		assert.NotNil(t, bcs)
+		assert.Empty(t, bcs)
+	})
+}
+
+func TestResolveIfaceName(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		wantErr error
+		name    string
+		in      string
+		want    string
+	}{{
+		wantErr: nil,
+		name:    "name",
+		in:      "eth1",
+		want:    "eth1",
+	}, {
+		wantErr: nil,
+		name:    "mac",
+		in:      "00:11:22:33:44:66",
+		want:    "eth1",
+	}, {
+		wantErr: nil,
+		name:    "mac_dashes",
+		in:      "00-11-22-33-44-55",
+		want:    "eth0",
+	}, {
+		wantErr: nil,
+		name:    "mac_no_separators",
+		in:      "001122334455",
+		want:    "eth0",
+	}, {
+		wantErr: ErrIfaceNotFound,
+		name:    "unknown_mac",
+		in:      "00:11:22:33:44:77",
+		want:    "",
+	}, {
+		wantErr: ErrIfaceNotFound,
+		name:    "unknown_name",
+		in:      "wlan0",
+		want:    "",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, err := ResolveIfaceName(tc.in)
+			assert.ErrorIs(t, err, tc.wantErr)
+
+			assert.Equal(t, tc.want, name)
+		})
+	}
+}
+
+func TestSubnetIsLocal(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		name     string
+		prefix   netip.Prefix
+		wantName string
+		wantOK   bool
+	}{{
+		name:     "ipv4",
+		prefix:   netip.MustParsePrefix("192.168.1.0/24"),
+		wantName: "eth0",
+		wantOK:   true,
+	}, {
+		name:     "ipv4_mapped",
+		prefix:   netip.MustParsePrefix("::ffff:192.168.0.0/112"),
+		wantName: "eth0",
+		wantOK:   true,
+	}, {
+		name:     "ipv4_supernet",
+		prefix:   netip.MustParsePrefix("192.168.0.0/16"),
+		wantName: "eth0",
+		wantOK:   true,
+	}, {
+		name:     "down",
+		prefix:   netip.MustParsePrefix("10.0.0.0/8"),
+		wantName: "",
+		wantOK:   false,
+	}, {
+		name:     "loopback",
+		prefix:   netip.MustParsePrefix("127.0.0.0/8"),
+		wantName: "",
+		wantOK:   false,
+	}, {
+		name:     "ipv6",
+		prefix:   netip.MustParsePrefix("2001:db8::/64"),
+		wantName: "eth0",
+		wantOK:   true,
+	}, {
+		name:     "not_local",
+		prefix:   netip.MustParsePrefix("192.168.2.0/24"),
+		wantName: "",
+		wantOK:   false,
+	}, {
+		name:     "link_local",
+		prefix:   netip.MustParsePrefix("fe80::/64"),
+		wantName: "",
+		wantOK:   false,
+	}, {
+		name:     "invalid",
+		prefix:   netip.Prefix{},
+		wantName: "",
+		wantOK:   false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, ok := SubnetIsLocal(tc.prefix)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantName, name)
+		})
+	}
+}
+
+func TestIfaceForBindAddr(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		wantErr error
+		name    string
+		want    string
+		bind    netip.Addr
+	}{{
+		wantErr: nil,
+		name:    "ipv4",
+		want:    "eth0",
+		bind:    netip.MustParseAddr("192.168.1.2"),
+	}, {
+		wantErr: nil,
+		name:    "ipv4_mapped",
+		want:    "eth1",
+		bind:    netip.MustParseAddr("::ffff:10.0.0.2"),
+	}, {
+		wantErr: nil,
+		name:    "ipv6",
+		want:    "eth0",
+		bind:    netip.MustParseAddr("2001:db8::2"),
+	}, {
+		wantErr: nil,
+		name:    "scoped",
+		want:    "eth0",
+		bind:    netip.MustParseAddr("fe80::211:22ff:fe33:4455%eth0"),
+	}, {
+		wantErr: ErrWildcardBind,
+		name:    "wildcard_ipv4",
+		want:    "",
+		bind:    netip.IPv4Unspecified(),
+	}, {
+		wantErr: ErrWildcardBind,
+		name:    "wildcard_ipv6",
+		want:    "",
+		bind:    netip.IPv6Unspecified(),
+	}, {
+		wantErr: ErrWildcardBind,
+		name:    "wildcard_ipv4_mapped",
+		want:    "",
+		bind:    netip.MustParseAddr("::ffff:0.0.0.0"),
+	}, {
+		wantErr: ErrIfaceNotFound,
+		name:    "not_found",
+		want:    "",
+		bind:    netip.MustParseAddr("192.168.1.3"),
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, err := IfaceForBindAddr(tc.bind)
+			assert.ErrorIs(t, err, tc.wantErr)
+
+			assert.Equal(t, tc.want, name)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := IfaceForBindAddr(netip.Addr{})
+		testutil.AssertErrorMsg(t, "bad bind address invalid IP", err)
+	})
+}
+
+func TestGetInterfaceByAddr(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		name string
+		addr netip.Addr
+		want string
+	}{{
+		name: "ipv4",
+		addr: netip.MustParseAddr("192.168.1.2"),
+		want: "eth0",
+	}, {
+		name: "ipv4_mapped",
+		addr: netip.MustParseAddr("::ffff:192.168.1.2"),
+		want: "eth0",
+	}, {
+		name: "ipv4_mapped_eth1",
+		addr: netip.MustParseAddr("::ffff:10.0.0.2"),
+		want: "eth1",
+	}, {
+		name: "ipv6",
+		addr: netip.MustParseAddr("::1"),
+		want: "lo",
+	}, {
+		name: "link_local",
+		addr: netip.MustParseAddr("fe80::211:22ff:fe33:4455"),
+		want: "",
+	}, {
+		name: "not_found",
+		addr: netip.MustParseAddr("192.168.1.3"),
+		want: "",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, GetInterfaceByAddr(tc.addr))
+			assert.Equal(t, tc.want, GetInterfaceByIP(tc.addr.AsSlice()))
+		})
+	}
+}
+
+func TestGetInterfaceByIP(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		name string
+		want string
+		ip   net.IP
+	}{{
+		name: "ipv4",
+		want: "eth0",
+		ip:   net.IP{192, 168, 1, 2},
+	}, {
+		name: "ipv4_16_bytes",
+		want: "eth0",
+		ip:   net.IPv4(192, 168, 1, 2),
+	}, {
+		name: "ipv4_mapped",
+		want: "eth0",
+		ip:   net.ParseIP("::ffff:192.168.1.2"),
+	}, {
+		name: "ipv6",
+		want: "eth0",
+		ip:   net.ParseIP("2001:db8::2"),
+	}, {
+		name: "bad_ip",
+		want: "",
+		ip:   net.IP{1, 2, 3},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, GetInterfaceByIP(tc.ip))
+		})
+	}
+}
+
+func TestSplitHost(t *testing.T) {
+	testCases := []struct {
+		name       string
+		in         string
+		wantHost   string
+		wantErrMsg string
+	}{{
+		name:       "ipv6_loopback",
+		in:         "::1",
+		wantHost:   "::1",
+		wantErrMsg: "",
+	}, {
+		name:       "ipv6",
+		in:         "2001:db8::1",
+		wantHost:   "2001:db8::1",
+		wantErrMsg: "",
+	}, {
+		name:       "ipv6_zoned",
+		in:         "fe80::1%eth0",
+		wantHost:   "fe80::1%eth0",
+		wantErrMsg: "",
+	}, {
+		name:       "ipv6_zoned_port",
+		in:         "[fe80::1%eth0]:53",
+		wantHost:   "fe80::1%eth0",
+		wantErrMsg: "",
+	}, {
+		name:       "ipv6_port",
+		in:         "[::1]:53",
+		wantHost:   "::1",
+		wantErrMsg: "",
+	}, {
+		name:       "host",
+		in:         "example.com",
+		wantHost:   "example.com",
+		wantErrMsg: "",
+	}, {
+		name:       "host_port",
+		in:         "example.com:53",
+		wantHost:   "example.com",
+		wantErrMsg: "",
+	}, {
+		name:       "too_many_colons",
+		in:         "example.com:1:2",
+		wantHost:   "",
+		wantErrMsg: "address example.com:1:2: too many colons in address",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			host, err := SplitHost(tc.in)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.wantHost, host)
+		})
+	}
+}
+
+func TestSplitHostPortDefault(t *testing.T) {
+	const defPort = 53
+
+	testCases := []struct {
+		name       string
+		in         string
+		wantHost   string
+		wantErrMsg string
+		wantPort   int
+	}{{
+		name:       "host",
+		in:         "example.com",
+		wantHost:   "example.com",
+		wantErrMsg: "",
+		wantPort:   defPort,
+	}, {
+		name:       "host_port",
+		in:         "example.com:853",
+		wantHost:   "example.com",
+		wantErrMsg: "",
+		wantPort:   853,
+	}, {
+		name:       "ipv4_port",
+		in:         "1.2.3.4:5353",
+		wantHost:   "1.2.3.4",
+		wantErrMsg: "",
+		wantPort:   5353,
+	}, {
+		name:       "ipv6_bare",
+		in:         "::1",
+		wantHost:   "::1",
+		wantErrMsg: "",
+		wantPort:   defPort,
+	}, {
+		name:       "ipv6_brackets",
+		in:         "[::1]",
+		wantHost:   "::1",
+		wantErrMsg: "",
+		wantPort:   defPort,
+	}, {
+		name:       "ipv6_port",
+		in:         "[::1]:5353",
+		wantHost:   "::1",
+		wantErrMsg: "",
+		wantPort:   5353,
+	}, {
+		name:       "bad_port",
+		in:         "example.com:dns",
+		wantHost:   "",
+		wantErrMsg: `bad port "dns" in address "example.com:dns"`,
+		wantPort:   0,
+	}, {
+		name:       "port_too_big",
+		in:         "example.com:65536",
+		wantHost:   "",
+		wantErrMsg: `bad port in address "example.com:65536": port 65536 is out of range [1, 65535]`,
+		wantPort:   0,
+	}, {
+		name:       "too_many_colons",
+		in:         "example.com:1:2",
+		wantHost:   "",
+		wantErrMsg: "address example.com:1:2: too many colons in address",
+		wantPort:   0,
+	}, {
+		name:       "empty",
+		in:         "",
+		wantHost:   "",
+		wantErrMsg: `empty host in address ""`,
+		wantPort:   0,
+	}, {
+		name:       "empty_host",
+		in:         ":853",
+		wantHost:   "",
+		wantErrMsg: `empty host in address ":853"`,
+		wantPort:   0,
+	}, {
+		name:       "empty_brackets",
+		in:         "[]",
+		wantHost:   "",
+		wantErrMsg: `empty host in address "[]"`,
+		wantPort:   0,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			host, port, err := SplitHostPortDefault(tc.in, defPort)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.wantHost, host)
+			assert.Equal(t, tc.wantPort, port)
+		})
+	}
+}
+
+func TestOutboundIP(t *testing.T) {
+	t.Run("loopback", func(t *testing.T) {
+		loopback := netip.MustParseAddr("127.0.0.1")
+
+		src, err := OutboundIP(loopback)
+		require.NoError(t, err)
+
+		assert.Equal(t, loopback, src)
+	})
+
+	t.Run("mapped", func(t *testing.T) {
+		src, err := OutboundIP(netip.MustParseAddr("::ffff:127.0.0.1"))
+		require.NoError(t, err)
+
+		assert.Equal(t, netip.MustParseAddr("127.0.0.1"), src)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := OutboundIP(netip.Addr{})
+		testutil.AssertErrorMsg(t, "bad destination address invalid IP", err)
+	})
+}
+
+func TestIfaceSetMTU_range(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		name       string
+		ifaceName  string
+		wantErrMsg string
+		mtu        int
+	}{{
+		name:       "too_small",
+		ifaceName:  "eth1",
+		wantErrMsg: "mtu 575 is out of range [576, 9000]",
+		mtu:        575,
+	}, {
+		name:       "too_big",
+		ifaceName:  "eth1",
+		wantErrMsg: "mtu 9001 is out of range [576, 9000]",
+		mtu:        9001,
+	}, {
+		name:       "negative",
+		ifaceName:  "eth1",
+		wantErrMsg: "mtu -1 is out of range [576, 9000]",
+		mtu:        -1,
+	}, {
+		name:       "too_small_ipv6",
+		ifaceName:  "eth0",
+		wantErrMsg: "mtu 1279 is out of range [1280, 9000]",
+		mtu:        1279,
+	}, {
+		name:       "ipv4_min_ipv6",
+		ifaceName:  "eth0",
+		wantErrMsg: "mtu 576 is out of range [1280, 9000]",
+		mtu:        576,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := IfaceSetMTU(tc.ifaceName, tc.mtu)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+		})
+	}
+}
+
+func TestIfaceEnsureAddr_present(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		name   string
+		prefix netip.Prefix
+	}{{
+		name:   "ipv4",
+		prefix: netip.MustParsePrefix("192.168.1.2/24"),
+	}, {
+		name:   "ipv6",
+		prefix: netip.MustParsePrefix("2001:db8::2/64"),
+	}, {
+		name:   "other_bits",
+		prefix: netip.MustParsePrefix("192.168.1.2/32"),
+	}, {
+		name:   "link_local",
+		prefix: netip.MustParsePrefix("fe80::211:22ff:fe33:4455/64"),
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			added, err := IfaceEnsureAddr("eth0", tc.prefix)
+			require.NoError(t, err)
+
+			assert.False(t, added)
+		})
+	}
+
+	t.Run("bad_prefix", func(t *testing.T) {
+		added, err := IfaceEnsureAddr("eth0", netip.Prefix{})
+		testutil.AssertErrorMsg(t, "bad prefix invalid Prefix", err)
+
+		assert.False(t, added)
+	})
+}
+
+func TestIfaceIndex(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	index, err := IfaceIndex("eth0")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, index)
+
+	_, err = IfaceIndex("eth2")
+	assert.ErrorIs(t, err, ErrIfaceNotFound)
+	testutil.AssertErrorMsg(t, `getting index of "eth2": network interface not found`, err)
+}
+
+func TestIfaceName(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	name, err := IfaceName(3)
+	require.NoError(t, err)
+
+	assert.Equal(t, "eth1", name)
+
+	_, err = IfaceName(4)
+	assert.ErrorIs(t, err, ErrIfaceNotFound)
+	testutil.AssertErrorMsg(t, "getting name of interface 4: network interface not found", err)
+}
+
+func TestGetValidNetInterfacesForWeb_index(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	ifaces, err := GetValidNetInterfacesForWeb()
+	require.NoError(t, err)
+
+	for _, iface := range ifaces {
+		index, idxErr := IfaceIndex(iface.Name)
+		require.NoError(t, idxErr)
+
+		assert.Equal(t, index, iface.Index)
+	}
+}
+
+func TestSameSubnet(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		name     string
+		ip       netip.Addr
+		wantName string
+		wantOK   bool
+	}{{
+		name:     "ipv4",
+		ip:       netip.MustParseAddr("192.168.1.100"),
+		wantName: "eth0",
+		wantOK:   true,
+	}, {
+		name:     "ipv4_mapped",
+		ip:       netip.MustParseAddr("::ffff:10.1.2.3"),
+		wantName: "eth1",
+		wantOK:   true,
+	}, {
+		name:     "ipv6",
+		ip:       netip.MustParseAddr("2001:db8::100"),
+		wantName: "eth0",
+		wantOK:   true,
+	}, {
+		name:     "loopback",
+		ip:       netip.MustParseAddr("127.0.0.1"),
+		wantName: "",
+		wantOK:   false,
+	}, {
+		name:     "unspecified",
+		ip:       netip.IPv6Unspecified(),
+		wantName: "",
+		wantOK:   false,
+	}, {
+		name:     "other",
+		ip:       netip.MustParseAddr("172.16.0.1"),
+		wantName: "",
+		wantOK:   false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, ok := SameSubnet(tc.ip)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantName, name)
+		})
+	}
+}
+
+func TestReplySrcAddr(t *testing.T) {
+	substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+	testCases := []struct {
+		name       string
+		dst        netip.Addr
+		want       netip.Addr
+		wantErrMsg string
+	}{{
+		name:       "lan_ipv4",
+		dst:        netip.MustParseAddr("192.168.1.100"),
+		want:       netip.MustParseAddr("192.168.1.2"),
+		wantErrMsg: "",
+	}, {
+		name:       "lan_ipv4_mapped",
+		dst:        netip.MustParseAddr("::ffff:192.168.1.100"),
+		want:       netip.MustParseAddr("192.168.1.2"),
+		wantErrMsg: "",
+	}, {
+		name:       "lan_ipv6",
+		dst:        netip.MustParseAddr("2001:db8::100"),
+		want:       netip.MustParseAddr("2001:db8::2"),
+		wantErrMsg: "",
+	}, {
+		// The loopback interface is never directly connected, so the route
+		// is used.
+		name:       "off_subnet",
+		dst:        netip.MustParseAddr("127.0.0.2"),
+		want:       netip.MustParseAddr("127.0.0.1"),
+		wantErrMsg: "",
+	}, {
+		name:       "bad",
+		dst:        netip.Addr{},
+		want:       netip.Addr{},
+		wantErrMsg: "bad destination address invalid IP",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src, err := ReplySrcAddr(tc.dst)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.want, src)
+		})
+	}
+}
+
+func TestCanonicalIP(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   net.IP
+		want net.IP
+	}{{
+		name: "ipv4",
+		in:   net.IP{1, 2, 3, 4},
+		want: net.IP{1, 2, 3, 4},
+	}, {
+		name: "ipv4_16",
+		in:   net.IPv4(1, 2, 3, 4),
+		want: net.IP{1, 2, 3, 4},
+	}, {
+		name: "mapped",
+		in:   net.ParseIP("::ffff:1.2.3.4"),
+		want: net.IP{1, 2, 3, 4},
+	}, {
+		name: "ipv6",
+		in:   net.ParseIP("2001:db8::1"),
+		want: net.ParseIP("2001:db8::1"),
+	}, {
+		name: "bad",
+		in:   net.IP{1, 2, 3},
+		want: nil,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, CanonicalIP(tc.in))
+		})
+	}
+
+	assert.Equal(t, CanonicalIP(net.ParseIP("::ffff:1.2.3.4")), CanonicalIP(net.ParseIP("1.2.3.4")))
+}
+
+func TestCanonicalAddr(t *testing.T) {
+	mapped := netip.MustParseAddr("::ffff:1.2.3.4")
+	plain := netip.MustParseAddr("1.2.3.4")
 
-	err = CheckPort("tcp", ipp.IP, ipp.Port)
-	target := &net.OpError{}
-	require.ErrorAs(t, err, &target)
+	assert.True(t, plain != mapped)
+	assert.True(t, plain == CanonicalAddr(mapped))
+	assert.True(t, CanonicalAddr(plain) == CanonicalAddr(mapped))
 
-	assert.Equal(t, "listen", target.Op)
+	ip6 := netip.MustParseAddr("2001:db8::1")
+	assert.Equal(t, ip6, CanonicalAddr(ip6))
 }
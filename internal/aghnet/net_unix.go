@@ -4,10 +4,12 @@
 package aghnet
 
 import (
+	"fmt"
 	"io"
 	"syscall"
 
 	"github.com/AdguardTeam/golibs/errors"
+	"golang.org/x/sys/unix"
 )
 
 // closePortChecker closes c.  c must be non-nil.
@@ -18,3 +20,56 @@ func closePortChecker(c io.Closer) (err error) {
 func isAddrInUse(err syscall.Errno) (ok bool) {
 	return errors.Is(err, syscall.EADDRINUSE)
 }
+
+func isAddrPermDenied(err syscall.Errno) (ok bool) {
+	return errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EPERM)
+}
+
+func isConnRefused(err syscall.Errno) (ok bool) {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func isNoRoute(err syscall.Errno) (ok bool) {
+	return errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH)
+}
+
+// reusePortControl sets the SO_REUSEADDR and the SO_REUSEPORT options on the
+// socket.  It's intended to be used as net.ListenConfig.Control.
+func reusePortControl(_, _ string, c syscall.RawConn) (err error) {
+	var opErr error
+	err = c.Control(func(fd uintptr) {
+		opErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+		if opErr != nil {
+			opErr = fmt.Errorf("setting SO_REUSEADDR: %w", opErr)
+
+			return
+		}
+
+		opErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+		if opErr != nil {
+			opErr = fmt.Errorf("setting SO_REUSEPORT: %w", opErr)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return opErr
+}
+
+// v6OnlyByDefault returns true if the IPV6_V6ONLY option is set on the new
+// IPv6 sockets by default, so that those don't accept the IPv4 connections.
+func v6OnlyByDefault() (ok bool, err error) {
+	fd, err := unix.Socket(unix.AF_INET6, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return false, fmt.Errorf("opening ipv6 socket: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, unix.Close(fd)) }()
+
+	v, err := unix.GetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_V6ONLY)
+	if err != nil {
+		return false, fmt.Errorf("getting IPV6_V6ONLY: %w", err)
+	}
+
+	return v != 0, nil
+}
@@ -0,0 +1,85 @@
+//go:build openbsd || freebsd || linux || darwin
+// +build openbsd freebsd linux darwin
+
+package aghnet
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/netutil"
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPortWithOpts_reusePort(t *testing.T) {
+	lc := &net.ListenConfig{Control: reusePortControl}
+
+	c, err := lc.ListenPacket(context.Background(), "udp", "127.0.0.1:")
+	require.NoError(t, err)
+	testutil.CleanupAndRequireSuccess(t, c.Close)
+
+	ipp := netutil.IPPortFromAddr(c.LocalAddr())
+	require.NotNil(t, ipp)
+
+	err = CheckPortWithOpts("udp", ipp.IP, ipp.Port, CheckPortOpts{ReusePort: true})
+	assert.NoError(t, err)
+
+	err = CheckPortWithOpts("udp", ipp.IP, ipp.Port, CheckPortOpts{ReusePort: false})
+	assert.True(t, IsAddrInUse(err))
+}
+
+func TestIsAddrPermDenied(t *testing.T) {
+	testCases := []struct {
+		err  error
+		name string
+		want bool
+	}{{
+		err: &net.OpError{
+			Op:  "listen",
+			Net: "tcp",
+			Err: os.NewSyscallError("bind", syscall.EACCES),
+		},
+		name: "eacces",
+		want: true,
+	}, {
+		err: &net.OpError{
+			Op:  "listen",
+			Net: "udp",
+			Err: os.NewSyscallError("bind", syscall.EPERM),
+		},
+		name: "eperm",
+		want: true,
+	}, {
+		err:  os.NewSyscallError("bind", syscall.EACCES),
+		name: "syscall_error",
+		want: true,
+	}, {
+		err: &net.OpError{
+			Op:  "listen",
+			Net: "tcp",
+			Err: os.NewSyscallError("bind", syscall.EADDRINUSE),
+		},
+		name: "addr_in_use",
+		want: false,
+	}, {
+		err:  errors.Error("test error"),
+		name: "not_syscall",
+		want: false,
+	}, {
+		err:  nil,
+		name: "nil",
+		want: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsAddrPermDenied(tc.err))
+		})
+	}
+}
@@ -4,7 +4,10 @@
 package aghnet
 
 import (
+	"fmt"
 	"io"
+	"net/netip"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,8 +24,65 @@ func ifaceHasStaticIP(string) (ok bool, err error) {
 	return false, aghos.Unsupported("checking static ip")
 }
 
-func ifaceSetStaticIP(string) (err error) {
-	return aghos.Unsupported("setting static ip")
+func ifaceSetStaticIP(string) (restore RestoreFunc, err error) {
+	return nil, aghos.Unsupported("setting static ip")
+}
+
+// ifaceSetMTU sets the MTU of both IPv4 and IPv6 subinterfaces of the network
+// interface persistently using netsh.  The IPv4 MTU is rolled back if setting
+// the IPv6 one fails.
+func ifaceSetMTU(ifaceName string, mtu int) (err error) {
+	if tmErr := lookTool("netsh", "mtu configuration"); tmErr != nil {
+		return tmErr
+	}
+
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return err
+	}
+
+	err = netshSetMTU(ifaceName, "ipv4", mtu)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return err
+	}
+
+	err = netshSetMTU(ifaceName, "ipv6", mtu)
+	if err != nil {
+		restoreErr := netshSetMTU(ifaceName, "ipv4", iface.MTU)
+
+		return errors.WithDeferred(err, errors.Annotate(restoreErr, "restoring ipv4 mtu: %w"))
+	}
+
+	return nil
+}
+
+// netshSetMTU sets the MTU of the subinterface of family persistently using
+// netsh.
+func netshSetMTU(ifaceName, family string, mtu int) (err error) {
+	code, out, err := aghosRunCommand(
+		"netsh",
+		"interface",
+		family,
+		"set",
+		"subinterface",
+		ifaceName,
+		fmt.Sprintf("mtu=%d", mtu),
+		"store=persistent",
+	)
+	if err != nil {
+		return fmt.Errorf("running netsh for %s: %w", family, err)
+	} else if code != 0 {
+		return fmt.Errorf(
+			"netsh for %s finished with code %d: %s",
+			family,
+			code,
+			strings.TrimSpace(out),
+		)
+	}
+
+	return nil
 }
 
 // closePortChecker closes c.  c must be non-nil.
@@ -44,3 +104,31 @@ func closePortChecker(c io.Closer) (err error) {
 func isAddrInUse(err syscall.Errno) (ok bool) {
 	return errors.Is(err, windows.WSAEADDRINUSE)
 }
+
+func isAddrPermDenied(err syscall.Errno) (ok bool) {
+	return errors.Is(err, windows.WSAEACCES)
+}
+
+func isConnRefused(err syscall.Errno) (ok bool) {
+	return errors.Is(err, windows.WSAECONNREFUSED)
+}
+
+func isNoRoute(err syscall.Errno) (ok bool) {
+	return errors.Is(err, windows.WSAEHOSTUNREACH) || errors.Is(err, windows.WSAENETUNREACH)
+}
+
+// reusePortControl does nothing, since Windows doesn't support SO_REUSEPORT,
+// and SO_REUSEADDR has different semantics there.
+func reusePortControl(_, _ string, _ syscall.RawConn) (err error) {
+	return nil
+}
+
+// v6OnlyByDefault returns true, since Windows sets the IPV6_V6ONLY option on
+// the new IPv6 sockets by default.
+func v6OnlyByDefault() (ok bool, err error) {
+	return true, nil
+}
+
+func ifaceAddAddr(string, netip.Prefix) (err error) {
+	return aghos.Unsupported("adding address")
+}
@@ -0,0 +1,119 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"sync"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// netlinkDial is the function to connect to the kernel's routing subsystem.
+var netlinkDial = func() (conn *netlink.Conn, err error) {
+	return netlink.Dial(unix.NETLINK_ROUTE, nil)
+}
+
+// routeConn is a lazily-established connection to the kernel's routing
+// subsystem shared between the route and address queries, so that the
+// frequent callers, like the interface-polling web page, don't open a socket
+// per query.  The interface watcher uses its own connection, since it
+// subscribes to the multicast groups.
+type routeConn struct {
+	// mu protects conn and serializes the requests.
+	mu *sync.Mutex
+
+	// conn is the current connection.  It's nil until the first request and
+	// after any failure.
+	conn *netlink.Conn
+}
+
+// sharedRouteConn is the connection used by netlinkRouteDump and
+// netlinkRouteRequest.
+var sharedRouteConn = &routeConn{
+	mu: &sync.Mutex{},
+}
+
+// execute sends msg and returns the replies.  The connection is established if
+// there isn't one yet.  Any failure closes the connection, since the
+// unconsumed replies of the failed request could confuse the following ones, so
+// the next request starts over with a fresh one.  It's safe for concurrent use.
+func (rc *routeConn) execute(msg netlink.Message) (msgs []netlink.Message, err error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.conn == nil {
+		rc.conn, err = netlinkDial()
+		if err != nil {
+			// Don't wrap the error, because it's informative enough as is.
+			return nil, err
+		}
+	}
+
+	msgs, err = rc.conn.Execute(msg)
+	if err != nil {
+		return nil, errors.WithDeferred(err, rc.closeLocked())
+	}
+
+	return msgs, nil
+}
+
+// close closes the current connection, if any.  It's safe for concurrent use.
+func (rc *routeConn) close() (err error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return rc.closeLocked()
+}
+
+// closeLocked closes the current connection, if any.  rc.mu is expected to be
+// locked.
+func (rc *routeConn) closeLocked() (err error) {
+	if rc.conn == nil {
+		return nil
+	}
+
+	err = rc.conn.Close()
+	rc.conn = nil
+
+	return err
+}
+
+// CloseNetlink closes the netlink connection shared by the functions querying
+// the network configuration.  The connection is established again on the next
+// query, so it's safe to call at any time, e.g. on shutdown or after each test.
+func CloseNetlink() (err error) {
+	return sharedRouteConn.close()
+}
+
+// netlinkRouteDump sends the dump request of typ with data to the kernel's
+// routing subsystem and returns the replies.
+func netlinkRouteDump(typ netlink.HeaderType, data []byte) (msgs []netlink.Message, err error) {
+	return sharedRouteConn.execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  typ,
+			Flags: netlink.Request | netlink.Dump,
+		},
+		Data: data,
+	})
+}
+
+// netlinkRouteRequest sends the request of typ with data and the additional
+// flags to the kernel's routing subsystem and waits for the acknowledgement.
+func netlinkRouteRequest(
+	typ netlink.HeaderType,
+	flags netlink.HeaderFlags,
+	data []byte,
+) (err error) {
+	_, err = sharedRouteConn.execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  typ,
+			Flags: netlink.Request | netlink.Acknowledge | flags,
+		},
+		Data: data,
+	})
+
+	return err
+}
@@ -0,0 +1,104 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// substNetlinkDial replaces the function connecting to the kernel's routing
+// subsystem with the one returning the fake connections handling the requests
+// with f for the duration of the test.  dials is incremented on each
+// connection.
+func substNetlinkDial(t testing.TB, f nltest.Func) (dials *int) {
+	t.Helper()
+
+	prev := netlinkDial
+	t.Cleanup(func() {
+		netlinkDial = prev
+		require.NoError(t, CloseNetlink())
+	})
+
+	require.NoError(t, CloseNetlink())
+
+	dials = new(int)
+	netlinkDial = func() (conn *netlink.Conn, err error) {
+		*dials++
+
+		return nltest.Dial(f), nil
+	}
+
+	return dials
+}
+
+func TestRouteConn(t *testing.T) {
+	const errTest errors.Error = "test error"
+
+	dials := substNetlinkDial(t, func(req []netlink.Message) (resp []netlink.Message, err error) {
+		if req[0].Header.Type == unix.RTM_DELROUTE {
+			return nil, errTest
+		}
+
+		return []netlink.Message{{
+			Header: netlink.Header{
+				Type:     unix.RTM_NEWROUTE,
+				Sequence: req[0].Header.Sequence,
+				PID:      req[0].Header.PID,
+			},
+			Data: req[0].Data,
+		}}, nil
+	})
+
+	msgs, err := netlinkRouteDump(unix.RTM_GETROUTE, []byte{1, 2, 3, 4})
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, []byte{1, 2, 3, 4}, msgs[0].Data)
+
+	t.Run("concurrent", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_, dumpErr := netlinkRouteDump(unix.RTM_GETROUTE, nil)
+				assert.NoError(t, dumpErr)
+			}()
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, 1, *dials)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		err = netlinkRouteRequest(unix.RTM_DELROUTE, 0, nil)
+		assert.ErrorIs(t, err, errTest)
+
+		_, err = netlinkRouteDump(unix.RTM_GETROUTE, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, *dials)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		// Closing is idempotent.
+		require.NoError(t, CloseNetlink())
+		require.NoError(t, CloseNetlink())
+
+		_, err = netlinkRouteDump(unix.RTM_GETROUTE, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, *dials)
+	})
+}
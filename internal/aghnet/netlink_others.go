@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+// CloseNetlink does nothing, since netlink is only used on Linux.
+func CloseNetlink() (err error) {
+	return nil
+}
@@ -0,0 +1,260 @@
+package aghnet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ProbeUnavailableError is returned by PathMTUToGateway along with the MTU of
+// the network interface when the path MTU can't be probed.
+type ProbeUnavailableError struct {
+	// Err is the underlying error.
+	Err error
+}
+
+// type check
+var _ error = (*ProbeUnavailableError)(nil)
+
+// Error implements the error interface for *ProbeUnavailableError.
+func (err *ProbeUnavailableError) Error() (msg string) {
+	return fmt.Sprintf("path mtu probe unavailable: %s", err.Err)
+}
+
+// type check
+var _ errors.Wrapper = (*ProbeUnavailableError)(nil)
+
+// Unwrap implements the errors.Wrapper interface for *ProbeUnavailableError.
+func (err *ProbeUnavailableError) Unwrap() (unwrapped error) {
+	return err.Err
+}
+
+// errNoProbeReply is returned by searchPathMTU when even the smallest probe
+// hasn't got through.
+const errNoProbeReply errors.Error = "no probe replies"
+
+// The minimum MTUs every link of the path must support, see RFC 791 and
+// RFC 8200.
+const (
+	minPathMTUv4 = 576
+	minPathMTUv6 = 1280
+)
+
+// Lengths of the headers preceding the payload of an ICMP echo request.
+const (
+	echoHdrLenV4 = 20 + 8
+	echoHdrLenV6 = 40 + 8
+)
+
+// defaultPathMTUTimeout is the time PathMTUToGateway spends probing if ctx
+// has no deadline.
+const defaultPathMTUTimeout = 10 * time.Second
+
+// pathMTUProbeTimeout is the time a single probe waits for the reply.  Since
+// the probes which don't get through are only detected by the timeout, it's
+// kept short.
+const pathMTUProbeTimeout = 500 * time.Millisecond
+
+// PathMTUToGateway returns the largest packet size which gets through to the
+// gateway of the network interface named ifaceName, as returned by
+// GatewayIPAddr.  The ICMP echo requests with the Don't Fragment bit set are
+// sent, starting with the MTU of the interface and then decreasing the size, so
+// the result is less than the interface MTU if there is an MTU black hole on
+// the link, e.g. with PPPoE or VPN.  If ctx has no deadline, the probing is
+// limited to ten seconds.
+//
+// If the OS doesn't permit or support such probes, the interface MTU is
+// returned along with a *ProbeUnavailableError.  err is ErrNoGateway if no
+// gateway is known, *ToolMissingError if it can't be detected, and
+// *GatewayUnreachableError if even the smallest probe hasn't got through.
+func PathMTUToGateway(ctx context.Context, ifaceName string) (mtu int, err error) {
+	defer func() { err = errors.Annotate(err, "probing path mtu of %s: %w", ifaceName) }()
+
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return 0, err
+	}
+
+	gw, err := GatewayIPAddr(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return 0, err
+	} else if !gw.IsValid() {
+		return 0, ErrNoGateway
+	}
+
+	if gw.IsLinkLocalUnicast() {
+		gw = gw.WithZone(ifaceName)
+	}
+
+	c, err := listenDontFragment(gw.Is6())
+	if err != nil {
+		return iface.MTU, &ProbeUnavailableError{Err: err}
+	}
+	defer func() { err = errors.WithDeferred(err, c.Close()) }()
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultPathMTUTimeout)
+		defer cancel()
+	}
+
+	p := newMTUProber(c, gw)
+	mtu, err = searchPathMTU(ctx, iface.MTU, p.minMTU, p.probe)
+	if errors.Is(err, errNoProbeReply) {
+		return 0, &GatewayUnreachableError{
+			Err:     err,
+			Gateway: gw,
+		}
+	}
+
+	return mtu, err
+}
+
+// probeFunc returns true if a probe of size bytes long has got through.
+type probeFunc func(ctx context.Context, size int) (ok bool, err error)
+
+// searchPathMTU returns the largest size within [minMTU, maxMTU] for which
+// probe reports success.  maxMTU is tried first, since usually there is no
+// black hole, then the size decreases as in the binary search.  err is
+// errNoProbeReply if the minMTU probe fails.
+func searchPathMTU(
+	ctx context.Context,
+	maxMTU int,
+	minMTU int,
+	probe probeFunc,
+) (mtu int, err error) {
+	ok, err := probe(ctx, maxMTU)
+	if err != nil || ok {
+		return maxMTU, err
+	} else if maxMTU <= minMTU {
+		return 0, errNoProbeReply
+	}
+
+	ok, err = probe(ctx, minMTU)
+	if err != nil {
+		return 0, err
+	} else if !ok {
+		return 0, errNoProbeReply
+	}
+
+	// Invariant: the lo probe has got through, the hi one hasn't.
+	lo, hi := minMTU, maxMTU
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		ok, err = probe(ctx, mid)
+		if err != nil {
+			return 0, err
+		} else if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, nil
+}
+
+// mtuProber sends the ICMP echo requests of the specified sizes with the Don't
+// Fragment bit set.
+type mtuProber struct {
+	conn      net.PacketConn
+	addr      netip.Addr
+	reqType   icmp.Type
+	replyType icmp.Type
+	proto     int
+	hdrLen    int
+	minMTU    int
+	seq       int
+}
+
+// newMTUProber returns a new properly initialized *mtuProber which sends the
+// probes to addr through c.
+func newMTUProber(c net.PacketConn, addr netip.Addr) (p *mtuProber) {
+	if addr.Is6() {
+		return &mtuProber{
+			conn:      c,
+			addr:      addr,
+			reqType:   ipv6.ICMPTypeEchoRequest,
+			replyType: ipv6.ICMPTypeEchoReply,
+			proto:     icmpv6Proto,
+			hdrLen:    echoHdrLenV6,
+			minMTU:    minPathMTUv6,
+		}
+	}
+
+	return &mtuProber{
+		conn:      c,
+		addr:      addr,
+		reqType:   ipv4.ICMPTypeEcho,
+		replyType: ipv4.ICMPTypeEchoReply,
+		proto:     1,
+		hdrLen:    echoHdrLenV4,
+		minMTU:    minPathMTUv4,
+	}
+}
+
+// probe implements the probeFunc for *mtuProber.  Each probe has its own
+// sequence number, so that the late replies to the previous ones are ignored.
+func (p *mtuProber) probe(ctx context.Context, size int) (ok bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return false, err
+	}
+
+	err = p.send(ctx, size)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return false, err
+	}
+
+	for {
+		err = readEchoReply(p.conn, p.addr, p.proto, p.replyType, p.seq)
+		if err == nil {
+			return true, nil
+		} else if errors.Is(err, os.ErrDeadlineExceeded) {
+			return false, nil
+		} else if !errors.Is(err, errNotEchoReply) {
+			return false, err
+		}
+	}
+}
+
+// send sends the next echo request of size bytes and sets the deadline for
+// the reply.
+func (p *mtuProber) send(ctx context.Context, size int) (err error) {
+	deadline := time.Now().Add(pathMTUProbeTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	if err = p.conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("setting deadline: %w", err)
+	}
+
+	p.seq++
+	msg := &icmp.Message{
+		Type: p.reqType,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: p.seq, Data: make([]byte, size-p.hdrLen)},
+	}
+
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return fmt.Errorf("encoding: %w", err)
+	}
+
+	_, err = p.conn.WriteTo(b, &net.UDPAddr{IP: p.addr.AsSlice(), Zone: p.addr.Zone()})
+	if err != nil {
+		return fmt.Errorf("sending probe of %d bytes: %w", size, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"net"
+	"os"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"golang.org/x/sys/unix"
+)
+
+// listenDontFragment opens an unprivileged ICMP datagram socket which sets the
+// Don't Fragment bit and ignores the cached path MTU, so that the probes larger
+// than the path MTU are dropped instead of being fragmented or rejected
+// locally.
+func listenDontFragment(v6 bool) (c net.PacketConn, err error) {
+	family, proto := unix.AF_INET, unix.IPPROTO_ICMP
+	level, opt, val := unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_PROBE
+	var sa unix.Sockaddr = &unix.SockaddrInet4{}
+	if v6 {
+		family, proto = unix.AF_INET6, unix.IPPROTO_ICMPV6
+		level, opt, val = unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_PROBE
+		sa = &unix.SockaddrInet6{}
+	}
+
+	fd, err := unix.Socket(family, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	// net.FilePacketConn duplicates the descriptor, so the file is closed in
+	// any case.
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer func() { err = errors.WithDeferred(err, f.Close()) }()
+
+	err = unix.SetsockoptInt(fd, level, opt, val)
+	if err != nil {
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+
+	err = unix.Bind(fd, sa)
+	if err != nil {
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	return net.FilePacketConn(f)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import (
+	"net"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+)
+
+func listenDontFragment(_ bool) (c net.PacketConn, err error) {
+	return nil, aghos.Unsupported("probing path mtu")
+}
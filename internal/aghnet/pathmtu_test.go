@@ -0,0 +1,87 @@
+package aghnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchPathMTU(t *testing.T) {
+	const testErr errors.Error = "test error"
+
+	newPathProbe := func(pathMTU int) (probe probeFunc, sizes *[]int) {
+		sizes = &[]int{}
+
+		return func(_ context.Context, size int) (ok bool, err error) {
+			*sizes = append(*sizes, size)
+
+			return size <= pathMTU, nil
+		}, sizes
+	}
+
+	testCases := []struct {
+		name       string
+		wantErrMsg string
+		pathMTU    int
+		ifaceMTU   int
+		want       int
+	}{{
+		name:       "no_black_hole",
+		wantErrMsg: "",
+		pathMTU:    1500,
+		ifaceMTU:   1500,
+		want:       1500,
+	}, {
+		name:       "pppoe",
+		wantErrMsg: "",
+		pathMTU:    1492,
+		ifaceMTU:   1500,
+		want:       1492,
+	}, {
+		name:       "jumbo",
+		wantErrMsg: "",
+		pathMTU:    1500,
+		ifaceMTU:   9000,
+		want:       1500,
+	}, {
+		name:       "minimum",
+		wantErrMsg: "",
+		pathMTU:    minPathMTUv4,
+		ifaceMTU:   1500,
+		want:       minPathMTUv4,
+	}, {
+		name:       "unreachable",
+		wantErrMsg: string(errNoProbeReply),
+		pathMTU:    0,
+		ifaceMTU:   1500,
+		want:       0,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			probe, sizes := newPathProbe(tc.pathMTU)
+
+			mtu, err := searchPathMTU(context.Background(), tc.ifaceMTU, minPathMTUv4, probe)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.want, mtu)
+			assert.Equal(t, tc.ifaceMTU, (*sizes)[0])
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		probe := func(_ context.Context, size int) (ok bool, err error) {
+			if size < 1500 {
+				return false, testErr
+			}
+
+			return false, nil
+		}
+
+		_, err := searchPathMTU(context.Background(), 1500, minPathMTUv4, probe)
+		assert.ErrorIs(t, err, testErr)
+	})
+}
@@ -0,0 +1,219 @@
+package aghnet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ErrNoGateway is returned by PingGateway and GatewayInfo when the network
+// interface has no known gateway.
+const ErrNoGateway errors.Error = "no gateway known"
+
+// GatewayUnreachableError is returned by PingGateway when the gateway hasn't
+// responded.
+type GatewayUnreachableError struct {
+	// Err is the underlying error.
+	Err error
+
+	// Gateway is the address of the gateway.
+	Gateway netip.Addr
+}
+
+// type check
+var _ error = (*GatewayUnreachableError)(nil)
+
+// Error implements the error interface for *GatewayUnreachableError.
+func (err *GatewayUnreachableError) Error() (msg string) {
+	return fmt.Sprintf("gateway %s is unreachable: %s", err.Gateway, err.Err)
+}
+
+// type check
+var _ errors.Wrapper = (*GatewayUnreachableError)(nil)
+
+// Unwrap implements the errors.Wrapper interface for *GatewayUnreachableError.
+func (err *GatewayUnreachableError) Unwrap() (unwrapped error) {
+	return err.Err
+}
+
+// defaultPingTimeout is the time PingGateway waits for the response if ctx has
+// no deadline.
+const defaultPingTimeout = 3 * time.Second
+
+// tcpProbePort is the port PingGateway connects to when ICMP isn't permitted.
+// It's the discard port, which is usually closed, so the gateway is expected to
+// respond with a reset.
+const tcpProbePort = 9
+
+// echoData is the payload of the ICMP echo requests.
+var echoData = []byte("AdGuardHome")
+
+// PingGateway sends an ICMP echo request to the gateway of the network
+// interface named ifaceName, as returned by GatewayIPAddr, and returns the
+// round-trip time.  The unprivileged ICMP datagram sockets are used, so if the
+// OS doesn't permit those, e.g. because of the net.ipv4.ping_group_range sysctl
+// on Linux, a TCP connection to a closed port of the gateway is attempted
+// instead and the reset is accepted as the response.  If ctx has no deadline,
+// the response is awaited for a few seconds.  err is ErrNoGateway if no gateway
+// is known, *ToolMissingError if it can't be detected, and
+// *GatewayUnreachableError if the gateway hasn't responded.
+func PingGateway(ctx context.Context, ifaceName string) (rtt time.Duration, err error) {
+	defer func() { err = errors.Annotate(err, "pinging gateway of %s: %w", ifaceName) }()
+
+	gw, err := GatewayIPAddr(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return 0, err
+	} else if !gw.IsValid() {
+		return 0, ErrNoGateway
+	}
+
+	if gw.IsLinkLocalUnicast() {
+		gw = gw.WithZone(ifaceName)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultPingTimeout)
+		defer cancel()
+	}
+
+	rtt, err = pingAddr(ctx, gw)
+	if err != nil {
+		return 0, &GatewayUnreachableError{
+			Err:     err,
+			Gateway: gw,
+		}
+	}
+
+	return rtt, nil
+}
+
+// pingAddr returns the round-trip time to addr measured using ICMP or, if it
+// isn't permitted, using TCP.
+func pingAddr(ctx context.Context, addr netip.Addr) (rtt time.Duration, err error) {
+	rtt, err = pingICMP(ctx, addr)
+	if !errors.Is(err, os.ErrPermission) && !errors.Is(err, syscall.EPROTONOSUPPORT) {
+		return rtt, err
+	}
+
+	loggerFromContext(ctx).Debug("pinging %s: %s; trying tcp", addr, err)
+
+	return pingTCP(ctx, addr)
+}
+
+// pingICMP sends an ICMP echo request to addr through an unprivileged ICMP
+// datagram socket and waits for the reply until the deadline of ctx.
+func pingICMP(ctx context.Context, addr netip.Addr) (rtt time.Duration, err error) {
+	network, laddr, proto := "udp4", "0.0.0.0", 1
+	var reqType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if addr.Is6() {
+		network, laddr, proto = "udp6", "::", icmpv6Proto
+		reqType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	c, err := icmp.ListenPacket(network, laddr)
+	if err != nil {
+		return 0, fmt.Errorf("listening: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, c.Close()) }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = c.SetDeadline(deadline); err != nil {
+			return 0, fmt.Errorf("setting deadline: %w", err)
+		}
+	}
+
+	// The identifier is overwritten by the kernel for the datagram sockets, so
+	// only the sequence number is checked.
+	const seq = 1
+	msg := &icmp.Message{
+		Type: reqType,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: seq, Data: echoData},
+	}
+
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, fmt.Errorf("encoding: %w", err)
+	}
+
+	start := time.Now()
+	_, err = c.WriteTo(b, &net.UDPAddr{IP: addr.AsSlice(), Zone: addr.Zone()})
+	if err != nil {
+		return 0, fmt.Errorf("sending echo request: %w", err)
+	}
+
+	for {
+		err = readEchoReply(c, addr, proto, replyType, seq)
+		if err == nil {
+			return time.Since(start), nil
+		} else if !errors.Is(err, errNotEchoReply) {
+			return 0, err
+		}
+	}
+}
+
+// errNotEchoReply is returned by readEchoReply when the received packet isn't
+// the expected echo reply.
+const errNotEchoReply errors.Error = "not an echo reply"
+
+// readEchoReply reads a single packet from c and returns errNotEchoReply if
+// it's not the echo reply of replyType with seq from addr.
+func readEchoReply(
+	c net.PacketConn,
+	addr netip.Addr,
+	proto int,
+	replyType icmp.Type,
+	seq int,
+) (err error) {
+	buf := make([]byte, 1500)
+	n, peer, err := c.ReadFrom(buf)
+	if err != nil {
+		return fmt.Errorf("reading: %w", err)
+	}
+
+	udpAddr, ok := peer.(*net.UDPAddr)
+	if !ok || !udpAddr.IP.Equal(addr.AsSlice()) {
+		return errNotEchoReply
+	}
+
+	msg, err := icmp.ParseMessage(proto, buf[:n])
+	if err != nil || msg.Type != replyType {
+		return errNotEchoReply
+	}
+
+	echo, ok := msg.Body.(*icmp.Echo)
+	if !ok || echo.Seq != seq {
+		return errNotEchoReply
+	}
+
+	return nil
+}
+
+// pingTCP connects to the tcpProbePort of addr and returns the time it took to
+// either establish the connection or to get it refused.
+func pingTCP(ctx context.Context, addr netip.Addr) (rtt time.Duration, err error) {
+	d := &net.Dialer{}
+
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", netip.AddrPortFrom(addr, tcpProbePort).String())
+	rtt = time.Since(start)
+	if err == nil {
+		return rtt, conn.Close()
+	} else if errors.Is(err, syscall.ECONNREFUSED) {
+		// The reset means that the host is reachable.
+		return rtt, nil
+	}
+
+	// Don't wrap the error, because it's informative enough as is.
+	return 0, err
+}
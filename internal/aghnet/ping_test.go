@@ -0,0 +1,47 @@
+package aghnet
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingAddr(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	rtt, err := pingAddr(ctx, netip.MustParseAddr("127.0.0.1"))
+	require.NoError(t, err)
+
+	assert.Positive(t, rtt)
+}
+
+func TestPingTCP(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	// The discard port is expected to be closed on the loopback.
+	rtt, err := pingTCP(ctx, netip.MustParseAddr("127.0.0.1"))
+	require.NoError(t, err)
+
+	assert.Positive(t, rtt)
+}
+
+func TestGatewayUnreachableError(t *testing.T) {
+	var err error = &GatewayUnreachableError{
+		Err:     net.ErrClosed,
+		Gateway: netip.MustParseAddr("192.168.1.1"),
+	}
+
+	assert.Equal(t, "gateway 192.168.1.1 is unreachable: "+net.ErrClosed.Error(), err.Error())
+	assert.ErrorIs(t, err, net.ErrClosed)
+
+	unreachErr := &GatewayUnreachableError{}
+	assert.True(t, errors.As(err, &unreachErr))
+}
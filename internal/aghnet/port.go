@@ -0,0 +1,287 @@
+package aghnet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/netutil"
+)
+
+// ErrPrivilegedPort is returned by ValidatePortForBind when the port requires
+// the privileges the process doesn't have.
+const ErrPrivilegedPort errors.Error = "port requires elevated privileges"
+
+// maxPrivilegedPort is the greatest port number which only the privileged
+// processes can bind to.
+const maxPrivilegedPort = 1023
+
+// ValidatePort returns an error if port isn't a valid port number to listen on
+// or to connect to, i.e. it's outside of the range from 1 to 65535.
+func ValidatePort(port int) (err error) {
+	if port < 1 || port > math.MaxUint16 {
+		return fmt.Errorf("port %d is out of range [1, %d]", port, math.MaxUint16)
+	}
+
+	return nil
+}
+
+// ValidatePortForBind is like ValidatePort but also returns an error wrapping
+// ErrPrivilegedPort if port is a privileged one and the process can't bind to
+// those, see CanBindPrivilegedPorts.  privileged should be true if the process
+// is known to have the privileges, in which case the check is skipped.  If the
+// privileges can't be checked, the port is considered valid.
+func ValidatePortForBind(port int, privileged bool) (err error) {
+	err = ValidatePort(port)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return err
+	}
+
+	if privileged || port > maxPrivilegedPort {
+		return nil
+	}
+
+	can, err := CanBindPrivilegedPorts()
+	if err != nil {
+		currentLogger().Debug("validating port %d: %s", port, err)
+
+		return nil
+	} else if !can {
+		return fmt.Errorf("port %d: %w", port, ErrPrivilegedPort)
+	}
+
+	return nil
+}
+
+// ErrUnknownService is returned by LookupPort when the service is neither known
+// to AdGuard Home nor listed in the system services database.  The actual
+// error is always *UnknownServiceError.
+const ErrUnknownService errors.Error = "unknown service"
+
+// UnknownServiceError is returned by LookupPort when the service is neither
+// known to AdGuard Home nor listed in the system services database.
+type UnknownServiceError struct {
+	// Err is the error returned by net.LookupPort.
+	Err error
+
+	// Network is the network the service has been looked up for.
+	Network string
+
+	// Service is the name of the service.
+	Service string
+}
+
+// type check
+var _ error = (*UnknownServiceError)(nil)
+
+// Error implements the error interface for *UnknownServiceError.
+func (err *UnknownServiceError) Error() (msg string) {
+	return fmt.Sprintf(
+		"%s %q for network %q: %s",
+		ErrUnknownService,
+		err.Service,
+		err.Network,
+		err.Err,
+	)
+}
+
+// Is implements the interface used by errors.Is for *UnknownServiceError.  It
+// returns true if target is ErrUnknownService.
+func (err *UnknownServiceError) Is(target error) (ok bool) {
+	return target == ErrUnknownService
+}
+
+// Unwrap implements the errors.Wrapper interface for *UnknownServiceError.
+func (err *UnknownServiceError) Unwrap() (unwrapped error) {
+	return err.Err
+}
+
+// servicePorts are the default ports of the DNS services which the system
+// services database usually doesn't list.
+var servicePorts = map[string]int{
+	"doh": 443,
+	"doq": 853,
+	"dot": 853,
+}
+
+// LookupPort returns the port number of the service for network, which must be
+// one of the networks supported by net.LookupPort.  service may also be
+// a decimal port number, which must be valid, see ValidatePort.  The
+// DNS-over-TLS, DNS-over-QUIC, and DNS-over-HTTPS services, named "dot",
+// "doq", and "doh" respectively, are recognized case-insensitively regardless
+// of network.  Other names are looked up using net.LookupPort, and if that
+// fails, the error is *UnknownServiceError.
+func LookupPort(network, service string) (port int, err error) {
+	port, ok := servicePorts[strings.ToLower(service)]
+	if ok {
+		return port, nil
+	}
+
+	port, err = strconv.Atoi(service)
+	if err == nil {
+		err = ValidatePort(port)
+		if err != nil {
+			// Don't wrap the error, because it's informative enough as is.
+			return 0, err
+		}
+
+		return port, nil
+	}
+
+	port, err = net.LookupPort(network, service)
+	if err != nil {
+		return 0, &UnknownServiceError{
+			Err:     err,
+			Network: network,
+			Service: service,
+		}
+	}
+
+	return port, nil
+}
+
+// CheckPort checks if the port is available for binding.  network is expected
+// to be one of "udp" and "tcp".  The listener is closed right after a
+// successful bind, and the errors of closing it are only logged.
+func CheckPort(network string, ip net.IP, port int) (err error) {
+	return CheckPortWithOpts(network, ip, port, CheckPortOpts{})
+}
+
+// CheckPortOpts are the options for CheckPortWithOpts.
+type CheckPortOpts struct {
+	// ReusePort, if true, makes the check set the SO_REUSEADDR and the
+	// SO_REUSEPORT options on the socket before binding, the same way the
+	// listeners sharing the port do.  It's ignored on Windows.
+	ReusePort bool
+}
+
+// CheckPortWithOpts is like CheckPort but allows to configure the socket
+// before binding.
+func CheckPortWithOpts(network string, ip net.IP, port int, opts CheckPortOpts) (err error) {
+	lc := &net.ListenConfig{}
+	if opts.ReusePort {
+		lc.Control = reusePortControl
+	}
+
+	return checkPort(lc, network, ip, port)
+}
+
+// CheckPortOnIface is like CheckPort but binds the socket to the network
+// interface with ifaceName first, the same way the interface-bound listeners
+// do, so that the port isn't reported as busy if it's only used on the other
+// interfaces.  It's only supported on Linux, where it sets SO_BINDTODEVICE.
+func CheckPortOnIface(network, ifaceName string, ip net.IP, port int) (err error) {
+	lc := &net.ListenConfig{}
+	lc.Control, err = bindToDeviceControl(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return err
+	}
+
+	return checkPort(lc, network, ip, port)
+}
+
+// checkPort checks if the port is available for binding using lc.
+func checkPort(lc *net.ListenConfig, network string, ip net.IP, port int) (err error) {
+	var c io.Closer
+	addr := netutil.IPPort{IP: ip, Port: port}.String()
+	switch network {
+	case "tcp":
+		c, err = lc.Listen(context.Background(), network, addr)
+	case "udp":
+		c, err = lc.ListenPacket(context.Background(), network, addr)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// The port is available since the bind has succeeded, so only log the
+	// closing errors.
+	err = closePortChecker(c)
+	if err != nil {
+		currentLogger().Debug("closing %s port checker for %s: %s", network, addr, err)
+	}
+
+	return nil
+}
+
+// Default intervals for WaitPortFreeWithOpts.
+const (
+	defaultWaitPortIvl    = 10 * time.Millisecond
+	defaultWaitPortMaxIvl = 1 * time.Second
+)
+
+// WaitPortOpts are the options for WaitPortFreeWithOpts.
+type WaitPortOpts struct {
+	// Interval is the initial interval between the checks.  It's doubled
+	// after each unsuccessful check until it reaches MaxInterval.  If it's
+	// zero, 10 milliseconds are used.
+	Interval time.Duration
+
+	// MaxInterval is the maximum interval between the checks.  If it's zero,
+	// 1 second is used.
+	MaxInterval time.Duration
+
+	// CheckPortOpts are the options for each check.
+	CheckPortOpts CheckPortOpts
+}
+
+// WaitPortFree waits until the port is available for binding or until ctx is
+// done, whichever happens first.  In the latter case, the error of the last
+// check is returned.
+func WaitPortFree(ctx context.Context, network string, ip net.IP, port int) (err error) {
+	return WaitPortFreeWithOpts(ctx, network, ip, port, WaitPortOpts{})
+}
+
+// WaitPortFreeWithOpts is like WaitPortFree but allows to configure the checks.
+func WaitPortFreeWithOpts(
+	ctx context.Context,
+	network string,
+	ip net.IP,
+	port int,
+	opts WaitPortOpts,
+) (err error) {
+	ivl, maxIvl := opts.Interval, opts.MaxInterval
+	if ivl <= 0 {
+		ivl = defaultWaitPortIvl
+	}
+
+	if maxIvl <= 0 {
+		maxIvl = defaultWaitPortMaxIvl
+	}
+
+	t := time.NewTimer(ivl)
+	defer t.Stop()
+
+	for {
+		err = CheckPortWithOpts(network, ip, port, opts.CheckPortOpts)
+		if err == nil {
+			return nil
+		}
+
+		loggerFromContext(ctx).Debug("waiting %s for %s port %d: %s", ivl, network, port, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-t.C:
+			// Go on.
+		}
+
+		if ivl *= 2; ivl > maxIvl {
+			ivl = maxIvl
+		}
+
+		t.Reset(ivl)
+	}
+}
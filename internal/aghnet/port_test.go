@@ -0,0 +1,272 @@
+package aghnet
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/netutil"
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupPort(t *testing.T) {
+	testCases := []struct {
+		name     string
+		network  string
+		service  string
+		wantPort int
+		wantErr  bool
+	}{{
+		name:     "stdlib_domain",
+		network:  "udp",
+		service:  "domain",
+		wantPort: 53,
+		wantErr:  false,
+	}, {
+		name:     "stdlib_https",
+		network:  "tcp",
+		service:  "https",
+		wantPort: 443,
+		wantErr:  false,
+	}, {
+		name:     "number",
+		network:  "tcp",
+		service:  "8080",
+		wantPort: 8080,
+		wantErr:  false,
+	}, {
+		name:     "dot",
+		network:  "tcp",
+		service:  "dot",
+		wantPort: 853,
+		wantErr:  false,
+	}, {
+		name:     "doq",
+		network:  "udp",
+		service:  "doq",
+		wantPort: 853,
+		wantErr:  false,
+	}, {
+		name:     "doh_upper",
+		network:  "tcp",
+		service:  "DoH",
+		wantPort: 443,
+		wantErr:  false,
+	}, {
+		name:     "unknown",
+		network:  "tcp",
+		service:  "no-such-service",
+		wantPort: 0,
+		wantErr:  true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			port, err := LookupPort(tc.network, tc.service)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrUnknownService)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.wantPort, port)
+		})
+	}
+
+	t.Run("out_of_range", func(t *testing.T) {
+		port, err := LookupPort("tcp", "65536")
+		testutil.AssertErrorMsg(t, "port 65536 is out of range [1, 65535]", err)
+
+		assert.NotErrorIs(t, err, ErrUnknownService)
+		assert.Zero(t, port)
+	})
+
+	t.Run("unknown_wrapped", func(t *testing.T) {
+		_, err := LookupPort("tcp", "no-such-service")
+
+		usErr := &UnknownServiceError{}
+		require.ErrorAs(t, err, &usErr)
+
+		assert.Equal(t, "no-such-service", usErr.Service)
+		assert.Equal(t, "tcp", usErr.Network)
+		assert.Error(t, errors.Unwrap(usErr))
+	})
+}
+
+func TestValidatePort(t *testing.T) {
+	testCases := []struct {
+		name       string
+		wantErrMsg string
+		port       int
+	}{{
+		name:       "valid",
+		wantErrMsg: "",
+		port:       53,
+	}, {
+		name:       "max",
+		wantErrMsg: "",
+		port:       65535,
+	}, {
+		name:       "zero",
+		wantErrMsg: "port 0 is out of range [1, 65535]",
+		port:       0,
+	}, {
+		name:       "negative",
+		wantErrMsg: "port -1 is out of range [1, 65535]",
+		port:       -1,
+	}, {
+		name:       "too_big",
+		wantErrMsg: "port 65536 is out of range [1, 65535]",
+		port:       65536,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, ValidatePort(tc.port))
+		})
+	}
+}
+
+func TestValidatePortForBind(t *testing.T) {
+	t.Cleanup(ResetPrivilegedPortsCache)
+
+	// Pretend the check has already been performed.
+	setCanBind := func(can bool) {
+		privPortsCache.mu.Lock()
+		defer privPortsCache.mu.Unlock()
+
+		privPortsCache.can, privPortsCache.ok = can, true
+	}
+
+	t.Run("unprivileged", func(t *testing.T) {
+		setCanBind(false)
+
+		assert.ErrorIs(t, ValidatePortForBind(53, false), ErrPrivilegedPort)
+		assert.ErrorIs(t, ValidatePortForBind(1023, false), ErrPrivilegedPort)
+		assert.NoError(t, ValidatePortForBind(1024, false))
+		assert.NoError(t, ValidatePortForBind(53, true))
+	})
+
+	t.Run("can_bind", func(t *testing.T) {
+		setCanBind(true)
+
+		assert.NoError(t, ValidatePortForBind(53, false))
+	})
+
+	t.Run("bad_port", func(t *testing.T) {
+		setCanBind(false)
+
+		err := ValidatePortForBind(0, true)
+		testutil.AssertErrorMsg(t, "port 0 is out of range [1, 65535]", err)
+		assert.NotErrorIs(t, err, ErrPrivilegedPort)
+	})
+}
+
+func TestCheckPort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+	testutil.CleanupAndRequireSuccess(t, l.Close)
+
+	ipp := netutil.IPPortFromAddr(l.Addr())
+	require.NotNil(t, ipp)
+	require.NotNil(t, ipp.IP)
+	require.NotZero(t, ipp.Port)
+
+	err = CheckPort("tcp", ipp.IP, ipp.Port)
+	target := &net.OpError{}
+	require.ErrorAs(t, err, &target)
+
+	assert.Equal(t, "listen", target.Op)
+}
+
+// listenLocal is a helper that binds to port of 127.0.0.1 on network, which is
+// either "tcp" or "udp", and returns the listener along with the bound port.
+// A free port is chosen if port is zero.
+func listenLocal(t *testing.T, network string, port int) (c io.Closer, bound int) {
+	t.Helper()
+
+	addr := netutil.IPPort{IP: net.IP{127, 0, 0, 1}, Port: port}.String()
+
+	var laddr net.Addr
+	switch network {
+	case "tcp":
+		l, err := net.Listen(network, addr)
+		require.NoError(t, err)
+
+		c, laddr = l, l.Addr()
+	case "udp":
+		pc, err := net.ListenPacket(network, addr)
+		require.NoError(t, err)
+
+		c, laddr = pc, pc.LocalAddr()
+	default:
+		t.Fatalf("unexpected network %q", network)
+	}
+
+	ipp := netutil.IPPortFromAddr(laddr)
+	require.NotNil(t, ipp)
+
+	return c, ipp.Port
+}
+
+func TestCheckPort_reusable(t *testing.T) {
+	for _, network := range []string{"tcp", "udp"} {
+		t.Run(network, func(t *testing.T) {
+			// Get a free port of the same protocol.
+			c, port := listenLocal(t, network, 0)
+			require.NoError(t, c.Close())
+
+			require.NoError(t, CheckPort(network, net.IP{127, 0, 0, 1}, port))
+
+			// The port must be available right after the check.
+			c, _ = listenLocal(t, network, port)
+			testutil.CleanupAndRequireSuccess(t, c.Close)
+		})
+	}
+}
+
+func TestWaitPortFree(t *testing.T) {
+	opts := WaitPortOpts{
+		Interval:    time.Millisecond,
+		MaxInterval: 5 * time.Millisecond,
+	}
+
+	t.Run("freed", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:")
+		require.NoError(t, err)
+
+		ipp := netutil.IPPortFromAddr(l.Addr())
+		require.NotNil(t, ipp)
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			_ = l.Close()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		t.Cleanup(cancel)
+
+		err = WaitPortFreeWithOpts(ctx, "tcp", ipp.IP, ipp.Port, opts)
+		assert.NoError(t, err)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:")
+		require.NoError(t, err)
+		testutil.CleanupAndRequireSuccess(t, l.Close)
+
+		ipp := netutil.IPPortFromAddr(l.Addr())
+		require.NotNil(t, ipp)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		t.Cleanup(cancel)
+
+		err = WaitPortFreeWithOpts(ctx, "tcp", ipp.IP, ipp.Port, opts)
+		assert.True(t, IsAddrInUse(err))
+	})
+}
@@ -0,0 +1,117 @@
+package aghnet
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/AdguardTeam/golibs/netutil"
+)
+
+// PortConflict describes an address which can't be bound.
+type PortConflict struct {
+	// Err is the error returned by the bind attempt.
+	Err error
+
+	// Process describes the process holding the address, if it could be
+	// determined.  Otherwise, it's empty.
+	Process string
+
+	// Network is the network of the bind attempt, either "tcp" or "udp".
+	Network string
+
+	// Addr is the address which can't be bound.
+	Addr netutil.IPPort
+}
+
+// type check
+var _ error = PortConflict{}
+
+// Error implements the error interface for PortConflict.
+func (c PortConflict) Error() (msg string) {
+	msg = fmt.Sprintf("%s %s: %s", c.Network, c.Addr, c.Err)
+	if c.Process != "" {
+		msg = fmt.Sprintf("%s; held by %s", msg, c.Process)
+	}
+
+	return msg
+}
+
+// Unwrap implements the errors.Wrapper interface for PortConflict.
+func (c PortConflict) Unwrap() (unwrapped error) {
+	return c.Err
+}
+
+// PreflightBind checks if each of addrs is available for binding on each of
+// networks, which are expected to be "tcp" and "udp", using CheckPort.  All the
+// checks are performed even if some of them fail, so that the conflicts are
+// reported at once.  The holding process of each conflicting address is
+// looked up on Linux.
+func PreflightBind(addrs []netutil.IPPort, networks []string) (conflicts []PortConflict) {
+	return PreflightBindWithOpts(addrs, networks, PreflightBindOpts{})
+}
+
+// PreflightBindOpts are the options for PreflightBindWithOpts.
+type PreflightBindOpts struct {
+	// Owned are the listeners currently held by this process, e.g. the ones
+	// of the running DNS server while validating the new configuration before
+	// applying it.  The addresses conflicting with any of those, see
+	// ValidateListenSet, aren't checked, since the binds would fail because of
+	// the process itself, and the listeners are going to be closed before the
+	// new ones are opened.
+	Owned []Listener
+}
+
+// PreflightBindWithOpts is like PreflightBind but allows to configure the
+// checks.
+func PreflightBindWithOpts(
+	addrs []netutil.IPPort,
+	networks []string,
+	opts PreflightBindOpts,
+) (conflicts []PortConflict) {
+	for _, addr := range addrs {
+		for _, network := range networks {
+			if isOwnedListener(opts.Owned, network, addr) {
+				continue
+			}
+
+			err := CheckPort(network, addr.IP, addr.Port)
+			if err == nil {
+				continue
+			}
+
+			conflicts = append(conflicts, PortConflict{
+				Err:     err,
+				Process: portHolder(network, addr),
+				Network: network,
+				Addr:    addr,
+			})
+		}
+	}
+
+	return conflicts
+}
+
+// isOwnedListener returns true if binding addr on network is covered by any of
+// the owned listeners, i.e. if the owned listener has the same address or it's
+// a wildcard one which also binds to addr.  The owned specific listeners don't
+// cover the new wildcard ones, since the latter may still conflict with the
+// listeners of other processes.
+func isOwnedListener(owned []Listener, network string, addr netutil.IPPort) (ok bool) {
+	ip, ok := netip.AddrFromSlice(addr.IP)
+	if !ok || ValidatePort(addr.Port) != nil {
+		return false
+	}
+
+	l := Listener{
+		Proto: network,
+		Addr:  netip.AddrPortFrom(ip, uint16(addr.Port)),
+	}
+	for _, o := range owned {
+		// swap is true if l shadows o, which doesn't make o cover l.
+		if _, swap, conflict := listenersConflict(o, l); conflict && !swap {
+			return true
+		}
+	}
+
+	return false
+}
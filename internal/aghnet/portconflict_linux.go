@@ -0,0 +1,182 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/AdguardTeam/golibs/netutil"
+)
+
+// tcpStateListen is the hexadecimal representation of the TCP_LISTEN state in
+// the /proc/net/tcp files.
+const tcpStateListen = "0A"
+
+// portHolder returns the description of the process holding addr on network
+// using procfs.  proc is empty if the process can't be found, for example
+// because of the lack of permissions.
+func portHolder(network string, addr netutil.IPPort) (proc string) {
+	ip, ok := netip.AddrFromSlice(addr.IP)
+	if !ok {
+		ip = netip.IPv4Unspecified()
+	}
+
+	inodes := map[string]unit{}
+	for _, file := range []string{network, network + "6"} {
+		p := path.Join("proc/net", file)
+		err := findSocketInodes(inodes, p, network == "tcp", ip.Unmap(), addr.Port)
+		if err != nil {
+			currentLogger().Debug("looking up sockets in %s: %s", p, err)
+		}
+	}
+
+	if len(inodes) == 0 {
+		return ""
+	}
+
+	return findInodesProcess(inodes)
+}
+
+// findSocketInodes adds the inodes of the sockets bound to ip and port from the
+// procfs file at p into inodes.  If listening is true, only the sockets in the
+// listening state are considered.  Unspecified addresses match any address.
+func findSocketInodes(
+	inodes map[string]unit,
+	p string,
+	listening bool,
+	ip netip.Addr,
+	port int,
+) (err error) {
+	f, err := rootDirFS.Open(p)
+	if err != nil {
+		return err
+	}
+	defer log.OnCloserError(f, log.DEBUG)
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		localIP, localPort, inode, ok := parseProcNetSocket(s.Text(), listening)
+		if !ok || localPort != port {
+			continue
+		}
+
+		if ip.IsUnspecified() || localIP.IsUnspecified() || ip == localIP {
+			inodes[inode] = unit{}
+		}
+	}
+
+	return s.Err()
+}
+
+// parseProcNetSocket parses the line of /proc/net/tcp or a similar file.  If
+// listening is true, only the sockets in the listening state are parsed.  ok is
+// false if the line isn't parsed, for example if it's the header one.
+func parseProcNetSocket(
+	line string,
+	listening bool,
+) (ip netip.Addr, port int, inode string, ok bool) {
+	// The fields are: sl, local_address, rem_address, st, tx_queue:rx_queue,
+	// tr:tm->when, retrnsmt, uid, timeout, inode, and some others.
+	fields := strings.Fields(line)
+	if len(fields) < 10 || (listening && fields[3] != tcpStateListen) {
+		return netip.Addr{}, 0, "", false
+	}
+
+	ip, port, err := parseProcNetAddr(fields[1])
+	if err != nil {
+		return netip.Addr{}, 0, "", false
+	}
+
+	return ip, port, fields[9], true
+}
+
+// parseProcNetAddr parses the address in the format used in /proc/net/tcp and
+// the similar files, like "0100007F:0035".  The address is printed as a
+// sequence of 32-bit words in the native byte order.
+func parseProcNetAddr(s string) (ip netip.Addr, port int, err error) {
+	ipStr, portStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return netip.Addr{}, 0, fmt.Errorf("bad address %q", s)
+	}
+
+	b, err := hex.DecodeString(ipStr)
+	if err != nil || (len(b) != 4 && len(b) != 16) {
+		return netip.Addr{}, 0, fmt.Errorf("bad ip %q", ipStr)
+	}
+
+	for i := 0; i < len(b); i += 4 {
+		word := b[i : i+4]
+		aghos.NativeEndian.PutUint32(word, binary.BigEndian.Uint32(word))
+	}
+
+	ip, _ = netip.AddrFromSlice(b)
+
+	p, err := strconv.ParseUint(portStr, 16, 16)
+	if err != nil {
+		return netip.Addr{}, 0, fmt.Errorf("bad port %q", portStr)
+	}
+
+	return ip.Unmap(), int(p), nil
+}
+
+// findInodesProcess returns the description of the first found process having
+// a file descriptor of a socket with one of inodes.
+func findInodesProcess(inodes map[string]unit) (proc string) {
+	procs, err := fs.ReadDir(rootDirFS, "proc")
+	if err != nil {
+		currentLogger().Debug("reading procfs: %s", err)
+
+		return ""
+	}
+
+	for _, p := range procs {
+		pid := p.Name()
+		if _, err = strconv.Atoi(pid); err != nil {
+			continue
+		}
+
+		if !procHasSocket(pid, inodes) {
+			continue
+		}
+
+		comm, _ := fs.ReadFile(rootDirFS, path.Join("proc", pid, "comm"))
+
+		return fmt.Sprintf("%s (pid %s)", strings.TrimSpace(string(comm)), pid)
+	}
+
+	return ""
+}
+
+// procHasSocket returns true if the process with pid has a file descriptor of
+// a socket with one of inodes.
+func procHasSocket(pid string, inodes map[string]unit) (ok bool) {
+	fdDir := path.Join("proc", pid, "fd")
+
+	// Reading the file descriptors of other users' processes usually
+	// requires the elevated privileges, so ignore the errors.
+	fds, _ := fs.ReadDir(rootDirFS, fdDir)
+	for _, fd := range fds {
+		link, err := readLink(rootDirFS, path.Join(fdDir, fd.Name()))
+		if err != nil {
+			continue
+		}
+
+		inode := strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")
+		if _, ok = inodes[inode]; ok && inode != link {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import "github.com/AdguardTeam/golibs/netutil"
+
+// portHolder returns an empty string, since looking up the process holding an
+// address isn't supported on this OS yet.
+func portHolder(_ string, _ netutil.IPPort) (proc string) {
+	return ""
+}
@@ -0,0 +1,138 @@
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/netutil"
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreflightBind(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+	testutil.CleanupAndRequireSuccess(t, l.Close)
+
+	held := *netutil.IPPortFromAddr(l.Addr())
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:")
+	require.NoError(t, err)
+	testutil.CleanupAndRequireSuccess(t, pc.Close)
+
+	heldUDP := *netutil.IPPortFromAddr(pc.LocalAddr())
+
+	free := netutil.IPPort{IP: net.IP{127, 0, 0, 1}, Port: 0}
+
+	conflicts := PreflightBind(
+		[]netutil.IPPort{held, free, heldUDP},
+		[]string{"tcp", "udp"},
+	)
+
+	// The held TCP port may or may not be free for UDP, and vice versa, so
+	// only check the conflicts which must be there.
+	var tcpFound, udpFound bool
+	for _, c := range conflicts {
+		require.Error(t, c.Err)
+		assert.NotEqual(t, free, c.Addr)
+
+		switch {
+		case c.Network == "tcp" && c.Addr.Port == held.Port:
+			tcpFound = true
+		case c.Network == "udp" && c.Addr.Port == heldUDP.Port:
+			udpFound = true
+		}
+
+		assert.True(t, IsAddrInUse(c))
+	}
+
+	assert.True(t, tcpFound)
+	assert.True(t, udpFound)
+}
+
+func TestPortConflict_Error(t *testing.T) {
+	c := PortConflict{
+		Err:     assert.AnError,
+		Process: "dnsmasq (pid 123)",
+		Network: "udp",
+		Addr:    netutil.IPPort{IP: net.IP{127, 0, 0, 1}, Port: 53},
+	}
+
+	assert.Equal(
+		t,
+		"udp 127.0.0.1:53: "+assert.AnError.Error()+"; held by dnsmasq (pid 123)",
+		c.Error(),
+	)
+	assert.ErrorIs(t, c, assert.AnError)
+
+	c.Process = ""
+	assert.Equal(t, "udp 127.0.0.1:53: "+assert.AnError.Error(), c.Error())
+}
+
+func TestPreflightBindWithOpts_owned(t *testing.T) {
+	owned, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+	testutil.CleanupAndRequireSuccess(t, owned.Close)
+
+	foreign, err := net.Listen("tcp", "127.0.0.1:")
+	require.NoError(t, err)
+	testutil.CleanupAndRequireSuccess(t, foreign.Close)
+
+	ownedAddr := *netutil.IPPortFromAddr(owned.Addr())
+	foreignAddr := *netutil.IPPortFromAddr(foreign.Addr())
+
+	opts := PreflightBindOpts{
+		Owned: []Listener{{
+			Proto: "tcp",
+			Addr:  netip.MustParseAddrPort(owned.Addr().String()),
+		}},
+	}
+
+	conflicts := PreflightBindWithOpts(
+		[]netutil.IPPort{ownedAddr, foreignAddr},
+		[]string{"tcp"},
+		opts,
+	)
+	require.Len(t, conflicts, 1)
+
+	assert.Equal(t, foreignAddr, conflicts[0].Addr)
+	assert.True(t, IsAddrInUse(conflicts[0]))
+
+	t.Run("not_owned", func(t *testing.T) {
+		conflicts = PreflightBind([]netutil.IPPort{ownedAddr}, []string{"tcp"})
+		require.Len(t, conflicts, 1)
+
+		assert.Equal(t, ownedAddr, conflicts[0].Addr)
+	})
+
+	t.Run("owned_wildcard", func(t *testing.T) {
+		wildcardOpts := PreflightBindOpts{
+			Owned: []Listener{{
+				Proto: "tcp",
+				Addr:  netip.AddrPortFrom(netip.IPv4Unspecified(), uint16(foreignAddr.Port)),
+			}},
+		}
+
+		conflicts = PreflightBindWithOpts(
+			[]netutil.IPPort{foreignAddr},
+			[]string{"tcp"},
+			wildcardOpts,
+		)
+		assert.Empty(t, conflicts)
+	})
+
+	t.Run("new_wildcard", func(t *testing.T) {
+		wildcardAddr := netutil.IPPort{IP: net.IPv4zero, Port: ownedAddr.Port}
+
+		conflicts = PreflightBindWithOpts(
+			[]netutil.IPPort{wildcardAddr},
+			[]string{"tcp"},
+			opts,
+		)
+		require.Len(t, conflicts, 1)
+
+		assert.Equal(t, wildcardAddr, conflicts[0].Addr)
+	})
+}
@@ -0,0 +1,110 @@
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// ErrNoPrimaryIPv4 is returned by PrimaryIPv4 when no network interface has
+// a suitable IPv4 address.
+const ErrNoPrimaryIPv4 errors.Error = "no suitable ipv4 address"
+
+// PrimaryIPv4 returns the single most sensible IPv4 address of the machine,
+// e.g. to suggest it for binding or to display it as the one AdGuard Home is
+// reachable at.  The heuristic is as follows:
+//
+//  1. Only the up interfaces which are neither loopback nor tunnel ones are
+//     considered, and their loopback and link-local addresses are skipped.
+//
+//  2. The addresses of the interface carrying the default route, see
+//     IsDefaultRouteIface, are preferred over the addresses of the others.
+//
+//  3. Among those, the private addresses, see RFC 1918, are preferred over
+//     the public ones.
+//
+//  4. The remaining ties are resolved by the order of the interfaces and the
+//     addresses reported by the OS.
+//
+// err is ErrNoPrimaryIPv4 if there is no such address.
+func PrimaryIPv4() (addr netip.Addr, err error) {
+	ifaces, err := GetValidNetInterfacesForWeb()
+	if err != nil && !errors.Is(err, ErrNoInterfaces) {
+		// Don't wrap the error, because it's informative enough as is.
+		return netip.Addr{}, err
+	}
+
+	return primaryIPv4(ifaces, func(ifaceName string) (ok bool) {
+		var defErr error
+		ok, defErr = IsDefaultRouteIface(ifaceName)
+		if defErr != nil {
+			currentLogger().Debug("choosing primary ipv4: %s", defErr)
+		}
+
+		return ok
+	})
+}
+
+// primaryIPv4 chooses the primary IPv4 address among the addresses of ifaces
+// as described by PrimaryIPv4.  isDefault reports if the interface with the
+// name carries the default route.
+func primaryIPv4(
+	ifaces []*NetInterface,
+	isDefault func(ifaceName string) (ok bool),
+) (addr netip.Addr, err error) {
+	bestScore := -1
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 ||
+			iface.Kind == IfaceKindLoopback ||
+			iface.Kind == IfaceKindTunnel {
+			continue
+		}
+
+		a, isPrivate, ok := firstIPv4(iface.Addresses)
+		if !ok {
+			continue
+		}
+
+		// The default route outweighs the privateness.
+		var score int
+		if isDefault(iface.Name) {
+			score += 2
+		}
+
+		if isPrivate {
+			score++
+		}
+
+		if score > bestScore {
+			addr, bestScore = a, score
+		}
+	}
+
+	if bestScore < 0 {
+		return netip.Addr{}, ErrNoPrimaryIPv4
+	}
+
+	return addr, nil
+}
+
+// firstIPv4 returns the first private IPv4 address among ips or, if there is
+// none, the first public one.  The loopback and link-local addresses are
+// skipped.
+func firstIPv4(ips []net.IP) (addr netip.Addr, isPrivate, ok bool) {
+	for _, ip := range ips {
+		a, valid := netip.AddrFromSlice(ip)
+		a = a.Unmap()
+		if !valid || !a.Is4() || a.IsLoopback() || a.IsLinkLocalUnicast() {
+			continue
+		}
+
+		if a.IsPrivate() {
+			return a, true, true
+		} else if !ok {
+			addr, ok = a, true
+		}
+	}
+
+	return addr, false, ok
+}
@@ -0,0 +1,119 @@
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrimaryIPv4(t *testing.T) {
+	newAddrs := func(cidrs ...string) (addrs []net.Addr) {
+		for _, c := range cidrs {
+			ip, n, err := net.ParseCIDR(c)
+			require.NoError(t, err)
+
+			n.IP = ip
+			addrs = append(addrs, n)
+		}
+
+		return addrs
+	}
+
+	lo := net.Interface{Index: 1, Name: "lo", Flags: net.FlagUp | net.FlagLoopback}
+	eth0 := net.Interface{Index: 2, Name: "eth0", Flags: net.FlagUp | net.FlagBroadcast}
+	wlan0 := net.Interface{Index: 3, Name: "wlan0", Flags: net.FlagUp | net.FlagBroadcast}
+	tun0 := net.Interface{Index: 4, Name: "tun0", Flags: net.FlagUp | net.FlagPointToPoint}
+	docker0 := net.Interface{Index: 5, Name: "docker0", Flags: net.FlagUp | net.FlagBroadcast}
+	eth1 := net.Interface{Index: 6, Name: "eth1", Flags: net.FlagBroadcast}
+
+	testCases := []struct {
+		ifaceAddrs  map[string][]net.Addr
+		want        netip.Addr
+		wantErr     error
+		name        string
+		defaultName string
+		ifaces      []net.Interface
+	}{{
+		ifaceAddrs: map[string][]net.Addr{
+			"lo":      newAddrs("127.0.0.1/8"),
+			"docker0": newAddrs("172.17.0.1/16"),
+			"eth0":    newAddrs("203.0.113.5/24", "192.168.1.2/24", "2001:db8::2/64"),
+			"wlan0":   newAddrs("192.168.2.10/24"),
+		},
+		want:        netip.MustParseAddr("192.168.1.2"),
+		wantErr:     nil,
+		name:        "multi_nic",
+		defaultName: "eth0",
+		ifaces:      []net.Interface{lo, docker0, eth0, wlan0},
+	}, {
+		ifaceAddrs: map[string][]net.Addr{
+			"lo":   newAddrs("127.0.0.1/8"),
+			"eth0": newAddrs("203.0.113.5/24"),
+			"eth1": newAddrs("192.168.1.2/24"),
+		},
+		want:        netip.MustParseAddr("203.0.113.5"),
+		wantErr:     nil,
+		name:        "public_default",
+		defaultName: "eth0",
+		ifaces:      []net.Interface{lo, eth0, eth1},
+	}, {
+		ifaceAddrs: map[string][]net.Addr{
+			"lo":    newAddrs("127.0.0.1/8"),
+			"tun0":  newAddrs("10.8.0.2/24"),
+			"wlan0": newAddrs("169.254.10.1/16", "192.168.1.10/24"),
+		},
+		want:        netip.MustParseAddr("192.168.1.10"),
+		wantErr:     nil,
+		name:        "vpn",
+		defaultName: "tun0",
+		ifaces:      []net.Interface{lo, tun0, wlan0},
+	}, {
+		ifaceAddrs: map[string][]net.Addr{
+			"docker0": newAddrs("172.17.0.1/16"),
+			"wlan0":   newAddrs("198.51.100.7/24"),
+		},
+		want:        netip.MustParseAddr("172.17.0.1"),
+		wantErr:     nil,
+		name:        "no_default_route",
+		defaultName: "",
+		ifaces:      []net.Interface{docker0, wlan0},
+	}, {
+		ifaceAddrs: map[string][]net.Addr{
+			"lo":   newAddrs("127.0.0.1/8"),
+			"eth0": newAddrs("2001:db8::2/64"),
+		},
+		want:        netip.Addr{},
+		wantErr:     ErrNoPrimaryIPv4,
+		name:        "none",
+		defaultName: "eth0",
+		ifaces:      []net.Interface{lo, eth0},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			substNetInterfaces(t, tc.ifaces, tc.ifaceAddrs)
+
+			ifaces, err := GetValidNetInterfacesForWeb()
+			require.NoError(t, err)
+
+			addr, err := primaryIPv4(ifaces, func(ifaceName string) (ok bool) {
+				return ifaceName == tc.defaultName
+			})
+			require.ErrorIs(t, err, tc.wantErr)
+
+			assert.Equal(t, tc.want, addr)
+		})
+	}
+
+	t.Run("fake_ifaces", func(t *testing.T) {
+		substNetInterfaces(t, fakeNetIfaces, fakeNetIfaceAddrs)
+
+		addr, err := PrimaryIPv4()
+		require.NoError(t, err)
+
+		assert.Equal(t, netip.MustParseAddr("192.168.1.2"), addr)
+	})
+}
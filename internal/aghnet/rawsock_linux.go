@@ -0,0 +1,20 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openRawSocket opens and closes an AF_PACKET socket.
+func openRawSocket() (err error) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+
+	return os.NewSyscallError("close", unix.Close(fd))
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import (
+	"net"
+)
+
+// openRawSocket opens and closes a raw ICMP socket.
+func openRawSocket() (err error) {
+	c, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return err
+	}
+
+	return c.Close()
+}
@@ -0,0 +1,67 @@
+package aghnet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync/atomic"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// ResolveHost returns the IPv4 and IPv6 addresses of host resolved using only
+// the bootstrap DNS servers, so that the system resolver, which may point to
+// AdGuard Home itself, is never contacted.  The bootstraps are tried in
+// a round-robin manner as the Go resolver retries.  Note that the system hosts
+// file is still consulted.  The returned IPv4 addresses are unmapped.
+func ResolveHost(
+	ctx context.Context,
+	host string,
+	bootstraps []netip.AddrPort,
+) (addrs []netip.Addr, err error) {
+	defer func() { err = errors.Annotate(err, "resolving %q: %w", host) }()
+
+	if len(bootstraps) == 0 {
+		return nil, errors.Error("no bootstrap servers")
+	}
+
+	for i, b := range bootstraps {
+		if !b.IsValid() {
+			return nil, fmt.Errorf("bootstrap at index %d: bad address %s", i, b)
+		}
+	}
+
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial:     newBootstrapDialer(bootstraps),
+	}
+
+	addrs, err = r.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return nil, err
+	}
+
+	for i, a := range addrs {
+		addrs[i] = a.Unmap()
+	}
+
+	return addrs, nil
+}
+
+// dialFunc is the signature of net.Resolver.Dial.
+type dialFunc func(ctx context.Context, network, address string) (conn net.Conn, err error)
+
+// newBootstrapDialer returns a dialFunc which connects to the bootstraps in
+// turn regardless of the requested address.
+func newBootstrapDialer(bootstraps []netip.AddrPort) (dial dialFunc) {
+	d := &net.Dialer{}
+	var next uint32
+
+	return func(ctx context.Context, network, _ string) (conn net.Conn, err error) {
+		i := (atomic.AddUint32(&next, 1) - 1) % uint32(len(bootstraps))
+
+		return d.DialContext(ctx, network, bootstraps[i].String())
+	}
+}
@@ -0,0 +1,86 @@
+package aghnet
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startBootstrap is a helper that starts a plain DNS server answering with ip4
+// and ip6 for host and returns its address.
+func startBootstrap(t *testing.T, host string, ip4, ip6 net.IP) (addr netip.AddrPort) {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &dns.Server{
+		PacketConn: pc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			resp := (&dns.Msg{}).SetReply(req)
+
+			q := req.Question[0]
+			hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: 60}
+			switch {
+			case q.Name != dns.Fqdn(host):
+				resp.Rcode = dns.RcodeNameError
+			case q.Qtype == dns.TypeA:
+				resp.Answer = append(resp.Answer, &dns.A{Hdr: hdr, A: ip4})
+			case q.Qtype == dns.TypeAAAA:
+				resp.Answer = append(resp.Answer, &dns.AAAA{Hdr: hdr, AAAA: ip6})
+			}
+
+			_ = w.WriteMsg(resp)
+		}),
+	}
+
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+
+	return pc.LocalAddr().(*net.UDPAddr).AddrPort()
+}
+
+func TestResolveHost(t *testing.T) {
+	const host = "bootstrap.test"
+
+	bootstrap := startBootstrap(t, host, net.IP{1, 2, 3, 4}, net.ParseIP("2001:db8::4"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	t.Run("success", func(t *testing.T) {
+		addrs, err := ResolveHost(ctx, host, []netip.AddrPort{bootstrap})
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []netip.Addr{
+			netip.MustParseAddr("1.2.3.4"),
+			netip.MustParseAddr("2001:db8::4"),
+		}, addrs)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		_, err := ResolveHost(ctx, "other.test", []netip.AddrPort{bootstrap})
+		assert.Error(t, err)
+	})
+
+	t.Run("no_bootstraps", func(t *testing.T) {
+		_, err := ResolveHost(ctx, host, nil)
+		testutil.AssertErrorMsg(t, `resolving "bootstrap.test": no bootstrap servers`, err)
+	})
+
+	t.Run("bad_bootstrap", func(t *testing.T) {
+		_, err := ResolveHost(ctx, host, []netip.AddrPort{{}})
+		testutil.AssertErrorMsg(
+			t,
+			`resolving "bootstrap.test": bootstrap at index 0: bad address invalid AddrPort`,
+			err,
+		)
+	})
+}
@@ -0,0 +1,59 @@
+package aghnet
+
+import (
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+)
+
+// readLinkFS is an fs.FS which can also read the symbolic links.  The
+// filesystems without this ability are considered to have no links.
+type readLinkFS interface {
+	fs.FS
+
+	// ReadLink returns the destination of the symbolic link name, which must
+	// be a valid path, see fs.ValidPath.
+	ReadLink(name string) (dst string, err error)
+}
+
+// dirFS is the fs.FS of the directory tree rooted at root, which can also read
+// the symbolic links.
+type dirFS struct {
+	fs.FS
+
+	// root is the path of the root directory.
+	root string
+}
+
+// type check
+var _ readLinkFS = dirFS{}
+
+// newRootDirFS returns the dirFS rooted at the operating system's root.
+func newRootDirFS() (fsys dirFS) {
+	return dirFS{
+		FS:   aghos.RootDirFS(),
+		root: "/",
+	}
+}
+
+// ReadLink implements the readLinkFS interface for dirFS.
+func (fsys dirFS) ReadLink(name string) (dst string, err error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return os.Readlink(path.Join(fsys.root, name))
+}
+
+// readLink returns the destination of the symbolic link name within fsys.  err
+// is fs.ErrInvalid if fsys can't read the links.
+func readLink(fsys fs.FS, name string) (dst string, err error) {
+	lfs, ok := fsys.(readLinkFS)
+	if !ok {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return lfs.ReadLink(name)
+}
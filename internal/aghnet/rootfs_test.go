@@ -0,0 +1,69 @@
+package aghnet
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLinkFS is a readLinkFS for tests.
+type fakeLinkFS struct {
+	fstest.MapFS
+
+	// links are the destinations of the symbolic links.
+	links map[string]string
+}
+
+// type check
+var _ readLinkFS = (*fakeLinkFS)(nil)
+
+// ReadLink implements the readLinkFS interface for *fakeLinkFS.
+func (fsys *fakeLinkFS) ReadLink(name string) (dst string, err error) {
+	dst, ok := fsys.links[name]
+	if !ok {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return dst, nil
+}
+
+func TestDirFS_ReadLink(t *testing.T) {
+	root := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(root, "proc", "1", "fd"), 0o700)
+	require.NoError(t, err)
+
+	err = os.Symlink("socket:[1001]", filepath.Join(root, "proc", "1", "fd", "3"))
+	if err != nil {
+		t.Skipf("creating symbolic link: %s", err)
+	}
+
+	fsys := dirFS{FS: os.DirFS(root), root: root}
+
+	t.Run("link", func(t *testing.T) {
+		dst, linkErr := readLink(fsys, "proc/1/fd/3")
+		require.NoError(t, linkErr)
+
+		assert.Equal(t, "socket:[1001]", dst)
+	})
+
+	t.Run("not_exist", func(t *testing.T) {
+		_, linkErr := readLink(fsys, "proc/1/fd/4")
+		assert.ErrorIs(t, linkErr, fs.ErrNotExist)
+	})
+
+	t.Run("invalid_path", func(t *testing.T) {
+		_, linkErr := readLink(fsys, "../etc/passwd")
+		assert.ErrorIs(t, linkErr, fs.ErrInvalid)
+	})
+
+	t.Run("no_links", func(t *testing.T) {
+		_, linkErr := readLink(fstest.MapFS{}, "proc/1/fd/3")
+		assert.ErrorIs(t, linkErr, fs.ErrInvalid)
+	})
+}
@@ -0,0 +1,209 @@
+package aghnet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+// ErrNoRouterAdvertisement is returned by RouterFromRA when no router
+// advertisement has been received within the timeout.
+const ErrNoRouterAdvertisement errors.Error = "no router advertisement received"
+
+// icmpv6Proto is the IANA protocol number of ICMPv6.
+const icmpv6Proto = 58
+
+// ndHopLimit is the hop limit of the Neighbor Discovery messages.  Messages
+// with any other hop limit must be discarded, see RFC 4861.
+const ndHopLimit = 255
+
+// allRoutersAddr is the link-local all-routers multicast address.
+var allRoutersAddr = net.ParseIP("ff02::2")
+
+// RouterFromRA listens for ICMPv6 Router Advertisement messages on the network
+// interface for at most timeout and returns the link-local address of the first
+// advertising default router, i.e. one with a non-zero router lifetime, with the
+// zone set to ifaceName.  A Router Solicitation is
+// sent first to speed things up.  Listening requires raw sockets, which usually
+// means CAP_NET_RAW on Linux, so if those aren't permitted, the IPv6 default
+// gateway from the routing table is returned instead.  The routing table is
+// only supported on Linux, where it's dumped through netlink, and on Windows,
+// where it's listed by netsh.
+// err is ErrNoRouterAdvertisement if no advertisement has arrived in time.
+func RouterFromRA(ifaceName string, timeout time.Duration) (router netip.Addr, err error) {
+	defer func() { err = errors.Annotate(err, "detecting router on %s: %w", ifaceName) }()
+
+	iface, err := ifaceByName(ifaceName)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return netip.Addr{}, err
+	}
+
+	router, err = listenRouterAdvertisement(iface, timeout)
+	if !errors.Is(err, os.ErrPermission) {
+		return router, err
+	}
+
+	currentLogger().Debug("listening for router advertisements: %s; using routing table", err)
+
+	router, gwErr := ipv6Gateway(iface)
+	if gwErr != nil {
+		return netip.Addr{}, errors.List("no raw sockets and no routes", err, gwErr)
+	}
+
+	return router, nil
+}
+
+// listenRouterAdvertisement solicits and waits for a router advertisement on
+// iface.
+func listenRouterAdvertisement(
+	iface *net.Interface,
+	timeout time.Duration,
+) (router netip.Addr, err error) {
+	c, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("listening: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, c.Close()) }()
+
+	p := c.IPv6PacketConn()
+
+	f := &ipv6.ICMPFilter{}
+	f.SetAll(true)
+	f.Accept(ipv6.ICMPTypeRouterAdvertisement)
+	err = p.SetICMPFilter(f)
+	if err != nil {
+		// The filter is only an optimization, since the messages are checked
+		// anyway.
+		currentLogger().Debug("setting icmp filter: %s", err)
+	}
+
+	err = p.SetControlMessage(ipv6.FlagInterface|ipv6.FlagHopLimit, true)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("setting control message flags: %w", err)
+	}
+
+	err = p.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("setting deadline: %w", err)
+	}
+
+	err = sendRouterSolicitation(p, iface)
+	if err != nil {
+		// Routers send the advertisements periodically anyway.
+		currentLogger().Debug("sending router solicitation on %s: %s", iface.Name, err)
+	}
+
+	return readRouterAdvertisement(p, iface)
+}
+
+// sendRouterSolicitation sends the ICMPv6 Router Solicitation message to the
+// all-routers multicast address through iface.
+func sendRouterSolicitation(p *ipv6.PacketConn, iface *net.Interface) (err error) {
+	msg := &icmp.Message{
+		Type: ipv6.ICMPTypeRouterSolicitation,
+		// The reserved field, no options.
+		Body: &icmp.RawBody{Data: make([]byte, 4)},
+	}
+
+	// The checksum of ICMPv6 messages is computed by the kernel.
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return fmt.Errorf("encoding: %w", err)
+	}
+
+	cm := &ipv6.ControlMessage{
+		HopLimit: ndHopLimit,
+		IfIndex:  iface.Index,
+	}
+
+	_, err = p.WriteTo(b, cm, &net.IPAddr{IP: allRoutersAddr, Zone: iface.Name})
+
+	return err
+}
+
+// readRouterAdvertisement reads the packets from p until a router
+// advertisement received through iface arrives or the deadline exceeds.
+func readRouterAdvertisement(p *ipv6.PacketConn, iface *net.Interface) (router netip.Addr, err error) {
+	buf := make([]byte, 1500)
+	for {
+		var n int
+		var cm *ipv6.ControlMessage
+		var src net.Addr
+		n, cm, src, err = p.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return netip.Addr{}, ErrNoRouterAdvertisement
+			}
+
+			return netip.Addr{}, fmt.Errorf("reading: %w", err)
+		}
+
+		var ok bool
+		router, ok = routerFromPacket(buf[:n], cm, src, iface.Index)
+		if ok {
+			return router.WithZone(iface.Name), nil
+		}
+	}
+}
+
+// routerFromPacket returns the address of the router if b is a valid router
+// advertisement received through the network interface with ifIndex from src.
+func routerFromPacket(
+	b []byte,
+	cm *ipv6.ControlMessage,
+	src net.Addr,
+	ifIndex int,
+) (router netip.Addr, ok bool) {
+	if cm == nil || cm.IfIndex != ifIndex || cm.HopLimit != ndHopLimit {
+		return netip.Addr{}, false
+	}
+
+	ipAddr, ok := src.(*net.IPAddr)
+	if !ok {
+		return netip.Addr{}, false
+	}
+
+	router, ok = netip.AddrFromSlice(ipAddr.IP)
+	if !ok || !router.Is6() || !router.IsLinkLocalUnicast() {
+		// Routers must use their link-local addresses as the source, see
+		// RFC 4861.
+		return netip.Addr{}, false
+	}
+
+	msg, err := icmp.ParseMessage(icmpv6Proto, b)
+	if err != nil || !isDefaultRouterAdv(msg) {
+		return netip.Addr{}, false
+	}
+
+	return router, true
+}
+
+// raMinBodyLen is the minimum length of the Router Advertisement message body
+// following the ICMPv6 header: the current hop limit, the flags, the router
+// lifetime, the reachable time, and the retransmission timer.
+const raMinBodyLen = 12
+
+// isDefaultRouterAdv returns true if msg is a valid router advertisement from
+// a default router.  The advertisements with a non-zero code must be discarded,
+// and the zero router lifetime means that the router isn't a default one, see
+// RFC 4861.
+func isDefaultRouterAdv(msg *icmp.Message) (ok bool) {
+	if msg.Type != ipv6.ICMPTypeRouterAdvertisement || msg.Code != 0 {
+		return false
+	}
+
+	body, ok := msg.Body.(*icmp.RawBody)
+	if !ok || len(body.Data) < raMinBodyLen {
+		return false
+	}
+
+	return binary.BigEndian.Uint16(body.Data[2:4]) != 0
+}
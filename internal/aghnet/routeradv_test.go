@@ -0,0 +1,127 @@
+package aghnet
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+func TestRouterFromPacket(t *testing.T) {
+	newMsg := func(typ ipv6.ICMPType, code int, lifetime uint16) (b []byte) {
+		// Current hop limit, flags, router lifetime, reachable time, and
+		// retransmit timer.
+		data := make([]byte, 12)
+		binary.BigEndian.PutUint16(data[2:4], lifetime)
+
+		msg := &icmp.Message{
+			Type: typ,
+			Code: code,
+			Body: &icmp.RawBody{Data: data},
+		}
+
+		var err error
+		b, err = msg.Marshal(nil)
+		require.NoError(t, err)
+
+		return b
+	}
+
+	const ifIndex = 2
+
+	ra := newMsg(ipv6.ICMPTypeRouterAdvertisement, 0, 1800)
+	validCM := &ipv6.ControlMessage{HopLimit: ndHopLimit, IfIndex: ifIndex}
+	linkLocal := &net.IPAddr{IP: net.ParseIP("fe80::1")}
+
+	testCases := []struct {
+		src    net.Addr
+		cm     *ipv6.ControlMessage
+		name   string
+		b      []byte
+		want   netip.Addr
+		wantOK bool
+	}{{
+		src:    linkLocal,
+		cm:     validCM,
+		name:   "valid",
+		b:      ra,
+		want:   netip.MustParseAddr("fe80::1"),
+		wantOK: true,
+	}, {
+		src:    linkLocal,
+		cm:     validCM,
+		name:   "not_ra",
+		b:      newMsg(ipv6.ICMPTypeNeighborAdvertisement, 0, 1800),
+		want:   netip.Addr{},
+		wantOK: false,
+	}, {
+		src:    linkLocal,
+		cm:     &ipv6.ControlMessage{HopLimit: 64, IfIndex: ifIndex},
+		name:   "bad_hop_limit",
+		b:      ra,
+		want:   netip.Addr{},
+		wantOK: false,
+	}, {
+		src:    linkLocal,
+		cm:     &ipv6.ControlMessage{HopLimit: ndHopLimit, IfIndex: ifIndex + 1},
+		name:   "other_iface",
+		b:      ra,
+		want:   netip.Addr{},
+		wantOK: false,
+	}, {
+		src:    linkLocal,
+		cm:     nil,
+		name:   "no_cm",
+		b:      ra,
+		want:   netip.Addr{},
+		wantOK: false,
+	}, {
+		src:    &net.IPAddr{IP: net.ParseIP("2001:db8::1")},
+		cm:     validCM,
+		name:   "not_link_local",
+		b:      ra,
+		want:   netip.Addr{},
+		wantOK: false,
+	}, {
+		src:    linkLocal,
+		cm:     validCM,
+		name:   "garbage",
+		b:      []byte{0x86},
+		want:   netip.Addr{},
+		wantOK: false,
+	}, {
+		src:    linkLocal,
+		cm:     validCM,
+		name:   "zero_lifetime",
+		b:      newMsg(ipv6.ICMPTypeRouterAdvertisement, 0, 0),
+		want:   netip.Addr{},
+		wantOK: false,
+	}, {
+		src:    linkLocal,
+		cm:     validCM,
+		name:   "non_zero_code",
+		b:      newMsg(ipv6.ICMPTypeRouterAdvertisement, 1, 1800),
+		want:   netip.Addr{},
+		wantOK: false,
+	}, {
+		src:    linkLocal,
+		cm:     validCM,
+		name:   "short_body",
+		b:      []byte{0x86, 0x00, 0x00, 0x00, 0x40, 0x00, 0x07, 0x08},
+		want:   netip.Addr{},
+		wantOK: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router, ok := routerFromPacket(tc.b, tc.cm, tc.src, ifIndex)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, router)
+		})
+	}
+}
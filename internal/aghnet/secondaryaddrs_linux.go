@@ -0,0 +1,90 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// secondaryIPv4Addrs returns the IPv4 addresses having the IFA_F_SECONDARY flag
+// mapped to the indexes of their network interfaces from the addresses dumped
+// through netlink.
+func secondaryIPv4Addrs() (secondary map[netip.Addr]int, err error) {
+	req := make([]byte, unix.SizeofIfAddrmsg)
+	req[0] = unix.AF_INET
+
+	msgs, err := netlinkRouteDump(unix.RTM_GETADDR, req)
+	if err != nil {
+		return nil, fmt.Errorf("dumping addresses: %w", err)
+	}
+
+	secondary = map[netip.Addr]int{}
+	for _, msg := range msgs {
+		var addr netip.Addr
+		var index uint32
+		var isSecondary bool
+		addr, index, isSecondary, err = parseIPv4AddrMsg(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		if isSecondary {
+			secondary[addr] = int(index)
+		}
+	}
+
+	return secondary, nil
+}
+
+// parseIPv4AddrMsg parses the data of the RTM_NEWADDR netlink message and
+// returns the local address along with the index of its network interface and
+// whether it's a secondary one.  addr is invalid if the address isn't an IPv4
+// one.
+//
+// See man rtnetlink(7).
+func parseIPv4AddrMsg(data []byte) (addr netip.Addr, index uint32, isSecondary bool, err error) {
+	if len(data) < unix.SizeofIfAddrmsg {
+		return netip.Addr{}, 0, false, fmt.Errorf("address message is too short: %d bytes", len(data))
+	}
+
+	family, flags := data[0], uint32(data[2])
+	index = aghos.NativeEndian.Uint32(data[4:8])
+	if family != unix.AF_INET {
+		return netip.Addr{}, index, false, nil
+	}
+
+	ad, err := netlink.NewAttributeDecoder(data[unix.SizeofIfAddrmsg:])
+	if err != nil {
+		return netip.Addr{}, 0, false, fmt.Errorf("decoding address attributes: %w", err)
+	}
+
+	var local netip.Addr
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.IFA_ADDRESS:
+			addr, _ = netip.AddrFromSlice(ad.Bytes())
+		case unix.IFA_LOCAL:
+			// IFA_ADDRESS is the address of the peer for the point-to-point
+			// interfaces.
+			local, _ = netip.AddrFromSlice(ad.Bytes())
+		case unix.IFA_FLAGS:
+			flags = ad.Uint32()
+		}
+	}
+
+	if err = ad.Err(); err != nil {
+		return netip.Addr{}, 0, false, fmt.Errorf("decoding address attributes: %w", err)
+	}
+
+	if local.IsValid() {
+		addr = local
+	}
+
+	return addr, index, flags&unix.IFA_F_SECONDARY != 0, nil
+}
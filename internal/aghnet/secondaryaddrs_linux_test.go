@@ -0,0 +1,53 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseIPv4AddrMsg(t *testing.T) {
+	testCases := []struct {
+		name          string
+		wantAddr      netip.Addr
+		data          []byte
+		wantSecondary bool
+	}{{
+		name:          "primary",
+		wantAddr:      netip.MustParseAddr("192.168.1.2"),
+		data:          newAddrMsg(t, netip.MustParsePrefix("192.168.1.2/24"), 2, unix.IFA_F_PERMANENT, 0, 0),
+		wantSecondary: false,
+	}, {
+		name:          "secondary",
+		wantAddr:      netip.MustParseAddr("192.168.1.3"),
+		data:          newAddrMsg(t, netip.MustParsePrefix("192.168.1.3/24"), 2, unix.IFA_F_SECONDARY, 0, 0),
+		wantSecondary: true,
+	}, {
+		name:          "ipv6",
+		wantAddr:      netip.Addr{},
+		data:          newAddrMsg(t, netip.MustParsePrefix("2001:db8::2/64"), 2, unix.IFA_F_SECONDARY, 0, 0),
+		wantSecondary: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, index, isSecondary, err := parseIPv4AddrMsg(tc.data)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.wantAddr, addr)
+			assert.Equal(t, uint32(2), index)
+			assert.Equal(t, tc.wantSecondary, isSecondary)
+		})
+	}
+
+	t.Run("short", func(t *testing.T) {
+		_, _, _, err := parseIPv4AddrMsg([]byte{unix.AF_INET})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package aghnet
+
+import "net/netip"
+
+// secondaryIPv4Addrs returns no addresses, since the OS doesn't distinguish the
+// primary and the secondary addresses.
+func secondaryIPv4Addrs() (secondary map[netip.Addr]int, err error) {
+	return nil, nil
+}
@@ -0,0 +1,232 @@
+package aghnet
+
+import (
+	"net/netip"
+
+	"github.com/AdguardTeam/golibs/netutil"
+)
+
+// privateNets are the networks of the private address space, which only the
+// hosts of the local network may use.
+var privateNets = []netip.Prefix{
+	// Private-Use Networks, RFC 1918.
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	// Shared Address Space, also known as CGNAT, RFC 6598.
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// Link Local, RFC 3927.
+	netip.MustParsePrefix("169.254.0.0/16"),
+
+	// Unique-Local, RFC 4193.
+	netip.MustParsePrefix("fc00::/7"),
+	// Linked-Scoped Unicast, RFC 4291.
+	netip.MustParsePrefix("fe80::/10"),
+}
+
+// specialPurposeNets are the special-purpose address registries as defined by
+// RFC 6890 and its updates.  See also the IANA IPv4 and IPv6 Special-Purpose
+// Address Registries.
+var specialPurposeNets = []netip.Prefix{
+	// "This" network.
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// Private-Use Networks.
+	netip.MustParsePrefix("10.0.0.0/8"),
+	// Shared Address Space.
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// Loopback.
+	netip.MustParsePrefix("127.0.0.0/8"),
+	// Link Local.
+	netip.MustParsePrefix("169.254.0.0/16"),
+	// Private-Use Networks.
+	netip.MustParsePrefix("172.16.0.0/12"),
+	// IETF Protocol Assignments.
+	netip.MustParsePrefix("192.0.0.0/24"),
+	// DS-Lite.
+	netip.MustParsePrefix("192.0.0.0/29"),
+	// TEST-NET-1
+	netip.MustParsePrefix("192.0.2.0/24"),
+	// 6to4 Relay Anycast.
+	netip.MustParsePrefix("192.88.99.0/24"),
+	// Private-Use Networks.
+	netip.MustParsePrefix("192.168.0.0/16"),
+	// Network Interconnect Device Benchmark Testing.
+	netip.MustParsePrefix("198.18.0.0/15"),
+	// TEST-NET-2.
+	netip.MustParsePrefix("198.51.100.0/24"),
+	// TEST-NET-3.
+	netip.MustParsePrefix("203.0.113.0/24"),
+	// Reserved for Future Use.
+	netip.MustParsePrefix("240.0.0.0/4"),
+	// Limited Broadcast.
+	netip.MustParsePrefix("255.255.255.255/32"),
+
+	// Loopback.
+	netip.MustParsePrefix("::1/128"),
+	// Unspecified.
+	netip.MustParsePrefix("::/128"),
+	// IPv4-IPv6 Translation Address.
+	netip.MustParsePrefix("64:ff9b::/96"),
+
+	// IPv4-Mapped Address.  Since this network is used for mapping IPv4
+	// addresses, we don't include it.
+	// netip.MustParsePrefix("::ffff:0:0/96"),
+
+	// Discard-Only Prefix.
+	netip.MustParsePrefix("100::/64"),
+	// IETF Protocol Assignments.
+	netip.MustParsePrefix("2001::/23"),
+	// TEREDO.
+	netip.MustParsePrefix("2001::/32"),
+	// Benchmarking.
+	netip.MustParsePrefix("2001:2::/48"),
+	// Documentation.
+	netip.MustParsePrefix("2001:db8::/32"),
+	// ORCHID.
+	netip.MustParsePrefix("2001:10::/28"),
+	// 6to4.
+	netip.MustParsePrefix("2002::/16"),
+	// Unique-Local.
+	netip.MustParsePrefix("fc00::/7"),
+	// Linked-Scoped Unicast.
+	netip.MustParsePrefix("fe80::/10"),
+}
+
+// IsPrivateAddr returns true if ip belongs to the private address space, which
+// includes the RFC 1918 networks, the CGNAT shared address space, the IPv6
+// unique local addresses, and the link-local addresses of both families.
+// IPv4-mapped IPv6 addresses are unmapped, and the zone is ignored.
+func IsPrivateAddr(ip netip.Addr) (ok bool) {
+	return prefixesContain(privateNets, ip)
+}
+
+// IsSpecialPurpose returns true if ip belongs to any of the special-purpose
+// address registries, see RFC 6890.  IPv4-mapped IPv6 addresses are unmapped,
+// and the zone is ignored.
+func IsSpecialPurpose(ip netip.Addr) (ok bool) {
+	return prefixesContain(specialPurposeNets, ip)
+}
+
+// apipaNet is the IPv4 link-local network, see RFC 3927.
+var apipaNet = netip.MustParsePrefix("169.254.0.0/16")
+
+// IsAPIPA returns true if ip is an IPv4 link-local address, which the hosts
+// assign themselves automatically when DHCP fails, also known as APIPA.
+// IPv4-mapped IPv6 addresses are unmapped.
+func IsAPIPA(ip netip.Addr) (ok bool) {
+	return apipaNet.Contains(ip.Unmap())
+}
+
+// IsWildcardAddr returns true if ip is an unspecified address of either
+// family, which means binding to all the addresses of the family, i.e. 0.0.0.0,
+// ::, or the IPv4-mapped ::ffff:0.0.0.0.  The zone is ignored.  It's the
+// authoritative definition of the wildcard bind address in this package.
+func IsWildcardAddr(ip netip.Addr) (ok bool) {
+	return ip.WithZone("").Unmap().IsUnspecified()
+}
+
+// Address scopes.  These are stable and may be used as keys, e.g. for the
+// translations in the frontend.
+const (
+	AddrScopeUnspecified = "unspecified"
+	AddrScopeLoopback    = "loopback"
+	AddrScopeMulticast   = "multicast"
+	AddrScopeLinkLocal   = "link-local"
+	AddrScopeCGNAT       = "cgnat"
+	AddrScopeULA         = "ula"
+	AddrScopePrivate     = "private"
+	AddrScopeGlobal      = "global"
+)
+
+// cgnatNet is the Shared Address Space, also known as CGNAT, see RFC 6598.
+var cgnatNet = netip.MustParsePrefix("100.64.0.0/10")
+
+// ulaNet is the IPv6 Unique-Local network, see RFC 4193.
+var ulaNet = netip.MustParsePrefix("fc00::/7")
+
+// AddrScope returns the label of the scope ip belongs to, which is one of the
+// AddrScope constants.  The narrower scopes take precedence, so that, for
+// example, a CGNAT address is labeled as AddrScopeCGNAT rather than
+// AddrScopePrivate, although IsPrivateAddr reports it as private.  IPv4-mapped
+// IPv6 addresses are unmapped, and the zone is ignored.  It returns an empty
+// string if ip is invalid.
+func AddrScope(ip netip.Addr) (scope string) {
+	if !ip.IsValid() {
+		return ""
+	}
+
+	ip = ip.Unmap().WithZone("")
+	switch {
+	case IsWildcardAddr(ip):
+		return AddrScopeUnspecified
+	case ip.IsLoopback():
+		return AddrScopeLoopback
+	case ip.IsMulticast():
+		return AddrScopeMulticast
+	case IsAPIPA(ip), ip.IsLinkLocalUnicast():
+		return AddrScopeLinkLocal
+	case cgnatNet.Contains(ip):
+		return AddrScopeCGNAT
+	case ulaNet.Contains(ip):
+		return AddrScopeULA
+	case IsPrivateAddr(ip):
+		return AddrScopePrivate
+	default:
+		return AddrScopeGlobal
+	}
+}
+
+// prefixesContain returns true if any of ps contains the unmapped ip without
+// the zone.
+func prefixesContain(ps []netip.Prefix, ip netip.Addr) (ok bool) {
+	ip = ip.Unmap().WithZone("")
+	for _, p := range ps {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsNetworkAddr returns true if ip is the network address of n, i.e. the one
+// with all the host bits set to zero.  Within the IPv6 subnets, it's the
+// Subnet-Router anycast address, see RFC 4291.  It's always false for the IPv4
+// /31 and /32 subnets, see RFC 3021, as well as for the IPv6 /128 ones, since
+// all the addresses there are usable hosts.  The IPv4-mapped IPv6 addresses
+// and subnets are treated as the IPv4 ones.
+func IsNetworkAddr(ip netip.Addr, n netip.Prefix) (ok bool) {
+	ip, n, ok = specialAddrSubnet(ip, n)
+
+	return ok && ip == n.Masked().Addr()
+}
+
+// IsBroadcastAddr returns true if ip is the broadcast address of n, see
+// BroadcastFromPrefix.  It's always false for the IPv6 subnets, since IPv6 has
+// no broadcast, and for the IPv4 /31 and /32 subnets, see RFC 3021.  The
+// IPv4-mapped IPv6 addresses and subnets are treated as the IPv4 ones.
+func IsBroadcastAddr(ip netip.Addr, n netip.Prefix) (ok bool) {
+	ip, n, ok = specialAddrSubnet(ip, n)
+
+	return ok && ip.Is4() && ip == BroadcastFromPrefix(n)
+}
+
+// specialAddrSubnet returns the canonical forms of ip and n.  ok is true if n
+// contains ip and is large enough to have the network and the broadcast
+// addresses.
+func specialAddrSubnet(
+	ip netip.Addr,
+	n netip.Prefix,
+) (canonIP netip.Addr, canonN netip.Prefix, ok bool) {
+	canonIP, canonN = CanonicalAddr(ip), canonicalPrefix(n)
+	if !canonN.IsValid() || !canonN.Contains(canonIP) {
+		return canonIP, canonN, false
+	}
+
+	maxBits := netutil.IPv6BitLen
+	if canonIP.Is4() {
+		maxBits = netutil.IPv4BitLen - 1
+	}
+
+	return canonIP, canonN, canonN.Bits() < maxBits
+}
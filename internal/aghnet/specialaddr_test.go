@@ -0,0 +1,355 @@
+package aghnet
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPrivateAddr(t *testing.T) {
+	testCases := []struct {
+		name string
+		ip   string
+		want bool
+	}{{
+		name: "rfc1918_10",
+		ip:   "10.1.2.3",
+		want: true,
+	}, {
+		name: "rfc1918_172",
+		ip:   "172.31.255.255",
+		want: true,
+	}, {
+		name: "rfc1918_192",
+		ip:   "192.168.1.1",
+		want: true,
+	}, {
+		name: "cgnat",
+		ip:   "100.64.0.1",
+		want: true,
+	}, {
+		name: "link_local_ipv4",
+		ip:   "169.254.1.1",
+		want: true,
+	}, {
+		name: "ula",
+		ip:   "fd12:3456::1",
+		want: true,
+	}, {
+		name: "link_local_ipv6_zone",
+		ip:   "fe80::1%eth0",
+		want: true,
+	}, {
+		name: "mapped",
+		ip:   "::ffff:192.168.1.1",
+		want: true,
+	}, {
+		name: "public_ipv4",
+		ip:   "172.32.0.1",
+		want: false,
+	}, {
+		name: "public_ipv6",
+		ip:   "2a00:1450::1",
+		want: false,
+	}, {
+		name: "loopback",
+		ip:   "127.0.0.1",
+		want: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsPrivateAddr(netip.MustParseAddr(tc.ip)))
+		})
+	}
+
+	assert.False(t, IsPrivateAddr(netip.Addr{}))
+}
+
+func TestIsAPIPA(t *testing.T) {
+	testCases := []struct {
+		name string
+		ip   netip.Addr
+		want bool
+	}{{
+		name: "apipa",
+		ip:   netip.MustParseAddr("169.254.12.34"),
+		want: true,
+	}, {
+		name: "apipa_mapped",
+		ip:   netip.MustParseAddr("::ffff:169.254.0.1"),
+		want: true,
+	}, {
+		name: "private",
+		ip:   netip.MustParseAddr("192.168.1.1"),
+		want: false,
+	}, {
+		name: "link_local_ipv6",
+		ip:   netip.MustParseAddr("fe80::1"),
+		want: false,
+	}, {
+		name: "invalid",
+		ip:   netip.Addr{},
+		want: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsAPIPA(tc.ip))
+		})
+	}
+}
+
+func TestIsWildcardAddr(t *testing.T) {
+	testCases := []struct {
+		name string
+		ip   netip.Addr
+		want bool
+	}{{
+		name: "ipv4",
+		ip:   netip.IPv4Unspecified(),
+		want: true,
+	}, {
+		name: "ipv6",
+		ip:   netip.IPv6Unspecified(),
+		want: true,
+	}, {
+		name: "ipv4_mapped",
+		ip:   netip.MustParseAddr("::ffff:0.0.0.0"),
+		want: true,
+	}, {
+		name: "ipv6_zone",
+		ip:   netip.MustParseAddr("::%eth0"),
+		want: true,
+	}, {
+		name: "ipv4_specified",
+		ip:   netip.MustParseAddr("192.168.1.1"),
+		want: false,
+	}, {
+		name: "ipv6_specified",
+		ip:   netip.MustParseAddr("::1"),
+		want: false,
+	}, {
+		name: "ipv4_compatible",
+		ip:   netip.MustParseAddr("::0.0.0.1"),
+		want: false,
+	}, {
+		name: "invalid",
+		ip:   netip.Addr{},
+		want: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsWildcardAddr(tc.ip))
+		})
+	}
+}
+
+func TestIsSpecialPurpose(t *testing.T) {
+	testCases := []struct {
+		name string
+		ip   string
+		want bool
+	}{{
+		name: "private",
+		ip:   "192.168.1.1",
+		want: true,
+	}, {
+		name: "loopback",
+		ip:   "127.0.0.1",
+		want: true,
+	}, {
+		name: "documentation",
+		ip:   "2001:db8::1",
+		want: true,
+	}, {
+		name: "broadcast",
+		ip:   "255.255.255.255",
+		want: true,
+	}, {
+		name: "mapped_public",
+		ip:   "::ffff:8.8.8.8",
+		want: false,
+	}, {
+		name: "public",
+		ip:   "1.1.1.1",
+		want: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsSpecialPurpose(netip.MustParseAddr(tc.ip)))
+		})
+	}
+}
+
+func TestAddrScope(t *testing.T) {
+	testCases := []struct {
+		name string
+		ip   string
+		want string
+	}{{
+		name: "unspecified_ipv4",
+		ip:   "0.0.0.0",
+		want: AddrScopeUnspecified,
+	}, {
+		name: "unspecified_ipv6",
+		ip:   "::",
+		want: AddrScopeUnspecified,
+	}, {
+		name: "loopback_ipv4",
+		ip:   "127.0.0.53",
+		want: AddrScopeLoopback,
+	}, {
+		name: "loopback_ipv6",
+		ip:   "::1",
+		want: AddrScopeLoopback,
+	}, {
+		name: "multicast_ipv4",
+		ip:   "224.0.0.251",
+		want: AddrScopeMulticast,
+	}, {
+		name: "multicast_ipv6",
+		ip:   "ff02::1",
+		want: AddrScopeMulticast,
+	}, {
+		name: "link_local_ipv4",
+		ip:   "169.254.1.1",
+		want: AddrScopeLinkLocal,
+	}, {
+		name: "link_local_ipv6_zone",
+		ip:   "fe80::1%eth0",
+		want: AddrScopeLinkLocal,
+	}, {
+		name: "cgnat",
+		ip:   "100.64.0.1",
+		want: AddrScopeCGNAT,
+	}, {
+		name: "ula",
+		ip:   "fd12:3456::1",
+		want: AddrScopeULA,
+	}, {
+		name: "private",
+		ip:   "192.168.1.1",
+		want: AddrScopePrivate,
+	}, {
+		name: "private_mapped",
+		ip:   "::ffff:10.0.0.1",
+		want: AddrScopePrivate,
+	}, {
+		name: "global_ipv4",
+		ip:   "8.8.8.8",
+		want: AddrScopeGlobal,
+	}, {
+		name: "global_ipv6",
+		ip:   "2a00:1450::1",
+		want: AddrScopeGlobal,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, AddrScope(netip.MustParseAddr(tc.ip)))
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.Empty(t, AddrScope(netip.Addr{}))
+	})
+}
+
+func TestIsNetworkAddr_IsBroadcastAddr(t *testing.T) {
+	testCases := []struct {
+		name          string
+		ip            netip.Addr
+		subnet        netip.Prefix
+		wantNetwork   bool
+		wantBroadcast bool
+	}{{
+		name:          "ipv4_network",
+		ip:            netip.MustParseAddr("192.168.1.0"),
+		subnet:        netip.MustParsePrefix("192.168.1.0/24"),
+		wantNetwork:   true,
+		wantBroadcast: false,
+	}, {
+		name:          "ipv4_broadcast",
+		ip:            netip.MustParseAddr("192.168.1.255"),
+		subnet:        netip.MustParsePrefix("192.168.1.0/24"),
+		wantNetwork:   false,
+		wantBroadcast: true,
+	}, {
+		name:          "ipv4_host",
+		ip:            netip.MustParseAddr("192.168.1.1"),
+		subnet:        netip.MustParsePrefix("192.168.1.0/24"),
+		wantNetwork:   false,
+		wantBroadcast: false,
+	}, {
+		name:          "ipv4_unmasked_subnet",
+		ip:            netip.MustParseAddr("10.15.255.255"),
+		subnet:        netip.MustParsePrefix("10.0.0.2/12"),
+		wantNetwork:   false,
+		wantBroadcast: true,
+	}, {
+		name:          "ipv4_mapped",
+		ip:            netip.MustParseAddr("::ffff:192.168.1.0"),
+		subnet:        netip.MustParsePrefix("::ffff:192.168.1.0/120"),
+		wantNetwork:   true,
+		wantBroadcast: false,
+	}, {
+		name:          "ipv4_31_low",
+		ip:            netip.MustParseAddr("192.168.1.0"),
+		subnet:        netip.MustParsePrefix("192.168.1.0/31"),
+		wantNetwork:   false,
+		wantBroadcast: false,
+	}, {
+		name:          "ipv4_31_high",
+		ip:            netip.MustParseAddr("192.168.1.1"),
+		subnet:        netip.MustParsePrefix("192.168.1.0/31"),
+		wantNetwork:   false,
+		wantBroadcast: false,
+	}, {
+		name:          "ipv4_32",
+		ip:            netip.MustParseAddr("192.168.1.1"),
+		subnet:        netip.MustParsePrefix("192.168.1.1/32"),
+		wantNetwork:   false,
+		wantBroadcast: false,
+	}, {
+		name:          "ipv4_outside",
+		ip:            netip.MustParseAddr("192.168.2.0"),
+		subnet:        netip.MustParsePrefix("192.168.1.0/24"),
+		wantNetwork:   false,
+		wantBroadcast: false,
+	}, {
+		name:          "ipv6_anycast",
+		ip:            netip.MustParseAddr("2001:db8::"),
+		subnet:        netip.MustParsePrefix("2001:db8::/64"),
+		wantNetwork:   true,
+		wantBroadcast: false,
+	}, {
+		name:          "ipv6_all_ones",
+		ip:            netip.MustParseAddr("2001:db8::ffff:ffff:ffff:ffff"),
+		subnet:        netip.MustParsePrefix("2001:db8::/64"),
+		wantNetwork:   false,
+		wantBroadcast: false,
+	}, {
+		name:          "ipv6_128",
+		ip:            netip.MustParseAddr("2001:db8::1"),
+		subnet:        netip.MustParsePrefix("2001:db8::1/128"),
+		wantNetwork:   false,
+		wantBroadcast: false,
+	}, {
+		name:          "invalid_subnet",
+		ip:            netip.MustParseAddr("192.168.1.0"),
+		subnet:        netip.Prefix{},
+		wantNetwork:   false,
+		wantBroadcast: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantNetwork, IsNetworkAddr(tc.ip, tc.subnet))
+			assert.Equal(t, tc.wantBroadcast, IsBroadcastAddr(tc.ip, tc.subnet))
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// Errors returned by ValidateStaticLease.
+const (
+	// ErrLeaseOutOfSubnet is returned when the IP address of the lease isn't
+	// within the subnet of the network interface.
+	ErrLeaseOutOfSubnet errors.Error = "lease ip is out of the subnet"
+
+	// ErrLeaseIsGateway is returned when the IP address of the lease is the
+	// address of the gateway.
+	ErrLeaseIsGateway errors.Error = "lease ip is the gateway ip"
+
+	// ErrLeaseIsServer is returned when the IP address of the lease is the
+	// address of the server itself.
+	ErrLeaseIsServer errors.Error = "lease ip is the server ip"
+
+	// ErrLeaseBadMAC is returned when the hardware address of the lease is
+	// invalid.
+	ErrLeaseBadMAC errors.Error = "bad lease mac"
+
+	// ErrLeaseIsNetwork is returned when the IP address of the lease is the
+	// network address of the subnet.
+	ErrLeaseIsNetwork errors.Error = "lease ip is the network address"
+
+	// ErrLeaseIsBroadcast is returned when the IP address of the lease is the
+	// broadcast address of the subnet.
+	ErrLeaseIsBroadcast errors.Error = "lease ip is the broadcast address"
+)
+
+// ValidateStaticLease checks if the static lease for the host with mac and
+// leaseIP can be served within ifaceSubnet by the server with serverIP and the
+// specified gateway.  The gateway and the server addresses are only checked if
+// they're valid.  The IPv4-mapped IPv6 addresses are treated as the IPv4 ones.
+// The network and the broadcast addresses are only reserved within the IPv4
+// subnets shorter than /31, see RFC 3021, and the network address is also
+// reserved within the IPv6 ones as the Subnet-Router anycast address.  mac is
+// validated with ValidateClientMAC.  err wraps one of the ErrLease* errors.
+func ValidateStaticLease(
+	ifaceSubnet netip.Prefix,
+	gateway netip.Addr,
+	serverIP netip.Addr,
+	leaseIP netip.Addr,
+	mac net.HardwareAddr,
+) (err error) {
+	defer func() { err = errors.Annotate(err, "validating static lease for %s: %w", leaseIP) }()
+
+	err = ValidateClientMAC(mac)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrLeaseBadMAC, err)
+	}
+
+	ifaceSubnet = canonicalPrefix(ifaceSubnet)
+	leaseIP = CanonicalAddr(leaseIP)
+	if !ifaceSubnet.IsValid() || !ifaceSubnet.Contains(leaseIP) {
+		return fmt.Errorf("%w %s", ErrLeaseOutOfSubnet, ifaceSubnet)
+	}
+
+	if gw := CanonicalAddr(gateway); gw.IsValid() && gw == leaseIP {
+		return ErrLeaseIsGateway
+	}
+
+	if srv := CanonicalAddr(serverIP); srv.IsValid() && srv == leaseIP {
+		return ErrLeaseIsServer
+	}
+
+	return validateSpecialAddr(ifaceSubnet, leaseIP)
+}
+
+// validateSpecialAddr returns an error if ip is the network or the broadcast
+// address of the canonical subnet n.
+func validateSpecialAddr(n netip.Prefix, ip netip.Addr) (err error) {
+	if IsNetworkAddr(ip, n) {
+		return ErrLeaseIsNetwork
+	} else if IsBroadcastAddr(ip, n) {
+		return ErrLeaseIsBroadcast
+	}
+
+	return nil
+}
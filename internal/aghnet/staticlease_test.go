@@ -0,0 +1,109 @@
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStaticLease(t *testing.T) {
+	subnet := netip.MustParsePrefix("192.168.1.0/24")
+	gateway := netip.MustParseAddr("192.168.1.1")
+	serverIP := netip.MustParseAddr("192.168.1.2")
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+
+	testCases := []struct {
+		wantErr error
+		subnet  netip.Prefix
+		leaseIP netip.Addr
+		name    string
+		mac     net.HardwareAddr
+	}{{
+		wantErr: nil,
+		subnet:  subnet,
+		leaseIP: netip.MustParseAddr("192.168.1.100"),
+		name:    "valid",
+		mac:     mac,
+	}, {
+		wantErr: nil,
+		subnet:  subnet,
+		leaseIP: netip.MustParseAddr("::ffff:192.168.1.100"),
+		name:    "valid_mapped",
+		mac:     mac,
+	}, {
+		wantErr: nil,
+		subnet:  netip.MustParsePrefix("192.168.1.0/31"),
+		leaseIP: netip.MustParseAddr("192.168.1.0"),
+		name:    "point_to_point",
+		mac:     mac,
+	}, {
+		wantErr: ErrLeaseOutOfSubnet,
+		subnet:  subnet,
+		leaseIP: netip.MustParseAddr("192.168.2.100"),
+		name:    "out_of_subnet",
+		mac:     mac,
+	}, {
+		wantErr: ErrLeaseOutOfSubnet,
+		subnet:  netip.Prefix{},
+		leaseIP: netip.MustParseAddr("192.168.1.100"),
+		name:    "no_subnet",
+		mac:     mac,
+	}, {
+		wantErr: ErrLeaseIsGateway,
+		subnet:  subnet,
+		leaseIP: gateway,
+		name:    "gateway",
+		mac:     mac,
+	}, {
+		wantErr: ErrLeaseIsServer,
+		subnet:  subnet,
+		leaseIP: serverIP,
+		name:    "server",
+		mac:     mac,
+	}, {
+		wantErr: ErrLeaseBadMAC,
+		subnet:  subnet,
+		leaseIP: netip.MustParseAddr("192.168.1.100"),
+		name:    "bad_mac",
+		mac:     net.HardwareAddr{0x00, 0x11},
+	}, {
+		wantErr: ErrLeaseIsNetwork,
+		subnet:  subnet,
+		leaseIP: netip.MustParseAddr("192.168.1.0"),
+		name:    "network",
+		mac:     mac,
+	}, {
+		wantErr: ErrLeaseIsBroadcast,
+		subnet:  subnet,
+		leaseIP: netip.MustParseAddr("192.168.1.255"),
+		name:    "broadcast",
+		mac:     mac,
+	}, {
+		wantErr: ErrLeaseIsNetwork,
+		subnet:  netip.MustParsePrefix("2001:db8::/64"),
+		leaseIP: netip.MustParseAddr("2001:db8::"),
+		name:    "ipv6_anycast",
+		mac:     mac,
+	}, {
+		wantErr: nil,
+		subnet:  netip.MustParsePrefix("2001:db8::/64"),
+		leaseIP: netip.MustParseAddr("2001:db8::ffff:ffff:ffff:ffff"),
+		name:    "ipv6_no_broadcast",
+		mac:     mac,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateStaticLease(tc.subnet, gateway, serverIP, tc.leaseIP, tc.mac)
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
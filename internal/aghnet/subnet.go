@@ -0,0 +1,567 @@
+package aghnet
+
+import (
+	"fmt"
+	"math/bits"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/netutil"
+)
+
+// SubnetContains returns true if n contains ip.  It returns false if either of
+// them is nil or if they belong to different address families.
+func SubnetContains(n *net.IPNet, ip net.IP) (ok bool) {
+	if n == nil || ip == nil {
+		return false
+	}
+
+	if isIPv4 := n.IP.To4() != nil; isIPv4 != (ip.To4() != nil) {
+		return false
+	}
+
+	return n.Contains(ip)
+}
+
+// PrefixesOverlap returns true if a and b contain at least one common address.
+// It returns false if either of them is invalid or if they belong to different
+// address families.
+func PrefixesOverlap(a, b netip.Prefix) (ok bool) {
+	return a.Overlaps(b)
+}
+
+// RangeInSubnet returns true if the inclusive range of addresses from start to
+// end lies within n entirely.  It returns false if the range is inverted, if any
+// of the arguments is invalid, or if they belong to different address families.
+// IPv4-mapped IPv6 addresses never belong to IPv4 prefixes, so callers should
+// unmap the addresses when needed.
+func RangeInSubnet(start, end netip.Addr, n netip.Prefix) (ok bool) {
+	if !start.IsValid() || !end.IsValid() || !n.IsValid() {
+		return false
+	}
+
+	if start.Is4() != end.Is4() || start.Compare(end) > 0 {
+		return false
+	}
+
+	return n.Contains(start) && n.Contains(end)
+}
+
+// IterateAddrRange calls f for each address from start to end, both inclusive,
+// in ascending order until f returns false.  The addresses are generated one at
+// a time, so the range may be arbitrarily large.  The zones of start and end
+// are ignored.  err is not nil if the range is inverted or if start and end are
+// invalid or belong to different address families.
+func IterateAddrRange(start, end netip.Addr, f func(ip netip.Addr) (cont bool)) (err error) {
+	switch {
+	case !start.IsValid(), !end.IsValid():
+		return fmt.Errorf("bad range: invalid address in %s-%s", start, end)
+	case start.Is4() != end.Is4():
+		return fmt.Errorf("bad range: family mismatch in %s-%s", start, end)
+	case start.Compare(end) > 0:
+		return fmt.Errorf("bad range: %s is greater than %s", start, end)
+	}
+
+	start, end = start.WithZone(""), end.WithZone("")
+	for ip := start; ; ip = ip.Next() {
+		if !f(ip) || ip == end {
+			return nil
+		}
+	}
+}
+
+// HostCount returns the number of assignable host addresses in n.  For IPv4,
+// the network and the broadcast addresses are excluded, except for /31
+// prefixes, which are point-to-point links with both addresses assignable, see
+// RFC 3021, and /32 ones, which contain a single host.  For IPv6, all addresses
+// are counted.  The IPv4-mapped IPv6 prefixes are treated as the IPv4 ones.  ok
+// is false if n is invalid or if the number doesn't fit into uint64.
+func HostCount(n netip.Prefix) (count uint64, ok bool) {
+	n = canonicalPrefix(n)
+	if !n.IsValid() {
+		return 0, false
+	}
+
+	hostBits := n.Addr().BitLen() - n.Bits()
+	if !n.Addr().Is4() {
+		if hostBits >= 64 {
+			return 0, false
+		}
+
+		return 1 << hostBits, true
+	}
+
+	switch hostBits {
+	case 0:
+		return 1, true
+	case 1:
+		return 2, true
+	default:
+		return 1<<hostBits - 2, true
+	}
+}
+
+// SubnetRange returns the first and the last assignable host addresses of n,
+// following the same rules as HostCount.  For IPv4, the network and the
+// broadcast addresses are excluded unless n is a /31 or a /32 prefix.  For
+// IPv6, the first and the last addresses of n are returned.  The IPv4-mapped
+// IPv6 prefixes are treated as the IPv4 ones, so first and last are unmapped.
+// first and last are invalid if n is invalid.
+func SubnetRange(n netip.Prefix) (first, last netip.Addr) {
+	n = canonicalPrefix(n)
+	if !n.IsValid() {
+		return netip.Addr{}, netip.Addr{}
+	}
+
+	n = n.Masked()
+	first, last = n.Addr(), lastAddr(n)
+	if n.Addr().Is4() && n.Bits() < 31 {
+		first, last = first.Next(), last.Prev()
+	}
+
+	return first, last
+}
+
+// SuggestGateway returns the gateway address conventionally used for the new
+// subnet n.  If serverIP belongs to n, it's returned, since the DHCP server is
+// usually the gateway itself.  Otherwise, it's the first assignable host
+// address of n for IPv4, see SubnetRange, and the ::1 address of n for IPv6.
+// The IPv4-mapped IPv6 serverIP is unmapped.  gw is invalid if n is invalid.
+func SuggestGateway(n netip.Prefix, serverIP netip.Addr) (gw netip.Addr) {
+	if !n.IsValid() {
+		return netip.Addr{}
+	}
+
+	n = canonicalPrefix(n).Masked()
+	if serverIP = serverIP.WithZone("").Unmap(); n.Contains(serverIP) {
+		return serverIP
+	}
+
+	if n.Addr().Is4() {
+		gw, _ = SubnetRange(n)
+
+		return gw
+	}
+
+	gw = n.Addr()
+	if n.Bits() < gw.BitLen() {
+		gw = gw.Next()
+	}
+
+	return gw
+}
+
+// SupernetOf returns the smallest prefix containing all addrs.  All addresses
+// must be valid and belong to the same address family, and IPv4-mapped IPv6
+// addresses are considered IPv6 ones.  The zones are ignored.
+func SupernetOf(addrs []netip.Addr) (p netip.Prefix, err error) {
+	if len(addrs) == 0 {
+		return netip.Prefix{}, errors.Error("no addresses")
+	}
+
+	first := addrs[0].WithZone("")
+	if !first.IsValid() {
+		return netip.Prefix{}, errors.Error("bad address at index 0")
+	}
+
+	firstBytes := first.AsSlice()
+	common := first.BitLen()
+	for i, a := range addrs[1:] {
+		if !a.IsValid() {
+			return netip.Prefix{}, fmt.Errorf("bad address at index %d", i+1)
+		} else if a.Is4() != first.Is4() {
+			return netip.Prefix{}, fmt.Errorf("family mismatch at index %d: %s", i+1, a)
+		}
+
+		if n := commonBits(firstBytes, a.AsSlice()); n < common {
+			common = n
+		}
+	}
+
+	// Since the number of bits is correct, the error is always nil.
+	return first.Prefix(common)
+}
+
+// commonBits returns the length of the longest common bit prefix of a and b,
+// which must have the same length.
+func commonBits(a, b []byte) (n int) {
+	for i := range a {
+		if x := a[i] ^ b[i]; x != 0 {
+			return n + bits.LeadingZeros8(x)
+		}
+
+		n += 8
+	}
+
+	return n
+}
+
+// MergePrefixes returns the minimal set of prefixes covering exactly the same
+// addresses as ps.  The invalid prefixes are dropped, the prefixes contained in
+// others are removed, and the adjacent sibling prefixes are coalesced into
+// their parent ones, for example 10.0.0.0/25 and 10.0.0.128/25 become
+// 10.0.0.0/24.  IPv4 and IPv6 prefixes are never merged.  The result is
+// sorted with the IPv4 prefixes first, so MergePrefixes is idempotent.  ps
+// isn't modified.
+func MergePrefixes(ps []netip.Prefix) (merged []netip.Prefix) {
+	sorted := make([]netip.Prefix, 0, len(ps))
+	for _, p := range ps {
+		if p.IsValid() {
+			sorted = append(sorted, p.Masked())
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) (less bool) {
+		if c := sorted[i].Addr().Compare(sorted[j].Addr()); c != 0 {
+			return c < 0
+		}
+
+		return sorted[i].Bits() < sorted[j].Bits()
+	})
+
+	for _, p := range sorted {
+		if l := len(merged); l > 0 && merged[l-1].Contains(p.Addr()) {
+			// Since the prefixes with the same address are sorted by length,
+			// the previous one contains p entirely.
+			continue
+		}
+
+		merged = append(merged, p)
+		for l := len(merged); l > 1; l = len(merged) {
+			parent, ok := siblingsParent(merged[l-2], merged[l-1])
+			if !ok {
+				break
+			}
+
+			merged = append(merged[:l-2], parent)
+		}
+	}
+
+	return merged
+}
+
+// SubtractAddrs returns the minimal sorted set of prefixes covering exactly the
+// addresses of pool which aren't covered by any of excluded.  The invalid
+// excluded prefixes and the ones of the other address family are ignored.  If
+// excluded cover the whole pool, the result is empty.  If pool is invalid, the
+// result is nil.
+func SubtractAddrs(pool netip.Prefix, excluded []netip.Prefix) (remaining []netip.Prefix) {
+	if !pool.IsValid() {
+		return nil
+	}
+
+	remaining = []netip.Prefix{pool.Masked()}
+	for _, e := range MergePrefixes(excluded) {
+		if e.Addr().Is4() != pool.Addr().Is4() {
+			continue
+		}
+
+		next := make([]netip.Prefix, 0, len(remaining))
+		for _, r := range remaining {
+			next = append(next, subtractPrefix(r, e)...)
+		}
+
+		remaining = next
+	}
+
+	if len(remaining) == 0 {
+		return []netip.Prefix{}
+	}
+
+	return MergePrefixes(remaining)
+}
+
+// subtractPrefix returns the prefixes covering the addresses of p which aren't
+// within e.  p and e must be masked and belong to the same address family.
+func subtractPrefix(p, e netip.Prefix) (diff []netip.Prefix) {
+	switch {
+	case !p.Overlaps(e):
+		return []netip.Prefix{p}
+	case e.Bits() <= p.Bits():
+		// Since the prefixes overlap, e contains p entirely.
+		return nil
+	}
+
+	// Split p into halves and only go on with the one containing e.  The
+	// errors are always nil, since the number of bits is correct.
+	lo, _ := p.Addr().Prefix(p.Bits() + 1)
+	hi := netip.PrefixFrom(lastAddr(lo).Next(), p.Bits()+1)
+	if lo.Contains(e.Addr()) {
+		return append(subtractPrefix(lo, e), hi)
+	}
+
+	return append([]netip.Prefix{lo}, subtractPrefix(hi, e)...)
+}
+
+// siblingsParent returns the parent prefix of a and b if they are two halves of
+// it.  a and b must be masked.
+func siblingsParent(a, b netip.Prefix) (parent netip.Prefix, ok bool) {
+	n := a.Bits()
+	if n == 0 || n != b.Bits() || a == b {
+		return netip.Prefix{}, false
+	}
+
+	// The errors are always nil, since the number of bits is correct.
+	parent, _ = a.Addr().Prefix(n - 1)
+	bParent, _ := b.Addr().Prefix(n - 1)
+
+	return parent, parent == bParent
+}
+
+// ParseAddrRange parses the inclusive range of IP addresses from s.  The
+// accepted formats are:
+//
+//   192.168.1.10-192.168.1.50  // Full form.
+//   192.168.1.10-50            // Short form with only the last octet of the end.
+//   192.168.1.0/24             // CIDR, the range of all its addresses.
+//   192.168.1.10               // Single address, start is equal to end.
+//
+// The full form, the CIDR, and the single address may also be IPv6.  err is not
+// nil if the addresses belong to different families or if start is greater than
+// end.
+func ParseAddrRange(s string) (start, end netip.Addr, err error) {
+	defer func() { err = errors.Annotate(err, "bad range %q: %w", s) }()
+
+	if strings.Contains(s, "/") {
+		var p netip.Prefix
+		p, err = netip.ParsePrefix(s)
+		if err != nil {
+			// Don't wrap the error, because it's informative enough as is.
+			return netip.Addr{}, netip.Addr{}, err
+		}
+
+		p = p.Masked()
+
+		return p.Addr(), lastAddr(p), nil
+	}
+
+	startStr, endStr, isRange := strings.Cut(s, "-")
+	start, err = netip.ParseAddr(startStr)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return netip.Addr{}, netip.Addr{}, err
+	} else if !isRange {
+		return start, start, nil
+	}
+
+	end, err = parseRangeEnd(start, endStr)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return netip.Addr{}, netip.Addr{}, err
+	}
+
+	if start.Is4() != end.Is4() {
+		return netip.Addr{}, netip.Addr{}, errors.Error("family mismatch")
+	} else if start.Compare(end) > 0 {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("%s is greater than %s", start, end)
+	}
+
+	return start, end, nil
+}
+
+// ParseHostCIDR parses s in the CIDR notation, like "192.168.1.5/24", keeping
+// the host address.  addr is the exact address from s and prefix is the masked
+// network, so that netip.PrefixFrom(addr, prefix.Bits()) reconstructs s.  The
+// IPv4-mapped IPv6 addresses are kept as is, and the number of bits must fit
+// the family of the address.  See also ParseHostCIDRStrict.
+func ParseHostCIDR(s string) (addr netip.Addr, prefix netip.Prefix, err error) {
+	return parseHostCIDR(s, false)
+}
+
+// ParseHostCIDRStrict is like ParseHostCIDR but also returns an error if the
+// address has any bits set beyond the mask, so that only the network CIDRs,
+// like "192.168.1.0/24", are accepted.
+func ParseHostCIDRStrict(s string) (addr netip.Addr, prefix netip.Prefix, err error) {
+	return parseHostCIDR(s, true)
+}
+
+// parseHostCIDR parses s in the CIDR notation.  If strict is true, the host
+// bits must be zero.
+func parseHostCIDR(s string, strict bool) (addr netip.Addr, prefix netip.Prefix, err error) {
+	defer func() { err = errors.Annotate(err, "bad cidr %q: %w", s) }()
+
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return netip.Addr{}, netip.Prefix{}, err
+	}
+
+	addr, prefix = p.Addr(), p.Masked()
+	if strict && addr != prefix.Addr() {
+		return netip.Addr{}, netip.Prefix{}, fmt.Errorf("host bits of %s are set beyond /%d", addr, p.Bits())
+	}
+
+	return addr, prefix, nil
+}
+
+// parseRangeEnd parses the end of the range starting at start, which may be
+// either a full address or only the last octet of an IPv4 one.
+func parseRangeEnd(start netip.Addr, s string) (end netip.Addr, err error) {
+	if !start.Is4() || strings.ContainsAny(s, ".:") {
+		return netip.ParseAddr(s)
+	}
+
+	octet, err := netip.ParseAddr("0.0.0." + s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("bad last octet %q", s)
+	}
+
+	b := start.As4()
+	b[3] = octet.As4()[3]
+
+	return netip.AddrFrom4(b), nil
+}
+
+// lastAddr returns the last address of the masked prefix p.
+func lastAddr(p netip.Prefix) (last netip.Addr) {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+
+	// The length of b is always correct.
+	last, _ = netip.AddrFromSlice(b)
+
+	return last
+}
+
+// AddrRangeToPrefixes returns the minimal sorted list of prefixes covering
+// exactly the inclusive range of addresses from start to end.  It returns nil if
+// the range is inverted or if start and end are invalid or belong to different
+// address families.
+func AddrRangeToPrefixes(start, end netip.Addr) (ps []netip.Prefix) {
+	if !start.IsValid() || !end.IsValid() || start.Is4() != end.Is4() {
+		return nil
+	}
+
+	start, end = start.WithZone(""), end.WithZone("")
+	for start.Compare(end) <= 0 {
+		p := largestPrefixFrom(start, end)
+		ps = append(ps, p)
+
+		last := lastAddr(p)
+		if last == end {
+			break
+		}
+
+		start = last.Next()
+	}
+
+	return ps
+}
+
+// largestPrefixFrom returns the largest prefix starting at start and ending not
+// after end.
+func largestPrefixFrom(start, end netip.Addr) (p netip.Prefix) {
+	// The errors are always nil, since the number of bits is correct.
+	p, _ = start.Prefix(start.BitLen())
+	for n := p.Bits() - 1; n >= 0; n-- {
+		wider, _ := start.Prefix(n)
+		if wider.Addr() != start || lastAddr(wider).Compare(end) > 0 {
+			break
+		}
+
+		p = wider
+	}
+
+	return p
+}
+
+// BroadcastFromPrefix calculates the broadcast IP address for p.  It returns
+// the zero netip.Addr if p is invalid.
+func BroadcastFromPrefix(p netip.Prefix) (bc netip.Addr) {
+	if !p.IsValid() {
+		return netip.Addr{}
+	}
+
+	addr := p.Addr()
+	b := addr.As16()
+	bits := p.Bits()
+	if addr.Is4() {
+		// Skip the IPv4-mapped IPv6 prefix in b.
+		bits += netutil.IPv6BitLen - netutil.IPv4BitLen
+	}
+
+	for i := bits; i < netutil.IPv6BitLen; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+
+	bc = netip.AddrFrom16(b)
+	if addr.Is4() {
+		return bc.Unmap()
+	}
+
+	return bc.WithZone(addr.Zone())
+}
+
+// ReverseAddr returns the fully-qualified ARPA domain name of ip suitable for
+// reverse DNS (PTR) record lookups.  IPv4-mapped IPv6 addresses are converted
+// into the in-addr.arpa form.
+func ReverseAddr(ip netip.Addr) (arpa string, err error) {
+	if !ip.IsValid() {
+		return "", errors.Error("invalid ip address")
+	}
+
+	arpa, err = netutil.IPToReversedAddr(ip.Unmap().AsSlice())
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return "", err
+	}
+
+	return arpa + ".", nil
+}
+
+// maxV4ZoneBits is the length of the longest IPv4 prefix having its own reverse
+// DNS zone, since the classless delegation of RFC 2317 isn't supported.
+const maxV4ZoneBits = 24
+
+// ReverseZones returns the fully-qualified names of the reverse DNS zones
+// covering n, e.g. to generate the PTR records for the DHCP leases.  The zones
+// are octet-aligned for IPv4 and nibble-aligned for IPv6, so the unaligned
+// prefixes are split into several zones, e.g. a /23 into two /24 ones.  IPv4
+// prefixes longer than /24 are covered by a single /24 zone.  IPv4-mapped IPv6
+// prefixes are converted into the in-addr.arpa form.
+func ReverseZones(n netip.Prefix) (zones []string, err error) {
+	defer func() { err = errors.Annotate(err, "reverse zones for %s: %w", n) }()
+
+	canon := canonicalPrefix(n).Masked()
+	if !canon.IsValid() {
+		return nil, errors.Error("invalid prefix")
+	}
+
+	labelBits, zoneBits := reverseZoneBits(canon)
+	dropLabels := (canon.Addr().BitLen() - zoneBits) / labelBits
+
+	start := netip.PrefixFrom(canon.Addr(), zoneBits).Masked().Addr()
+	end := lastAddr(canon)
+	for addr := start; addr.IsValid() && addr.Compare(end) <= 0; {
+		var arpa string
+		arpa, err = ReverseAddr(addr)
+		if err != nil {
+			// Don't wrap the error, because it's informative enough as is.
+			return nil, err
+		}
+
+		zones = append(zones, strings.SplitN(arpa, ".", dropLabels+1)[dropLabels])
+		addr = lastAddr(netip.PrefixFrom(addr, zoneBits)).Next()
+	}
+
+	return zones, nil
+}
+
+// reverseZoneBits returns the length of a reverse DNS zone label in bits and
+// the length of the reverse DNS zones covering the canonical prefix n.
+func reverseZoneBits(n netip.Prefix) (labelBits, zoneBits int) {
+	labelBits = 4
+	if n.Addr().Is4() {
+		labelBits = 8
+		if n.Bits() > maxV4ZoneBits {
+			return labelBits, maxV4ZoneBits
+		}
+	}
+
+	return labelBits, (n.Bits() + labelBits - 1) / labelBits * labelBits
+}
@@ -0,0 +1,1041 @@
+package aghnet
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubnetContains(t *testing.T) {
+	_, n4, err := net.ParseCIDR("192.168.1.0/24")
+	require.NoError(t, err)
+
+	_, n6, err := net.ParseCIDR("2001:db8::/64")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		n    *net.IPNet
+		name string
+		ip   net.IP
+		want bool
+	}{{
+		n:    n4,
+		name: "v4_inside",
+		ip:   net.IP{192, 168, 1, 100},
+		want: true,
+	}, {
+		n:    n4,
+		name: "v4_inside_16",
+		ip:   net.ParseIP("192.168.1.255"),
+		want: true,
+	}, {
+		n:    n4,
+		name: "v4_adjacent",
+		ip:   net.IP{192, 168, 2, 0},
+		want: false,
+	}, {
+		n:    n6,
+		name: "v6_inside",
+		ip:   net.ParseIP("2001:db8::1"),
+		want: true,
+	}, {
+		n:    n6,
+		name: "v6_outside",
+		ip:   net.ParseIP("2001:db8:0:1::1"),
+		want: false,
+	}, {
+		n:    n4,
+		name: "family_mismatch",
+		ip:   net.ParseIP("2001:db8::1"),
+		want: false,
+	}, {
+		n:    n6,
+		name: "family_mismatch_v4",
+		ip:   net.IP{192, 168, 1, 100},
+		want: false,
+	}, {
+		n:    nil,
+		name: "nil_subnet",
+		ip:   net.IP{192, 168, 1, 100},
+		want: false,
+	}, {
+		n:    n4,
+		name: "nil_ip",
+		ip:   nil,
+		want: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, SubnetContains(tc.n, tc.ip))
+		})
+	}
+}
+
+func TestPrefixesOverlap(t *testing.T) {
+	testCases := []struct {
+		name string
+		a    netip.Prefix
+		b    netip.Prefix
+		want bool
+	}{{
+		name: "same",
+		a:    netip.MustParsePrefix("192.168.1.0/24"),
+		b:    netip.MustParsePrefix("192.168.1.0/24"),
+		want: true,
+	}, {
+		name: "nested",
+		a:    netip.MustParsePrefix("192.168.0.0/16"),
+		b:    netip.MustParsePrefix("192.168.1.0/24"),
+		want: true,
+	}, {
+		name: "adjacent",
+		a:    netip.MustParsePrefix("192.168.0.0/24"),
+		b:    netip.MustParsePrefix("192.168.1.0/24"),
+		want: false,
+	}, {
+		name: "v6_nested",
+		a:    netip.MustParsePrefix("2001:db8::/32"),
+		b:    netip.MustParsePrefix("2001:db8:1::/48"),
+		want: true,
+	}, {
+		name: "family_mismatch",
+		a:    netip.MustParsePrefix("0.0.0.0/0"),
+		b:    netip.MustParsePrefix("::/0"),
+		want: false,
+	}, {
+		name: "invalid",
+		a:    netip.Prefix{},
+		b:    netip.MustParsePrefix("192.168.1.0/24"),
+		want: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, PrefixesOverlap(tc.a, tc.b))
+			assert.Equal(t, tc.want, PrefixesOverlap(tc.b, tc.a))
+		})
+	}
+}
+
+func TestRangeInSubnet(t *testing.T) {
+	n4 := netip.MustParsePrefix("192.168.1.0/24")
+	n6 := netip.MustParsePrefix("2001:db8::/64")
+
+	testCases := []struct {
+		name  string
+		start netip.Addr
+		end   netip.Addr
+		n     netip.Prefix
+		want  bool
+	}{{
+		name:  "inside",
+		start: netip.MustParseAddr("192.168.1.100"),
+		end:   netip.MustParseAddr("192.168.1.200"),
+		n:     n4,
+		want:  true,
+	}, {
+		name:  "whole",
+		start: netip.MustParseAddr("192.168.1.0"),
+		end:   netip.MustParseAddr("192.168.1.255"),
+		n:     n4,
+		want:  true,
+	}, {
+		name:  "single",
+		start: netip.MustParseAddr("192.168.1.1"),
+		end:   netip.MustParseAddr("192.168.1.1"),
+		n:     n4,
+		want:  true,
+	}, {
+		name:  "end_outside",
+		start: netip.MustParseAddr("192.168.1.100"),
+		end:   netip.MustParseAddr("192.168.2.0"),
+		n:     n4,
+		want:  false,
+	}, {
+		name:  "start_outside",
+		start: netip.MustParseAddr("192.168.0.255"),
+		end:   netip.MustParseAddr("192.168.1.100"),
+		n:     n4,
+		want:  false,
+	}, {
+		name:  "inverted",
+		start: netip.MustParseAddr("192.168.1.200"),
+		end:   netip.MustParseAddr("192.168.1.100"),
+		n:     n4,
+		want:  false,
+	}, {
+		name:  "v6",
+		start: netip.MustParseAddr("2001:db8::1"),
+		end:   netip.MustParseAddr("2001:db8::ffff"),
+		n:     n6,
+		want:  true,
+	}, {
+		name:  "family_mismatch",
+		start: netip.MustParseAddr("192.168.1.100"),
+		end:   netip.MustParseAddr("192.168.1.200"),
+		n:     n6,
+		want:  false,
+	}, {
+		name:  "mixed_range",
+		start: netip.MustParseAddr("::ffff:192.168.1.100"),
+		end:   netip.MustParseAddr("192.168.1.200"),
+		n:     n4,
+		want:  false,
+	}, {
+		name:  "invalid",
+		start: netip.Addr{},
+		end:   netip.MustParseAddr("192.168.1.200"),
+		n:     n4,
+		want:  false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, RangeInSubnet(tc.start, tc.end, tc.n))
+		})
+	}
+}
+
+func TestIterateAddrRange(t *testing.T) {
+	collect := func(limit int) (ips *[]netip.Addr, f func(ip netip.Addr) (cont bool)) {
+		ips = &[]netip.Addr{}
+
+		return ips, func(ip netip.Addr) (cont bool) {
+			*ips = append(*ips, ip)
+
+			return len(*ips) < limit
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		start      netip.Addr
+		end        netip.Addr
+		wantErrMsg string
+		want       []netip.Addr
+		limit      int
+	}{{
+		name:       "v4",
+		start:      netip.MustParseAddr("192.168.1.254"),
+		end:        netip.MustParseAddr("192.168.2.1"),
+		wantErrMsg: "",
+		want: []netip.Addr{
+			netip.MustParseAddr("192.168.1.254"),
+			netip.MustParseAddr("192.168.1.255"),
+			netip.MustParseAddr("192.168.2.0"),
+			netip.MustParseAddr("192.168.2.1"),
+		},
+		limit: 10,
+	}, {
+		name:       "single",
+		start:      netip.MustParseAddr("2001:db8::1"),
+		end:        netip.MustParseAddr("2001:db8::1"),
+		wantErrMsg: "",
+		want:       []netip.Addr{netip.MustParseAddr("2001:db8::1")},
+		limit:      10,
+	}, {
+		name:       "last_addr",
+		start:      netip.MustParseAddr("255.255.255.254"),
+		end:        netip.MustParseAddr("255.255.255.255"),
+		wantErrMsg: "",
+		want: []netip.Addr{
+			netip.MustParseAddr("255.255.255.254"),
+			netip.MustParseAddr("255.255.255.255"),
+		},
+		limit: 10,
+	}, {
+		name:       "stop_early",
+		start:      netip.MustParseAddr("2001:db8::"),
+		end:        netip.MustParseAddr("2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"),
+		wantErrMsg: "",
+		want: []netip.Addr{
+			netip.MustParseAddr("2001:db8::"),
+			netip.MustParseAddr("2001:db8::1"),
+		},
+		limit: 2,
+	}, {
+		name:       "inverted",
+		start:      netip.MustParseAddr("192.168.1.2"),
+		end:        netip.MustParseAddr("192.168.1.1"),
+		wantErrMsg: "bad range: 192.168.1.2 is greater than 192.168.1.1",
+		want:       []netip.Addr{},
+		limit:      10,
+	}, {
+		name:       "family_mismatch",
+		start:      netip.MustParseAddr("192.168.1.1"),
+		end:        netip.MustParseAddr("2001:db8::1"),
+		wantErrMsg: "bad range: family mismatch in 192.168.1.1-2001:db8::1",
+		want:       []netip.Addr{},
+		limit:      10,
+	}, {
+		name:       "invalid",
+		start:      netip.Addr{},
+		end:        netip.MustParseAddr("192.168.1.1"),
+		wantErrMsg: "bad range: invalid address in invalid IP-192.168.1.1",
+		want:       []netip.Addr{},
+		limit:      10,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ips, f := collect(tc.limit)
+
+			err := IterateAddrRange(tc.start, tc.end, f)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.want, *ips)
+		})
+	}
+}
+
+func TestHostCount(t *testing.T) {
+	testCases := []struct {
+		name   string
+		prefix netip.Prefix
+		want   uint64
+		wantOK bool
+	}{{
+		name:   "ipv4_24",
+		prefix: netip.MustParsePrefix("192.168.1.0/24"),
+		want:   254,
+		wantOK: true,
+	}, {
+		name:   "ipv4_30",
+		prefix: netip.MustParsePrefix("192.168.1.0/30"),
+		want:   2,
+		wantOK: true,
+	}, {
+		name:   "ipv4_31",
+		prefix: netip.MustParsePrefix("192.168.1.0/31"),
+		want:   2,
+		wantOK: true,
+	}, {
+		name:   "ipv4_32",
+		prefix: netip.MustParsePrefix("192.168.1.1/32"),
+		want:   1,
+		wantOK: true,
+	}, {
+		name:   "ipv4_0",
+		prefix: netip.MustParsePrefix("0.0.0.0/0"),
+		want:   1<<32 - 2,
+		wantOK: true,
+	}, {
+		name:   "ipv4_mapped",
+		prefix: netip.MustParsePrefix("::ffff:10.0.0.0/120"),
+		want:   254,
+		wantOK: true,
+	}, {
+		name:   "ipv6_120",
+		prefix: netip.MustParsePrefix("2001:db8::/120"),
+		want:   256,
+		wantOK: true,
+	}, {
+		name:   "ipv6_65",
+		prefix: netip.MustParsePrefix("2001:db8::/65"),
+		want:   1 << 63,
+		wantOK: true,
+	}, {
+		name:   "ipv6_64",
+		prefix: netip.MustParsePrefix("2001:db8::/64"),
+		want:   0,
+		wantOK: false,
+	}, {
+		name:   "invalid",
+		prefix: netip.Prefix{},
+		want:   0,
+		wantOK: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			n, ok := HostCount(tc.prefix)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, n)
+		})
+	}
+}
+
+func TestSuggestGateway(t *testing.T) {
+	testCases := []struct {
+		name     string
+		prefix   netip.Prefix
+		serverIP netip.Addr
+		want     netip.Addr
+	}{{
+		name:     "ipv4_24",
+		prefix:   netip.MustParsePrefix("192.168.1.0/24"),
+		serverIP: netip.Addr{},
+		want:     netip.MustParseAddr("192.168.1.1"),
+	}, {
+		name:     "ipv4_24_server",
+		prefix:   netip.MustParsePrefix("192.168.1.0/24"),
+		serverIP: netip.MustParseAddr("192.168.1.2"),
+		want:     netip.MustParseAddr("192.168.1.2"),
+	}, {
+		name:     "ipv4_24_server_mapped",
+		prefix:   netip.MustParsePrefix("192.168.1.0/24"),
+		serverIP: netip.MustParseAddr("::ffff:192.168.1.2"),
+		want:     netip.MustParseAddr("192.168.1.2"),
+	}, {
+		name:     "ipv4_24_server_outside",
+		prefix:   netip.MustParsePrefix("192.168.1.0/24"),
+		serverIP: netip.MustParseAddr("192.168.2.2"),
+		want:     netip.MustParseAddr("192.168.1.1"),
+	}, {
+		name:     "ipv4_30_unmasked",
+		prefix:   netip.MustParsePrefix("10.0.0.6/30"),
+		serverIP: netip.Addr{},
+		want:     netip.MustParseAddr("10.0.0.5"),
+	}, {
+		name:     "ipv6_64",
+		prefix:   netip.MustParsePrefix("2001:db8::/64"),
+		serverIP: netip.MustParseAddr("192.168.1.2"),
+		want:     netip.MustParseAddr("2001:db8::1"),
+	}, {
+		name:     "ipv6_64_server",
+		prefix:   netip.MustParsePrefix("2001:db8::/64"),
+		serverIP: netip.MustParseAddr("2001:db8::2"),
+		want:     netip.MustParseAddr("2001:db8::2"),
+	}, {
+		name:     "invalid",
+		prefix:   netip.Prefix{},
+		serverIP: netip.MustParseAddr("192.168.1.2"),
+		want:     netip.Addr{},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, SuggestGateway(tc.prefix, tc.serverIP))
+		})
+	}
+}
+
+func TestSubnetRange(t *testing.T) {
+	testCases := []struct {
+		name      string
+		prefix    netip.Prefix
+		wantFirst netip.Addr
+		wantLast  netip.Addr
+	}{{
+		name:      "ipv4_24",
+		prefix:    netip.MustParsePrefix("192.168.1.0/24"),
+		wantFirst: netip.MustParseAddr("192.168.1.1"),
+		wantLast:  netip.MustParseAddr("192.168.1.254"),
+	}, {
+		name:      "ipv4_24_unmasked",
+		prefix:    netip.MustParsePrefix("192.168.1.2/24"),
+		wantFirst: netip.MustParseAddr("192.168.1.1"),
+		wantLast:  netip.MustParseAddr("192.168.1.254"),
+	}, {
+		name:      "ipv4_30",
+		prefix:    netip.MustParsePrefix("10.0.0.4/30"),
+		wantFirst: netip.MustParseAddr("10.0.0.5"),
+		wantLast:  netip.MustParseAddr("10.0.0.6"),
+	}, {
+		name:      "ipv4_31",
+		prefix:    netip.MustParsePrefix("10.0.0.4/31"),
+		wantFirst: netip.MustParseAddr("10.0.0.4"),
+		wantLast:  netip.MustParseAddr("10.0.0.5"),
+	}, {
+		name:      "ipv4_32",
+		prefix:    netip.MustParsePrefix("10.0.0.4/32"),
+		wantFirst: netip.MustParseAddr("10.0.0.4"),
+		wantLast:  netip.MustParseAddr("10.0.0.4"),
+	}, {
+		name:      "ipv4_mapped",
+		prefix:    netip.MustParsePrefix("::ffff:10.0.0.0/120"),
+		wantFirst: netip.MustParseAddr("10.0.0.1"),
+		wantLast:  netip.MustParseAddr("10.0.0.254"),
+	}, {
+		name:      "ipv6_64",
+		prefix:    netip.MustParsePrefix("2001:db8::/64"),
+		wantFirst: netip.MustParseAddr("2001:db8::"),
+		wantLast:  netip.MustParseAddr("2001:db8::ffff:ffff:ffff:ffff"),
+	}, {
+		name:      "invalid",
+		prefix:    netip.Prefix{},
+		wantFirst: netip.Addr{},
+		wantLast:  netip.Addr{},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			first, last := SubnetRange(tc.prefix)
+			assert.Equal(t, tc.wantFirst, first)
+			assert.Equal(t, tc.wantLast, last)
+		})
+	}
+}
+
+func TestSupernetOf(t *testing.T) {
+	testCases := []struct {
+		name       string
+		wantErrMsg string
+		addrs      []netip.Addr
+		want       netip.Prefix
+	}{{
+		name:       "single_ipv4",
+		wantErrMsg: "",
+		addrs:      []netip.Addr{netip.MustParseAddr("192.168.1.1")},
+		want:       netip.MustParsePrefix("192.168.1.1/32"),
+	}, {
+		name:       "single_ipv6",
+		wantErrMsg: "",
+		addrs:      []netip.Addr{netip.MustParseAddr("2001:db8::1")},
+		want:       netip.MustParsePrefix("2001:db8::1/128"),
+	}, {
+		name:       "same_subnet",
+		wantErrMsg: "",
+		addrs: []netip.Addr{
+			netip.MustParseAddr("192.168.1.10"),
+			netip.MustParseAddr("192.168.1.200"),
+		},
+		want: netip.MustParsePrefix("192.168.1.0/24"),
+	}, {
+		name:       "adjacent_subnets",
+		wantErrMsg: "",
+		addrs: []netip.Addr{
+			netip.MustParseAddr("192.168.0.1"),
+			netip.MustParseAddr("192.168.1.1"),
+		},
+		want: netip.MustParsePrefix("192.168.0.0/23"),
+	}, {
+		name:       "ipv6",
+		wantErrMsg: "",
+		addrs: []netip.Addr{
+			netip.MustParseAddr("2001:db8::1"),
+			netip.MustParseAddr("2001:db8::ffff"),
+			netip.MustParseAddr("2001:db8::8000"),
+		},
+		want: netip.MustParsePrefix("2001:db8::/112"),
+	}, {
+		name:       "empty",
+		wantErrMsg: "no addresses",
+		addrs:      nil,
+		want:       netip.Prefix{},
+	}, {
+		name:       "family_mismatch",
+		wantErrMsg: "family mismatch at index 1: 2001:db8::1",
+		addrs: []netip.Addr{
+			netip.MustParseAddr("192.168.1.1"),
+			netip.MustParseAddr("2001:db8::1"),
+		},
+		want: netip.Prefix{},
+	}, {
+		name:       "invalid",
+		wantErrMsg: "bad address at index 1",
+		addrs:      []netip.Addr{netip.MustParseAddr("192.168.1.1"), {}},
+		want:       netip.Prefix{},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := SupernetOf(tc.addrs)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.want, p)
+		})
+	}
+}
+
+func TestMergePrefixes(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   []string
+		want []string
+	}{{
+		name: "siblings",
+		in:   []string{"10.0.0.128/25", "10.0.0.0/25"},
+		want: []string{"10.0.0.0/24"},
+	}, {
+		name: "cascade",
+		in:   []string{"10.0.0.0/25", "10.0.0.192/26", "10.0.0.128/26", "10.0.1.0/24"},
+		want: []string{"10.0.0.0/23"},
+	}, {
+		name: "contained",
+		in:   []string{"10.0.0.1/32", "10.0.0.0/8", "10.1.0.0/16", "10.0.0.0/8"},
+		want: []string{"10.0.0.0/8"},
+	}, {
+		name: "not_siblings",
+		in:   []string{"10.0.0.128/25", "10.0.1.0/25"},
+		want: []string{"10.0.0.128/25", "10.0.1.0/25"},
+	}, {
+		name: "unmasked",
+		in:   []string{"192.168.1.1/25", "192.168.1.200/25"},
+		want: []string{"192.168.1.0/24"},
+	}, {
+		name: "families",
+		in:   []string{"2001:db8::/33", "2001:db8:8000::/33", "0.0.0.0/1", "128.0.0.0/1"},
+		want: []string{"0.0.0.0/0", "2001:db8::/32"},
+	}, {
+		name: "empty",
+		in:   nil,
+		want: nil,
+	}}
+
+	parse := func(ss []string) (ps []netip.Prefix) {
+		for _, s := range ss {
+			ps = append(ps, netip.MustParsePrefix(s))
+		}
+
+		return ps
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			want := parse(tc.want)
+
+			merged := MergePrefixes(parse(tc.in))
+			assert.Equal(t, want, merged)
+
+			assert.Equal(t, want, MergePrefixes(merged))
+		})
+	}
+}
+
+func TestSubtractAddrs(t *testing.T) {
+	pool := netip.MustParsePrefix("192.168.1.0/24")
+
+	testCases := []struct {
+		name     string
+		pool     netip.Prefix
+		excluded []netip.Prefix
+		want     []netip.Prefix
+	}{{
+		name:     "nothing",
+		pool:     pool,
+		excluded: nil,
+		want:     []netip.Prefix{pool},
+	}, {
+		name:     "everything",
+		pool:     pool,
+		excluded: []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")},
+		want:     []netip.Prefix{},
+	}, {
+		name:     "exact",
+		pool:     pool,
+		excluded: []netip.Prefix{pool},
+		want:     []netip.Prefix{},
+	}, {
+		name:     "half",
+		pool:     pool,
+		excluded: []netip.Prefix{netip.MustParsePrefix("192.168.1.128/25")},
+		want:     []netip.Prefix{netip.MustParsePrefix("192.168.1.0/25")},
+	}, {
+		name:     "single_addr",
+		pool:     netip.MustParsePrefix("10.0.0.0/29"),
+		excluded: []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")},
+		want: []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/32"),
+			netip.MustParsePrefix("10.0.0.2/31"),
+			netip.MustParsePrefix("10.0.0.4/30"),
+		},
+	}, {
+		name: "several",
+		pool: netip.MustParsePrefix("10.0.0.0/29"),
+		excluded: []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.7/32"),
+			netip.MustParsePrefix("10.0.0.0/31"),
+			netip.MustParsePrefix("10.0.0.6/32"),
+		},
+		want: []netip.Prefix{netip.MustParsePrefix("10.0.0.2/31"), netip.MustParsePrefix("10.0.0.4/31")},
+	}, {
+		name: "unmasked_and_outside",
+		pool: netip.MustParsePrefix("10.0.0.5/30"),
+		excluded: []netip.Prefix{
+			netip.MustParsePrefix("10.0.1.0/24"),
+			netip.MustParsePrefix("10.0.0.6/31"),
+		},
+		want: []netip.Prefix{netip.MustParsePrefix("10.0.0.4/31")},
+	}, {
+		name: "other_family",
+		pool: pool,
+		excluded: []netip.Prefix{
+			netip.MustParsePrefix("::/0"),
+			{},
+		},
+		want: []netip.Prefix{pool},
+	}, {
+		name:     "ipv6",
+		pool:     netip.MustParsePrefix("2001:db8::/120"),
+		excluded: []netip.Prefix{netip.MustParsePrefix("2001:db8::/121")},
+		want:     []netip.Prefix{netip.MustParsePrefix("2001:db8::80/121")},
+	}, {
+		name:     "invalid_pool",
+		pool:     netip.Prefix{},
+		excluded: []netip.Prefix{pool},
+		want:     nil,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, SubtractAddrs(tc.pool, tc.excluded))
+		})
+	}
+}
+
+func TestParseAddrRange(t *testing.T) {
+	testCases := []struct {
+		name       string
+		in         string
+		wantErrMsg string
+		wantStart  netip.Addr
+		wantEnd    netip.Addr
+	}{{
+		name:       "full",
+		in:         "192.168.1.10-192.168.1.50",
+		wantErrMsg: "",
+		wantStart:  netip.MustParseAddr("192.168.1.10"),
+		wantEnd:    netip.MustParseAddr("192.168.1.50"),
+	}, {
+		name:       "short",
+		in:         "192.168.1.10-50",
+		wantErrMsg: "",
+		wantStart:  netip.MustParseAddr("192.168.1.10"),
+		wantEnd:    netip.MustParseAddr("192.168.1.50"),
+	}, {
+		name:       "cidr",
+		in:         "192.168.1.1/24",
+		wantErrMsg: "",
+		wantStart:  netip.MustParseAddr("192.168.1.0"),
+		wantEnd:    netip.MustParseAddr("192.168.1.255"),
+	}, {
+		name:       "single",
+		in:         "192.168.1.10",
+		wantErrMsg: "",
+		wantStart:  netip.MustParseAddr("192.168.1.10"),
+		wantEnd:    netip.MustParseAddr("192.168.1.10"),
+	}, {
+		name:       "ipv6",
+		in:         "2001:db8::1-2001:db8::ff",
+		wantErrMsg: "",
+		wantStart:  netip.MustParseAddr("2001:db8::1"),
+		wantEnd:    netip.MustParseAddr("2001:db8::ff"),
+	}, {
+		name:       "ipv6_cidr",
+		in:         "2001:db8::/120",
+		wantErrMsg: "",
+		wantStart:  netip.MustParseAddr("2001:db8::"),
+		wantEnd:    netip.MustParseAddr("2001:db8::ff"),
+	}, {
+		name:       "family_mismatch",
+		in:         "192.168.1.10-2001:db8::1",
+		wantErrMsg: `bad range "192.168.1.10-2001:db8::1": family mismatch`,
+		wantStart:  netip.Addr{},
+		wantEnd:    netip.Addr{},
+	}, {
+		name:       "inverted",
+		in:         "192.168.1.10-5",
+		wantErrMsg: `bad range "192.168.1.10-5": 192.168.1.10 is greater than 192.168.1.5`,
+		wantStart:  netip.Addr{},
+		wantEnd:    netip.Addr{},
+	}, {
+		name:       "bad_octet",
+		in:         "192.168.1.10-256",
+		wantErrMsg: `bad range "192.168.1.10-256": bad last octet "256"`,
+		wantStart:  netip.Addr{},
+		wantEnd:    netip.Addr{},
+	}, {
+		name: "bad_start",
+		in:   "bad-192.168.1.1",
+		wantErrMsg: `bad range "bad-192.168.1.1": ParseAddr("bad"): ` +
+			`unable to parse IP`,
+		wantStart: netip.Addr{},
+		wantEnd:   netip.Addr{},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start, end, err := ParseAddrRange(tc.in)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.wantStart, start)
+			assert.Equal(t, tc.wantEnd, end)
+		})
+	}
+}
+
+func TestParseHostCIDR(t *testing.T) {
+	testCases := []struct {
+		name          string
+		in            string
+		wantErrMsg    string
+		wantStrictMsg string
+		wantAddr      netip.Addr
+		wantPrefix    netip.Prefix
+	}{{
+		name:          "host",
+		in:            "192.168.1.5/24",
+		wantErrMsg:    "",
+		wantStrictMsg: `bad cidr "192.168.1.5/24": host bits of 192.168.1.5 are set beyond /24`,
+		wantAddr:      netip.MustParseAddr("192.168.1.5"),
+		wantPrefix:    netip.MustParsePrefix("192.168.1.0/24"),
+	}, {
+		name:          "network",
+		in:            "192.168.1.0/24",
+		wantErrMsg:    "",
+		wantStrictMsg: "",
+		wantAddr:      netip.MustParseAddr("192.168.1.0"),
+		wantPrefix:    netip.MustParsePrefix("192.168.1.0/24"),
+	}, {
+		name:          "ipv6_host",
+		in:            "2001:db8::5/64",
+		wantErrMsg:    "",
+		wantStrictMsg: `bad cidr "2001:db8::5/64": host bits of 2001:db8::5 are set beyond /64`,
+		wantAddr:      netip.MustParseAddr("2001:db8::5"),
+		wantPrefix:    netip.MustParsePrefix("2001:db8::/64"),
+	}, {
+		name: "bad_mask",
+		in:   "192.168.1.5/33",
+		wantErrMsg: `bad cidr "192.168.1.5/33": netip.ParsePrefix("192.168.1.5/33": ` +
+			`prefix length out of range`,
+		wantStrictMsg: `bad cidr "192.168.1.5/33": netip.ParsePrefix("192.168.1.5/33": ` +
+			`prefix length out of range`,
+		wantAddr:   netip.Addr{},
+		wantPrefix: netip.Prefix{},
+	}, {
+		name: "no_mask",
+		in:   "192.168.1.5",
+		wantErrMsg: `bad cidr "192.168.1.5": netip.ParsePrefix("192.168.1.5"): ` +
+			`no '/'`,
+		wantStrictMsg: `bad cidr "192.168.1.5": netip.ParsePrefix("192.168.1.5"): ` +
+			`no '/'`,
+		wantAddr:   netip.Addr{},
+		wantPrefix: netip.Prefix{},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, prefix, err := ParseHostCIDR(tc.in)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.wantAddr, addr)
+			assert.Equal(t, tc.wantPrefix, prefix)
+
+			if err == nil {
+				assert.Equal(t, tc.in, netip.PrefixFrom(addr, prefix.Bits()).String())
+			}
+
+			_, _, err = ParseHostCIDRStrict(tc.in)
+			testutil.AssertErrorMsg(t, tc.wantStrictMsg, err)
+		})
+	}
+}
+
+func TestAddrRangeToPrefixes(t *testing.T) {
+	testCases := []struct {
+		name  string
+		start netip.Addr
+		end   netip.Addr
+		want  []netip.Prefix
+	}{{
+		name:  "single",
+		start: netip.MustParseAddr("192.168.1.1"),
+		end:   netip.MustParseAddr("192.168.1.1"),
+		want:  []netip.Prefix{netip.MustParsePrefix("192.168.1.1/32")},
+	}, {
+		name:  "aligned",
+		start: netip.MustParseAddr("192.168.1.0"),
+		end:   netip.MustParseAddr("192.168.1.255"),
+		want:  []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")},
+	}, {
+		name:  "unaligned",
+		start: netip.MustParseAddr("192.168.1.10"),
+		end:   netip.MustParseAddr("192.168.1.50"),
+		want: []netip.Prefix{
+			netip.MustParsePrefix("192.168.1.10/31"),
+			netip.MustParsePrefix("192.168.1.12/30"),
+			netip.MustParsePrefix("192.168.1.16/28"),
+			netip.MustParsePrefix("192.168.1.32/28"),
+			netip.MustParsePrefix("192.168.1.48/31"),
+			netip.MustParsePrefix("192.168.1.50/32"),
+		},
+	}, {
+		name:  "all_ipv4",
+		start: netip.MustParseAddr("0.0.0.0"),
+		end:   netip.MustParseAddr("255.255.255.255"),
+		want:  []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")},
+	}, {
+		name:  "ipv6_end",
+		start: netip.MustParseAddr("ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe"),
+		end:   netip.MustParseAddr("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+		want: []netip.Prefix{
+			netip.MustParsePrefix("ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/127"),
+		},
+	}, {
+		name:  "inverted",
+		start: netip.MustParseAddr("192.168.1.2"),
+		end:   netip.MustParseAddr("192.168.1.1"),
+		want:  nil,
+	}, {
+		name:  "family_mismatch",
+		start: netip.MustParseAddr("192.168.1.1"),
+		end:   netip.MustParseAddr("2001:db8::1"),
+		want:  nil,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, AddrRangeToPrefixes(tc.start, tc.end))
+		})
+	}
+}
+
+func TestBroadcastFromPrefix(t *testing.T) {
+	testCases := []struct {
+		name string
+		pref netip.Prefix
+		want netip.Addr
+	}{{
+		name: "ipv4",
+		pref: netip.MustParsePrefix("192.168.0.1/24"),
+		want: netip.MustParseAddr("192.168.0.255"),
+	}, {
+		name: "ipv4_unaligned",
+		pref: netip.MustParsePrefix("192.168.0.1/20"),
+		want: netip.MustParseAddr("192.168.15.255"),
+	}, {
+		name: "ipv4_host",
+		pref: netip.MustParsePrefix("192.168.1.2/32"),
+		want: netip.MustParseAddr("192.168.1.2"),
+	}, {
+		name: "unspecified",
+		pref: netip.MustParsePrefix("0.0.0.0/0"),
+		want: netip.AddrFrom4([4]byte{255, 255, 255, 255}),
+	}, {
+		name: "ipv6",
+		pref: netip.MustParsePrefix("2001:db8::1/64"),
+		want: netip.MustParseAddr("2001:db8::ffff:ffff:ffff:ffff"),
+	}, {
+		name: "ipv6_host",
+		pref: netip.MustParsePrefix("2001:db8::1/128"),
+		want: netip.MustParseAddr("2001:db8::1"),
+	}, {
+		name: "invalid",
+		pref: netip.Prefix{},
+		want: netip.Addr{},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, BroadcastFromPrefix(tc.pref))
+		})
+	}
+}
+
+func TestReverseAddr(t *testing.T) {
+	testCases := []struct {
+		name       string
+		want       string
+		wantErrMsg string
+		ip         netip.Addr
+	}{{
+		// See RFC 1035, section 3.5.
+		name:       "ipv4",
+		want:       "52.0.2.10.in-addr.arpa.",
+		wantErrMsg: "",
+		ip:         netip.MustParseAddr("10.2.0.52"),
+	}, {
+		// See RFC 3596, section 2.5.
+		name: "ipv6",
+		want: "b.a.9.8.7.6.5.0.4.0.0.0.3.0.0.0.2.0.0.0.1.0.0.0.0.0.0.0.1.2.3.4" +
+			".ip6.arpa.",
+		wantErrMsg: "",
+		ip:         netip.MustParseAddr("4321:0:1:2:3:4:567:89ab"),
+	}, {
+		name:       "ipv4_mapped",
+		want:       "4.3.2.1.in-addr.arpa.",
+		wantErrMsg: "",
+		ip:         netip.MustParseAddr("::ffff:1.2.3.4"),
+	}, {
+		name:       "ipv6_zone",
+		want:       "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.e.f.ip6.arpa.",
+		wantErrMsg: "",
+		ip:         netip.MustParseAddr("fe80::1%eth0"),
+	}, {
+		name:       "invalid",
+		want:       "",
+		wantErrMsg: "invalid ip address",
+		ip:         netip.Addr{},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			arpa, err := ReverseAddr(tc.ip)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.want, arpa)
+		})
+	}
+}
+
+func TestReverseZones(t *testing.T) {
+	testCases := []struct {
+		name       string
+		wantErrMsg string
+		want       []string
+		prefix     netip.Prefix
+	}{{
+		name:       "ipv4_24",
+		wantErrMsg: "",
+		want:       []string{"1.168.192.in-addr.arpa."},
+		prefix:     netip.MustParsePrefix("192.168.1.0/24"),
+	}, {
+		name:       "ipv4_23",
+		wantErrMsg: "",
+		want:       []string{"0.168.192.in-addr.arpa.", "1.168.192.in-addr.arpa."},
+		prefix:     netip.MustParsePrefix("192.168.1.0/23"),
+	}, {
+		name:       "ipv4_25",
+		wantErrMsg: "",
+		want:       []string{"1.168.192.in-addr.arpa."},
+		prefix:     netip.MustParsePrefix("192.168.1.128/25"),
+	}, {
+		name:       "ipv4_16",
+		wantErrMsg: "",
+		want:       []string{"168.192.in-addr.arpa."},
+		prefix:     netip.MustParsePrefix("192.168.0.0/16"),
+	}, {
+		name:       "ipv4_mapped",
+		wantErrMsg: "",
+		want:       []string{"2.0.10.in-addr.arpa."},
+		prefix:     netip.MustParsePrefix("::ffff:10.0.2.0/120"),
+	}, {
+		name:       "ipv4_last",
+		wantErrMsg: "",
+		want:       []string{"254.255.255.in-addr.arpa.", "255.255.255.in-addr.arpa."},
+		prefix:     netip.MustParsePrefix("255.255.254.0/23"),
+	}, {
+		name:       "ipv6_64",
+		wantErrMsg: "",
+		want:       []string{"0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+		prefix:     netip.MustParsePrefix("2001:db8::/64"),
+	}, {
+		name:       "ipv6_63",
+		wantErrMsg: "",
+		want: []string{
+			"0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+			"1.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+		},
+		prefix: netip.MustParsePrefix("2001:db8::/63"),
+	}, {
+		name:       "invalid",
+		wantErrMsg: "reverse zones for invalid Prefix: invalid prefix",
+		want:       nil,
+		prefix:     netip.Prefix{},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			zones, err := ReverseZones(tc.prefix)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Equal(t, tc.want, zones)
+		})
+	}
+}
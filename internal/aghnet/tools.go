@@ -0,0 +1,73 @@
+package aghnet
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// ErrToolMissing is returned by the functions which rely on external network
+// tools, like ip or nmcli, when those aren't found in PATH.  The actual error
+// is always *ToolMissingError.
+const ErrToolMissing errors.Error = "network tool is missing"
+
+// ToolMissingError is returned when an external tool required for some
+// capability isn't found in PATH.
+type ToolMissingError struct {
+	// Err is the error the function would return without the tool anyway, if
+	// any.  For example, it's ErrNoStaticIPInfo when the static IP can't be
+	// detected.
+	Err error
+
+	// Tool is the name of the missing executable.
+	Tool string
+
+	// Capability is the human-readable description of the functionality which
+	// requires the tool.
+	Capability string
+}
+
+// type check
+var _ error = (*ToolMissingError)(nil)
+
+// Error implements the error interface for *ToolMissingError.
+func (err *ToolMissingError) Error() (msg string) {
+	return fmt.Sprintf("%s requires %q, which is not found in PATH", err.Capability, err.Tool)
+}
+
+// Is implements the interface used by errors.Is for *ToolMissingError.  It
+// returns true if target is ErrToolMissing.
+func (err *ToolMissingError) Is(target error) (ok bool) {
+	return target == ErrToolMissing
+}
+
+// Unwrap implements the errors.Wrapper interface for *ToolMissingError.
+func (err *ToolMissingError) Unwrap() (unwrapped error) {
+	return err.Err
+}
+
+// reportedMissingTools are the names of the missing tools which have already
+// been logged.
+var reportedMissingTools = &sync.Map{}
+
+// lookTool checks if the tool is available in PATH.  If it isn't, tmErr
+// describes the capability which requires it, and the absence is logged once
+// per tool.
+func lookTool(tool, capability string) (tmErr *ToolMissingError) {
+	_, err := execLookPath(tool)
+	if err == nil {
+		return nil
+	}
+
+	tmErr = &ToolMissingError{
+		Tool:       tool,
+		Capability: capability,
+	}
+
+	if _, loaded := reportedMissingTools.LoadOrStore(tool, struct{}{}); !loaded {
+		currentLogger().Info("%s", tmErr)
+	}
+
+	return tmErr
+}
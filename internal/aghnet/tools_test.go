@@ -0,0 +1,43 @@
+package aghnet
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookTool(t *testing.T) {
+	var looked []string
+	substLookPath(t, func(file string) (path string, err error) {
+		looked = append(looked, file)
+		if file == "missing" {
+			return "", exec.ErrNotFound
+		}
+
+		return "/usr/bin/" + file, nil
+	})
+
+	tmErr := lookTool("present", "testing")
+	assert.Nil(t, tmErr)
+
+	tmErr = lookTool("missing", "gateway detection")
+	require.NotNil(t, tmErr)
+
+	var err error = tmErr
+	assert.ErrorIs(t, err, ErrToolMissing)
+	assert.Equal(t, `gateway detection requires "missing", which is not found in PATH`, err.Error())
+
+	tmErr.Err = ErrNoStaticIPInfo
+	assert.ErrorIs(t, err, ErrNoStaticIPInfo)
+
+	wrapped := errors.Annotate(err, "wrapped: %w")
+	target := &ToolMissingError{}
+	require.ErrorAs(t, wrapped, &target)
+
+	assert.Equal(t, "missing", target.Tool)
+	assert.Equal(t, "gateway detection", target.Capability)
+	assert.Equal(t, []string{"present", "missing"}, looked)
+}
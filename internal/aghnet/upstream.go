@@ -0,0 +1,159 @@
+package aghnet
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// Upstream DNS address schemes.
+const (
+	UpstreamSchemeUDP   = "udp"
+	UpstreamSchemeTCP   = "tcp"
+	UpstreamSchemeTLS   = "tls"
+	UpstreamSchemeHTTPS = "https"
+	UpstreamSchemeH3    = "h3"
+	UpstreamSchemeQUIC  = "quic"
+)
+
+// upstreamDefaultPorts are the default ports of the supported upstream DNS
+// address schemes.
+var upstreamDefaultPorts = map[string]int{
+	UpstreamSchemeUDP:   53,
+	UpstreamSchemeTCP:   53,
+	UpstreamSchemeTLS:   853,
+	UpstreamSchemeHTTPS: 443,
+	UpstreamSchemeH3:    443,
+	UpstreamSchemeQUIC:  853,
+}
+
+// UpstreamAddr is the parsed address of an upstream DNS server.
+type UpstreamAddr struct {
+	// Domains are the domains the upstream is specified for, if any.  An
+	// empty string means unqualified names.
+	Domains []string
+
+	// Scheme is the lowercased scheme of the address.  It's
+	// UpstreamSchemeUDP if the address has no scheme.
+	Scheme string
+
+	// Host is the hostname or the IP address of the upstream, without square
+	// brackets.
+	Host string
+
+	// Path is the path of the DNS-over-HTTPS address.  It's empty for other
+	// schemes.
+	Path string
+
+	// Port is the port of the upstream, the scheme's default one if the
+	// address has no port.
+	Port int
+}
+
+// String implements the fmt.Stringer interface for UpstreamAddr.  The domains
+// aren't included.  The plain DNS addresses are formatted without the scheme.
+func (u UpstreamAddr) String() (s string) {
+	hostport := net.JoinHostPort(u.Host, strconv.Itoa(u.Port))
+	if u.Scheme == UpstreamSchemeUDP {
+		return hostport
+	}
+
+	return u.Scheme + "://" + hostport + u.Path
+}
+
+// ParseUpstreamAddr parses the upstream DNS server address.  The following
+// forms are supported:
+//
+//   - a plain DNS address with an optional port, e.g. "1.1.1.1", "1.1.1.1:53",
+//     "[::1]:5353", or "::1";
+//   - an address with one of the schemes, e.g. "tcp://1.1.1.1",
+//     "tls://dns.google", or "https://dns.google/dns-query";
+//   - any of the above prefixed with the domains, e.g.
+//     "[/example.com/example.org/]8.8.8.8".
+//
+// The host must be a valid IP address or domain name.  DNS stamps aren't
+// supported.
+func ParseUpstreamAddr(s string) (u UpstreamAddr, err error) {
+	defer func() { err = errors.Annotate(err, "parsing upstream %q: %w", s) }()
+
+	var addr string
+	u.Domains, addr, err = splitUpstreamDomains(strings.TrimSpace(s))
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return UpstreamAddr{}, err
+	} else if addr == "" {
+		return UpstreamAddr{}, errors.Error("empty address")
+	}
+
+	u.Scheme = UpstreamSchemeUDP
+	if i := strings.Index(addr, "://"); i >= 0 {
+		u.Scheme, addr = strings.ToLower(addr[:i]), addr[i+len("://"):]
+	}
+
+	defPort, ok := upstreamDefaultPorts[u.Scheme]
+	if !ok {
+		return UpstreamAddr{}, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	if i := strings.IndexByte(addr, '/'); i >= 0 {
+		if u.Scheme != UpstreamSchemeHTTPS && u.Scheme != UpstreamSchemeH3 {
+			return UpstreamAddr{}, fmt.Errorf("unexpected path %q", addr[i:])
+		}
+
+		addr, u.Path = addr[:i], addr[i:]
+	}
+
+	u.Host, u.Port, err = SplitHostPortDefault(addr, defPort)
+	if err != nil {
+		// Don't wrap the error, because it's informative enough as is.
+		return UpstreamAddr{}, err
+	}
+
+	return u, validateUpstreamHost(u.Host)
+}
+
+// splitUpstreamDomains splits the domains specification, if any, from the
+// upstream address s and validates the domains.
+func splitUpstreamDomains(s string) (domains []string, addr string, err error) {
+	if !strings.HasPrefix(s, "[/") {
+		return nil, s, nil
+	}
+
+	end := strings.Index(s, "/]")
+	if end < 0 {
+		return nil, "", errors.Error("missing closing /] in domains")
+	}
+
+	domains = strings.Split(s[len("[/"):end], "/")
+	for i, d := range domains {
+		if d == "" {
+			continue
+		}
+
+		err = ValidateDomainName(strings.TrimPrefix(d, "*."))
+		if err != nil {
+			return nil, "", fmt.Errorf("domain at index %d: %w", i, err)
+		}
+	}
+
+	return domains, s[end+len("/]"):], nil
+}
+
+// validateUpstreamHost returns an error if host is neither a valid IP address
+// nor a valid domain name.
+func validateUpstreamHost(host string) (err error) {
+	if _, err = netip.ParseAddr(host); err == nil {
+		return nil
+	}
+
+	err = ValidateDomainName(host)
+	if err != nil {
+		return fmt.Errorf("bad host: %w", err)
+	}
+
+	return nil
+}
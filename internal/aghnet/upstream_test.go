@@ -0,0 +1,141 @@
+package aghnet
+
+import (
+	"testing"
+
+	"github.com/AdguardTeam/golibs/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUpstreamAddr(t *testing.T) {
+	testCases := []struct {
+		want       UpstreamAddr
+		name       string
+		in         string
+		wantStr    string
+		wantErrMsg string
+	}{{
+		want: UpstreamAddr{
+			Scheme: UpstreamSchemeUDP,
+			Host:   "1.1.1.1",
+			Port:   53,
+		},
+		name:       "plain",
+		in:         "1.1.1.1",
+		wantStr:    "1.1.1.1:53",
+		wantErrMsg: "",
+	}, {
+		want: UpstreamAddr{
+			Scheme: UpstreamSchemeUDP,
+			Host:   "1.1.1.1",
+			Port:   53,
+		},
+		name:       "plain_port",
+		in:         "1.1.1.1:53",
+		wantStr:    "1.1.1.1:53",
+		wantErrMsg: "",
+	}, {
+		want: UpstreamAddr{
+			Scheme: UpstreamSchemeUDP,
+			Host:   "::1",
+			Port:   5353,
+		},
+		name:       "ipv6_port",
+		in:         "[::1]:5353",
+		wantStr:    "[::1]:5353",
+		wantErrMsg: "",
+	}, {
+		want: UpstreamAddr{
+			Scheme: UpstreamSchemeUDP,
+			Host:   "::1",
+			Port:   53,
+		},
+		name:       "ipv6_no_brackets",
+		in:         "::1",
+		wantStr:    "[::1]:53",
+		wantErrMsg: "",
+	}, {
+		want: UpstreamAddr{
+			Scheme: UpstreamSchemeTLS,
+			Host:   "dns.google",
+			Port:   853,
+		},
+		name:       "tls",
+		in:         "TLS://dns.google",
+		wantStr:    "tls://dns.google:853",
+		wantErrMsg: "",
+	}, {
+		want: UpstreamAddr{
+			Scheme: UpstreamSchemeHTTPS,
+			Host:   "dns.google",
+			Path:   "/dns-query",
+			Port:   443,
+		},
+		name:       "https",
+		in:         "https://dns.google/dns-query",
+		wantStr:    "https://dns.google:443/dns-query",
+		wantErrMsg: "",
+	}, {
+		want: UpstreamAddr{
+			Domains: []string{"example.com", "*.example.org"},
+			Scheme:  UpstreamSchemeUDP,
+			Host:    "8.8.8.8",
+			Port:    53,
+		},
+		name:       "domains",
+		in:         "[/example.com/*.example.org/]8.8.8.8",
+		wantStr:    "8.8.8.8:53",
+		wantErrMsg: "",
+	}, {
+		want:       UpstreamAddr{},
+		name:       "empty",
+		in:         " ",
+		wantStr:    "",
+		wantErrMsg: `parsing upstream " ": empty address`,
+	}, {
+		want:       UpstreamAddr{},
+		name:       "bad_scheme",
+		in:         "sdns://AQ",
+		wantStr:    "",
+		wantErrMsg: `parsing upstream "sdns://AQ": unsupported scheme "sdns"`,
+	}, {
+		want:       UpstreamAddr{},
+		name:       "unexpected_path",
+		in:         "tls://dns.google/path",
+		wantStr:    "",
+		wantErrMsg: `parsing upstream "tls://dns.google/path": unexpected path "/path"`,
+	}, {
+		want:       UpstreamAddr{},
+		name:       "bad_domains",
+		in:         "[/example.com]8.8.8.8",
+		wantStr:    "",
+		wantErrMsg: `parsing upstream "[/example.com]8.8.8.8": missing closing /] in domains`,
+	}, {
+		want:       UpstreamAddr{},
+		name:       "bad_port",
+		in:         "1.1.1.1:0",
+		wantStr:    "",
+		wantErrMsg: `parsing upstream "1.1.1.1:0": bad port in address "1.1.1.1:0": port 0 is out of range [1, 65535]`,
+	}, {
+		want:    UpstreamAddr{},
+		name:    "bad_host",
+		in:      "tls://bad_host",
+		wantStr: "",
+		wantErrMsg: `parsing upstream "tls://bad_host": bad host: ` +
+			`bad domain name "bad_host": bad domain name label "bad_host": ` +
+			`bad domain name label rune '_'`,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := ParseUpstreamAddr(tc.in)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+			if tc.wantErrMsg != "" {
+				return
+			}
+
+			assert.Equal(t, tc.want, u)
+			assert.Equal(t, tc.wantStr, u.String())
+		})
+	}
+}
@@ -0,0 +1,70 @@
+//go:build darwin
+// +build darwin
+
+package aghnet
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AdguardTeam/AdGuardHome/internal/aghos"
+)
+
+// wirelessHardwarePorts are the names of the macOS hardware ports of the
+// wireless network interfaces.  AirPort is the name used by the older versions
+// of macOS.
+var wirelessHardwarePorts = []string{"Wi-Fi", "AirPort"}
+
+// isWireless returns true if the hardware port of the network interface, as
+// reported by networksetup, is a wireless one.  The interfaces not backed by
+// any hardware port, like bridges and tunnels, aren't wireless.
+func isWireless(ifaceName string) (ok bool, err error) {
+	if tmErr := lookTool("networksetup", "wireless interface detection"); tmErr != nil {
+		return false, tmErr
+	}
+
+	_, out, err := aghos.RunCommand("networksetup", "-listallhardwareports")
+	if err != nil {
+		return false, fmt.Errorf("listing hardware ports: %w", err)
+	}
+
+	port := parseHardwarePorts(out)[ifaceName]
+	for _, p := range wirelessHardwarePorts {
+		if port == p {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// hardwarePortRe matches the pair of lines describing a single hardware port in
+// the output of `networksetup -listallhardwareports`.
+var hardwarePortRe = regexp.MustCompile(`(?m)^Hardware Port: (.+)\nDevice: (.*)$`)
+
+// parseHardwarePorts parses the output of the `networksetup
+// -listallhardwareports` command.  It returns a map where the key is the BSD
+// interface name, and the value is the hardware port name.  The output looks
+// like:
+//
+//   Hardware Port: Wi-Fi
+//   Device: en0
+//   Ethernet Address: 00:11:22:33:44:55
+//
+//   Hardware Port: Thunderbolt Bridge
+//   Device: bridge0
+//   Ethernet Address: N/A
+func parseHardwarePorts(out string) (ports map[string]string) {
+	ports = map[string]string{}
+	for _, m := range hardwarePortRe.FindAllStringSubmatch(out, -1) {
+		port, device := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		if device == "" {
+			continue
+		}
+
+		ports[device] = port
+	}
+
+	return ports
+}
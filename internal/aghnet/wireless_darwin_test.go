@@ -0,0 +1,32 @@
+//go:build darwin
+// +build darwin
+
+package aghnet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHardwarePorts(t *testing.T) {
+	const out = nl +
+		`Hardware Port: Wi-Fi` + nl +
+		`Device: en0` + nl +
+		`Ethernet Address: 00:11:22:33:44:55` + nl +
+		nl +
+		`Hardware Port: Thunderbolt Bridge` + nl +
+		`Device: bridge0` + nl +
+		`Ethernet Address: N/A` + nl +
+		nl +
+		`Hardware Port: Empty` + nl +
+		`Device: ` + nl +
+		nl +
+		`VLAN Configurations` + nl +
+		`===================` + nl
+
+	assert.Equal(t, map[string]string{
+		"en0":     "Wi-Fi",
+		"bridge0": "Thunderbolt Bridge",
+	}, parseHardwarePorts(out))
+}
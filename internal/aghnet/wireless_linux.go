@@ -0,0 +1,21 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"io/fs"
+	"path"
+)
+
+// isWireless returns true if the sysfs directory of the network interface has
+// the wireless extensions or the cfg80211 PHY link.
+func isWireless(ifaceName string) (ok bool, err error) {
+	_, err = fs.Stat(rootDirFS, path.Join(sysClassNetPath, ifaceName))
+	if err != nil {
+		// Don't wrap the error, because it already contains the path.
+		return false, err
+	}
+
+	return sysfsIfaceHas(ifaceName, "wireless") || sysfsIfaceHas(ifaceName, "phy80211"), nil
+}
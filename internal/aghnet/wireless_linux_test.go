@@ -0,0 +1,66 @@
+//go:build linux
+// +build linux
+
+package aghnet
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsWireless(t *testing.T) {
+	substRootDirFS(t, fstest.MapFS{
+		"sys/class/net/eth0/device":     &fstest.MapFile{Mode: fs.ModeDir},
+		"sys/class/net/wlan0/wireless":  &fstest.MapFile{Mode: fs.ModeDir},
+		"sys/class/net/wlp2s0/phy80211": &fstest.MapFile{Mode: fs.ModeSymlink},
+		"sys/class/net/wlan1/uevent":    &fstest.MapFile{},
+	})
+
+	testCases := []struct {
+		name      string
+		ifaceName string
+		want      bool
+		wantErr   bool
+	}{{
+		name:      "ethernet",
+		ifaceName: "eth0",
+		want:      false,
+		wantErr:   false,
+	}, {
+		name:      "wireless_extensions",
+		ifaceName: "wlan0",
+		want:      true,
+		wantErr:   false,
+	}, {
+		name:      "phy80211",
+		ifaceName: "wlp2s0",
+		want:      true,
+		wantErr:   false,
+	}, {
+		name:      "wireless_name",
+		ifaceName: "wlan1",
+		want:      false,
+		wantErr:   false,
+	}, {
+		name:      "no_iface",
+		ifaceName: "eth1",
+		want:      false,
+		wantErr:   true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, err := IsWireless(tc.ifaceName)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, fs.ErrNotExist)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.want, ok)
+		})
+	}
+}
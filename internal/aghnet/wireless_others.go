@@ -0,0 +1,12 @@
+//go:build !(linux || darwin)
+// +build !linux,!darwin
+
+package aghnet
+
+import "github.com/AdguardTeam/AdGuardHome/internal/aghos"
+
+// isWireless returns an error, since there is no reliable way to tell the
+// wireless network interfaces apart on this OS.
+func isWireless(_ string) (ok bool, err error) {
+	return false, aghos.Unsupported("wireless interface detection")
+}
@@ -0,0 +1,13 @@
+//go:build !(darwin || freebsd || linux || netbsd || openbsd || windows)
+// +build !darwin,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package aghos
+
+import (
+	"io/fs"
+	"os"
+)
+
+func rootDirFS() (fsys fs.FS) {
+	return os.DirFS("/")
+}
@@ -2,6 +2,7 @@ package aghos
 
 import (
 	"bytes"
+	"io/fs"
 	"testing"
 
 	"github.com/AdguardTeam/AdGuardHome/internal/aghio"
@@ -96,3 +97,10 @@ func TestLargestLabeled(t *testing.T) {
 		assert.Equal(t, 1, instances)
 	})
 }
+
+func TestRootDirFS(t *testing.T) {
+	fi, err := fs.Stat(RootDirFS(), ".")
+	require.NoError(t, err)
+
+	assert.True(t, fi.IsDir())
+}
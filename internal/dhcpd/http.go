@@ -124,9 +124,14 @@ func (s *Server) enableDHCP(ifaceName string) (code int, err error) {
 		}
 	}
 
+	var restore aghnet.RestoreFunc
 	if !hasStaticIP {
-		err = aghnet.IfaceSetStaticIP(ifaceName)
-		if err != nil {
+		restore, err = aghnet.IfaceSetStaticIP(ifaceName)
+		if errors.Is(err, aghnet.ErrContainerized) {
+			// The network configuration is managed by the container's
+			// host, so just go on.
+			log.Info("can't set static ip inside a container; going on")
+		} else if err != nil {
 			err = fmt.Errorf("setting static ip: %w", err)
 
 			return http.StatusInternalServerError, err
@@ -135,7 +140,12 @@ func (s *Server) enableDHCP(ifaceName string) (code int, err error) {
 
 	err = s.Start()
 	if err != nil {
-		return http.StatusBadRequest, fmt.Errorf("starting dhcp server: %w", err)
+		err = fmt.Errorf("starting dhcp server: %w", err)
+		if restore != nil {
+			err = errors.WithDeferred(err, errors.Annotate(restore(), "restoring static ip: %w"))
+		}
+
+		return http.StatusBadRequest, err
 	}
 
 	return 0, nil
@@ -445,7 +455,9 @@ func (s *Server) handleDHCPFindActiveServer(w http.ResponseWriter, r *http.Reque
 		},
 	}
 
-	if isStaticIP, serr := aghnet.IfaceHasStaticIP(ifaceName); serr != nil {
+	if aghnet.IsContainerized() {
+		result.V4.StaticIP.Static = "unsupported"
+	} else if isStaticIP, serr := aghnet.IfaceHasStaticIP(ifaceName); serr != nil {
 		result.V4.StaticIP.Static = "error"
 		result.V4.StaticIP.Error = serr.Error()
 	} else if !isStaticIP {
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"net/netip"
 	"strings"
 	"sync"
 	"time"
@@ -93,7 +94,7 @@ func (s *v4Server) validHostnameForClient(cliHostname string, ip net.IP) (hostna
 		hostname = aghnet.GenerateHostname(ip)
 	}
 
-	err = netutil.ValidateDomainName(hostname)
+	err = aghnet.ValidateDomainName(hostname)
 	if err != nil {
 		log.Info("dhcpv4: %s", err)
 		hostname = ""
@@ -333,6 +334,29 @@ func (s *v4Server) rmLease(lease *Lease) (err error) {
 	return errors.Error("lease not found")
 }
 
+// validateStaticLease checks if l can be served within the configured subnet.
+func (s *v4Server) validateStaticLease(l *Lease) (err error) {
+	sn := s.conf.subnet
+	if sn == nil {
+		// TODO(a.garipov, d.seregin): Subnet can be nil when dhcp server is
+		// disabled.
+		return aghnet.ValidateClientMAC(l.HWAddr)
+	}
+
+	gw, _ := netip.AddrFromSlice(sn.IP)
+	ones, _ := sn.Mask.Size()
+	subnet := netip.PrefixFrom(gw.Unmap(), ones)
+
+	var serverIP netip.Addr
+	if len(s.conf.dnsIPAddrs) > 0 {
+		serverIP, _ = netip.AddrFromSlice(s.conf.dnsIPAddrs[0])
+	}
+
+	leaseIP, _ := netip.AddrFromSlice(l.IP)
+
+	return aghnet.ValidateStaticLease(subnet, gw, serverIP, leaseIP, l.HWAddr)
+}
+
 // AddStaticLease adds a static lease.  It is safe for concurrent use.
 func (s *v4Server) AddStaticLease(l *Lease) (err error) {
 	defer func() { err = errors.Annotate(err, "dhcpv4: adding static lease: %w") }()
@@ -343,7 +367,7 @@ func (s *v4Server) AddStaticLease(l *Lease) (err error) {
 
 	l.Expiry = time.Unix(leaseExpireStatic, 0)
 
-	err = netutil.ValidateMAC(l.HWAddr)
+	err = s.validateStaticLease(l)
 	if err != nil {
 		return err
 	}
@@ -354,7 +378,7 @@ func (s *v4Server) AddStaticLease(l *Lease) (err error) {
 			return err
 		}
 
-		err = netutil.ValidateDomainName(hostname)
+		err = aghnet.ValidateDomainName(hostname)
 		if err != nil {
 			return fmt.Errorf("validating hostname: %w", err)
 		}
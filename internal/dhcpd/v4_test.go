@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/AdguardTeam/AdGuardHome/internal/aghnet"
 	"github.com/AdguardTeam/golibs/stringutil"
 	"github.com/AdguardTeam/golibs/testutil"
 	"github.com/insomniacslk/dhcp/dhcpv4"
@@ -107,7 +108,7 @@ func TestV4_AddReplace(t *testing.T) {
 
 	stLeases := []*Lease{{
 		Hostname: "static-1.local",
-		HWAddr:   net.HardwareAddr{0x33, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA},
+		HWAddr:   net.HardwareAddr{0x32, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA},
 		IP:       net.IP{192, 168, 10, 150},
 	}, {
 		Hostname: "static-2.local",
@@ -130,6 +131,46 @@ func TestV4_AddReplace(t *testing.T) {
 	}
 }
 
+func TestV4_AddStaticLease_badMAC(t *testing.T) {
+	testCases := []struct {
+		wantErrMsg string
+		name       string
+		hwAddr     net.HardwareAddr
+	}{{
+		wantErrMsg: "dhcpv4: adding static lease: validating static lease for 192.168.10.150: bad lease mac: mac address is all zeros",
+		name:       "zero",
+		hwAddr:     net.HardwareAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	}, {
+		wantErrMsg: "dhcpv4: adding static lease: validating static lease for 192.168.10.150: bad lease mac: mac address is broadcast",
+		name:       "broadcast",
+		hwAddr:     net.HardwareAddr{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+	}, {
+		wantErrMsg: "dhcpv4: adding static lease: validating static lease for 192.168.10.150: bad lease mac: mac address is multicast",
+		name:       "multicast",
+		hwAddr:     net.HardwareAddr{0x01, 0x00, 0x5E, 0x00, 0x00, 0x01},
+	}, {
+		wantErrMsg: "dhcpv4: adding static lease: validating static lease for 192.168.10.150: bad lease mac: bad mac address length 8, want 6",
+		name:       "eui64",
+		hwAddr:     net.HardwareAddr{0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := defaultSrv(t)
+
+			err := s.AddStaticLease(&Lease{
+				Hostname: "static-1.local",
+				HWAddr:   tc.hwAddr,
+				IP:       net.IP{192, 168, 10, 150},
+			})
+			assert.ErrorIs(t, err, aghnet.ErrLeaseBadMAC)
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+
+			assert.Empty(t, s.GetLeases(LeasesStatic))
+		})
+	}
+}
+
 func TestV4Server_Process_optionsPriority(t *testing.T) {
 	defaultIP := net.IP{192, 168, 1, 1}
 	knownIP := net.IP{1, 2, 3, 4}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/AdguardTeam/AdGuardHome/internal/aghalgo"
 	"github.com/AdguardTeam/AdGuardHome/internal/aghhttp"
+	"github.com/AdguardTeam/AdGuardHome/internal/aghnet"
 	"github.com/AdguardTeam/golibs/log"
 	"github.com/AdguardTeam/golibs/netutil"
 	"github.com/AdguardTeam/golibs/stringutil"
@@ -46,9 +47,9 @@ func processAccessClients(
 ) (err error) {
 	for i, s := range clientStrs {
 		if ip := net.ParseIP(s); ip != nil {
-			ips.Set(ip, unit{})
+			ips.Set(aghnet.CanonicalIP(ip), unit{})
 		} else if cidrIP, ipnet, cidrErr := net.ParseCIDR(s); cidrErr == nil {
-			ipnet.IP = cidrIP
+			ipnet.IP = aghnet.CanonicalIP(cidrIP)
 			*nets = append(*nets, ipnet)
 		} else {
 			idErr := ValidateClientID(s)
@@ -141,6 +142,8 @@ func (a *accessCtx) isBlockedHost(host string) (ok bool) {
 // isBlockedIP returns the status of the IP address blocking as well as the rule
 // that blocked it.
 func (a *accessCtx) isBlockedIP(ip net.IP) (blocked bool, rule string) {
+	ip = aghnet.CanonicalIP(ip)
+
 	blocked = true
 	ips := a.blockedIPs
 	ipnets := a.blockedNets
@@ -102,6 +102,11 @@ func TestIsBlockedIP(t *testing.T) {
 		wantRule:    "1.2.3.4",
 		ip:          net.IP{1, 2, 3, 4},
 		wantBlocked: true,
+	}, {
+		name:        "match_mapped_ip",
+		wantRule:    "1.2.3.4",
+		ip:          net.ParseIP("::ffff:1.2.3.4"),
+		wantBlocked: true,
 	}, {
 		name:        "match_cidr",
 		wantRule:    "5.6.7.8/24",
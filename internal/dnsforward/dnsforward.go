@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"runtime"
 	"strings"
 	"sync"
@@ -405,6 +406,10 @@ func (s *Server) collectDNSIPAddrs() (addrs []string, err error) {
 	return addrs[:i], nil
 }
 
+// filterOurDNSAddrs returns addrs without the IP addresses the server is
+// listening on.  The addresses are compared regardless of their zones, so that
+// the link-local addresses of the network interfaces are filtered out as well.
+// IPv4-mapped IPv6 addresses are considered equal to the IPv4 ones.
 func (s *Server) filterOurDNSAddrs(addrs []string) (filtered []string, err error) {
 	var ourAddrs []string
 	ourAddrs, err = s.collectDNSIPAddrs()
@@ -412,13 +417,27 @@ func (s *Server) filterOurDNSAddrs(addrs []string) (filtered []string, err error
 		return nil, err
 	}
 
-	ourAddrsSet := stringutil.NewSet(ourAddrs...)
+	ourAddrsSet := make(map[netip.Addr]struct{}, len(ourAddrs))
+	for _, a := range ourAddrs {
+		if ip, parseErr := netip.ParseAddr(a); parseErr == nil {
+			ourAddrsSet[aghnet.CanonicalAddr(ip.WithZone(""))] = struct{}{}
+		}
+	}
 
 	// TODO(e.burkov): The approach of subtracting sets of strings is not
 	// really applicable here since in case of listening on all network
 	// interfaces we should check the whole interface's network to cut off
 	// all the loopback addresses as well.
-	return stringutil.FilterOut(addrs, ourAddrsSet.Has), nil
+	return stringutil.FilterOut(addrs, func(a string) (ok bool) {
+		ip, parseErr := netip.ParseAddr(a)
+		if parseErr != nil {
+			return false
+		}
+
+		_, ok = ourAddrsSet[aghnet.CanonicalAddr(ip.WithZone(""))]
+
+		return ok
+	}), nil
 }
 
 // setupResolvers initializes the resolvers for local addresses.  For internal
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"math/big"
 	"net"
+	"net/netip"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -1293,3 +1294,47 @@ func TestServer_Exchange(t *testing.T) {
 		assert.Empty(t, host)
 	})
 }
+
+func TestServer_FilterOurDNSAddrs(t *testing.T) {
+	t.Run("specified", func(t *testing.T) {
+		s := &Server{
+			conf: ServerConfig{
+				UDPListenAddrs: []*net.UDPAddr{{IP: net.ParseIP("fe80::1"), Port: 53}},
+				TCPListenAddrs: []*net.TCPAddr{{IP: net.IP{192, 168, 1, 1}, Port: 53}},
+			},
+		}
+
+		filtered, err := s.filterOurDNSAddrs([]string{
+			"fe80::1%eth0",
+			"fe80::2%eth0",
+			"::ffff:192.168.1.1",
+			"192.168.1.2",
+			"tls://dns.example",
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"fe80::2%eth0", "192.168.1.2", "tls://dns.example"}, filtered)
+	})
+
+	t.Run("unspecified", func(t *testing.T) {
+		ifaceAddrs, err := aghnet.CollectAllIfacesAddrs()
+		require.NoError(t, err)
+
+		// Strip the zones to make sure those don't matter either way.
+		addrs := make([]string, 0, 2*len(ifaceAddrs))
+		for _, a := range ifaceAddrs {
+			addrs = append(addrs, a, netip.MustParseAddr(a).WithZone("").String())
+		}
+
+		s := &Server{
+			conf: ServerConfig{
+				UDPListenAddrs: []*net.UDPAddr{{IP: net.IPv4zero, Port: 53}},
+			},
+		}
+
+		filtered, err := s.filterOurDNSAddrs(addrs)
+		require.NoError(t, err)
+
+		assert.Empty(t, filtered)
+	})
+}
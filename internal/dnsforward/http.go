@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/AdguardTeam/AdGuardHome/internal/aghhttp"
+	"github.com/AdguardTeam/AdGuardHome/internal/aghnet"
 	"github.com/AdguardTeam/dnsproxy/proxy"
 	"github.com/AdguardTeam/dnsproxy/upstream"
 	"github.com/AdguardTeam/golibs/errors"
@@ -200,7 +202,12 @@ func (s *Server) handleSetConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.Upstreams != nil {
-		if err := ValidateUpstreams(*req.Upstreams); err != nil {
+		err := ValidateUpstreams(*req.Upstreams)
+		if err == nil {
+			err = s.validateUpstreamsNotSelf(*req.Upstreams)
+		}
+
+		if err != nil {
 			aghhttp.Error(r, w, http.StatusBadRequest, "wrong upstreams specification: %s", err)
 
 			return
@@ -405,6 +412,73 @@ func ValidateUpstreams(upstreams []string) (err error) {
 	return nil
 }
 
+// validateUpstreamsNotSelf returns an error if any of upstreams, which are
+// expected to be validated with ValidateUpstreams, is an IP address of one of
+// the DNS listeners of s, see aghnet.IsSelfAddr, so that using it would cause
+// a query loop.  The hostnames aren't resolved.
+func (s *Server) validateUpstreamsNotSelf(upstreams []string) (err error) {
+	s.serverLock.RLock()
+	defer s.serverLock.RUnlock()
+
+	for _, u := range stringutil.FilterOut(upstreams, IsCommentOrEmpty) {
+		ua, parseErr := aghnet.ParseUpstreamAddr(u)
+		if parseErr != nil {
+			// The special address "#" and the DNS stamps aren't supported by
+			// ParseUpstreamAddr.
+			continue
+		}
+
+		ip, parseErr := netip.ParseAddr(ua.Host)
+		if parseErr != nil {
+			continue
+		}
+
+		addr := netip.AddrPortFrom(ip, uint16(ua.Port))
+		if aghnet.IsSelfAddr(addr, s.listenAddrPorts(ua.Scheme)) {
+			return fmt.Errorf("upstream %q points to this server, which would cause a loop", u)
+		}
+	}
+
+	return nil
+}
+
+// listenAddrPorts returns the addresses s listens on for the DNS queries over
+// the protocol of the upstream scheme.  s.serverLock is expected to be locked.
+func (s *Server) listenAddrPorts(scheme string) (addrs []netip.AddrPort) {
+	switch scheme {
+	case aghnet.UpstreamSchemeUDP:
+		for _, a := range s.conf.UDPListenAddrs {
+			addrs = append(addrs, listenAddrPort(a.IP, a.Port))
+		}
+	case aghnet.UpstreamSchemeTCP:
+		for _, a := range s.conf.TCPListenAddrs {
+			addrs = append(addrs, listenAddrPort(a.IP, a.Port))
+		}
+	case aghnet.UpstreamSchemeTLS:
+		for _, a := range s.conf.TLSListenAddrs {
+			addrs = append(addrs, listenAddrPort(a.IP, a.Port))
+		}
+	case aghnet.UpstreamSchemeQUIC:
+		for _, a := range s.conf.QUICListenAddrs {
+			addrs = append(addrs, listenAddrPort(a.IP, a.Port))
+		}
+	}
+
+	return addrs
+}
+
+// listenAddrPort converts the listen address with ip and port into
+// a netip.AddrPort.  An empty ip means all the addresses, like it does for
+// net.ListenUDP and net.ListenTCP.
+func listenAddrPort(ip net.IP, port int) (addr netip.AddrPort) {
+	a, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		a = netip.IPv6Unspecified()
+	}
+
+	return netip.AddrPortFrom(a, uint16(port))
+}
+
 var protocols = []string{"tls://", "https://", "tcp://", "sdns://", "quic://"}
 
 func validateUpstream(u string) (bool, error) {
@@ -234,6 +234,78 @@ func TestDNSForwardHTTP_handleSetConfig(t *testing.T) {
 	}
 }
 
+func TestServer_validateUpstreamsNotSelf(t *testing.T) {
+	s := &Server{
+		conf: ServerConfig{
+			UDPListenAddrs: []*net.UDPAddr{{
+				IP:   net.IP{127, 0, 0, 1},
+				Port: 53,
+			}, {
+				Port: 5353,
+			}},
+			TLSConfig: TLSConfig{
+				TLSListenAddrs: []*net.TCPAddr{{
+					IP:   net.IP{127, 0, 0, 1},
+					Port: 853,
+				}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		upstream   string
+		wantErrMsg string
+	}{{
+		name:       "remote",
+		upstream:   "8.8.8.8",
+		wantErrMsg: "",
+	}, {
+		name:     "udp_self",
+		upstream: "127.0.0.1",
+		wantErrMsg: `upstream "127.0.0.1" points to this server, ` +
+			`which would cause a loop`,
+	}, {
+		name:       "tcp_not_listened",
+		upstream:   "tcp://127.0.0.1",
+		wantErrMsg: "",
+	}, {
+		name:     "wildcard_self",
+		upstream: "[::1]:5353",
+		wantErrMsg: `upstream "[::1]:5353" points to this server, ` +
+			`which would cause a loop`,
+	}, {
+		name:     "tls_self",
+		upstream: "tls://127.0.0.1",
+		wantErrMsg: `upstream "tls://127.0.0.1" points to this server, ` +
+			`which would cause a loop`,
+	}, {
+		name:     "domain_self",
+		upstream: "[/example.com/]127.0.0.1:53",
+		wantErrMsg: `upstream "[/example.com/]127.0.0.1:53" points to this ` +
+			`server, which would cause a loop`,
+	}, {
+		name:       "https",
+		upstream:   "https://127.0.0.1/dns-query",
+		wantErrMsg: "",
+	}, {
+		name:       "hostname",
+		upstream:   "localhost",
+		wantErrMsg: "",
+	}, {
+		name:       "default",
+		upstream:   "[/example.com/]#",
+		wantErrMsg: "",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := s.validateUpstreamsNotSelf([]string{"# comment", tc.upstream})
+			testutil.AssertErrorMsg(t, tc.wantErrMsg, err)
+		})
+	}
+}
+
 func TestIsCommentOrEmpty(t *testing.T) {
 	assert.True(t, IsCommentOrEmpty(""))
 	assert.True(t, IsCommentOrEmpty("# comment"))
@@ -36,7 +36,10 @@ func (web *Web) handleInstallGetAddresses(w http.ResponseWriter, r *http.Request
 	}
 
 	ifaces, err := aghnet.GetValidNetInterfacesForWeb()
-	if err != nil {
+	if errors.Is(err, aghnet.ErrNoInterfaces) {
+		// Let the user enter the address manually.
+		log.Info("install: %s", err)
+	} else if err != nil {
 		aghhttp.Error(r, w, http.StatusInternalServerError, "Couldn't get interfaces: %s", err)
 
 		return
@@ -168,6 +171,13 @@ func (web *Web) handleInstallCheckConfig(w http.ResponseWriter, r *http.Request)
 // Or if set=true, it tries to set it
 func handleStaticIP(ip net.IP, set bool) staticIPJSON {
 	resp := staticIPJSON{}
+	if aghnet.IsContainerized() {
+		// Don't offer to configure the host's network from inside a
+		// container.
+		resp.Static = "unsupported"
+
+		return resp
+	}
 
 	interfaceName := aghnet.GetInterfaceByIP(ip)
 	resp.Static = "no"
@@ -178,9 +188,11 @@ func handleStaticIP(ip net.IP, set bool) staticIPJSON {
 		return resp
 	}
 
+	var restore aghnet.RestoreFunc
 	if set {
 		// Try to set static IP for the specified interface
-		err := aghnet.IfaceSetStaticIP(interfaceName)
+		var err error
+		restore, err = aghnet.IfaceSetStaticIP(interfaceName)
 		if err != nil {
 			resp.Static = "error"
 			resp.Error = err.Error()
@@ -191,7 +203,15 @@ func handleStaticIP(ip net.IP, set bool) staticIPJSON {
 	// Fallthrough here even if we set static IP
 	// Check if we have a static IP and return the details
 	isStaticIP, err := aghnet.IfaceHasStaticIP(interfaceName)
+	if err == nil && set && !isStaticIP {
+		err = fmt.Errorf("interface %s has no static ip after setting it", interfaceName)
+	}
+
 	if err != nil {
+		if restore != nil {
+			err = errors.WithDeferred(err, errors.Annotate(restore(), "restoring static ip: %w"))
+		}
+
 		resp.Static = "error"
 		resp.Error = err.Error()
 	} else {
@@ -615,8 +635,15 @@ func (web *Web) handleInstallGetAddressesBeta(w http.ResponseWriter, r *http.Req
 		DNSPort: defaultPortDNS,
 	}
 
-	ifaces, err := aghnet.GetValidNetInterfacesForWeb()
-	if err != nil {
+	ifaces, err := aghnet.GetValidNetInterfacesForWebWithOpts(aghnet.NetInterfacesOpts{
+		WithStats:    true,
+		WithWarnings: true,
+		Sorted:       true,
+	})
+	if errors.Is(err, aghnet.ErrNoInterfaces) {
+		// Let the user enter the address manually.
+		log.Info("install: %s", err)
+	} else if err != nil {
 		aghhttp.Error(r, w, http.StatusInternalServerError, "Couldn't get interfaces: %s", err)
 
 		return
@@ -716,6 +716,10 @@ func cleanup(ctx context.Context) {
 		Context.tls.Close()
 		Context.tls = nil
 	}
+
+	if err = aghnet.CloseNetlink(); err != nil {
+		log.Error("closing netlink: %s", err)
+	}
 }
 
 // This function is called before application exits